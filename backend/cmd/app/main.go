@@ -1,14 +1,15 @@
 package main
 
 import (
+	"errors"
+	"flag"
 	"log"
 	"os"
-	"strconv"
+	_ "time/tzdata" // event time zones must resolve even on images without zoneinfo
 
-	"github.com/HermanPlay/web-app-backend/internal/api/http"
-	"github.com/HermanPlay/web-app-backend/internal/api/http/server"
 	"github.com/HermanPlay/web-app-backend/internal/config"
 	"github.com/joho/godotenv"
+	"github.com/sirupsen/logrus"
 )
 
 func main() {
@@ -18,14 +19,25 @@ func main() {
 			panic(err)
 		}
 	}
-	cfg, err := config.GetConfig()
+	cfg, args, err := config.Load(os.Args[1:])
+	if errors.Is(err, flag.ErrHelp) {
+		return
+	}
 	if err != nil {
-		log.Fatal("No config!")
+		log.Fatalf("Invalid config:\n%v", err)
 	}
+	logrus.SetLevel(cfg.Log.Level)
 
-	init := http.Init(cfg)
-	app := server.Init(init)
+	if len(args) > 0 && args[0] == "migrate" {
+		err = runMigrate(cfg, args[1:])
+		if err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
 
-	log.Println("Server is running on port:", cfg.App.Port)
-	app.Run(":" + strconv.Itoa(cfg.App.Port))
+	err = serve(cfg)
+	if err != nil {
+		log.Fatal(err)
+	}
 }
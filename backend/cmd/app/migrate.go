@@ -0,0 +1,95 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"text/tabwriter"
+
+	"github.com/HermanPlay/web-app-backend/internal/config"
+	"github.com/HermanPlay/web-app-backend/internal/database"
+	"github.com/HermanPlay/web-app-backend/internal/migration"
+)
+
+var errMigrateUsage = errors.New(`usage: app migrate <command>
+
+commands:
+  status        list the migrations and when they were applied
+  up            apply all pending migrations
+  down          revert the last applied migration
+  to <version>  apply or revert migrations until version is the last applied, 0 reverts all`)
+
+// runMigrate runs the migrate subcommand with the arguments after it.
+func runMigrate(cfg *config.Config, args []string) error {
+	if len(args) == 0 {
+		return errMigrateUsage
+	}
+	db, err := database.New(cfg)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	migrator, err := migration.ForDatabase(db.Connect())
+	if err != nil {
+		return err
+	}
+
+	var ran []migration.Migration
+	switch args[0] {
+	case "status":
+		if len(args) != 1 {
+			return errMigrateUsage
+		}
+		return printMigrationStatus(migrator)
+	case "up":
+		if len(args) != 1 {
+			return errMigrateUsage
+		}
+		ran, err = migrator.Up()
+	case "down":
+		if len(args) != 1 {
+			return errMigrateUsage
+		}
+		ran, err = migrator.Down()
+	case "to":
+		if len(args) != 2 {
+			return errMigrateUsage
+		}
+		version, parseErr := strconv.ParseInt(args[1], 10, 64)
+		if parseErr != nil || version < 0 {
+			return errMigrateUsage
+		}
+		ran, err = migrator.To(version)
+	default:
+		return errMigrateUsage
+	}
+
+	for _, m := range ran {
+		fmt.Printf("Ran migration %d_%s\n", m.Version, m.Name)
+	}
+	if err == nil && len(ran) == 0 {
+		fmt.Println("Nothing to migrate")
+	}
+	return err
+}
+
+func printMigrationStatus(migrator *migration.Migrator) error {
+	statuses, err := migrator.Status()
+	if err != nil {
+		return err
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "VERSION\tNAME\tAPPLIED AT")
+	for _, status := range statuses {
+		applied := "pending"
+		if status.AppliedAt != nil {
+			applied = status.AppliedAt.Format("2006-01-02 15:04:05 MST")
+		}
+		if status.Unknown {
+			applied += " (not in this build)"
+		}
+		fmt.Fprintf(w, "%d\t%s\t%s\n", status.Version, status.Name, applied)
+	}
+	return w.Flush()
+}
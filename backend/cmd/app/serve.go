@@ -0,0 +1,94 @@
+package main
+
+import (
+	"context"
+	"log"
+	"net"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/HermanPlay/web-app-backend/internal/api/http"
+	"github.com/HermanPlay/web-app-backend/internal/api/http/server"
+	"github.com/HermanPlay/web-app-backend/internal/config"
+	"github.com/HermanPlay/web-app-backend/internal/tracing"
+	"github.com/sirupsen/logrus"
+)
+
+// serve runs the api and the background work until SIGINT or SIGTERM. On a
+// signal the server stops accepting connections, and the requests and jobs
+// in progress get cfg.App.ShutdownTimeout to finish before the database is
+// closed. A second signal ends the process right away.
+func serve(cfg *config.Config) error {
+	shutdownTracing, err := tracing.Setup(cfg)
+	if err != nil {
+		return err
+	}
+	init := http.Init(cfg)
+	srv := server.NewHTTPServer(cfg, server.Init(init))
+	ln, err := net.Listen("tcp", srv.Addr)
+	if err != nil {
+		init.Database.Close()
+		shutdownTracing(context.Background())
+		return err
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	// Restore the default handling once the first signal arrived, so the next
+	// one ends the process
+	context.AfterFunc(ctx, stop)
+	deadline := make(chan time.Time, 1)
+	context.AfterFunc(ctx, func() {
+		deadline <- time.Now().Add(cfg.App.ShutdownTimeout)
+	})
+
+	var background sync.WaitGroup
+	if init.Scheduler != nil {
+		background.Add(1)
+		go func() {
+			defer background.Done()
+			init.Scheduler.Run(ctx)
+		}()
+	}
+	if init.Workers != nil {
+		background.Add(1)
+		go func() {
+			defer background.Done()
+			init.Workers.Run(ctx)
+		}()
+	}
+
+	log.Println("Server is running on port:", cfg.App.Port)
+	serveErr := server.Serve(ctx, srv, ln, cfg.App.ShutdownTimeout)
+	// Also stops the background work when the server failed on its own
+	stop()
+	log.Println("Server stopped, waiting for background jobs")
+
+	stopped := make(chan struct{})
+	go func() {
+		background.Wait()
+		close(stopped)
+	}()
+	shutdownBy := <-deadline
+	select {
+	case <-stopped:
+	case <-time.After(time.Until(shutdownBy)):
+		logrus.Warn("Background jobs still running after the shutdown timeout, closing the database anyway")
+	}
+
+	err = init.Database.Close()
+	if err != nil {
+		logrus.Error("Could not close the database. Error: ", err)
+	}
+	// Export the spans of the last requests with what is left of the timeout
+	flushCtx, cancel := context.WithDeadline(context.Background(), shutdownBy)
+	defer cancel()
+	err = shutdownTracing(flushCtx)
+	if err != nil {
+		logrus.Error("Could not export the remaining traces. Error: ", err)
+	}
+	return serveErr
+}
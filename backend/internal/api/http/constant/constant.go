@@ -16,20 +16,19 @@ const (
 	AlreadyExists
 	InvalidCredentials
 	NotFound
-)
-
-const (
-	TokenHourLifespan = 1
+	EventFull
+	Forbidden
+	ServiceUnavailable
 )
 
 func (r ResponseStatus) GetResponseStatus() string {
-	return [...]string{"SUCCESS", "DATA_NOT_FOUND", "UNKNOWN_ERROR", "INVALID_REQUEST", "UNAUTHORIZED", "ALREADY_EXISTS", "INVALID_CREDENTIALS", "NOT_FOUND"}[r-1]
+	return [...]string{"SUCCESS", "DATA_NOT_FOUND", "UNKNOWN_ERROR", "INVALID_REQUEST", "UNAUTHORIZED", "ALREADY_EXISTS", "INVALID_CREDENTIALS", "NOT_FOUND", "EVENT_FULL", "FORBIDDEN", "SERVICE_UNAVAILABLE"}[r-1]
 }
 
 func (r ResponseStatus) GetResponseStatusCode() int {
-	return [...]int{http.StatusOK, http.StatusNotFound, http.StatusInternalServerError, http.StatusBadRequest, http.StatusUnauthorized, http.StatusConflict, http.StatusBadRequest, http.StatusNotFound}[r-1]
+	return [...]int{http.StatusOK, http.StatusNotFound, http.StatusInternalServerError, http.StatusBadRequest, http.StatusUnauthorized, http.StatusConflict, http.StatusBadRequest, http.StatusNotFound, http.StatusConflict, http.StatusForbidden, http.StatusServiceUnavailable}[r-1]
 }
 
 func (r ResponseStatus) GetResponseMessage() string {
-	return [...]string{"Success", "Data Not Found", "Unknown Error", "Invalid Request", "Unauthorized", "Already Exists", "Invalid credentials", "Not found"}[r-1]
+	return [...]string{"Success", "Data Not Found", "Unknown Error", "Invalid Request", "Unauthorized", "Already Exists", "Invalid credentials", "Not found", "Event is fully booked", "Forbidden", "Service unavailable"}[r-1]
 }
@@ -1,18 +1,29 @@
 package http
 
 import (
+	"context"
 	"fmt"
+	"time"
 
 	"github.com/HermanPlay/web-app-backend/internal/api/http/routes"
 	"github.com/HermanPlay/web-app-backend/internal/config"
 	"github.com/HermanPlay/web-app-backend/internal/database"
+	"github.com/HermanPlay/web-app-backend/internal/metrics"
+	"github.com/HermanPlay/web-app-backend/internal/migration"
+	"github.com/HermanPlay/web-app-backend/internal/notification"
+	"github.com/HermanPlay/web-app-backend/internal/scheduler"
+	"github.com/HermanPlay/web-app-backend/internal/tracing"
+	"github.com/HermanPlay/web-app-backend/internal/worker"
 	"github.com/HermanPlay/web-app-backend/package/domain/models"
 	"github.com/HermanPlay/web-app-backend/package/repository"
 	"github.com/HermanPlay/web-app-backend/package/service"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
 )
 
 type Initialization struct {
 	Cfg             *config.Config
+	Database        database.Database
 	DevRoute        routes.DevRoute
 	UserRepository  repository.UserRepository
 	UserService     service.UserService
@@ -23,10 +34,23 @@ type Initialization struct {
 	EventRepository repository.EventRepository
 	EventService    service.EventService
 	EventRoute      routes.EventRoute
+	ReminderService service.ReminderService
+	Scheduler       *scheduler.Scheduler // nil when there is no background work
+	JobRepository   repository.JobRepository
+	JobService      service.JobService
+	JobRoute        routes.JobRoute
+	Workers         *worker.Pool // nil when this replica runs no workers
+	WebhookService  service.WebhookService
+	WebhookRoute    routes.WebhookRoute
+	CalendarService service.CalendarService
+	CalendarRoute   routes.CalendarRoute
+	HealthService   service.HealthService
+	HealthRoute     routes.HealthRoute
 }
 
 func NewInitialization(
 	config *config.Config,
+	db database.Database,
 	devRoute routes.DevRoute,
 	userRepo repository.UserRepository,
 	userService service.UserService,
@@ -37,9 +61,22 @@ func NewInitialization(
 	eventRepository repository.EventRepository,
 	eventService service.EventService,
 	eventRoute routes.EventRoute,
+	reminderService service.ReminderService,
+	scheduler *scheduler.Scheduler,
+	jobRepository repository.JobRepository,
+	jobService service.JobService,
+	jobRoute routes.JobRoute,
+	workers *worker.Pool,
+	webhookService service.WebhookService,
+	webhookRoute routes.WebhookRoute,
+	calendarService service.CalendarService,
+	calendarRoute routes.CalendarRoute,
+	healthService service.HealthService,
+	healthRoute routes.HealthRoute,
 ) *Initialization {
 	return &Initialization{
 		Cfg:             config,
+		Database:        db,
 		DevRoute:        devRoute,
 		UserRepository:  userRepo,
 		UserService:     userService,
@@ -50,35 +87,120 @@ func NewInitialization(
 		EventRepository: eventRepository,
 		EventService:    eventService,
 		EventRoute:      eventRoute,
+		ReminderService: reminderService,
+		Scheduler:       scheduler,
+		JobRepository:   jobRepository,
+		JobService:      jobService,
+		JobRoute:        jobRoute,
+		Workers:         workers,
+		WebhookService:  webhookService,
+		WebhookRoute:    webhookRoute,
+		CalendarService: calendarService,
+		CalendarRoute:   calendarRoute,
+		HealthService:   healthService,
+		HealthRoute:     healthRoute,
 	}
 }
 
 func Init(cfg *config.Config) *Initialization {
-	db, err := database.NewPostgresDatabase(cfg)
+	db, err := database.New(cfg)
+	if err != nil {
+		panic(err)
+	}
+	gormDb := db.Connect()
+	err = checkMigrations(gormDb, cfg.Db.Migrations)
+	if err != nil {
+		panic(err)
+	}
+	err = metrics.InstrumentDatabase(gormDb)
+	if err != nil {
+		panic(err)
+	}
+	err = tracing.InstrumentDatabase(gormDb)
 	if err != nil {
 		panic(err)
 	}
-	pgDb := db.Connect()
 	devRouteImpl := routes.NewDevRoute()
-	userRepositoryImpl, err := repository.NewUserRepository(pgDb)
+	userRepositoryImpl, err := repository.NewUserRepository(gormDb)
 	if err != nil {
 		panic(err)
 	}
 	userServiceImpl := service.NewUserService(userRepositoryImpl, cfg)
 	userRouteImpl := routes.NewUserRoute(userServiceImpl)
-	authRepositoryImpl := repository.NewAuthRepository(pgDb, cfg)
-	authServiceImpl := service.NewAuthService(authRepositoryImpl, userRepositoryImpl)
-	authRouteImpl := routes.NewAuthRoute(authServiceImpl)
-	eventRepositoryImpl, err := repository.NewEventRepository(pgDb)
+	authRepositoryImpl, err := repository.NewAuthRepository(gormDb)
+	if err != nil {
+		panic(err)
+	}
+	notifierImpl, err := notification.NewNotifier(cfg)
+	if err != nil {
+		panic(err)
+	}
+	jobRepositoryImpl, err := repository.NewJobRepository(gormDb)
+	if err != nil {
+		panic(err)
+	}
+	transactorImpl := repository.NewTransactor(gormDb)
+	webhookRepositoryImpl, err := repository.NewWebhookRepository(gormDb)
+	if err != nil {
+		panic(err)
+	}
+	webhookServiceImpl := service.NewWebhookService(webhookRepositoryImpl)
+	webhookRouteImpl := routes.NewWebhookRoute(webhookServiceImpl)
+	jobServiceImpl := service.NewJobService(jobRepositoryImpl, map[string]service.JobHandler{
+		service.NotificationJob: service.NewNotificationHandler(notifierImpl),
+		service.WebhookJob:      service.NewWebhookHandler(webhookRepositoryImpl, service.NewWebhookClient()),
+	})
+	jobRouteImpl := routes.NewJobRoute(jobServiceImpl)
+	var workerPool *worker.Pool
+	if cfg.Jobs.Workers > 0 {
+		workerPool = worker.NewPool(jobServiceImpl, cfg.Jobs.Workers, cfg.Jobs.PollInterval)
+	}
+	authServiceImpl := service.NewAuthService(authRepositoryImpl, userRepositoryImpl, jobRepositoryImpl, transactorImpl, cfg)
+	authRouteImpl := routes.NewAuthRoute(authServiceImpl, cfg)
+	eventRepositoryImpl, err := repository.NewEventRepository(gormDb)
+	if err != nil {
+		panic(err)
+	}
+	eventServiceImpl := service.NewEventService(eventRepositoryImpl, webhookRepositoryImpl, jobRepositoryImpl, transactorImpl, cfg)
+	eventRouteImpl := routes.NewEventRoute(eventServiceImpl)
+	calendarServiceImpl := service.NewCalendarService(eventRepositoryImpl, authRepositoryImpl, cfg)
+	calendarRouteImpl := routes.NewCalendarRoute(calendarServiceImpl)
+	reminderRepositoryImpl, err := repository.NewReminderRepository(gormDb)
 	if err != nil {
 		panic(err)
 	}
-	eventServiceImpl := service.NewEventService(eventRepositoryImpl)
-	eventRouteImpl := routes.NewEventRoute(eventServiceImpl, userServiceImpl)
-	initialization := NewInitialization(cfg, devRouteImpl, userRepositoryImpl, userServiceImpl, userRouteImpl, authRepositoryImpl, authServiceImpl, authRouteImpl, eventRepositoryImpl, eventServiceImpl, eventRouteImpl)
+	reminderServiceImpl := service.NewReminderService(reminderRepositoryImpl, jobRepositoryImpl, transactorImpl, cfg)
+	var schedulerImpl *scheduler.Scheduler
+	if cfg.App.ReminderInterval > 0 {
+		schedulerImpl = scheduler.NewScheduler(cfg.App.ReminderInterval)
+		schedulerImpl.Add("reminders", func(ctx context.Context, now time.Time) error {
+			sent, err := reminderServiceImpl.SendDueReminders(ctx, now)
+			if sent > 0 {
+				logrus.Infof("Queued %d event reminders", sent)
+			}
+			return err
+		})
+		// Done jobs are purged on the same schedule
+		schedulerImpl.Add("jobs", func(ctx context.Context, now time.Time) error {
+			purged, err := jobServiceImpl.PurgeDoneJobs(ctx, now)
+			if purged > 0 {
+				logrus.Infof("Purged %d done jobs", purged)
+			}
+			return err
+		})
+	}
+	healthChecks := map[string]service.HealthCheck{
+		"database": service.DatabaseCheck(gormDb),
+	}
+	if cfg.Db.Migrations != "ignore" {
+		healthChecks["migrations"] = service.MigrationCheck(gormDb)
+	}
+	healthServiceImpl := service.NewHealthService(healthChecks)
+	healthRouteImpl := routes.NewHealthRoute(healthServiceImpl)
+	initialization := NewInitialization(cfg, db, devRouteImpl, userRepositoryImpl, userServiceImpl, userRouteImpl, authRepositoryImpl, authServiceImpl, authRouteImpl, eventRepositoryImpl, eventServiceImpl, eventRouteImpl, reminderServiceImpl, schedulerImpl, jobRepositoryImpl, jobServiceImpl, jobRouteImpl, workerPool, webhookServiceImpl, webhookRouteImpl, calendarServiceImpl, calendarRouteImpl, healthServiceImpl, healthRouteImpl)
 
 	var count int64
-	pgDb.Model(&models.User{}).Count(&count)
+	gormDb.Model(&models.User{}).Count(&count)
 
 	if count == 0 {
 		fmt.Println("Seeding database...")
@@ -90,14 +212,16 @@ func Init(cfg *config.Config) *Initialization {
 			{Name: "Mike Johnson", Email: "mikejohnson@example.com", Password: "password123", Role: "user"},
 			{Name: "Alice Brown", Email: "alicebrown@example.com", Password: "password123", Role: "user"},
 		}
-		pgDb.Create(&users)
+		gormDb.Create(&users)
 
 		// Seed events
+		newYork, _ := time.LoadLocation("America/New_York")
+		losAngeles, _ := time.LoadLocation("America/Los_Angeles")
 		events := []models.Event{
-			{Title: "Tech Conference 2024", Description: "A conference for tech enthusiasts.", Location: "New York", Date: "2024-11-15", Time: "09:00 AM", IsFeatured: true, CreatedBy: 1, ShortDescription: "Tech event for 2024"},
-			{Title: "Music Festival", Description: "An outdoor music festival.", Location: "Los Angeles", Date: "2024-12-05", Time: "04:00 PM", IsFeatured: true, CreatedBy: 2, ShortDescription: "Enjoy live music all day"},
+			{Title: "Tech Conference 2024", Description: "A conference for tech enthusiasts.", Location: "New York", StartsAt: time.Date(2024, 11, 15, 9, 0, 0, 0, newYork).UTC(), EndsAt: time.Date(2024, 11, 15, 17, 0, 0, 0, newYork).UTC(), TimeZone: "America/New_York", IsFeatured: true, CreatedBy: 1, ShortDescription: "Tech event for 2024"},
+			{Title: "Music Festival", Description: "An outdoor music festival.", Location: "Los Angeles", StartsAt: time.Date(2024, 12, 5, 16, 0, 0, 0, losAngeles).UTC(), EndsAt: time.Date(2024, 12, 5, 23, 0, 0, 0, losAngeles).UTC(), TimeZone: "America/Los_Angeles", IsFeatured: true, CreatedBy: 2, ShortDescription: "Enjoy live music all day"},
 		}
-		pgDb.Create(&events)
+		gormDb.Create(&events)
 
 		fmt.Println("Database seeded successfully.")
 	} else {
@@ -105,3 +229,31 @@ func Init(cfg *config.Config) *Initialization {
 	}
 	return initialization
 }
+
+// checkMigrations compares the schema with the migrations of this build. By
+// default pending migrations stop the start, since the code would run
+// against a schema it does not expect.
+func checkMigrations(db *gorm.DB, mode string) error {
+	if mode == "ignore" {
+		return nil
+	}
+	migrator, err := migration.ForDatabase(db)
+	if err != nil {
+		return err
+	}
+	if mode == "apply" {
+		ran, err := migrator.Up()
+		for _, m := range ran {
+			logrus.Infof("Applied migration %d_%s", m.Version, m.Name)
+		}
+		return err
+	}
+	pending, err := migrator.Pending()
+	if err != nil {
+		return err
+	}
+	if len(pending) > 0 {
+		return fmt.Errorf("%d pending migrations, first %d_%s: run the migrate up command or set db_migrations=apply", len(pending), pending[0].Version, pending[0].Name)
+	}
+	return nil
+}
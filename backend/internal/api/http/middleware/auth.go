@@ -1,28 +1,52 @@
 package middleware
 
 import (
-	"errors"
 	"net/http"
+	"slices"
 	"strings"
 
-	"github.com/HermanPlay/web-app-backend/internal/config"
+	"github.com/HermanPlay/web-app-backend/package/domain/models"
+	"github.com/HermanPlay/web-app-backend/package/domain/schemas"
+	"github.com/HermanPlay/web-app-backend/package/service"
 	"github.com/gin-gonic/gin"
-	"github.com/golang-jwt/jwt"
 )
 
-func JwtAuthMiddleware(cfg *config.Config) gin.HandlerFunc {
+const currentUserKey = "currentUser"
+
+// JwtAuthMiddleware validates the token, loads its user and keeps it in the
+// context for CurrentUser.
+func JwtAuthMiddleware(userService service.UserService) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		err := TokenValid(c, cfg)
+		user, err := userService.DecodeToken(c.Request.Context(), ExtractToken(c))
 		if err != nil {
 			// Abort the request with the appropriate error code
 			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
 			return
 		}
+		c.Set(currentUserKey, user)
 		// Continue down the chain to handler etc
 		c.Next()
 	}
 }
 
+// CurrentUser returns the caller authenticated by JwtAuthMiddleware. It panics
+// on routes that are not behind the middleware.
+func CurrentUser(c *gin.Context) *schemas.User {
+	return c.MustGet(currentUserKey).(*schemas.User)
+}
+
+// RequireRoles lets the request through only when the caller holds one of the
+// given roles. It must be chained after JwtAuthMiddleware.
+func RequireRoles(roles ...models.Role) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !slices.Contains(roles, CurrentUser(c).Role) {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "forbidden"})
+			return
+		}
+		c.Next()
+	}
+}
+
 func ExtractToken(c *gin.Context) string {
 	token := c.Query("token")
 	if token != "" {
@@ -36,17 +60,3 @@ func ExtractToken(c *gin.Context) string {
 
 	return ""
 }
-
-func TokenValid(c *gin.Context, cfg *config.Config) error {
-	tokenString := ExtractToken(c)
-	_, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, errors.New("unexpected signing method")
-		}
-		return []byte(cfg.App.ApiSecret), nil
-	})
-	if err != nil {
-		return err
-	}
-	return nil
-}
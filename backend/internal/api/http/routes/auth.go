@@ -2,10 +2,10 @@ package routes
 
 import (
 	"net/http"
-	"time"
 
 	"github.com/HermanPlay/web-app-backend/internal/api/http/constant"
 	"github.com/HermanPlay/web-app-backend/internal/api/http/util"
+	"github.com/HermanPlay/web-app-backend/internal/config"
 	"github.com/HermanPlay/web-app-backend/package/domain/schemas"
 	"github.com/HermanPlay/web-app-backend/package/service"
 	"github.com/gin-gonic/gin"
@@ -14,11 +14,21 @@ import (
 type AuthRoute interface {
 	RegisterUser(c *gin.Context)
 	LoginUser(c *gin.Context)
+	RefreshToken(c *gin.Context)
+	Logout(c *gin.Context)
 	ResetPassword(c *gin.Context)
+	ConfirmPasswordReset(c *gin.Context)
 }
 
+const (
+	accessTokenCookie  = "token"
+	refreshTokenCookie = "refresh_token"
+	refreshTokenPath   = "/api/auth"
+)
+
 type AuthRouteImpl struct {
 	service service.AuthService
+	cfg     *config.Config
 }
 
 func (a AuthRouteImpl) RegisterUser(c *gin.Context) {
@@ -28,7 +38,7 @@ func (a AuthRouteImpl) RegisterUser(c *gin.Context) {
 		return
 	}
 
-	data, err := a.service.RegisterUser(userRegister)
+	data, err := a.service.RegisterUser(c.Request.Context(), userRegister)
 	if err != nil {
 		if err == service.ErrAlreadyExists {
 			c.JSON(http.StatusConflict, util.BuildResponse(constant.AlreadyExists, "User with given email already exists"))
@@ -47,7 +57,7 @@ func (a AuthRouteImpl) LoginUser(c *gin.Context) {
 		return
 	}
 
-	token, err := a.service.LoginUser(userLogin)
+	tokens, err := a.service.LoginUser(c.Request.Context(), userLogin)
 	if err != nil {
 		if err == service.ErrNotFound {
 			c.JSON(http.StatusNotFound, util.BuildResponse(constant.NotFound, "User not found"))
@@ -60,8 +70,63 @@ func (a AuthRouteImpl) LoginUser(c *gin.Context) {
 		c.JSON(http.StatusInternalServerError, util.BuildResponse(constant.UnknownError, "Unknown internal server error"))
 		return
 	}
-	c.SetCookie("token", token, int((constant.TokenHourLifespan * time.Hour).Seconds()), "/", "localhost", false, true)
-	c.JSON(http.StatusOK, util.BuildResponse(constant.Success, token))
+	a.setTokenCookies(c, tokens)
+	c.JSON(http.StatusOK, util.BuildResponse(constant.Success, tokens))
+}
+
+func (a AuthRouteImpl) RefreshToken(c *gin.Context) {
+	refreshToken := requestRefreshToken(c)
+	if refreshToken == "" {
+		c.JSON(http.StatusBadRequest, util.BuildResponse(constant.InvalidRequest, "Missing refresh token"))
+		return
+	}
+
+	tokens, err := a.service.RefreshToken(c.Request.Context(), refreshToken)
+	if err != nil {
+		if err == service.ErrInvalidToken || err == service.ErrTokenReused {
+			clearTokenCookies(c)
+			c.JSON(http.StatusUnauthorized, util.BuildResponse(constant.Unauthorized, "Invalid refresh token"))
+			return
+		}
+		c.JSON(http.StatusInternalServerError, util.BuildResponse(constant.UnknownError, "Unknown internal server error"))
+		return
+	}
+	a.setTokenCookies(c, tokens)
+	c.JSON(http.StatusOK, util.BuildResponse(constant.Success, tokens))
+}
+
+func (a AuthRouteImpl) Logout(c *gin.Context) {
+	refreshToken := requestRefreshToken(c)
+	if refreshToken != "" {
+		err := a.service.Logout(c.Request.Context(), refreshToken)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, util.BuildResponse(constant.UnknownError, "Unknown internal server error"))
+			return
+		}
+	}
+	clearTokenCookies(c)
+	c.JSON(http.StatusOK, util.BuildResponse(constant.Success, map[string]string{"message": "Logged out"}))
+}
+
+// requestRefreshToken reads the refresh token from the JSON body, falling back
+// to the cookie set on login.
+func requestRefreshToken(c *gin.Context) string {
+	var input schemas.RefreshTokenInput
+	if err := c.ShouldBindJSON(&input); err == nil && input.RefreshToken != "" {
+		return input.RefreshToken
+	}
+	refreshToken, _ := c.Cookie(refreshTokenCookie)
+	return refreshToken
+}
+
+func (a AuthRouteImpl) setTokenCookies(c *gin.Context, tokens *schemas.TokenPair) {
+	c.SetCookie(accessTokenCookie, tokens.AccessToken, int(a.cfg.Auth.AccessTokenLifetime.Seconds()), "/", "localhost", false, true)
+	c.SetCookie(refreshTokenCookie, tokens.RefreshToken, int(a.cfg.Auth.RefreshTokenLifetime.Seconds()), refreshTokenPath, "localhost", false, true)
+}
+
+func clearTokenCookies(c *gin.Context) {
+	c.SetCookie(accessTokenCookie, "", -1, "/", "localhost", false, true)
+	c.SetCookie(refreshTokenCookie, "", -1, refreshTokenPath, "localhost", false, true)
 }
 
 func (a AuthRouteImpl) ResetPassword(c *gin.Context) {
@@ -71,20 +136,40 @@ func (a AuthRouteImpl) ResetPassword(c *gin.Context) {
 		return
 	}
 
-	newPassword, err := a.service.ResetPassword(userResetPassword)
+	err := a.service.ResetPassword(c.Request.Context(), userResetPassword)
 	if err != nil {
-		if err == service.ErrNotFound {
-			c.JSON(http.StatusNotFound, util.BuildResponse(constant.NotFound, "User not found"))
+		c.JSON(http.StatusInternalServerError, util.BuildResponse(constant.UnknownError, "Unknown internal server error"))
+		return
+	}
+	c.JSON(http.StatusOK, util.BuildResponse(constant.Success, map[string]string{"message": "If the account exists, a reset link was sent to its email"}))
+}
+
+func (a AuthRouteImpl) ConfirmPasswordReset(c *gin.Context) {
+	var confirm schemas.UserResetPasswordConfirm
+	if err := c.ShouldBindJSON(&confirm); err != nil {
+		c.JSON(http.StatusBadRequest, util.BuildResponse(constant.InvalidRequest, "Invalid request data"))
+		return
+	}
+
+	err := a.service.ConfirmPasswordReset(c.Request.Context(), confirm)
+	if err != nil {
+		if err == service.ErrInvalidToken {
+			c.JSON(http.StatusBadRequest, util.BuildResponse(constant.InvalidRequest, "Reset link is invalid or expired"))
+			return
+		}
+		if err == service.ErrInvalidPassword {
+			c.JSON(http.StatusBadRequest, util.BuildResponse(constant.InvalidRequest, "Password must be at least 8 characters long"))
 			return
 		}
 		c.JSON(http.StatusInternalServerError, util.BuildResponse(constant.UnknownError, "Unknown internal server error"))
 		return
 	}
-	c.JSON(http.StatusOK, util.BuildResponse(constant.Success, newPassword))
+	c.JSON(http.StatusOK, util.BuildResponse(constant.Success, map[string]string{"message": "Password changed"}))
 }
 
-func NewAuthRoute(service service.AuthService) *AuthRouteImpl {
+func NewAuthRoute(service service.AuthService, cfg *config.Config) *AuthRouteImpl {
 	return &AuthRouteImpl{
 		service: service,
+		cfg:     cfg,
 	}
 }
@@ -0,0 +1,111 @@
+package routes
+
+import (
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"github.com/HermanPlay/web-app-backend/internal/api/http/constant"
+	"github.com/HermanPlay/web-app-backend/internal/api/http/middleware"
+	"github.com/HermanPlay/web-app-backend/internal/api/http/util"
+	"github.com/HermanPlay/web-app-backend/internal/calendar"
+	"github.com/HermanPlay/web-app-backend/package/domain/schemas"
+	"github.com/HermanPlay/web-app-backend/package/service"
+	"github.com/gin-gonic/gin"
+)
+
+type CalendarRoute interface {
+	GetEventCalendar(c *gin.Context)
+	GetFeed(c *gin.Context)
+	CreateFeedToken(c *gin.Context)
+	RevokeFeedToken(c *gin.Context)
+}
+
+type CalendarRouteImpl struct {
+	calendarService service.CalendarService
+}
+
+func NewCalendarRoute(calendarService service.CalendarService) CalendarRoute {
+	return &CalendarRouteImpl{
+		calendarService: calendarService,
+	}
+}
+
+func (r CalendarRouteImpl) GetEventCalendar(c *gin.Context) {
+	eventID, err := strconv.Atoi(c.Param("eventID"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, util.BuildResponse(constant.InvalidRequest, "Invalid id supplied"))
+		return
+	}
+
+	data, err := r.calendarService.GetEventCalendar(c.Request.Context(), eventID)
+	if err != nil {
+		if err == service.ErrNotFound {
+			c.JSON(http.StatusNotFound, util.BuildResponse(constant.NotFound, "Event not found"))
+			return
+		}
+		c.JSON(http.StatusInternalServerError, util.BuildResponse(constant.UnknownError, "Unknown internal server error"))
+		return
+	}
+	c.Header("Content-Disposition", `attachment; filename="event-`+strconv.Itoa(eventID)+`.ics"`)
+	c.Data(http.StatusOK, calendar.ContentType, data)
+}
+
+// GetFeed serves the calendar of the user owning the token in the path.
+// Calendar apps poll it without any other credentials.
+func (r CalendarRouteImpl) GetFeed(c *gin.Context) {
+	data, err := r.calendarService.GetFeed(c.Request.Context(), c.Param("token"))
+	if err != nil {
+		if err == service.ErrInvalidToken {
+			c.JSON(http.StatusNotFound, util.BuildResponse(constant.NotFound, "Calendar feed not found"))
+			return
+		}
+		c.JSON(http.StatusInternalServerError, util.BuildResponse(constant.UnknownError, "Unknown internal server error"))
+		return
+	}
+	c.Data(http.StatusOK, calendar.ContentType, data)
+}
+
+// CreateFeedToken issues a feed URL for the current user. The previous URL
+// stops working.
+func (r CalendarRouteImpl) CreateFeedToken(c *gin.Context) {
+	user := middleware.CurrentUser(c)
+
+	token, err := r.calendarService.CreateFeedToken(c.Request.Context(), user.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, util.BuildResponse(constant.UnknownError, "Unknown internal server error"))
+		return
+	}
+	c.JSON(http.StatusCreated, util.BuildResponse(constant.Success, schemas.FeedToken{
+		Token: token,
+		URL:   feedURL(c, token),
+	}))
+}
+
+func (r CalendarRouteImpl) RevokeFeedToken(c *gin.Context) {
+	user := middleware.CurrentUser(c)
+
+	err := r.calendarService.RevokeFeedToken(c.Request.Context(), user.ID)
+	if err != nil {
+		if err == service.ErrNotFound {
+			c.JSON(http.StatusNotFound, util.BuildResponse(constant.NotFound, "No calendar feed to revoke"))
+			return
+		}
+		c.JSON(http.StatusInternalServerError, util.BuildResponse(constant.UnknownError, "Unknown internal server error"))
+		return
+	}
+	c.JSON(http.StatusOK, util.BuildResponse(constant.Success, map[string]string{"message": "Calendar feed revoked"}))
+}
+
+// feedURL points at GetFeed on the host the request was sent to.
+func feedURL(c *gin.Context, token string) string {
+	scheme := "http"
+	if c.Request.TLS != nil {
+		scheme = "https"
+	}
+	if proto := c.GetHeader("X-Forwarded-Proto"); proto != "" {
+		scheme = proto
+	}
+	feed := url.URL{Scheme: scheme, Host: c.Request.Host, Path: "/api/calendar/feed/" + token}
+	return feed.String()
+}
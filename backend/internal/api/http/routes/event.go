@@ -1,15 +1,21 @@
 package routes
 
 import (
+	"encoding/json"
+	"errors"
 	"net/http"
+	"path/filepath"
 	"strconv"
 	"strings"
 
 	"github.com/HermanPlay/web-app-backend/internal/api/http/constant"
+	"github.com/HermanPlay/web-app-backend/internal/api/http/middleware"
 	"github.com/HermanPlay/web-app-backend/internal/api/http/util"
+	"github.com/HermanPlay/web-app-backend/internal/spreadsheet"
 	"github.com/HermanPlay/web-app-backend/package/domain/schemas"
 	"github.com/HermanPlay/web-app-backend/package/service"
 	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
 )
 
 type EventRoute interface {
@@ -21,27 +27,129 @@ type EventRoute interface {
 	GetFeaturedEvents(c *gin.Context)
 	GetMyEvents(c *gin.Context)
 	BookEvent(c *gin.Context)
+	CancelBooking(c *gin.Context)
+	GetAttendees(c *gin.Context)
+	GetOrganizers(c *gin.Context)
+	AddOrganizer(c *gin.Context)
+	RemoveOrganizer(c *gin.Context)
+	ImportEvents(c *gin.Context)
+	ExportEvents(c *gin.Context)
+	ExportAttendees(c *gin.Context)
 }
 
 type EventRouteImpl struct {
 	eventService service.EventService
-	userService  service.UserService
 }
 
-func NewEventRoute(eventService service.EventService, userService service.UserService) EventRoute {
+func (e EventRouteImpl) GetOrganizers(c *gin.Context) {
+	eventID, err := strconv.Atoi(c.Param("eventID"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": "Invalid id supplied"})
+		return
+	}
+
+	data, err := e.eventService.GetOrganizers(c.Request.Context(), eventID)
+	if err != nil {
+		if err == service.ErrNotFound {
+			c.JSON(http.StatusNotFound, util.BuildResponse(constant.NotFound, "Event not found"))
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"message": "Error when getting data"})
+		return
+	}
+
+	c.JSON(http.StatusOK, util.BuildResponse(constant.Success, data))
+}
+
+func (e EventRouteImpl) AddOrganizer(c *gin.Context) {
+	eventID, err := strconv.Atoi(c.Param("eventID"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": "Invalid id supplied"})
+		return
+	}
+
+	var input schemas.OrganizerInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": "Invalid data schema, verify data types"})
+		return
+	}
+
+	user := middleware.CurrentUser(c)
+
+	err = e.eventService.AddOrganizer(c.Request.Context(), eventID, input.UserID, user)
+	if err != nil {
+		switch err {
+		case service.ErrNotFound:
+			c.JSON(http.StatusNotFound, util.BuildResponse(constant.NotFound, "Event or user not found"))
+		case service.ErrForbidden:
+			c.JSON(http.StatusForbidden, util.BuildResponse(constant.Forbidden, "Only the event creator can add co-organizers"))
+		case service.ErrAlreadyExists:
+			c.JSON(http.StatusConflict, util.BuildResponse(constant.AlreadyExists, "User is already a co-organizer"))
+		case service.ErrInvalidInput:
+			c.JSON(http.StatusBadRequest, util.BuildResponse(constant.InvalidRequest, "The event creator is already an organizer"))
+		default:
+			c.JSON(http.StatusBadRequest, gin.H{"message": "Error when adding co-organizer"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusCreated, util.BuildResponse(constant.Success, map[string]string{"message": "Co-organizer added"}))
+}
+
+func (e EventRouteImpl) RemoveOrganizer(c *gin.Context) {
+	eventID, err := strconv.Atoi(c.Param("eventID"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": "Invalid id supplied"})
+		return
+	}
+	userID, err := strconv.Atoi(c.Param("userID"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": "Invalid id supplied"})
+		return
+	}
+
+	user := middleware.CurrentUser(c)
+
+	err = e.eventService.RemoveOrganizer(c.Request.Context(), eventID, userID, user)
+	if err != nil {
+		if err == service.ErrNotFound {
+			c.JSON(http.StatusNotFound, util.BuildResponse(constant.NotFound, "Co-organizer not found"))
+			return
+		}
+		if err == service.ErrForbidden {
+			c.JSON(http.StatusForbidden, util.BuildResponse(constant.Forbidden, "Only the event creator can remove co-organizers"))
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"message": "Error when removing co-organizer"})
+		return
+	}
+
+	c.JSON(http.StatusOK, util.BuildResponse(constant.Success, map[string]string{"message": "Co-organizer removed"}))
+}
+
+func NewEventRoute(eventService service.EventService) EventRoute {
 	return &EventRouteImpl{
 		eventService: eventService,
-		userService:  userService,
 	}
 }
 
 func (e EventRouteImpl) GetAllEvent(c *gin.Context) {
-	data, err := e.eventService.GetAllEvent()
+	var filter schemas.EventFilter
+	if err := c.ShouldBindQuery(&filter); err != nil {
+		c.JSON(http.StatusBadRequest, util.BuildResponse(constant.InvalidRequest, "Could not parse query! "+err.Error()))
+		return
+	}
+
+	data, pagination, err := e.eventService.GetAllEvent(c.Request.Context(), filter)
 	if err != nil {
+		if isEventValidationError(err) || err == service.ErrInvalidSort {
+			c.JSON(http.StatusBadRequest, util.BuildResponse(constant.InvalidRequest, err.Error()))
+			return
+		}
 		c.JSON(http.StatusBadRequest, gin.H{"message": "Error when getting data"})
 		return
 	}
-	c.JSON(http.StatusOK, util.BuildResponse(constant.Success, data))
+	c.JSON(http.StatusOK, util.BuildPaginatedResponse(constant.Success, data, pagination))
 }
 
 func (e EventRouteImpl) GetEventById(c *gin.Context) {
@@ -51,7 +159,7 @@ func (e EventRouteImpl) GetEventById(c *gin.Context) {
 		return
 	}
 
-	data, err := e.eventService.GetEventByID(id)
+	data, err := e.eventService.GetEventByID(c.Request.Context(), id)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"message": "Error when getting data"})
 		return
@@ -67,15 +175,14 @@ func (e EventRouteImpl) CreateEvent(c *gin.Context) {
 		return
 	}
 
-	authHeader := c.GetHeader("Authorization")
-	token := strings.Split(authHeader, "Bearer ")[1]
-	user, err := e.userService.DecodeToken(token)
-	if err != nil {
-		c.JSON(http.StatusUnauthorized, util.BuildResponse(constant.InvalidRequest, "Could not decode user token"))
-	}
+	user := middleware.CurrentUser(c)
 	userId := user.ID
-	data, err := e.eventService.CreateEvent(&eventInput, userId)
+	data, err := e.eventService.CreateEvent(c.Request.Context(), &eventInput, userId)
 	if err != nil {
+		if isEventValidationError(err) {
+			c.JSON(http.StatusBadRequest, util.BuildResponse(constant.InvalidRequest, err.Error()))
+			return
+		}
 		c.JSON(http.StatusBadRequest, gin.H{"message": "Error when saving data"})
 		return
 	}
@@ -83,6 +190,162 @@ func (e EventRouteImpl) CreateEvent(c *gin.Context) {
 	c.JSON(http.StatusOK, util.BuildResponse(constant.Success, data))
 }
 
+// maxImportSize caps the upload of ImportEvents in bytes.
+const maxImportSize = 5 << 20
+
+// ImportEvents validates an uploaded CSV or iCalendar file of events and, with
+// commit=true, creates all of them. The file goes in the "file" form field; a
+// CSV column mapping can be given as a JSON object in "mapping".
+func (e EventRouteImpl) ImportEvents(c *gin.Context) {
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxImportSize)
+	var options schemas.EventImport
+	if err := c.ShouldBind(&options); err != nil {
+		c.JSON(http.StatusBadRequest, util.BuildResponse(constant.InvalidRequest, "Could not parse form! "+err.Error()))
+		return
+	}
+	file, header, err := c.Request.FormFile("file")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, util.BuildResponse(constant.InvalidRequest, "A file of at most 5 MB is required"))
+		return
+	}
+	defer file.Close()
+	if mapping := c.PostForm("mapping"); mapping != "" {
+		if err := json.Unmarshal([]byte(mapping), &options.Mapping); err != nil {
+			c.JSON(http.StatusBadRequest, util.BuildResponse(constant.InvalidRequest, "Mapping must be a JSON object of event fields to column names"))
+			return
+		}
+	}
+	if options.Format == "" {
+		options.Format = strings.TrimPrefix(strings.ToLower(filepath.Ext(header.Filename)), ".")
+	}
+
+	user := middleware.CurrentUser(c)
+	result, err := e.eventService.ImportEvents(c.Request.Context(), file, options, user.ID)
+	if err != nil {
+		if isImportError(err) || err == service.ErrInvalidZone {
+			c.JSON(http.StatusBadRequest, util.BuildResponse(constant.InvalidRequest, err.Error()))
+			return
+		}
+		c.JSON(http.StatusInternalServerError, util.BuildResponse(constant.UnknownError, "Error when importing events"))
+		return
+	}
+	switch {
+	case result.Committed:
+		c.JSON(http.StatusCreated, util.BuildResponse(constant.Success, result))
+	case options.Commit:
+		// Nothing was saved, the report tells which rows to fix
+		c.JSON(http.StatusBadRequest, util.BuildResponse(constant.InvalidRequest, result))
+	default:
+		c.JSON(http.StatusOK, util.BuildResponse(constant.Success, result))
+	}
+}
+
+// ExportEvents streams the events matching the list filters as a csv or
+// xlsx file, given by the format query parameter.
+func (e EventRouteImpl) ExportEvents(c *gin.Context) {
+	var filter schemas.EventFilter
+	if err := c.ShouldBindQuery(&filter); err != nil {
+		c.JSON(http.StatusBadRequest, util.BuildResponse(constant.InvalidRequest, "Could not parse query! "+err.Error()))
+		return
+	}
+	format, ok := exportFormat(c)
+	if !ok {
+		return
+	}
+
+	user := middleware.CurrentUser(c)
+	export, err := e.eventService.ExportEvents(c.Request.Context(), filter, user)
+	if err != nil {
+		if isEventValidationError(err) || err == service.ErrInvalidSort {
+			c.JSON(http.StatusBadRequest, util.BuildResponse(constant.InvalidRequest, err.Error()))
+			return
+		}
+		c.JSON(http.StatusInternalServerError, util.BuildResponse(constant.UnknownError, "Error when exporting events"))
+		return
+	}
+	writeExport(c, format, export)
+}
+
+// ExportAttendees streams the attendees of the event as a csv or xlsx file,
+// given by the format query parameter.
+func (e EventRouteImpl) ExportAttendees(c *gin.Context) {
+	eventID, err := strconv.Atoi(c.Param("eventID"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": "Invalid id supplied"})
+		return
+	}
+	format, ok := exportFormat(c)
+	if !ok {
+		return
+	}
+
+	user := middleware.CurrentUser(c)
+	export, err := e.eventService.ExportAttendees(c.Request.Context(), eventID, user)
+	if err != nil {
+		if err == service.ErrNotFound {
+			c.JSON(http.StatusNotFound, util.BuildResponse(constant.NotFound, "Event not found"))
+			return
+		}
+		if err == service.ErrForbidden {
+			c.JSON(http.StatusForbidden, util.BuildResponse(constant.Forbidden, "Only event organizers can export attendees"))
+			return
+		}
+		c.JSON(http.StatusInternalServerError, util.BuildResponse(constant.UnknownError, "Error when exporting attendees"))
+		return
+	}
+	writeExport(c, format, export)
+}
+
+// exportFormat reads the format query parameter, csv by default. It responds
+// with an error and returns false when the format is unknown.
+func exportFormat(c *gin.Context) (spreadsheet.Format, bool) {
+	format := spreadsheet.Format(strings.ToLower(c.DefaultQuery("format", string(spreadsheet.CSV))))
+	if !format.IsValid() {
+		c.JSON(http.StatusBadRequest, util.BuildResponse(constant.InvalidRequest, spreadsheet.ErrUnknownFormat.Error()))
+		return "", false
+	}
+	return format, true
+}
+
+// writeExport streams the export as the response body. Once the first bytes
+// are out the status cannot change anymore, so a failure halfway is only
+// logged and the client gets a truncated file.
+func writeExport(c *gin.Context, format spreadsheet.Format, export *schemas.Export) {
+	c.Header("Content-Type", format.ContentType())
+	c.Header("Content-Disposition", `attachment; filename="`+export.Name+"."+string(format)+`"`)
+	c.Status(http.StatusOK)
+
+	writer, err := spreadsheet.NewWriter(format, c.Writer, export.Name)
+	if err == nil {
+		err = writer.WriteHeader(export.Columns...)
+	}
+	if err == nil {
+		err = export.Rows(writer.WriteRow)
+	}
+	if err == nil {
+		err = writer.Close()
+	}
+	if err != nil {
+		logrus.Error("Got an error when writing export "+export.Name+". Error: ", err)
+		_ = c.Error(err)
+	}
+}
+
+func isImportError(err error) bool {
+	return errors.Is(err, service.ErrImportFormat) ||
+		errors.Is(err, service.ErrImportFile) ||
+		errors.Is(err, service.ErrImportMapping) ||
+		errors.Is(err, service.ErrImportEmpty) ||
+		errors.Is(err, service.ErrImportTooLarge)
+}
+
+func isEventValidationError(err error) bool {
+	return err == service.ErrInvalidInput ||
+		err == service.ErrInputTooLong ||
+		err == service.ErrInvalidZone ||
+		err == service.ErrInvalidTimes
+}
+
 func (e EventRouteImpl) UpdateEvent(c *gin.Context) {
 	id, err := strconv.Atoi(c.Param("eventID"))
 	if err != nil {
@@ -96,8 +359,22 @@ func (e EventRouteImpl) UpdateEvent(c *gin.Context) {
 		return
 	}
 
-	data, err := e.eventService.UpdateEvent(&eventUpdate, id)
+	user := middleware.CurrentUser(c)
+
+	data, err := e.eventService.UpdateEvent(c.Request.Context(), &eventUpdate, id, user)
 	if err != nil {
+		if isEventValidationError(err) {
+			c.JSON(http.StatusBadRequest, util.BuildResponse(constant.InvalidRequest, err.Error()))
+			return
+		}
+		if err == service.ErrNotFound {
+			c.JSON(http.StatusNotFound, util.BuildResponse(constant.NotFound, "Event not found"))
+			return
+		}
+		if err == service.ErrForbidden {
+			c.JSON(http.StatusForbidden, util.BuildResponse(constant.Forbidden, "Only event organizers can update the event"))
+			return
+		}
 		c.JSON(http.StatusBadRequest, gin.H{"message": "Error when updating data"})
 		return
 	}
@@ -112,8 +389,18 @@ func (e EventRouteImpl) DeleteEvent(c *gin.Context) {
 		return
 	}
 
-	err = e.eventService.DeleteEvent(id)
+	user := middleware.CurrentUser(c)
+
+	err = e.eventService.DeleteEvent(c.Request.Context(), id, user)
 	if err != nil {
+		if err == service.ErrNotFound {
+			c.JSON(http.StatusNotFound, util.BuildResponse(constant.NotFound, "Event not found"))
+			return
+		}
+		if err == service.ErrForbidden {
+			c.JSON(http.StatusForbidden, util.BuildResponse(constant.Forbidden, "Only event organizers can delete the event"))
+			return
+		}
 		c.JSON(http.StatusBadRequest, gin.H{"message": "Error when deleting data"})
 		return
 	}
@@ -122,7 +409,7 @@ func (e EventRouteImpl) DeleteEvent(c *gin.Context) {
 }
 
 func (e EventRouteImpl) GetFeaturedEvents(c *gin.Context) {
-	data, err := e.eventService.GetFeaturedEvents()
+	data, err := e.eventService.GetFeaturedEvents(c.Request.Context())
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"message": "Error when getting data"})
 		return
@@ -137,7 +424,7 @@ func (e EventRouteImpl) GetMyEvents(c *gin.Context) {
 		return
 	}
 
-	data, err := e.eventService.GetMyEvents(userID)
+	data, err := e.eventService.GetMyEvents(c.Request.Context(), userID)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"message": "Error when getting data"})
 		return
@@ -152,23 +439,74 @@ func (e EventRouteImpl) BookEvent(c *gin.Context) {
 		return
 	}
 
-	authHeader := c.GetHeader("Authorization")
-	token := strings.Split(authHeader, "Bearer ")[1]
-	user, err := e.userService.DecodeToken(token)
-	if err != nil {
-		c.JSON(http.StatusUnauthorized, util.BuildResponse(constant.InvalidRequest, "Could not decode user token"))
-	}
+	user := middleware.CurrentUser(c)
 	userID := user.ID
 
-	err = e.eventService.BookEvent(eventID, userID)
+	data, err := e.eventService.BookEvent(c.Request.Context(), eventID, userID)
 	if err != nil {
 		if err == service.ErrBookingExists {
 			c.JSON(http.StatusConflict, util.BuildResponse(constant.AlreadyExists, "Event already booked"))
 			return
 		}
+		if err == service.ErrEventFull {
+			c.JSON(http.StatusConflict, util.BuildResponse(constant.EventFull, "Event is fully booked"))
+			return
+		}
+		if err == service.ErrNotFound {
+			c.JSON(http.StatusNotFound, util.BuildResponse(constant.NotFound, "Event not found"))
+			return
+		}
 		c.JSON(http.StatusBadRequest, gin.H{"message": "Error when booking event"})
 		return
 	}
 
-	c.JSON(http.StatusOK, util.BuildResponse(constant.Success, []string{"Event booked successfully"}))
+	c.JSON(http.StatusOK, util.BuildResponse(constant.Success, data))
+}
+
+func (e EventRouteImpl) CancelBooking(c *gin.Context) {
+	eventID, err := strconv.Atoi(c.Param("eventID"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": "Invalid id supplied"})
+		return
+	}
+
+	user := middleware.CurrentUser(c)
+
+	err = e.eventService.CancelBooking(c.Request.Context(), eventID, user.ID)
+	if err != nil {
+		if err == service.ErrNotFound {
+			c.JSON(http.StatusNotFound, util.BuildResponse(constant.NotFound, "Booking not found"))
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"message": "Error when cancelling booking"})
+		return
+	}
+
+	c.JSON(http.StatusOK, util.BuildResponse(constant.Success, map[string]string{"message": "Booking cancelled"}))
+}
+
+func (e EventRouteImpl) GetAttendees(c *gin.Context) {
+	eventID, err := strconv.Atoi(c.Param("eventID"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": "Invalid id supplied"})
+		return
+	}
+
+	user := middleware.CurrentUser(c)
+
+	data, err := e.eventService.GetAttendees(c.Request.Context(), eventID, user)
+	if err != nil {
+		if err == service.ErrNotFound {
+			c.JSON(http.StatusNotFound, util.BuildResponse(constant.NotFound, "Event not found"))
+			return
+		}
+		if err == service.ErrForbidden {
+			c.JSON(http.StatusForbidden, util.BuildResponse(constant.Forbidden, "Only event organizers can see attendees"))
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"message": "Error when getting data"})
+		return
+	}
+
+	c.JSON(http.StatusOK, util.BuildResponse(constant.Success, data))
 }
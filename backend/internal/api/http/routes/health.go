@@ -0,0 +1,42 @@
+package routes
+
+import (
+	"net/http"
+
+	"github.com/HermanPlay/web-app-backend/internal/api/http/constant"
+	"github.com/HermanPlay/web-app-backend/internal/api/http/util"
+	"github.com/HermanPlay/web-app-backend/package/service"
+	"github.com/gin-gonic/gin"
+)
+
+type HealthRoute interface {
+	Livez(c *gin.Context)
+	Readyz(c *gin.Context)
+}
+
+type HealthRouteImpl struct {
+	healthService service.HealthService
+}
+
+// Livez reports that the process serves requests, it checks no dependencies
+// so that an outage of one does not get the process restarted.
+func (h HealthRouteImpl) Livez(c *gin.Context) {
+	c.JSON(http.StatusOK, util.BuildResponse(constant.Success, map[string]string{"status": "ok"}))
+}
+
+// Readyz reports whether the dependencies work, traffic should only be sent
+// while it returns 200.
+func (h HealthRouteImpl) Readyz(c *gin.Context) {
+	readiness, ok := h.healthService.Ready(c.Request.Context())
+	if !ok {
+		c.JSON(http.StatusServiceUnavailable, util.BuildResponse(constant.ServiceUnavailable, readiness))
+		return
+	}
+	c.JSON(http.StatusOK, util.BuildResponse(constant.Success, readiness))
+}
+
+func NewHealthRoute(healthService service.HealthService) HealthRoute {
+	return HealthRouteImpl{
+		healthService: healthService,
+	}
+}
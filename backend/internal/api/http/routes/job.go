@@ -0,0 +1,89 @@
+package routes
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/HermanPlay/web-app-backend/internal/api/http/constant"
+	"github.com/HermanPlay/web-app-backend/internal/api/http/util"
+	"github.com/HermanPlay/web-app-backend/package/domain/schemas"
+	"github.com/HermanPlay/web-app-backend/package/service"
+	"github.com/gin-gonic/gin"
+)
+
+type JobRoute interface {
+	GetJobs(c *gin.Context)
+	GetJob(c *gin.Context)
+	RetryJob(c *gin.Context)
+}
+
+type JobRouteImpl struct {
+	jobService service.JobService
+}
+
+func NewJobRoute(jobService service.JobService) JobRoute {
+	return &JobRouteImpl{
+		jobService: jobService,
+	}
+}
+
+func (j JobRouteImpl) GetJobs(c *gin.Context) {
+	var filter schemas.JobFilter
+	if err := c.ShouldBindQuery(&filter); err != nil {
+		c.JSON(http.StatusBadRequest, util.BuildResponse(constant.InvalidRequest, "Could not parse query! "+err.Error()))
+		return
+	}
+
+	data, pagination, err := j.jobService.GetJobs(c.Request.Context(), filter)
+	if err != nil {
+		if err == service.ErrInvalidInput {
+			c.JSON(http.StatusBadRequest, util.BuildResponse(constant.InvalidRequest, "Invalid status or page"))
+			return
+		}
+		c.JSON(http.StatusInternalServerError, util.BuildResponse(constant.UnknownError, "Unknown internal server error"))
+		return
+	}
+	c.JSON(http.StatusOK, util.BuildPaginatedResponse(constant.Success, data, pagination))
+}
+
+func (j JobRouteImpl) GetJob(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("jobID"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, util.BuildResponse(constant.InvalidRequest, "Invalid id supplied"))
+		return
+	}
+
+	data, err := j.jobService.GetJob(c.Request.Context(), id)
+	if err != nil {
+		if err == service.ErrNotFound {
+			c.JSON(http.StatusNotFound, util.BuildResponse(constant.NotFound, "Job not found"))
+			return
+		}
+		c.JSON(http.StatusInternalServerError, util.BuildResponse(constant.UnknownError, "Unknown internal server error"))
+		return
+	}
+	c.JSON(http.StatusOK, util.BuildResponse(constant.Success, data))
+}
+
+func (j JobRouteImpl) RetryJob(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("jobID"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, util.BuildResponse(constant.InvalidRequest, "Invalid id supplied"))
+		return
+	}
+
+	data, err := j.jobService.RetryJob(c.Request.Context(), id)
+	if err != nil {
+		if err == service.ErrNotFound {
+			c.JSON(http.StatusNotFound, util.BuildResponse(constant.NotFound, "Job not found"))
+			return
+		}
+		if err == service.ErrInvalidInput {
+			c.JSON(http.StatusBadRequest, util.BuildResponse(constant.InvalidRequest, "Only dead or waiting jobs can be retried"))
+			return
+		}
+		c.JSON(http.StatusInternalServerError, util.BuildResponse(constant.UnknownError, "Unknown internal server error"))
+		return
+	}
+	c.JSON(http.StatusOK, util.BuildResponse(constant.Success, data))
+}
@@ -3,9 +3,9 @@ package routes
 import (
 	"net/http"
 	"strconv"
-	"strings"
 
 	"github.com/HermanPlay/web-app-backend/internal/api/http/constant"
+	"github.com/HermanPlay/web-app-backend/internal/api/http/middleware"
 	"github.com/HermanPlay/web-app-backend/internal/api/http/util"
 	"github.com/HermanPlay/web-app-backend/package/domain/schemas"
 	"github.com/HermanPlay/web-app-backend/package/service"
@@ -26,7 +26,7 @@ type UserRouteImpl struct {
 }
 
 func (u UserRouteImpl) GetAllUserData(c *gin.Context) {
-	data, err := u.service.GetAllUser()
+	data, err := u.service.GetAllUser(c.Request.Context())
 	if err != nil {
 		c.JSON(http.StatusBadRequest, util.BuildResponse(constant.InvalidRequest, "Error when getting data"))
 		return
@@ -41,8 +41,14 @@ func (u UserRouteImpl) AddUserData(c *gin.Context) {
 		return
 	}
 
-	user, err := u.service.AddUserData(data)
+	requester := middleware.CurrentUser(c)
+
+	user, err := u.service.AddUserData(c.Request.Context(), data, requester)
 	if err != nil {
+		if err == service.ErrForbidden {
+			c.JSON(http.StatusForbidden, util.BuildResponse(constant.Forbidden, "Only admins can add users"))
+			return
+		}
 		if err == service.ErrAlreadyExists {
 			c.JSON(http.StatusConflict, util.BuildResponse(constant.AlreadyExists, "User with given email already exists"))
 			return
@@ -63,7 +69,7 @@ func (u UserRouteImpl) GetUserById(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, util.BuildResponse(constant.InvalidRequest, "Invalid id supplied"))
 	}
 
-	data, err := u.service.GetUserById(id)
+	data, err := u.service.GetUserById(c.Request.Context(), id)
 	if err != nil {
 		if err == service.ErrNotFound {
 			c.JSON(http.StatusNotFound, util.BuildResponse(constant.NotFound, "User not found"))
@@ -85,9 +91,18 @@ func (u UserRouteImpl) UpdateUserData(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, util.BuildResponse(constant.InvalidRequest, "Invalid input. Check your input types."))
 		return
 	}
+	requester := middleware.CurrentUser(c)
 
-	data, err := u.service.UpdateUserData(user, id)
+	data, err := u.service.UpdateUserData(c.Request.Context(), user, id, requester)
 	if err != nil {
+		if err == service.ErrForbidden {
+			c.JSON(http.StatusForbidden, util.BuildResponse(constant.Forbidden, "Only admins can edit other users or change roles"))
+			return
+		}
+		if err == service.ErrInvalidInput {
+			c.JSON(http.StatusBadRequest, util.BuildResponse(constant.InvalidRequest, "Unknown role"))
+			return
+		}
 		if err == service.ErrNotFound {
 			c.JSON(http.StatusNotFound, util.BuildResponse(constant.NotFound, "User not found"))
 			return
@@ -111,22 +126,25 @@ func (u UserRouteImpl) DeleteUser(c *gin.Context) {
 		return
 	}
 
-	err = u.service.DeleteUser(id)
+	requester := middleware.CurrentUser(c)
+
+	err = u.service.DeleteUser(c.Request.Context(), id, requester)
 	if err != nil {
+		if err == service.ErrForbidden {
+			c.JSON(http.StatusForbidden, util.BuildResponse(constant.Forbidden, "Only admins can delete users"))
+			return
+		}
+		if err == service.ErrNotFound {
+			c.JSON(http.StatusNotFound, util.BuildResponse(constant.NotFound, "User not found"))
+			return
+		}
 		c.JSON(http.StatusInternalServerError, util.BuildResponse(constant.InvalidRequest, "Unkonwn internal server error"))
 		return
 	}
 }
 
 func (u UserRouteImpl) DecodeToken(c *gin.Context) {
-	authHeader := c.GetHeader("Authorization")
-	token := strings.Split(authHeader, "Bearer ")[1]
-	user, err := u.service.DecodeToken(token)
-	if err != nil {
-		c.JSON(http.StatusBadRequest, util.BuildResponse(constant.InvalidRequest, "Could not decode token. "+err.Error()))
-		return
-	}
-	c.JSON(http.StatusOK, util.BuildResponse(constant.Success, user))
+	c.JSON(http.StatusOK, util.BuildResponse(constant.Success, middleware.CurrentUser(c)))
 }
 
 func NewUserRoute(userService service.UserService) UserRoute {
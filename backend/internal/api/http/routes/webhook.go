@@ -0,0 +1,154 @@
+package routes
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/HermanPlay/web-app-backend/internal/api/http/constant"
+	"github.com/HermanPlay/web-app-backend/internal/api/http/middleware"
+	"github.com/HermanPlay/web-app-backend/internal/api/http/util"
+	"github.com/HermanPlay/web-app-backend/package/domain/schemas"
+	"github.com/HermanPlay/web-app-backend/package/service"
+	"github.com/gin-gonic/gin"
+)
+
+type WebhookRoute interface {
+	GetWebhooks(c *gin.Context)
+	GetWebhook(c *gin.Context)
+	CreateWebhook(c *gin.Context)
+	UpdateWebhook(c *gin.Context)
+	DeleteWebhook(c *gin.Context)
+	GetDeliveries(c *gin.Context)
+}
+
+type WebhookRouteImpl struct {
+	webhookService service.WebhookService
+}
+
+func NewWebhookRoute(webhookService service.WebhookService) WebhookRoute {
+	return &WebhookRouteImpl{
+		webhookService: webhookService,
+	}
+}
+
+func (w WebhookRouteImpl) GetWebhooks(c *gin.Context) {
+	data, err := w.webhookService.GetWebhooks(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, util.BuildResponse(constant.UnknownError, "Unknown internal server error"))
+		return
+	}
+	c.JSON(http.StatusOK, util.BuildResponse(constant.Success, data))
+}
+
+func (w WebhookRouteImpl) GetWebhook(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("webhookID"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, util.BuildResponse(constant.InvalidRequest, "Invalid id supplied"))
+		return
+	}
+
+	data, err := w.webhookService.GetWebhook(c.Request.Context(), id)
+	if err != nil {
+		if err == service.ErrNotFound {
+			c.JSON(http.StatusNotFound, util.BuildResponse(constant.NotFound, "Webhook not found"))
+			return
+		}
+		c.JSON(http.StatusInternalServerError, util.BuildResponse(constant.UnknownError, "Unknown internal server error"))
+		return
+	}
+	c.JSON(http.StatusOK, util.BuildResponse(constant.Success, data))
+}
+
+func (w WebhookRouteImpl) CreateWebhook(c *gin.Context) {
+	var input schemas.WebhookInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, util.BuildResponse(constant.InvalidRequest, "Invalid input. Check your input types."))
+		return
+	}
+
+	data, err := w.webhookService.CreateWebhook(c.Request.Context(), input, middleware.CurrentUser(c).ID)
+	if err != nil {
+		if err == service.ErrInvalidInput {
+			c.JSON(http.StatusBadRequest, util.BuildResponse(constant.InvalidRequest, "Webhooks need an http(s) url and known events"))
+			return
+		}
+		c.JSON(http.StatusInternalServerError, util.BuildResponse(constant.UnknownError, "Unknown internal server error"))
+		return
+	}
+	c.JSON(http.StatusCreated, util.BuildResponse(constant.Success, data))
+}
+
+func (w WebhookRouteImpl) UpdateWebhook(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("webhookID"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, util.BuildResponse(constant.InvalidRequest, "Invalid id supplied"))
+		return
+	}
+	var input schemas.WebhookUpdate
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, util.BuildResponse(constant.InvalidRequest, "Invalid input. Check your input types."))
+		return
+	}
+
+	data, err := w.webhookService.UpdateWebhook(c.Request.Context(), input, id)
+	if err != nil {
+		if err == service.ErrNotFound {
+			c.JSON(http.StatusNotFound, util.BuildResponse(constant.NotFound, "Webhook not found"))
+			return
+		}
+		if err == service.ErrInvalidInput {
+			c.JSON(http.StatusBadRequest, util.BuildResponse(constant.InvalidRequest, "Webhooks need an http(s) url and known events"))
+			return
+		}
+		c.JSON(http.StatusInternalServerError, util.BuildResponse(constant.UnknownError, "Unknown internal server error"))
+		return
+	}
+	c.JSON(http.StatusOK, util.BuildResponse(constant.Success, data))
+}
+
+func (w WebhookRouteImpl) DeleteWebhook(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("webhookID"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, util.BuildResponse(constant.InvalidRequest, "Invalid id supplied"))
+		return
+	}
+
+	err = w.webhookService.DeleteWebhook(c.Request.Context(), id)
+	if err != nil {
+		if err == service.ErrNotFound {
+			c.JSON(http.StatusNotFound, util.BuildResponse(constant.NotFound, "Webhook not found"))
+			return
+		}
+		c.JSON(http.StatusInternalServerError, util.BuildResponse(constant.UnknownError, "Unknown internal server error"))
+		return
+	}
+	c.JSON(http.StatusOK, util.BuildResponse(constant.Success, map[string]string{"message": "Webhook deleted"}))
+}
+
+func (w WebhookRouteImpl) GetDeliveries(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("webhookID"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, util.BuildResponse(constant.InvalidRequest, "Invalid id supplied"))
+		return
+	}
+	var filter schemas.DeliveryFilter
+	if err := c.ShouldBindQuery(&filter); err != nil {
+		c.JSON(http.StatusBadRequest, util.BuildResponse(constant.InvalidRequest, "Could not parse query! "+err.Error()))
+		return
+	}
+
+	data, pagination, err := w.webhookService.GetDeliveries(c.Request.Context(), id, filter)
+	if err != nil {
+		if err == service.ErrNotFound {
+			c.JSON(http.StatusNotFound, util.BuildResponse(constant.NotFound, "Webhook not found"))
+			return
+		}
+		if err == service.ErrInvalidInput {
+			c.JSON(http.StatusBadRequest, util.BuildResponse(constant.InvalidRequest, "Invalid page"))
+			return
+		}
+		c.JSON(http.StatusInternalServerError, util.BuildResponse(constant.UnknownError, "Unknown internal server error"))
+		return
+	}
+	c.JSON(http.StatusOK, util.BuildPaginatedResponse(constant.Success, data, pagination))
+}
@@ -0,0 +1,53 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/HermanPlay/web-app-backend/internal/config"
+)
+
+// NewHTTPServer serves handler on the configured port with the configured
+// timeouts.
+func NewHTTPServer(cfg *config.Config, handler http.Handler) *http.Server {
+	return &http.Server{
+		Addr:         ":" + strconv.Itoa(cfg.App.Port),
+		Handler:      handler,
+		ReadTimeout:  cfg.App.ReadTimeout,
+		WriteTimeout: cfg.App.WriteTimeout,
+		IdleTimeout:  cfg.App.IdleTimeout,
+	}
+}
+
+// Serve serves on ln until ctx is done, then stops accepting connections and
+// waits up to drain for the requests in flight. Requests still running after
+// that are cut off and reported in the error.
+func Serve(ctx context.Context, srv *http.Server, ln net.Listener, drain time.Duration) error {
+	served := make(chan error, 1)
+	go func() {
+		served <- srv.Serve(ln)
+	}()
+	select {
+	case err := <-served:
+		return err
+	case <-ctx.Done():
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), drain)
+	defer cancel()
+	err := srv.Shutdown(shutdownCtx)
+	if err != nil {
+		srv.Close()
+		return fmt.Errorf("requests still running after %v: %w", drain, err)
+	}
+	err = <-served
+	if !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
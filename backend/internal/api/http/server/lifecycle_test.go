@@ -0,0 +1,101 @@
+package server
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestServe(t *testing.T) {
+	t.Run("Drains requests in flight", func(t *testing.T) {
+		started := make(chan struct{})
+		srv := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			close(started)
+			time.Sleep(100 * time.Millisecond)
+			io.WriteString(w, "done")
+		})}
+		ln := listen(t)
+		ctx, cancel := context.WithCancel(context.Background())
+		served := make(chan error, 1)
+		go func() {
+			served <- Serve(ctx, srv, ln, time.Second)
+		}()
+
+		response := make(chan string, 1)
+		go func() {
+			body, err := get("http://" + ln.Addr().String())
+			if err != nil {
+				t.Errorf("Error when get in-flight request, when not expected. Error: %v", err)
+			}
+			response <- body
+		}()
+		<-started
+		cancel()
+
+		if body := <-response; body != "done" {
+			t.Errorf("Expected in-flight request to finish, got %q", body)
+		}
+		err := <-served
+		if err != nil {
+			t.Errorf("Error when serve, when not expected. Error: %v", err)
+		}
+		_, err = get("http://" + ln.Addr().String())
+		if err == nil {
+			t.Errorf("Expected connections to be refused after shutdown")
+		}
+	})
+	t.Run("Cuts off requests after the drain period", func(t *testing.T) {
+		started := make(chan struct{})
+		release := make(chan struct{})
+		defer close(release)
+		srv := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			close(started)
+			<-release
+		})}
+		ln := listen(t)
+		ctx, cancel := context.WithCancel(context.Background())
+		served := make(chan error, 1)
+		go func() {
+			served <- Serve(ctx, srv, ln, 50*time.Millisecond)
+		}()
+
+		go get("http://" + ln.Addr().String())
+		<-started
+		cancel()
+
+		err := <-served
+		if err == nil {
+			t.Errorf("Expected error when requests outlive the drain period, got nil")
+		}
+	})
+	t.Run("Server error", func(t *testing.T) {
+		ln := listen(t)
+		ln.Close()
+		err := Serve(context.Background(), &http.Server{}, ln, time.Second)
+		if err == nil {
+			t.Errorf("Expected error when listener is closed, got nil")
+		}
+	})
+}
+
+func listen(t *testing.T) net.Listener {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Error when listen, when not expected. Error: %v", err)
+	}
+	return ln
+}
+
+func get(url string) (string, error) {
+	response, err := http.Get(url)
+	if err != nil {
+		return "", err
+	}
+	defer response.Body.Close()
+	body, err := io.ReadAll(response.Body)
+	return string(body), err
+}
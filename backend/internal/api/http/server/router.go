@@ -5,6 +5,9 @@ import (
 
 	"github.com/HermanPlay/web-app-backend/internal/api/http"
 	"github.com/HermanPlay/web-app-backend/internal/api/http/middleware"
+	"github.com/HermanPlay/web-app-backend/internal/metrics"
+	"github.com/HermanPlay/web-app-backend/internal/tracing"
+	"github.com/HermanPlay/web-app-backend/package/domain/models"
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
 )
@@ -14,11 +17,18 @@ func Init(init *http.Initialization) *gin.Engine {
 	router := gin.New()
 	router.Use(gin.Logger())
 	router.Use(gin.Recovery())
+	router.Use(tracing.Middleware())
+	router.Use(metrics.Middleware())
+
+	// Probes of the orchestrator and the Prometheus scrape, outside of /api so
+	// they need no CORS or auth
+	router.GET("/livez", init.HealthRoute.Livez)
+	router.GET("/readyz", init.HealthRoute.Readyz)
+	router.GET("/metrics", gin.WrapH(metrics.Handler()))
 
 	api := router.Group("/api")
-	cors.Default()
 	corsConfig := cors.Config{
-		AllowOrigins:     []string{"http://localhost:5173", "http://localhost:3000"},
+		AllowOrigins:     init.Cfg.Cors.Origins,
 		AllowMethods:     []string{"POST", "GET", "OPTIONS", "PUT", "PATCH", "DELETE"},
 		AllowHeaders:     []string{"Origin", "Content-Type", "Content-Length", "Accept-Encoding", "X-CSRF-Token", "Authorization"},
 		ExposeHeaders:    []string{"Content-Length"},
@@ -28,6 +38,9 @@ func Init(init *http.Initialization) *gin.Engine {
 	}
 	api.Use(middleware.CORSMiddleware(corsConfig))
 	api.OPTIONS("/*path", cors.New(corsConfig))
+	authenticated := middleware.JwtAuthMiddleware(init.UserService)
+	adminOnly := middleware.RequireRoles(models.AdminRole)
+	organizersOnly := middleware.RequireRoles(models.ManagerRole, models.AdminRole)
 	{
 		dev := api.Group("/dev")
 		dev.GET("/status", init.DevRoute.HealthCheck)
@@ -35,28 +48,62 @@ func Init(init *http.Initialization) *gin.Engine {
 		auth := api.Group("/auth")
 		auth.POST("/register", init.AuthRoute.RegisterUser)
 		auth.POST("/login", init.AuthRoute.LoginUser)
+		auth.POST("/refresh", init.AuthRoute.RefreshToken)
+		auth.POST("/logout", init.AuthRoute.Logout)
 		auth.POST("/reset", init.AuthRoute.ResetPassword)
+		auth.POST("/reset/confirm", init.AuthRoute.ConfirmPasswordReset)
 
 		user := api.Group("/user")
-		user.Use(middleware.JwtAuthMiddleware(init.Cfg))
-		user.GET("", init.UserRoute.GetAllUserData)
-		user.POST("", init.UserRoute.AddUserData)
+		user.Use(authenticated)
+		user.GET("", adminOnly, init.UserRoute.GetAllUserData)
+		user.POST("", adminOnly, init.UserRoute.AddUserData)
 		user.GET("/:userID", init.UserRoute.GetUserById)
 		user.PATCH("/:userID", init.UserRoute.UpdateUserData)
-		user.DELETE("/:userID", init.UserRoute.DeleteUser)
+		user.DELETE("/:userID", adminOnly, init.UserRoute.DeleteUser)
 		user.GET("/decode", init.UserRoute.DecodeToken)
 
 		// Can be accessed without authentication
 		api.GET("/event/featured", init.EventRoute.GetFeaturedEvents)
 		event := api.Group("/event")
-		event.Use(middleware.JwtAuthMiddleware(init.Cfg))
+		event.Use(authenticated)
 		event.GET("", init.EventRoute.GetAllEvent)
 		event.GET("/my/:userID", init.EventRoute.GetMyEvents)
+		event.GET("/export", init.EventRoute.ExportEvents)
 		event.POST("/book/:eventID", init.EventRoute.BookEvent)
+		event.DELETE("/book/:eventID", init.EventRoute.CancelBooking)
 		event.GET("/:eventID", init.EventRoute.GetEventById)
-		event.POST("", init.EventRoute.CreateEvent)
+		event.GET("/:eventID/ics", init.CalendarRoute.GetEventCalendar)
+		event.GET("/:eventID/attendees", init.EventRoute.GetAttendees)
+		event.GET("/:eventID/attendees/export", init.EventRoute.ExportAttendees)
+		event.GET("/:eventID/organizers", init.EventRoute.GetOrganizers)
+		event.POST("/:eventID/organizers", init.EventRoute.AddOrganizer)
+		event.DELETE("/:eventID/organizers/:userID", init.EventRoute.RemoveOrganizer)
+		event.POST("", organizersOnly, init.EventRoute.CreateEvent)
+		event.POST("/import", organizersOnly, init.EventRoute.ImportEvents)
 		event.PATCH("/:eventID", init.EventRoute.UpdateEvent)
 		event.DELETE("/:eventID", init.EventRoute.DeleteEvent)
+
+		// Authenticated by the feed token, calendar apps cannot send a JWT
+		api.GET("/calendar/feed/:token", init.CalendarRoute.GetFeed)
+		calendar := api.Group("/calendar")
+		calendar.Use(authenticated)
+		calendar.POST("/token", init.CalendarRoute.CreateFeedToken)
+		calendar.DELETE("/token", init.CalendarRoute.RevokeFeedToken)
+
+		jobs := api.Group("/jobs")
+		jobs.Use(authenticated, adminOnly)
+		jobs.GET("", init.JobRoute.GetJobs)
+		jobs.GET("/:jobID", init.JobRoute.GetJob)
+		jobs.POST("/:jobID/retry", init.JobRoute.RetryJob)
+
+		webhooks := api.Group("/webhooks")
+		webhooks.Use(authenticated, adminOnly)
+		webhooks.GET("", init.WebhookRoute.GetWebhooks)
+		webhooks.POST("", init.WebhookRoute.CreateWebhook)
+		webhooks.GET("/:webhookID", init.WebhookRoute.GetWebhook)
+		webhooks.PATCH("/:webhookID", init.WebhookRoute.UpdateWebhook)
+		webhooks.DELETE("/:webhookID", init.WebhookRoute.DeleteWebhook)
+		webhooks.GET("/:webhookID/deliveries", init.WebhookRoute.GetDeliveries)
 	}
 
 	return router
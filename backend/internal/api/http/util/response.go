@@ -13,6 +13,12 @@ func BuildResponse[T any](responseStatus constant.ResponseStatus, data T) schema
 	return BuildResponse_(responseStatus.GetResponseStatusCode(), responseStatus.GetResponseStatus(), responseStatus.GetResponseMessage(), data)
 }
 
+func BuildPaginatedResponse[T any](responseStatus constant.ResponseStatus, data T, pagination *schemas.Pagination) schemas.ApiResponse[T] {
+	response := BuildResponse(responseStatus, data)
+	response.Pagination = pagination
+	return response
+}
+
 func BuildResponse_[T any](statuscode int, status, message string, data T) schemas.ApiResponse[T] {
 	return schemas.ApiResponse[T]{
 		StatusCode:      statuscode,
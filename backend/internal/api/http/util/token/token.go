@@ -1,20 +1,18 @@
 package token
 
 import (
+	"errors"
 	"time"
 
-	"github.com/HermanPlay/web-app-backend/internal/api/http/constant"
 	"github.com/HermanPlay/web-app-backend/internal/config"
 	"github.com/golang-jwt/jwt"
 )
 
 func GenerateToken(user_id int, cfg *config.Config) (string, error) {
-	token_lifespan := constant.TokenHourLifespan
-
 	claims := jwt.MapClaims{}
 	claims["authorized"] = true
 	claims["user_id"] = user_id
-	claims["exp"] = time.Now().Add(time.Hour * time.Duration(token_lifespan)).Unix()
+	claims["exp"] = time.Now().Add(cfg.Auth.AccessTokenLifetime).Unix()
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
 
 	return token.SignedString([]byte(cfg.App.ApiSecret))
@@ -22,6 +20,9 @@ func GenerateToken(user_id int, cfg *config.Config) (string, error) {
 
 func DecodeToken(tokenString string, cfg *config.Config) (jwt.MapClaims, error) {
 	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, errors.New("unexpected signing method")
+		}
 		return []byte(cfg.App.ApiSecret), nil
 	})
 	if err != nil {
@@ -30,7 +31,7 @@ func DecodeToken(tokenString string, cfg *config.Config) (jwt.MapClaims, error)
 
 	claims, ok := token.Claims.(jwt.MapClaims)
 	if !ok || !token.Valid {
-		return nil, err
+		return nil, errors.New("invalid token claims")
 	}
 
 	return claims, nil
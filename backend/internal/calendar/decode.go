@@ -0,0 +1,259 @@
+package calendar
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var ErrMalformed = errors.New("malformed iCalendar data")
+
+const dateOnly = "20060102"
+
+// Decode reads the VEVENTs of an iCalendar document. Floating times and
+// all-day dates carry no zone and are read in floating. An event whose TZID is
+// not in the tz database keeps the name as TimeZone and zero times, so that
+// validating it reports the zone.
+func Decode(r io.Reader, floating *time.Location) ([]Event, error) {
+	lines, err := unfold(r)
+	if err != nil {
+		return nil, err
+	}
+
+	events := []Event{}
+	var components []string
+	var event *Event
+	var duration time.Duration
+	var hasEnd, allDay bool
+	sawCalendar := false
+	for _, line := range lines {
+		name, params, value, ok := parseLine(line)
+		if !ok {
+			return nil, ErrMalformed
+		}
+		switch name {
+		case "BEGIN":
+			value = strings.ToUpper(value)
+			if len(components) == 0 && value != "VCALENDAR" {
+				return nil, ErrMalformed
+			}
+			if value == "VCALENDAR" {
+				sawCalendar = true
+			}
+			components = append(components, value)
+			if value == "VEVENT" && len(components) == 2 {
+				event = &Event{}
+				duration, hasEnd, allDay = 0, false, false
+			}
+			continue
+		case "END":
+			value = strings.ToUpper(value)
+			if len(components) == 0 || components[len(components)-1] != value {
+				return nil, ErrMalformed
+			}
+			components = components[:len(components)-1]
+			if value == "VEVENT" && event != nil && len(components) == 1 {
+				if !hasEnd && !event.Start.IsZero() {
+					switch {
+					case duration > 0:
+						event.End = event.Start.Add(duration)
+					case allDay:
+						// An all-day event without an end lasts that day
+						event.End = event.Start.AddDate(0, 0, 1)
+					default:
+						event.End = event.Start
+					}
+				}
+				events = append(events, *event)
+				event = nil
+			}
+			continue
+		}
+		// Properties of alarms and other nested components are skipped
+		if event == nil || len(components) != 2 {
+			continue
+		}
+		switch name {
+		case "UID":
+			event.UID = unescapeText(value)
+		case "SEQUENCE":
+			event.Sequence, _ = strconv.Atoi(value)
+		case "SUMMARY":
+			event.Summary = unescapeText(value)
+		case "DESCRIPTION":
+			event.Description = unescapeText(value)
+		case "LOCATION":
+			event.Location = unescapeText(value)
+		case "URL":
+			event.URL = value
+		case "DTSTART":
+			event.Start, event.TimeZone, allDay = parseTime(value, params, floating)
+		case "DTEND":
+			event.End, _, _ = parseTime(value, params, floating)
+			hasEnd = true
+		case "DURATION":
+			duration, _ = parseDuration(value)
+		}
+	}
+	if !sawCalendar || len(components) != 0 {
+		return nil, ErrMalformed
+	}
+	return events, nil
+}
+
+// unfold joins continuation lines, which start with a space or a tab, to the
+// line before them.
+func unfold(r io.Reader) ([]string, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	var lines []string
+	for scanner.Scan() {
+		line := strings.TrimSuffix(scanner.Text(), "\r")
+		if line == "" {
+			continue
+		}
+		if (line[0] == ' ' || line[0] == '\t') && len(lines) > 0 {
+			lines[len(lines)-1] += line[1:]
+			continue
+		}
+		lines = append(lines, line)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, ErrMalformed
+	}
+	return lines, nil
+}
+
+// parseLine splits a content line into its upper-cased name, its parameters
+// and its value. Parameter values may be quoted and contain colons.
+func parseLine(line string) (string, map[string]string, string, bool) {
+	quoted := false
+	colon := -1
+	for i := 0; i < len(line); i++ {
+		if line[i] == '"' {
+			quoted = !quoted
+		}
+		if line[i] == ':' && !quoted {
+			colon = i
+			break
+		}
+	}
+	if colon <= 0 {
+		return "", nil, "", false
+	}
+	head, value := line[:colon], line[colon+1:]
+	parts := strings.Split(head, ";")
+	params := map[string]string{}
+	for _, param := range parts[1:] {
+		key, val, ok := strings.Cut(param, "=")
+		if !ok {
+			return "", nil, "", false
+		}
+		params[strings.ToUpper(key)] = strings.Trim(val, `"`)
+	}
+	return strings.ToUpper(parts[0]), params, value, true
+}
+
+// parseTime reads a DATE or DATE-TIME value and reports the zone it is in and
+// whether it was a date.
+func parseTime(value string, params map[string]string, floating *time.Location) (time.Time, string, bool) {
+	if params["VALUE"] == "DATE" || len(value) == len(dateOnly) {
+		t, err := time.ParseInLocation(dateOnly, value, floating)
+		if err != nil {
+			return time.Time{}, floating.String(), true
+		}
+		return t, floating.String(), true
+	}
+	if strings.HasSuffix(value, "Z") {
+		t, err := time.Parse(dateTimeUTC, value)
+		if err != nil {
+			return time.Time{}, "UTC", false
+		}
+		return t, "UTC", false
+	}
+	loc := floating
+	if tzid, ok := params["TZID"]; ok {
+		// Some producers prefix globally unique zone names with a slash
+		tzid = strings.TrimPrefix(tzid, "/")
+		var err error
+		loc, err = time.LoadLocation(tzid)
+		if err != nil || tzid == "Local" {
+			return time.Time{}, tzid, false
+		}
+	}
+	t, err := time.ParseInLocation(dateTimeLocal, value, loc)
+	if err != nil {
+		return time.Time{}, loc.String(), false
+	}
+	return t, loc.String(), false
+}
+
+// parseDuration reads a DURATION value such as PT1H30M or P1D. Days and
+// weeks are taken as 24 hours.
+func parseDuration(value string) (time.Duration, bool) {
+	value = strings.TrimPrefix(value, "+")
+	rest, ok := strings.CutPrefix(value, "P")
+	if !ok || rest == "" {
+		return 0, false
+	}
+	var total time.Duration
+	inTime := false
+	number := ""
+	for _, c := range rest {
+		switch {
+		case c >= '0' && c <= '9':
+			number += string(c)
+			continue
+		case c == 'T':
+			inTime = true
+			continue
+		}
+		n, err := strconv.Atoi(number)
+		if err != nil {
+			return 0, false
+		}
+		number = ""
+		switch {
+		case c == 'W' && !inTime:
+			total += time.Duration(n) * 7 * 24 * time.Hour
+		case c == 'D' && !inTime:
+			total += time.Duration(n) * 24 * time.Hour
+		case c == 'H' && inTime:
+			total += time.Duration(n) * time.Hour
+		case c == 'M' && inTime:
+			total += time.Duration(n) * time.Minute
+		case c == 'S' && inTime:
+			total += time.Duration(n) * time.Second
+		default:
+			return 0, false
+		}
+	}
+	if number != "" {
+		return 0, false
+	}
+	return total, true
+}
+
+func unescapeText(s string) string {
+	if !strings.Contains(s, `\`) {
+		return s
+	}
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] != '\\' || i == len(s)-1 {
+			b.WriteByte(s[i])
+			continue
+		}
+		i++
+		switch s[i] {
+		case 'n', 'N':
+			b.WriteByte('\n')
+		default:
+			b.WriteByte(s[i])
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,144 @@
+package calendar
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDecode(t *testing.T) {
+	warsaw, _ := time.LoadLocation("Europe/Warsaw")
+	ics := strings.Join([]string{
+		"BEGIN:VCALENDAR",
+		"VERSION:2.0",
+		"BEGIN:VTIMEZONE",
+		"TZID:Europe/Warsaw",
+		"BEGIN:STANDARD",
+		"DTSTART:20231029T030000",
+		"END:STANDARD",
+		"END:VTIMEZONE",
+		"BEGIN:VEVENT",
+		"UID:1@example.com",
+		"SUMMARY:Jazz\\; Blues\\, and more",
+		"DESCRIPTION:First line\\nSecond line that is long enough to be folded over",
+		"  two lines",
+		`DTSTART;TZID="Europe/Warsaw":20241115T190000`,
+		"DTEND;TZID=Europe/Warsaw:20241115T220000",
+		"LOCATION:Warsaw",
+		"BEGIN:VALARM",
+		"DESCRIPTION:Reminder",
+		"END:VALARM",
+		"END:VEVENT",
+		"BEGIN:VEVENT",
+		"SUMMARY:Floating",
+		"DTSTART:20241116T100000",
+		"DURATION:PT1H30M",
+		"END:VEVENT",
+		"BEGIN:VEVENT",
+		"SUMMARY:All day",
+		"DTSTART;VALUE=DATE:20241117",
+		"END:VEVENT",
+		"BEGIN:VEVENT",
+		"SUMMARY:Outlook",
+		"DTSTART;TZID=W. Europe Standard Time:20241118T100000",
+		"DTEND;TZID=W. Europe Standard Time:20241118T110000",
+		"END:VEVENT",
+		"END:VCALENDAR",
+	}, "\r\n")
+
+	events, err := Decode(strings.NewReader(ics), warsaw)
+	if err != nil {
+		t.Fatalf("Error when decode calendar, when not expected. Error: %v", err)
+	}
+	if len(events) != 4 {
+		t.Fatalf("Decoded %d events, when expected 4", len(events))
+	}
+
+	jazz := events[0]
+	if jazz.Summary != "Jazz; Blues, and more" || jazz.Location != "Warsaw" || jazz.UID != "1@example.com" {
+		t.Errorf("Event is not the same, got: %+v", jazz)
+	}
+	if jazz.Description != "First line\nSecond line that is long enough to be folded over two lines" {
+		t.Errorf("Description is not unescaped and unfolded, got: %q", jazz.Description)
+	}
+	if !jazz.Start.Equal(time.Date(2024, 11, 15, 18, 0, 0, 0, time.UTC)) || !jazz.End.Equal(time.Date(2024, 11, 15, 21, 0, 0, 0, time.UTC)) || jazz.TimeZone != "Europe/Warsaw" {
+		t.Errorf("Times are not the same, got: %v - %v (%v)", jazz.Start, jazz.End, jazz.TimeZone)
+	}
+
+	floating := events[1]
+	if !floating.Start.Equal(time.Date(2024, 11, 16, 10, 0, 0, 0, warsaw)) || floating.End.Sub(floating.Start) != 90*time.Minute {
+		t.Errorf("Floating event is not read in the default zone, got: %v - %v", floating.Start, floating.End)
+	}
+
+	allDay := events[2]
+	if !allDay.Start.Equal(time.Date(2024, 11, 17, 0, 0, 0, 0, warsaw)) || allDay.End.Sub(allDay.Start) != 24*time.Hour {
+		t.Errorf("All day event does not last the day, got: %v - %v", allDay.Start, allDay.End)
+	}
+
+	outlook := events[3]
+	if outlook.TimeZone != "W. Europe Standard Time" || !outlook.Start.IsZero() {
+		t.Errorf("Event with an unknown zone is not left for validation, got: %+v", outlook)
+	}
+}
+
+func TestDecodeRoundTrip(t *testing.T) {
+	start := time.Date(2024, 11, 15, 18, 0, 0, 0, time.UTC)
+	want := Event{
+		UID:         "event-1@example.com",
+		Sequence:    4,
+		Summary:     "Jazz; Blues, and more",
+		Description: strings.Repeat("Long description, ", 10),
+		Location:    "Warsaw",
+		Start:       start,
+		End:         start.Add(3 * time.Hour),
+		TimeZone:    "America/New_York",
+	}
+	events, err := Decode(strings.NewReader(string(Encode(Calendar{Events: []Event{want}}, start))), time.UTC)
+	if err != nil {
+		t.Fatalf("Error when decode calendar, when not expected. Error: %v", err)
+	}
+	if len(events) != 1 {
+		t.Fatalf("Decoded %d events, when expected 1", len(events))
+	}
+	got := events[0]
+	if got.UID != want.UID || got.Sequence != want.Sequence || got.Summary != want.Summary || got.Description != want.Description ||
+		!got.Start.Equal(want.Start) || !got.End.Equal(want.End) || got.TimeZone != want.TimeZone {
+		t.Errorf("Event is not the same, got: %+v, want: %+v", got, want)
+	}
+}
+
+func TestDecodeMalformed(t *testing.T) {
+	for _, ics := range []string{
+		"",
+		"not a calendar",
+		"BEGIN:VEVENT\r\nEND:VEVENT",
+		"BEGIN:VCALENDAR\r\nBEGIN:VEVENT\r\nEND:VCALENDAR",
+		"BEGIN:VCALENDAR\r\nBEGIN:VEVENT\r\nEND:VEVENT",
+	} {
+		_, err := Decode(strings.NewReader(ics), time.UTC)
+		if err != ErrMalformed {
+			t.Errorf("Error for %q is not ErrMalformed, when expected. Error: %v", ics, err)
+		}
+	}
+}
+
+func TestParseDuration(t *testing.T) {
+	for value, want := range map[string]time.Duration{
+		"PT1H30M": 90 * time.Minute,
+		"P1D":     24 * time.Hour,
+		"P1W":     7 * 24 * time.Hour,
+		"P1DT2H":  26 * time.Hour,
+		"+PT15S":  15 * time.Second,
+	} {
+		got, ok := parseDuration(value)
+		if !ok || got != want {
+			t.Errorf("Duration of %q is %v, when expected %v", value, got, want)
+		}
+	}
+	for _, value := range []string{"", "P", "1H", "PT1D", "P1H", "PT1"} {
+		_, ok := parseDuration(value)
+		if ok {
+			t.Errorf("Duration %q is accepted, when expected to be rejected", value)
+		}
+	}
+}
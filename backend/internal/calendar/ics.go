@@ -0,0 +1,229 @@
+// Package calendar writes events as iCalendar documents (RFC 5545) that
+// calendar apps can import or subscribe to.
+package calendar
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	ContentType = "text/calendar; charset=utf-8"
+
+	prodID        = "-//HermanPlay//Event Manager//EN"
+	lineLimit     = 75 // octets per content line, longer lines are folded
+	dateTimeLocal = "20060102T150405"
+	dateTimeUTC   = "20060102T150405Z"
+)
+
+type Event struct {
+	UID         string // stable across exports, calendar apps match updates by it
+	Sequence    int    // revision of the event, raised on every change
+	Summary     string
+	Description string
+	Location    string
+	URL         string
+	Start       time.Time
+	End         time.Time
+	TimeZone    string // IANA name the event is shown in, UTC when empty or unknown
+	Created     time.Time
+	Modified    time.Time
+}
+
+type Calendar struct {
+	Name   string
+	Events []Event
+}
+
+// Encode renders cal as a VCALENDAR. Every time zone used by an event gets a
+// VTIMEZONE with the offset changes in the span of its events, so clients do
+// not need to know the IANA name. now is used as the DTSTAMP.
+func Encode(cal Calendar, now time.Time) []byte {
+	w := &writer{}
+	w.line("BEGIN", "VCALENDAR")
+	w.line("VERSION", "2.0")
+	w.line("PRODID", prodID)
+	w.line("CALSCALE", "GREGORIAN")
+	w.line("METHOD", "PUBLISH")
+	if cal.Name != "" {
+		w.line("X-WR-CALNAME", escapeText(cal.Name))
+	}
+
+	zones := map[string]*zoneSpan{}
+	for _, event := range cal.Events {
+		loc := location(event.TimeZone)
+		if loc == time.UTC {
+			continue
+		}
+		span, ok := zones[loc.String()]
+		if !ok {
+			zones[loc.String()] = &zoneSpan{loc: loc, from: event.Start, to: event.End}
+			continue
+		}
+		if event.Start.Before(span.from) {
+			span.from = event.Start
+		}
+		if event.End.After(span.to) {
+			span.to = event.End
+		}
+	}
+	names := make([]string, 0, len(zones))
+	for name := range zones {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		writeTimeZone(w, zones[name])
+	}
+
+	for _, event := range cal.Events {
+		writeEvent(w, event, now)
+	}
+	w.line("END", "VCALENDAR")
+	return w.buf.Bytes()
+}
+
+type zoneSpan struct {
+	loc      *time.Location
+	from, to time.Time
+}
+
+func location(name string) *time.Location {
+	if name == "" || name == "Local" {
+		return time.UTC
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		// Zones are validated when events are saved; an unknown one here
+		// means the tz database changed, the UTC instant is still right
+		return time.UTC
+	}
+	return loc
+}
+
+func writeEvent(w *writer, event Event, now time.Time) {
+	loc := location(event.TimeZone)
+	w.line("BEGIN", "VEVENT")
+	w.line("UID", escapeText(event.UID))
+	w.line("DTSTAMP", now.UTC().Format(dateTimeUTC))
+	if !event.Created.IsZero() {
+		w.line("CREATED", event.Created.UTC().Format(dateTimeUTC))
+	}
+	if !event.Modified.IsZero() {
+		w.line("LAST-MODIFIED", event.Modified.UTC().Format(dateTimeUTC))
+	}
+	w.line("SEQUENCE", strconv.Itoa(event.Sequence))
+	writeTime(w, "DTSTART", event.Start, loc)
+	writeTime(w, "DTEND", event.End, loc)
+	w.line("SUMMARY", escapeText(event.Summary))
+	if event.Description != "" {
+		w.line("DESCRIPTION", escapeText(event.Description))
+	}
+	if event.Location != "" {
+		w.line("LOCATION", escapeText(event.Location))
+	}
+	if event.URL != "" {
+		w.line("URL", event.URL)
+	}
+	w.line("END", "VEVENT")
+}
+
+func writeTime(w *writer, name string, t time.Time, loc *time.Location) {
+	if loc == time.UTC {
+		w.line(name, t.UTC().Format(dateTimeUTC))
+		return
+	}
+	w.line(name+";TZID="+loc.String(), t.In(loc).Format(dateTimeLocal))
+}
+
+// writeTimeZone lists the observance in effect at the start of the span and
+// every offset change up to its end. Listing each change instead of
+// recurrence rules keeps the output exact for zones whose rules changed.
+func writeTimeZone(w *writer, span *zoneSpan) {
+	w.line("BEGIN", "VTIMEZONE")
+	w.line("TZID", span.loc.String())
+	start, end := span.from.In(span.loc).ZoneBounds()
+	if start.IsZero() {
+		// The zone never changed before the span, pick a date before any event
+		start = time.Date(1970, 1, 1, 0, 0, 0, 0, span.loc)
+		_, offset := start.Zone()
+		writeObservance(w, start, offset)
+	} else {
+		_, offset := start.Add(-time.Second).Zone()
+		writeObservance(w, start, offset)
+	}
+	for !end.IsZero() && !end.After(span.to) {
+		_, offset := end.Add(-time.Second).Zone()
+		writeObservance(w, end, offset)
+		_, end = end.ZoneBounds()
+	}
+	w.line("END", "VTIMEZONE")
+}
+
+// writeObservance writes the zone that starts at t. DTSTART is the local time
+// of the change in the offset that applied before it.
+func writeObservance(w *writer, t time.Time, offsetFrom int) {
+	kind := "STANDARD"
+	if t.IsDST() {
+		kind = "DAYLIGHT"
+	}
+	abbreviation, offsetTo := t.Zone()
+	w.line("BEGIN", kind)
+	w.line("DTSTART", t.In(time.FixedZone("", offsetFrom)).Format(dateTimeLocal))
+	w.line("TZOFFSETFROM", formatOffset(offsetFrom))
+	w.line("TZOFFSETTO", formatOffset(offsetTo))
+	w.line("TZNAME", escapeText(abbreviation))
+	w.line("END", kind)
+}
+
+func formatOffset(seconds int) string {
+	sign := '+'
+	if seconds < 0 {
+		sign = '-'
+		seconds = -seconds
+	}
+	offset := fmt.Sprintf("%c%02d%02d", sign, seconds/3600, seconds/60%60)
+	if seconds%60 != 0 {
+		offset += fmt.Sprintf("%02d", seconds%60)
+	}
+	return offset
+}
+
+var textEscaper = strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\r\n", `\n`, "\n", `\n`, "\r", "")
+
+func escapeText(s string) string {
+	return textEscaper.Replace(s)
+}
+
+type writer struct {
+	buf bytes.Buffer
+}
+
+// line writes a content line, folding it into continuation lines that start
+// with a space once it gets longer than lineLimit octets.
+func (w *writer) line(name, value string) {
+	line := name + ":" + value
+	width := 0
+	for len(line) > 0 {
+		limit := lineLimit - width
+		if len(line) <= limit {
+			w.buf.WriteString(line)
+			break
+		}
+		// Never split a multi-byte character
+		cut := limit
+		for cut > 0 && !utf8.RuneStart(line[cut]) {
+			cut--
+		}
+		w.buf.WriteString(line[:cut])
+		w.buf.WriteString("\r\n ")
+		line = line[cut:]
+		width = 1
+	}
+	w.buf.WriteString("\r\n")
+}
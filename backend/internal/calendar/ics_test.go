@@ -0,0 +1,130 @@
+package calendar
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestEncode(t *testing.T) {
+	now := time.Date(2024, 11, 1, 12, 0, 0, 0, time.UTC)
+	start := time.Date(2024, 11, 15, 18, 0, 0, 0, time.UTC)
+	ics := string(Encode(Calendar{
+		Name: "My events",
+		Events: []Event{
+			{
+				UID:         "event-1@example.com",
+				Sequence:    2,
+				Summary:     "Jazz; Blues, and more",
+				Description: "First line\nSecond line",
+				Location:    "Warsaw",
+				URL:         "http://frontend/events/1",
+				Start:       start,
+				End:         start.Add(3 * time.Hour),
+				TimeZone:    "Europe/Warsaw",
+				Modified:    now,
+			},
+			{
+				UID:     "event-2@example.com",
+				Summary: "Online",
+				Start:   start,
+				End:     start.Add(time.Hour),
+			},
+		},
+	}, now))
+
+	if !strings.HasPrefix(ics, "BEGIN:VCALENDAR\r\nVERSION:2.0\r\n") || !strings.HasSuffix(ics, "END:VCALENDAR\r\n") {
+		t.Errorf("Calendar is not wrapped in VCALENDAR, got: %q", ics)
+	}
+	for _, line := range []string{
+		"X-WR-CALNAME:My events",
+		"UID:event-1@example.com",
+		"DTSTAMP:20241101T120000Z",
+		"LAST-MODIFIED:20241101T120000Z",
+		"SEQUENCE:2",
+		"DTSTART;TZID=Europe/Warsaw:20241115T190000",
+		"DTEND;TZID=Europe/Warsaw:20241115T220000",
+		`SUMMARY:Jazz\; Blues\, and more`,
+		`DESCRIPTION:First line\nSecond line`,
+		"URL:http://frontend/events/1",
+		"TZID:Europe/Warsaw",
+		"DTSTART:20241115T180000Z",
+		"SEQUENCE:0",
+	} {
+		if !strings.Contains(ics, "\r\n"+line+"\r\n") {
+			t.Errorf("Calendar does not contain %q, got: %q", line, ics)
+		}
+	}
+	if strings.Count(ics, "BEGIN:VTIMEZONE") != 1 {
+		t.Errorf("Calendar has %d time zones, when expected only Europe/Warsaw", strings.Count(ics, "BEGIN:VTIMEZONE"))
+	}
+}
+
+func TestTimeZone(t *testing.T) {
+	t.Run("Offset changes", func(t *testing.T) {
+		// Spans the switch to summer time on 31 March 2024
+		w := &writer{}
+		loc, _ := time.LoadLocation("Europe/Warsaw")
+		writeTimeZone(w, &zoneSpan{
+			loc:  loc,
+			from: time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC),
+			to:   time.Date(2024, 4, 1, 10, 0, 0, 0, time.UTC),
+		})
+		want := strings.Join([]string{
+			"BEGIN:VTIMEZONE",
+			"TZID:Europe/Warsaw",
+			"BEGIN:STANDARD",
+			"DTSTART:20231029T030000",
+			"TZOFFSETFROM:+0200",
+			"TZOFFSETTO:+0100",
+			"TZNAME:CET",
+			"END:STANDARD",
+			"BEGIN:DAYLIGHT",
+			"DTSTART:20240331T020000",
+			"TZOFFSETFROM:+0100",
+			"TZOFFSETTO:+0200",
+			"TZNAME:CEST",
+			"END:DAYLIGHT",
+			"END:VTIMEZONE",
+		}, "\r\n") + "\r\n"
+		if w.buf.String() != want {
+			t.Errorf("Time zone is not the same, got: %q, want: %q", w.buf.String(), want)
+		}
+	})
+	t.Run("Fixed offset", func(t *testing.T) {
+		w := &writer{}
+		writeTimeZone(w, &zoneSpan{
+			loc:  time.FixedZone("IST", 5*3600+30*60),
+			from: time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC),
+			to:   time.Date(2024, 4, 1, 10, 0, 0, 0, time.UTC),
+		})
+		if strings.Count(w.buf.String(), "BEGIN:STANDARD") != 1 || !strings.Contains(w.buf.String(), "TZOFFSETTO:+0530\r\n") {
+			t.Errorf("Time zone is not a single fixed offset, got: %q", w.buf.String())
+		}
+	})
+}
+
+func TestLineFolding(t *testing.T) {
+	w := &writer{}
+	w.line("DESCRIPTION", strings.Repeat("ż", 100))
+	lines := strings.Split(strings.TrimSuffix(w.buf.String(), "\r\n"), "\r\n")
+	if len(lines) < 3 {
+		t.Fatalf("Line is not folded, got: %q", w.buf.String())
+	}
+	unfolded := ""
+	for i, line := range lines {
+		if len(line) > lineLimit {
+			t.Errorf("Line %d has %d octets, when expected at most %d", i, len(line), lineLimit)
+		}
+		if i > 0 {
+			if !strings.HasPrefix(line, " ") {
+				t.Errorf("Continuation line %d does not start with a space, got: %q", i, line)
+			}
+			line = line[1:]
+		}
+		unfolded += line
+	}
+	if unfolded != "DESCRIPTION:"+strings.Repeat("ż", 100) {
+		t.Errorf("Unfolded line is not the same, got: %q", unfolded)
+	}
+}
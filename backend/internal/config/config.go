@@ -2,122 +2,250 @@ package config
 
 import (
 	"errors"
+	"flag"
+	"fmt"
 	"os"
-	"strconv"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pelletier/go-toml/v2"
+	"github.com/sirupsen/logrus"
+	"gopkg.in/yaml.v3"
 )
 
 type (
 	Config struct {
-		App App
-		Db  Db
+		App     App
+		Db      Db
+		Mail    Mail
+		Jobs    Jobs
+		Cors    Cors
+		Auth    Auth
+		Log     Log
+		Tracing Tracing
 	}
 
 	App struct {
 		Port      int
 		ApiSecret string
+		PublicURL string // where the frontend is served, used for links in emails
+		// How often due event reminders are looked for, 0 turns reminders off
+		ReminderInterval time.Duration
+		// Limits of the http server, 0 means no limit
+		ReadTimeout  time.Duration
+		WriteTimeout time.Duration
+		IdleTimeout  time.Duration
+		// How long in-flight requests and jobs may finish on shutdown
+		ShutdownTimeout time.Duration
 	}
 
 	Db struct {
+		Driver   string // "postgres" or "sqlite"
+		Path     string // database file of the sqlite driver, ":memory:" keeps it in memory
 		Host     string
 		Port     int
 		User     string
 		Password string
 		DBName   string
 		SSLMode  string
-		TimeZone string
+		TimeZone string // session time zone of postgres connections, empty keeps the server's
+		// What to do about pending migrations on start: "check" refuses to
+		// start, "apply" runs them, "ignore" starts anyway
+		Migrations string
+		// Connection pool of the postgres driver, 0 open connections means no
+		// limit and 0 lifetime keeps connections forever
+		MaxOpenConns    int
+		MaxIdleConns    int
+		ConnMaxLifetime time.Duration
 	}
-)
 
-var errApiPort = errors.New("error parsing env variable port")
-var errApiPortMissing = errors.New("error api port is not present in env")
-var errApiSecret = errors.New("error parsing env variable api_secret")
-var errApiSecretMissing = errors.New("error api secret is not present in env")
-var errDbHost = errors.New("error parsing env variable db_host")
-var errDbHostMissing = errors.New("error db host is not present in env")
-var errDbPort = errors.New("error parsing env variable db_port")
-var errDbPortMissing = errors.New("error db port is not present in env")
-var errDbUser = errors.New("error parsing env variable db_user")
-var errDbUserMissing = errors.New("error db user is not present in env")
-var errDbPassword = errors.New("error parsing env variable db_password")
-var errDbPasswordMissing = errors.New("error db password is not present in env")
-var errDbName = errors.New("error parsing env variable db_name")
-var errDbNameMissing = errors.New("error db name is not present in env")
-
-// Loads config from ENVIRONEMT
-func GetConfig() (*Config, error) {
-	port, ok := os.LookupEnv("port")
-	if !ok {
-		return nil, errApiPortMissing
-	}
-	app_port, err := strconv.Atoi(port)
-	if err != nil {
-		return nil, errApiPort
+	Mail struct {
+		Driver   string // "file", "smtp" or "memory"
+		Dir      string // output directory of the file driver
+		Host     string
+		Port     int
+		Username string
+		Password string
+		From     string
 	}
 
-	api_secret, ok := os.LookupEnv("api_secret")
-	if !ok {
-		return nil, errApiSecretMissing
-	}
-	if len(api_secret) == 0 {
-		return nil, errApiSecret
+	Jobs struct {
+		Workers      int           // background job workers in this process, 0 leaves the queue to other replicas
+		PollInterval time.Duration // how long an idle worker waits before looking for jobs again
 	}
 
-	app := App{
-		Port:      app_port,
-		ApiSecret: api_secret,
+	Cors struct {
+		Origins []string // origins allowed to call the api with credentials
 	}
 
-	db_host, ok := os.LookupEnv("db_host")
-	if !ok {
-		return nil, errDbHostMissing
+	Auth struct {
+		AccessTokenLifetime  time.Duration
+		RefreshTokenLifetime time.Duration
+		ResetTokenLifetime   time.Duration // how long a password reset link works
 	}
-	if len(db_host) == 0 {
-		return nil, errDbHost
+
+	Log struct {
+		Level logrus.Level
 	}
 
-	db_port_env, ok := os.LookupEnv("db_port")
-	if !ok {
-		return nil, errDbPortMissing
+	Tracing struct {
+		Exporter    string  // "none", "stdout" or "otlp"
+		Endpoint    string  // url of the OTLP/HTTP collector, empty for the exporter's default
+		SampleRatio float64 // share of the new traces that are recorded
+		ServiceName string
 	}
-	db_port, err := strconv.Atoi(db_port_env)
+)
+
+var errConfigFile = errors.New("error reading config file")
+var errConfigFileFormat = errors.New("error config file must be .yaml, .yml or .toml")
+var errUnknownSetting = errors.New("error unknown setting")
+
+// GetConfig loads the config without command line flags.
+func GetConfig() (*Config, error) {
+	cfg, _, err := Load(nil)
+	return cfg, err
+}
+
+// Load builds the config from, in increasing precedence, the defaults, a
+// YAML or TOML file, the environment and the command line flags in args. The
+// file is named by the -config flag or the config_file variable. Every
+// setting is validated and all problems are reported together. The arguments
+// left after the flags are returned.
+func Load(args []string) (*Config, []string, error) {
+	flags := flag.NewFlagSet("app", flag.ContinueOnError)
+	configFile := flags.String("config", os.Getenv("config_file"), "YAML or TOML config file (env config_file)")
+	flagValues := make([]string, len(settings))
+	for i, s := range settings {
+		flags.StringVar(&flagValues[i], s.key, "", fmt.Sprintf("%s (env %s)", s.usage, s.env))
+	}
+	err := flags.Parse(args)
 	if err != nil {
-		return nil, errDbPort
+		return nil, nil, err
 	}
 
-	db_user, ok := os.LookupEnv("db_user")
-	if !ok {
-		return nil, errDbUserMissing
-	}
-	if len(db_user) == 0 {
-		return nil, errDbUser
-	}
+	values := map[string]value{}
+	for _, s := range settings {
+		if s.fallback != "" {
+			values[s.key] = value{raw: s.fallback, origin: "default"}
+		}
+	}
+	if *configFile != "" {
+		fileValues, err := readConfigFile(*configFile)
+		if err != nil {
+			return nil, nil, err
+		}
+		for key, v := range fileValues {
+			values[key] = v
+		}
+	}
+	for _, s := range settings {
+		if raw, ok := os.LookupEnv(s.env); ok {
+			values[s.key] = value{raw: raw, origin: "env " + s.env}
+		}
+	}
+	flags.Visit(func(f *flag.Flag) {
+		for i, s := range settings {
+			if s.key == f.Name {
+				values[s.key] = value{raw: flagValues[i], origin: "flag -" + s.key}
+			}
+		}
+	})
 
-	db_password, ok := os.LookupEnv("db_password")
-	if !ok {
-		return nil, errDbPasswordMissing
-	}
-	if len(db_password) == 0 {
-		return nil, errDbPassword
+	cfg, err := build(values)
+	if err != nil {
+		return nil, nil, err
 	}
+	return cfg, flags.Args(), nil
+}
+
+// value is the raw text of a setting and where it came from, for errors.
+type value struct {
+	raw    string
+	origin string
+}
 
-	db_name, ok := os.LookupEnv("db_name")
-	if !ok {
-		return nil, errDbNameMissing
+func build(values map[string]value) (*Config, error) {
+	var errs []error
+	known := map[string]bool{}
+	cfg := &Config{}
+	for _, s := range settings {
+		known[s.key] = true
+		v, ok := values[s.key]
+		// An empty optional setting keeps its default
+		if ok && v.raw == "" && s.fallback != "" {
+			v = value{raw: s.fallback, origin: "default"}
+		}
+		if !ok {
+			continue
+		}
+		err := s.set(cfg, v.raw)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", v.origin, err))
+		}
+	}
+	for _, s := range settings {
+		if _, ok := values[s.key]; !ok && s.required != nil && s.required(cfg) {
+			errs = append(errs, fmt.Errorf("%w, set env %s or flag -%s", s.missing, s.env, s.key))
+		}
+	}
+	var unknown []string
+	for key := range values {
+		if !known[key] {
+			unknown = append(unknown, key)
+		}
+	}
+	sort.Strings(unknown)
+	for _, key := range unknown {
+		errs = append(errs, fmt.Errorf("%s: %w %s", values[key].origin, errUnknownSetting, key))
+	}
+	if len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+	return cfg, nil
+}
+
+// readConfigFile returns the settings of a YAML or TOML file, sections and
+// keys joined by dots as in "db.host". Lists are joined by commas.
+func readConfigFile(path string) (map[string]value, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", errConfigFile, err)
 	}
-	if len(db_name) == 0 {
-		return nil, errDbName
+	tree := map[string]any{}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, &tree)
+	case ".toml":
+		err = toml.Unmarshal(data, &tree)
+	default:
+		return nil, errConfigFileFormat
 	}
-
-	db := Db{
-		Host:     db_host,
-		Port:     db_port,
-		User:     db_user,
-		Password: db_password,
-		DBName:   db_name,
+	if err != nil {
+		return nil, fmt.Errorf("%w %s: %w", errConfigFile, path, err)
 	}
+	values := map[string]value{}
+	flatten(path, "", tree, values)
+	return values, nil
+}
 
-	return &Config{
-		App: app,
-		Db:  db,
-	}, nil
+func flatten(path, prefix string, tree map[string]any, values map[string]value) {
+	for key, node := range tree {
+		key = prefix + key
+		switch node := node.(type) {
+		case map[string]any:
+			flatten(path, key+".", node, values)
+		case []any:
+			items := make([]string, len(node))
+			for i, item := range node {
+				items[i] = fmt.Sprint(item)
+			}
+			values[key] = value{raw: strings.Join(items, ","), origin: path + " " + key}
+		case nil:
+			values[key] = value{origin: path + " " + key}
+		default:
+			values[key] = value{raw: fmt.Sprint(node), origin: path + " " + key}
+		}
+	}
 }
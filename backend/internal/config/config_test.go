@@ -1,16 +1,26 @@
 package config
 
 import (
+	"errors"
 	"os"
+	"path/filepath"
 	"testing"
+	"time"
 
+	"github.com/sirupsen/logrus"
 	"gotest.tools/assert"
 )
 
 func TestGetConfig(t *testing.T) {
 	correct := &Config{
-		App{Port: 8080, ApiSecret: "secret"},
-		Db{Port: 5432, Host: "localhost", User: "postgres", Password: "postgres", DBName: "backend"},
+		App{Port: 8080, ApiSecret: "secret", PublicURL: "http://localhost:3000", ReminderInterval: time.Minute, ReadTimeout: 15 * time.Second, WriteTimeout: time.Minute, IdleTimeout: 2 * time.Minute, ShutdownTimeout: 20 * time.Second},
+		Db{Driver: "postgres", Path: "backend.db", Port: 5432, Host: "localhost", User: "postgres", Password: "postgres", DBName: "backend", SSLMode: "disable", Migrations: "check", MaxIdleConns: 2},
+		Mail{Driver: "file", Dir: "mail", Port: 587, From: "no-reply@eventmanager.com"},
+		Jobs{Workers: 2, PollInterval: time.Second},
+		Cors{Origins: []string{"http://localhost:5173", "http://localhost:3000"}},
+		Auth{AccessTokenLifetime: 15 * time.Minute, RefreshTokenLifetime: 30 * 24 * time.Hour, ResetTokenLifetime: time.Hour},
+		Log{Level: logrus.InfoLevel},
+		Tracing{Exporter: "none", SampleRatio: 1, ServiceName: "event-manager-backend"},
 	}
 	t.Run("correct config", func(t *testing.T) {
 		generateConfig(true, true, true, true, true, true, true)
@@ -110,6 +120,369 @@ func TestGetConfig(t *testing.T) {
 		assertError(t, err, errDbName)
 		resetConfig()
 	})
+	t.Run("smtp mail", func(t *testing.T) {
+		generateConfig(true, true, true, true, true, true, true)
+		os.Setenv("mail_driver", "smtp")
+		os.Setenv("smtp_host", "smtp.example.com")
+		os.Setenv("smtp_port", "2525")
+		result, err := GetConfig()
+		assert.NilError(t, err)
+		assert.DeepEqual(t, result.Mail, Mail{Driver: "smtp", Dir: "mail", Host: "smtp.example.com", Port: 2525, From: "no-reply@eventmanager.com"})
+		resetConfig()
+	})
+	t.Run("missing smtp_host", func(t *testing.T) {
+		generateConfig(true, true, true, true, true, true, true)
+		os.Setenv("mail_driver", "smtp")
+		_, err := GetConfig()
+		assertError(t, err, errSmtpHostMissing)
+		resetConfig()
+	})
+	t.Run("invalid mail_driver", func(t *testing.T) {
+		generateConfig(true, true, true, true, true, true, true)
+		os.Setenv("mail_driver", "pigeon")
+		_, err := GetConfig()
+		assertError(t, err, errMailDriver)
+		resetConfig()
+	})
+	t.Run("invalid smtp_port", func(t *testing.T) {
+		generateConfig(true, true, true, true, true, true, true)
+		os.Setenv("smtp_port", "invalid")
+		_, err := GetConfig()
+		assertError(t, err, errSmtpPort)
+		resetConfig()
+	})
+	t.Run("reminders off", func(t *testing.T) {
+		generateConfig(true, true, true, true, true, true, true)
+		os.Setenv("reminder_interval", "0")
+		result, err := GetConfig()
+		assert.NilError(t, err)
+		assert.Equal(t, result.App.ReminderInterval, time.Duration(0))
+		resetConfig()
+	})
+	t.Run("invalid reminder_interval", func(t *testing.T) {
+		generateConfig(true, true, true, true, true, true, true)
+		os.Setenv("reminder_interval", "often")
+		_, err := GetConfig()
+		assertError(t, err, errReminderInterval)
+		resetConfig()
+	})
+	t.Run("db_migrations", func(t *testing.T) {
+		generateConfig(true, true, true, true, true, true, true)
+		os.Setenv("db_migrations", "apply")
+		result, err := GetConfig()
+		assert.NilError(t, err)
+		assert.Equal(t, result.Db.Migrations, "apply")
+		resetConfig()
+	})
+	t.Run("invalid db_migrations", func(t *testing.T) {
+		generateConfig(true, true, true, true, true, true, true)
+		os.Setenv("db_migrations", "auto")
+		_, err := GetConfig()
+		assertError(t, err, errDbMigrations)
+		resetConfig()
+	})
+	t.Run("sqlite db_driver", func(t *testing.T) {
+		generateConfig(true, true, false, false, false, false, false)
+		os.Setenv("db_driver", "sqlite")
+		result, err := GetConfig()
+		assert.NilError(t, err)
+		assert.DeepEqual(t, result.Db, Db{Driver: "sqlite", Path: "backend.db", SSLMode: "disable", Migrations: "check", MaxIdleConns: 2})
+		os.Setenv("db_path", ":memory:")
+		result, err = GetConfig()
+		assert.NilError(t, err)
+		assert.Equal(t, result.Db.Path, ":memory:")
+		resetConfig()
+	})
+	t.Run("invalid db_driver", func(t *testing.T) {
+		generateConfig(true, true, true, true, true, true, true)
+		os.Setenv("db_driver", "mysql")
+		_, err := GetConfig()
+		assertError(t, err, errDbDriver)
+		resetConfig()
+	})
+	t.Run("jobs", func(t *testing.T) {
+		generateConfig(true, true, true, true, true, true, true)
+		os.Setenv("job_workers", "0")
+		os.Setenv("job_poll_interval", "250ms")
+		result, err := GetConfig()
+		assert.NilError(t, err)
+		assert.DeepEqual(t, result.Jobs, Jobs{Workers: 0, PollInterval: 250 * time.Millisecond})
+		resetConfig()
+	})
+	t.Run("invalid job_workers", func(t *testing.T) {
+		generateConfig(true, true, true, true, true, true, true)
+		os.Setenv("job_workers", "-1")
+		_, err := GetConfig()
+		assertError(t, err, errJobWorkers)
+		resetConfig()
+	})
+	t.Run("invalid job_poll_interval", func(t *testing.T) {
+		generateConfig(true, true, true, true, true, true, true)
+		os.Setenv("job_poll_interval", "0s")
+		_, err := GetConfig()
+		assertError(t, err, errJobPollInterval)
+		resetConfig()
+	})
+	t.Run("postgres connection", func(t *testing.T) {
+		generateConfig(true, true, true, true, true, true, true)
+		os.Setenv("db_sslmode", "require")
+		os.Setenv("db_timezone", "UTC")
+		os.Setenv("db_max_open_conns", "20")
+		os.Setenv("db_max_idle_conns", "5")
+		os.Setenv("db_conn_max_lifetime", "30m")
+		result, err := GetConfig()
+		assert.NilError(t, err)
+		assert.Equal(t, result.Db.SSLMode, "require")
+		assert.Equal(t, result.Db.TimeZone, "UTC")
+		assert.Equal(t, result.Db.MaxOpenConns, 20)
+		assert.Equal(t, result.Db.MaxIdleConns, 5)
+		assert.Equal(t, result.Db.ConnMaxLifetime, 30*time.Minute)
+		resetConfig()
+	})
+	t.Run("invalid db_sslmode", func(t *testing.T) {
+		generateConfig(true, true, true, true, true, true, true)
+		os.Setenv("db_sslmode", "sometimes")
+		_, err := GetConfig()
+		assertError(t, err, errDbSSLMode)
+		resetConfig()
+	})
+	t.Run("invalid db_timezone", func(t *testing.T) {
+		generateConfig(true, true, true, true, true, true, true)
+		os.Setenv("db_timezone", "Mars/Olympus_Mons")
+		_, err := GetConfig()
+		assertError(t, err, errDbTimeZone)
+		resetConfig()
+	})
+	t.Run("invalid db_max_open_conns", func(t *testing.T) {
+		generateConfig(true, true, true, true, true, true, true)
+		os.Setenv("db_max_open_conns", "-1")
+		_, err := GetConfig()
+		assertError(t, err, errDbMaxOpenConns)
+		resetConfig()
+	})
+	t.Run("cors_origins", func(t *testing.T) {
+		generateConfig(true, true, true, true, true, true, true)
+		os.Setenv("cors_origins", "https://events.example.com, http://localhost:5173")
+		result, err := GetConfig()
+		assert.NilError(t, err)
+		assert.DeepEqual(t, result.Cors.Origins, []string{"https://events.example.com", "http://localhost:5173"})
+		resetConfig()
+	})
+	t.Run("invalid cors_origins", func(t *testing.T) {
+		generateConfig(true, true, true, true, true, true, true)
+		os.Setenv("cors_origins", "events.example.com")
+		_, err := GetConfig()
+		assertError(t, err, errCorsOrigins)
+		resetConfig()
+	})
+	t.Run("token lifetimes", func(t *testing.T) {
+		generateConfig(true, true, true, true, true, true, true)
+		os.Setenv("access_token_lifetime", "5m")
+		os.Setenv("refresh_token_lifetime", "24h")
+		os.Setenv("reset_token_lifetime", "30m")
+		result, err := GetConfig()
+		assert.NilError(t, err)
+		assert.DeepEqual(t, result.Auth, Auth{AccessTokenLifetime: 5 * time.Minute, RefreshTokenLifetime: 24 * time.Hour, ResetTokenLifetime: 30 * time.Minute})
+		resetConfig()
+	})
+	t.Run("invalid access_token_lifetime", func(t *testing.T) {
+		generateConfig(true, true, true, true, true, true, true)
+		os.Setenv("access_token_lifetime", "0s")
+		_, err := GetConfig()
+		assertError(t, err, errAccessTokenLifetime)
+		resetConfig()
+	})
+	t.Run("log_level", func(t *testing.T) {
+		generateConfig(true, true, true, true, true, true, true)
+		os.Setenv("log_level", "debug")
+		result, err := GetConfig()
+		assert.NilError(t, err)
+		assert.Equal(t, result.Log.Level, logrus.DebugLevel)
+		resetConfig()
+	})
+	t.Run("invalid log_level", func(t *testing.T) {
+		generateConfig(true, true, true, true, true, true, true)
+		os.Setenv("log_level", "loud")
+		_, err := GetConfig()
+		assertError(t, err, errLogLevel)
+		resetConfig()
+	})
+	t.Run("server timeouts", func(t *testing.T) {
+		generateConfig(true, true, true, true, true, true, true)
+		os.Setenv("write_timeout", "0")
+		os.Setenv("shutdown_timeout", "5s")
+		result, err := GetConfig()
+		assert.NilError(t, err)
+		assert.Equal(t, result.App.WriteTimeout, time.Duration(0))
+		assert.Equal(t, result.App.ShutdownTimeout, 5*time.Second)
+		resetConfig()
+	})
+	t.Run("invalid shutdown_timeout", func(t *testing.T) {
+		generateConfig(true, true, true, true, true, true, true)
+		os.Setenv("shutdown_timeout", "-1s")
+		_, err := GetConfig()
+		assertError(t, err, errShutdownTimeout)
+		resetConfig()
+	})
+	t.Run("otlp tracing", func(t *testing.T) {
+		generateConfig(true, true, true, true, true, true, true)
+		os.Setenv("tracing_exporter", "otlp")
+		os.Setenv("tracing_endpoint", "http://collector:4318")
+		os.Setenv("tracing_sample_ratio", "0.25")
+		result, err := GetConfig()
+		assert.NilError(t, err)
+		assert.DeepEqual(t, result.Tracing, Tracing{Exporter: "otlp", Endpoint: "http://collector:4318", SampleRatio: 0.25, ServiceName: "event-manager-backend"})
+		resetConfig()
+	})
+	t.Run("invalid tracing_exporter", func(t *testing.T) {
+		generateConfig(true, true, true, true, true, true, true)
+		os.Setenv("tracing_exporter", "jaeger")
+		_, err := GetConfig()
+		assertError(t, err, errTracingExporter)
+		resetConfig()
+	})
+	t.Run("invalid tracing_endpoint", func(t *testing.T) {
+		generateConfig(true, true, true, true, true, true, true)
+		os.Setenv("tracing_endpoint", "collector:4318")
+		_, err := GetConfig()
+		assertError(t, err, errTracingEndpoint)
+		resetConfig()
+	})
+	t.Run("invalid tracing_sample_ratio", func(t *testing.T) {
+		generateConfig(true, true, true, true, true, true, true)
+		os.Setenv("tracing_sample_ratio", "2")
+		_, err := GetConfig()
+		assertError(t, err, errTracingSampleRatio)
+		resetConfig()
+	})
+	t.Run("all errors", func(t *testing.T) {
+		generateConfig(true, false, true, false, true, true, true)
+		os.Setenv("port", "invalid")
+		os.Setenv("job_workers", "-1")
+		_, err := GetConfig()
+		assertError(t, err, errApiPort)
+		assertError(t, err, errApiSecretMissing)
+		assertError(t, err, errDbPortMissing)
+		assertError(t, err, errJobWorkers)
+		resetConfig()
+	})
+}
+
+func TestLoad(t *testing.T) {
+	t.Run("yaml file", func(t *testing.T) {
+		path := writeConfigFile(t, "config.yaml", `
+app:
+  port: 9090
+  api_secret: file-secret
+db:
+  driver: sqlite
+  path: ":memory:"
+cors:
+  origins:
+    - https://events.example.com
+auth:
+  access_token_lifetime: 5m
+`)
+		result, args, err := Load([]string{"-config", path})
+		assert.NilError(t, err)
+		assert.Equal(t, len(args), 0)
+		assert.Equal(t, result.App.Port, 9090)
+		assert.Equal(t, result.App.ApiSecret, "file-secret")
+		assert.Equal(t, result.Db.Path, ":memory:")
+		assert.DeepEqual(t, result.Cors.Origins, []string{"https://events.example.com"})
+		assert.Equal(t, result.Auth.AccessTokenLifetime, 5*time.Minute)
+		resetConfig()
+	})
+	t.Run("toml file", func(t *testing.T) {
+		path := writeConfigFile(t, "config.toml", `
+[app]
+port = 9090
+api_secret = "file-secret"
+
+[db]
+driver = "sqlite"
+
+[log]
+level = "warn"
+`)
+		os.Setenv("config_file", path)
+		result, _, err := Load(nil)
+		assert.NilError(t, err)
+		assert.Equal(t, result.App.Port, 9090)
+		assert.Equal(t, result.Db.Driver, "sqlite")
+		assert.Equal(t, result.Log.Level, logrus.WarnLevel)
+		resetConfig()
+	})
+	t.Run("precedence", func(t *testing.T) {
+		path := writeConfigFile(t, "config.yaml", `
+app:
+  port: 9090
+  api_secret: file-secret
+  public_url: https://file.example.com
+db:
+  driver: sqlite
+jobs:
+  workers: 4
+`)
+		os.Setenv("port", "8080")
+		os.Setenv("job_workers", "3")
+		result, args, err := Load([]string{"-config", path, "-jobs.workers", "1", "migrate", "up"})
+		assert.NilError(t, err)
+		assert.DeepEqual(t, args, []string{"migrate", "up"})
+		assert.Equal(t, result.App.ApiSecret, "file-secret")
+		assert.Equal(t, result.App.PublicURL, "https://file.example.com")
+		assert.Equal(t, result.App.Port, 8080)
+		assert.Equal(t, result.Jobs.Workers, 1)
+		resetConfig()
+	})
+	t.Run("flags", func(t *testing.T) {
+		result, _, err := Load([]string{"-app.port=8081", "-app.api_secret=flag-secret", "-db.driver=sqlite"})
+		assert.NilError(t, err)
+		assert.Equal(t, result.App.Port, 8081)
+		assert.Equal(t, result.App.ApiSecret, "flag-secret")
+		resetConfig()
+	})
+	t.Run("invalid flag value", func(t *testing.T) {
+		generateConfig(true, true, true, true, true, true, true)
+		_, _, err := Load([]string{"-db.port=invalid"})
+		assertError(t, err, errDbPort)
+		resetConfig()
+	})
+	t.Run("unknown setting", func(t *testing.T) {
+		generateConfig(true, true, true, true, true, true, true)
+		path := writeConfigFile(t, "config.yaml", "db:\n  hostname: localhost\n")
+		_, _, err := Load([]string{"-config", path})
+		assertError(t, err, errUnknownSetting)
+		resetConfig()
+	})
+	t.Run("example file", func(t *testing.T) {
+		_, _, err := Load([]string{"-config", "../../config.example.yaml"})
+		assert.NilError(t, err)
+		resetConfig()
+	})
+	t.Run("missing file", func(t *testing.T) {
+		generateConfig(true, true, true, true, true, true, true)
+		_, _, err := Load([]string{"-config", filepath.Join(t.TempDir(), "config.yaml")})
+		assertError(t, err, errConfigFile)
+		resetConfig()
+	})
+	t.Run("invalid file format", func(t *testing.T) {
+		generateConfig(true, true, true, true, true, true, true)
+		path := writeConfigFile(t, "config.json", "{}")
+		_, _, err := Load([]string{"-config", path})
+		assertError(t, err, errConfigFileFormat)
+		resetConfig()
+	})
+}
+
+func writeConfigFile(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	err := os.WriteFile(path, []byte(content), 0o644)
+	if err != nil {
+		t.Fatalf("Error when writing config file, when not expected. Error: %v", err)
+	}
+	return path
 }
 
 func generateConfig(port, api_secret, db_host, db_port, db_user, db_password, db_name bool) {
@@ -144,6 +517,34 @@ func resetConfig() {
 	os.Unsetenv("db_user")
 	os.Unsetenv("db_password")
 	os.Unsetenv("db_name")
+	os.Unsetenv("db_migrations")
+	os.Unsetenv("db_driver")
+	os.Unsetenv("db_path")
+	os.Unsetenv("mail_driver")
+	os.Unsetenv("smtp_host")
+	os.Unsetenv("smtp_port")
+	os.Unsetenv("reminder_interval")
+	os.Unsetenv("job_workers")
+	os.Unsetenv("job_poll_interval")
+	os.Unsetenv("db_sslmode")
+	os.Unsetenv("db_timezone")
+	os.Unsetenv("db_max_open_conns")
+	os.Unsetenv("db_max_idle_conns")
+	os.Unsetenv("db_conn_max_lifetime")
+	os.Unsetenv("cors_origins")
+	os.Unsetenv("access_token_lifetime")
+	os.Unsetenv("refresh_token_lifetime")
+	os.Unsetenv("reset_token_lifetime")
+	os.Unsetenv("log_level")
+	os.Unsetenv("tracing_exporter")
+	os.Unsetenv("tracing_endpoint")
+	os.Unsetenv("tracing_sample_ratio")
+	os.Unsetenv("tracing_service_name")
+	os.Unsetenv("config_file")
+	os.Unsetenv("read_timeout")
+	os.Unsetenv("write_timeout")
+	os.Unsetenv("idle_timeout")
+	os.Unsetenv("shutdown_timeout")
 }
 
 func assertError(t testing.TB, err, want error) {
@@ -151,7 +552,7 @@ func assertError(t testing.TB, err, want error) {
 	if err == nil {
 		t.Error("wanted an error but didn't get one")
 	}
-	if err != want {
+	if !errors.Is(err, want) {
 		t.Errorf("got %q, want %q", err, want)
 	}
 }
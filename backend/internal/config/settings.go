@@ -0,0 +1,455 @@
+package config
+
+import (
+	"errors"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+var errApiPort = errors.New("error parsing port")
+var errApiPortMissing = errors.New("error port is not set")
+var errApiSecret = errors.New("error parsing api_secret")
+var errApiSecretMissing = errors.New("error api_secret is not set")
+var errDbHost = errors.New("error parsing db_host")
+var errDbHostMissing = errors.New("error db_host is not set")
+var errDbPort = errors.New("error parsing db_port")
+var errDbPortMissing = errors.New("error db_port is not set")
+var errDbUser = errors.New("error parsing db_user")
+var errDbUserMissing = errors.New("error db_user is not set")
+var errDbPassword = errors.New("error parsing db_password")
+var errDbPasswordMissing = errors.New("error db_password is not set")
+var errDbName = errors.New("error parsing db_name")
+var errDbNameMissing = errors.New("error db_name is not set")
+var errDbSSLMode = errors.New("error parsing db_sslmode")
+var errDbTimeZone = errors.New("error parsing db_timezone")
+var errDbMigrations = errors.New("error parsing db_migrations")
+var errDbDriver = errors.New("error parsing db_driver")
+var errDbMaxOpenConns = errors.New("error parsing db_max_open_conns")
+var errDbMaxIdleConns = errors.New("error parsing db_max_idle_conns")
+var errDbConnMaxLifetime = errors.New("error parsing db_conn_max_lifetime")
+var errMailDriver = errors.New("error parsing mail_driver")
+var errSmtpPort = errors.New("error parsing smtp_port")
+var errSmtpHostMissing = errors.New("error smtp_host is not set")
+var errReminderInterval = errors.New("error parsing reminder_interval")
+var errReadTimeout = errors.New("error parsing read_timeout")
+var errWriteTimeout = errors.New("error parsing write_timeout")
+var errIdleTimeout = errors.New("error parsing idle_timeout")
+var errShutdownTimeout = errors.New("error parsing shutdown_timeout")
+var errJobWorkers = errors.New("error parsing job_workers")
+var errJobPollInterval = errors.New("error parsing job_poll_interval")
+var errCorsOrigins = errors.New("error parsing cors_origins")
+var errAccessTokenLifetime = errors.New("error parsing access_token_lifetime")
+var errRefreshTokenLifetime = errors.New("error parsing refresh_token_lifetime")
+var errResetTokenLifetime = errors.New("error parsing reset_token_lifetime")
+var errLogLevel = errors.New("error parsing log_level")
+var errTracingExporter = errors.New("error parsing tracing_exporter")
+var errTracingEndpoint = errors.New("error parsing tracing_endpoint")
+var errTracingSampleRatio = errors.New("error parsing tracing_sample_ratio")
+var errTracingServiceName = errors.New("error parsing tracing_service_name")
+
+// setting is one config value. The key names it in the config file and as a
+// flag, env names its environment variable.
+type setting struct {
+	key      string
+	env      string
+	usage    string
+	fallback string // default value, empty when there is none
+	// required reports whether the setting must be given, nil when it never is
+	required func(cfg *Config) bool
+	missing  error
+	// set parses the value into cfg, it returns the setting's parse error
+	// when the value is invalid
+	set func(cfg *Config, value string) error
+}
+
+func always(cfg *Config) bool { return true }
+
+func usesPostgres(cfg *Config) bool { return cfg.Db.Driver == "postgres" }
+
+func usesSmtp(cfg *Config) bool { return cfg.Mail.Driver == "smtp" }
+
+var settings = []setting{
+	{
+		key: "app.port", env: "port", usage: "port the api listens on",
+		required: always, missing: errApiPortMissing,
+		set: func(cfg *Config, value string) (err error) {
+			cfg.App.Port, err = parseInt(value, 1, errApiPort)
+			return err
+		},
+	},
+	{
+		key: "app.api_secret", env: "api_secret", usage: "secret signing the access tokens",
+		required: always, missing: errApiSecretMissing,
+		set: func(cfg *Config, value string) (err error) {
+			cfg.App.ApiSecret, err = parseText(value, errApiSecret)
+			return err
+		},
+	},
+	{
+		key: "app.public_url", env: "public_url", usage: "where the frontend is served",
+		fallback: "http://localhost:3000",
+		set: func(cfg *Config, value string) error {
+			cfg.App.PublicURL = value
+			return nil
+		},
+	},
+	{
+		key: "app.reminder_interval", env: "reminder_interval", usage: "how often due event reminders are looked for, 0 turns them off",
+		fallback: "1m",
+		set: func(cfg *Config, value string) (err error) {
+			cfg.App.ReminderInterval, err = parseDuration(value, 0, errReminderInterval)
+			return err
+		},
+	},
+	{
+		key: "app.read_timeout", env: "read_timeout", usage: "how long reading a request may take, 0 for no limit",
+		fallback: "15s",
+		set: func(cfg *Config, value string) (err error) {
+			cfg.App.ReadTimeout, err = parseDuration(value, 0, errReadTimeout)
+			return err
+		},
+	},
+	{
+		key: "app.write_timeout", env: "write_timeout", usage: "how long writing a response may take, 0 for no limit",
+		fallback: "60s",
+		set: func(cfg *Config, value string) (err error) {
+			cfg.App.WriteTimeout, err = parseDuration(value, 0, errWriteTimeout)
+			return err
+		},
+	},
+	{
+		key: "app.idle_timeout", env: "idle_timeout", usage: "how long an idle keep-alive connection stays open, 0 for no limit",
+		fallback: "120s",
+		set: func(cfg *Config, value string) (err error) {
+			cfg.App.IdleTimeout, err = parseDuration(value, 0, errIdleTimeout)
+			return err
+		},
+	},
+	{
+		key: "app.shutdown_timeout", env: "shutdown_timeout", usage: "how long in-flight requests and jobs may finish on shutdown",
+		fallback: "20s",
+		set: func(cfg *Config, value string) (err error) {
+			cfg.App.ShutdownTimeout, err = parseDuration(value, 0, errShutdownTimeout)
+			return err
+		},
+	},
+
+	// Postgres is the default, its connection settings are required. The
+	// sqlite driver needs only a file and is meant for local development.
+	{
+		key: "db.driver", env: "db_driver", usage: `database driver, "postgres" or "sqlite"`,
+		fallback: "postgres",
+		set: func(cfg *Config, value string) (err error) {
+			cfg.Db.Driver, err = parseChoice(value, errDbDriver, "postgres", "sqlite")
+			return err
+		},
+	},
+	{
+		key: "db.path", env: "db_path", usage: "database file of the sqlite driver",
+		fallback: "backend.db",
+		set: func(cfg *Config, value string) error {
+			cfg.Db.Path = value
+			return nil
+		},
+	},
+	{
+		key: "db.host", env: "db_host", usage: "postgres host",
+		required: usesPostgres, missing: errDbHostMissing,
+		set: func(cfg *Config, value string) (err error) {
+			cfg.Db.Host, err = parseText(value, errDbHost)
+			return err
+		},
+	},
+	{
+		key: "db.port", env: "db_port", usage: "postgres port",
+		required: usesPostgres, missing: errDbPortMissing,
+		set: func(cfg *Config, value string) (err error) {
+			cfg.Db.Port, err = parseInt(value, 1, errDbPort)
+			return err
+		},
+	},
+	{
+		key: "db.user", env: "db_user", usage: "postgres user",
+		required: usesPostgres, missing: errDbUserMissing,
+		set: func(cfg *Config, value string) (err error) {
+			cfg.Db.User, err = parseText(value, errDbUser)
+			return err
+		},
+	},
+	{
+		key: "db.password", env: "db_password", usage: "postgres password",
+		required: usesPostgres, missing: errDbPasswordMissing,
+		set: func(cfg *Config, value string) (err error) {
+			cfg.Db.Password, err = parseText(value, errDbPassword)
+			return err
+		},
+	},
+	{
+		key: "db.name", env: "db_name", usage: "postgres database",
+		required: usesPostgres, missing: errDbNameMissing,
+		set: func(cfg *Config, value string) (err error) {
+			cfg.Db.DBName, err = parseText(value, errDbName)
+			return err
+		},
+	},
+	{
+		key: "db.sslmode", env: "db_sslmode", usage: "sslmode of postgres connections",
+		fallback: "disable",
+		set: func(cfg *Config, value string) (err error) {
+			cfg.Db.SSLMode, err = parseChoice(value, errDbSSLMode, "disable", "allow", "prefer", "require", "verify-ca", "verify-full")
+			return err
+		},
+	},
+	{
+		key: "db.timezone", env: "db_timezone", usage: "session time zone of postgres connections",
+		set: func(cfg *Config, value string) error {
+			if value == "" {
+				cfg.Db.TimeZone = ""
+				return nil
+			}
+			_, err := time.LoadLocation(value)
+			if err != nil {
+				return errDbTimeZone
+			}
+			cfg.Db.TimeZone = value
+			return nil
+		},
+	},
+	{
+		key: "db.migrations", env: "db_migrations", usage: `pending migrations on start, "check", "apply" or "ignore"`,
+		fallback: "check",
+		set: func(cfg *Config, value string) (err error) {
+			cfg.Db.Migrations, err = parseChoice(value, errDbMigrations, "check", "apply", "ignore")
+			return err
+		},
+	},
+	{
+		key: "db.max_open_conns", env: "db_max_open_conns", usage: "most open postgres connections, 0 for no limit",
+		fallback: "0",
+		set: func(cfg *Config, value string) (err error) {
+			cfg.Db.MaxOpenConns, err = parseInt(value, 0, errDbMaxOpenConns)
+			return err
+		},
+	},
+	{
+		key: "db.max_idle_conns", env: "db_max_idle_conns", usage: "most idle postgres connections kept open",
+		fallback: "2",
+		set: func(cfg *Config, value string) (err error) {
+			cfg.Db.MaxIdleConns, err = parseInt(value, 0, errDbMaxIdleConns)
+			return err
+		},
+	},
+	{
+		key: "db.conn_max_lifetime", env: "db_conn_max_lifetime", usage: "how long a postgres connection is reused, 0 for ever",
+		fallback: "0",
+		set: func(cfg *Config, value string) (err error) {
+			cfg.Db.ConnMaxLifetime, err = parseDuration(value, 0, errDbConnMaxLifetime)
+			return err
+		},
+	},
+
+	// Mail settings are optional, by default emails are written to the mail directory
+	{
+		key: "mail.driver", env: "mail_driver", usage: `mail driver, "file", "smtp" or "memory"`,
+		fallback: "file",
+		set: func(cfg *Config, value string) (err error) {
+			cfg.Mail.Driver, err = parseChoice(value, errMailDriver, "file", "smtp", "memory")
+			return err
+		},
+	},
+	{
+		key: "mail.dir", env: "mail_dir", usage: "output directory of the file mail driver",
+		fallback: "mail",
+		set: func(cfg *Config, value string) error {
+			cfg.Mail.Dir = value
+			return nil
+		},
+	},
+	{
+		key: "mail.host", env: "smtp_host", usage: "smtp host",
+		required: usesSmtp, missing: errSmtpHostMissing,
+		set: func(cfg *Config, value string) error {
+			cfg.Mail.Host = value
+			return nil
+		},
+	},
+	{
+		key: "mail.port", env: "smtp_port", usage: "smtp port",
+		fallback: "587",
+		set: func(cfg *Config, value string) (err error) {
+			cfg.Mail.Port, err = parseInt(value, 1, errSmtpPort)
+			return err
+		},
+	},
+	{
+		key: "mail.username", env: "smtp_user", usage: "smtp user",
+		set: func(cfg *Config, value string) error {
+			cfg.Mail.Username = value
+			return nil
+		},
+	},
+	{
+		key: "mail.password", env: "smtp_password", usage: "smtp password",
+		set: func(cfg *Config, value string) error {
+			cfg.Mail.Password = value
+			return nil
+		},
+	},
+	{
+		key: "mail.from", env: "mail_from", usage: "sender of emails",
+		fallback: "no-reply@eventmanager.com",
+		set: func(cfg *Config, value string) error {
+			cfg.Mail.From = value
+			return nil
+		},
+	},
+
+	// Job settings are optional, by default every replica runs two workers
+	{
+		key: "jobs.workers", env: "job_workers", usage: "background job workers, 0 leaves the queue to other replicas",
+		fallback: "2",
+		set: func(cfg *Config, value string) (err error) {
+			cfg.Jobs.Workers, err = parseInt(value, 0, errJobWorkers)
+			return err
+		},
+	},
+	{
+		key: "jobs.poll_interval", env: "job_poll_interval", usage: "how long an idle worker waits for jobs",
+		fallback: "1s",
+		set: func(cfg *Config, value string) (err error) {
+			cfg.Jobs.PollInterval, err = parseDuration(value, time.Nanosecond, errJobPollInterval)
+			return err
+		},
+	},
+
+	{
+		key: "cors.origins", env: "cors_origins", usage: "comma separated origins allowed to call the api",
+		fallback: "http://localhost:5173,http://localhost:3000",
+		set: func(cfg *Config, value string) error {
+			cfg.Cors.Origins = nil
+			for _, origin := range strings.Split(value, ",") {
+				origin = strings.TrimSpace(origin)
+				u, err := url.Parse(origin)
+				if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" || u.Path != "" {
+					return errCorsOrigins
+				}
+				cfg.Cors.Origins = append(cfg.Cors.Origins, origin)
+			}
+			return nil
+		},
+	},
+
+	{
+		key: "auth.access_token_lifetime", env: "access_token_lifetime", usage: "how long an access token is valid",
+		fallback: "15m",
+		set: func(cfg *Config, value string) (err error) {
+			cfg.Auth.AccessTokenLifetime, err = parseDuration(value, time.Second, errAccessTokenLifetime)
+			return err
+		},
+	},
+	{
+		key: "auth.refresh_token_lifetime", env: "refresh_token_lifetime", usage: "how long a refresh token is valid",
+		fallback: "720h",
+		set: func(cfg *Config, value string) (err error) {
+			cfg.Auth.RefreshTokenLifetime, err = parseDuration(value, time.Second, errRefreshTokenLifetime)
+			return err
+		},
+	},
+	{
+		key: "auth.reset_token_lifetime", env: "reset_token_lifetime", usage: "how long a password reset link works",
+		fallback: "1h",
+		set: func(cfg *Config, value string) (err error) {
+			cfg.Auth.ResetTokenLifetime, err = parseDuration(value, time.Second, errResetTokenLifetime)
+			return err
+		},
+	},
+
+	{
+		key: "log.level", env: "log_level", usage: `log level, "debug", "info", "warn" or "error"`,
+		fallback: "info",
+		set: func(cfg *Config, value string) error {
+			level, err := logrus.ParseLevel(value)
+			if err != nil {
+				return errLogLevel
+			}
+			cfg.Log.Level = level
+			return nil
+		},
+	},
+
+	// Tracing is off by default, "stdout" prints the spans for local use
+	{
+		key: "tracing.exporter", env: "tracing_exporter", usage: `trace exporter, "none", "stdout" or "otlp"`,
+		fallback: "none",
+		set: func(cfg *Config, value string) (err error) {
+			cfg.Tracing.Exporter, err = parseChoice(value, errTracingExporter, "none", "stdout", "otlp")
+			return err
+		},
+	},
+	{
+		key: "tracing.endpoint", env: "tracing_endpoint", usage: "url of the OTLP/HTTP collector, such as http://localhost:4318",
+		set: func(cfg *Config, value string) error {
+			u, err := url.Parse(value)
+			if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+				return errTracingEndpoint
+			}
+			cfg.Tracing.Endpoint = value
+			return nil
+		},
+	},
+	{
+		key: "tracing.sample_ratio", env: "tracing_sample_ratio", usage: "share of the new traces that are recorded, from 0 to 1",
+		fallback: "1",
+		set: func(cfg *Config, value string) error {
+			ratio, err := strconv.ParseFloat(value, 64)
+			if err != nil || ratio < 0 || ratio > 1 {
+				return errTracingSampleRatio
+			}
+			cfg.Tracing.SampleRatio = ratio
+			return nil
+		},
+	},
+	{
+		key: "tracing.service_name", env: "tracing_service_name", usage: "service name of the spans",
+		fallback: "event-manager-backend",
+		set: func(cfg *Config, value string) (err error) {
+			cfg.Tracing.ServiceName, err = parseText(value, errTracingServiceName)
+			return err
+		},
+	},
+}
+
+func parseText(value string, invalid error) (string, error) {
+	if value == "" {
+		return "", invalid
+	}
+	return value, nil
+}
+
+func parseInt(value string, lowest int, invalid error) (int, error) {
+	n, err := strconv.Atoi(value)
+	if err != nil || n < lowest {
+		return 0, invalid
+	}
+	return n, nil
+}
+
+func parseDuration(value string, lowest time.Duration, invalid error) (time.Duration, error) {
+	d, err := time.ParseDuration(value)
+	if err != nil || d < lowest {
+		return 0, invalid
+	}
+	return d, nil
+}
+
+func parseChoice(value string, invalid error, choices ...string) (string, error) {
+	if !slices.Contains(choices, value) {
+		return "", invalid
+	}
+	return value, nil
+}
@@ -1,7 +1,33 @@
 package database
 
-import "gorm.io/gorm"
+import (
+	"fmt"
+
+	"github.com/HermanPlay/web-app-backend/internal/config"
+	"gorm.io/gorm"
+)
 
 type Database interface {
 	Connect() *gorm.DB
+	// Close closes the connection pool, queries fail afterwards
+	Close() error
+}
+
+// New opens the database of the configured driver.
+func New(cfg *config.Config) (Database, error) {
+	switch cfg.Db.Driver {
+	case "", "postgres":
+		return NewPostgresDatabase(cfg)
+	case "sqlite":
+		return NewSQLiteDatabase(cfg)
+	default:
+		return nil, fmt.Errorf("unknown db driver %q", cfg.Db.Driver)
+	}
+}
+
+// gormConfig is the gorm config of the database drivers. Constraint violations
+// are reported as gorm.ErrDuplicatedKey and friends, which the services map to
+// their own errors.
+func gormConfig() *gorm.Config {
+	return &gorm.Config{TranslateError: true}
 }
@@ -2,6 +2,7 @@ package database
 
 import (
 	"fmt"
+	"net/url"
 
 	"github.com/HermanPlay/web-app-backend/internal/config"
 	"gorm.io/driver/postgres"
@@ -14,17 +15,28 @@ type postgresDatabase struct {
 
 func NewPostgresDatabase(cfg *config.Config) (Database, error) {
 	dsn := fmt.Sprintf(
-		"postgres://%s:%s@%s:%v/%s?sslmode=disable",
+		"postgres://%s:%s@%s:%v/%s?sslmode=%s",
 		cfg.Db.User,
 		cfg.Db.Password,
 		cfg.Db.Host,
 		cfg.Db.Port,
 		cfg.Db.DBName,
+		cfg.Db.SSLMode,
 	)
-	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{})
+	if cfg.Db.TimeZone != "" {
+		dsn += "&TimeZone=" + url.QueryEscape(cfg.Db.TimeZone)
+	}
+	db, err := gorm.Open(postgres.Open(dsn), gormConfig())
 	if err != nil {
 		return nil, fmt.Errorf("cannot open db connection")
 	}
+	sqlDb, err := db.DB()
+	if err != nil {
+		return nil, err
+	}
+	sqlDb.SetMaxOpenConns(cfg.Db.MaxOpenConns)
+	sqlDb.SetMaxIdleConns(cfg.Db.MaxIdleConns)
+	sqlDb.SetConnMaxLifetime(cfg.Db.ConnMaxLifetime)
 
 	return &postgresDatabase{Db: db}, nil
 }
@@ -32,3 +44,11 @@ func NewPostgresDatabase(cfg *config.Config) (Database, error) {
 func (p *postgresDatabase) Connect() *gorm.DB {
 	return p.Db
 }
+
+func (p *postgresDatabase) Close() error {
+	sqlDb, err := p.Db.DB()
+	if err != nil {
+		return err
+	}
+	return sqlDb.Close()
+}
@@ -1,12 +1,14 @@
 package database
 
 import (
+	"os"
 	"testing"
 
 	"github.com/HermanPlay/web-app-backend/internal/config"
 )
 
 func TestNewPostgresDatabase(t *testing.T) {
+	skipWithoutPostgres(t)
 	cfg_error := getCfg(false)
 	cfg := getCfg(true)
 	t.Run("correct db config", func(t *testing.T) {
@@ -20,6 +22,15 @@ func TestNewPostgresDatabase(t *testing.T) {
 		assertError(t, err)
 	})
 }
+
+// The Postgres tests need a running database, see make unit-test-postgres
+func skipWithoutPostgres(t testing.TB) {
+	t.Helper()
+	if os.Getenv("test_db_driver") != "postgres" {
+		t.Skip("test_db_driver is not postgres")
+	}
+}
+
 func assertError(t testing.TB, err error) {
 	t.Helper()
 	if err == nil {
@@ -34,6 +45,7 @@ func getCfg(correct bool) *config.Config {
 				Password: "postgres",
 				Host:     "localhost",
 				Port:     5432,
+				SSLMode:  "disable",
 				DBName:   "test_db",
 			},
 		}
@@ -44,6 +56,7 @@ func getCfg(correct bool) *config.Config {
 				Password: "testpassword",
 				Host:     "localhost",
 				Port:     5432,
+				SSLMode:  "disable",
 				DBName:   "wrong",
 			},
 		}
@@ -51,6 +64,7 @@ func getCfg(correct bool) *config.Config {
 }
 
 func TestConnect(t *testing.T) {
+	skipWithoutPostgres(t)
 	cfg := getCfg(true)
 	db, _ := NewPostgresDatabase(cfg)
 	got := db.Connect()
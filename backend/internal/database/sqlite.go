@@ -0,0 +1,119 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/HermanPlay/web-app-backend/internal/config"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+type sqliteDatabase struct {
+	Db *gorm.DB
+}
+
+// NewSQLiteDatabase opens the SQLite file at cfg.Db.Path, or a private
+// in-memory database for ":memory:". It is meant for local development and
+// tests: all queries share one connection, so a long request holds up the
+// others.
+func NewSQLiteDatabase(cfg *config.Config) (Database, error) {
+	dsn := fmt.Sprintf("file:%s?_foreign_keys=on&_busy_timeout=5000", cfg.Db.Path)
+	sqlDb, err := sql.Open(sqlite.DriverName, dsn)
+	if err != nil {
+		return nil, fmt.Errorf("cannot open db connection")
+	}
+	// SQLite has a single writer, and every connection to ":memory:" would
+	// see a database of its own
+	sqlDb.SetMaxOpenConns(1)
+
+	gormCfg := gormConfig()
+	gormCfg.NowFunc = func() time.Time {
+		return time.Now().UTC()
+	}
+	db, err := gorm.Open(sqlite.Dialector{Conn: &utcConnPool{sqlDb}}, gormCfg)
+	if err != nil {
+		return nil, fmt.Errorf("cannot open db connection")
+	}
+
+	return &sqliteDatabase{Db: db}, nil
+}
+
+func (s *sqliteDatabase) Connect() *gorm.DB {
+	return s.Db
+}
+
+func (s *sqliteDatabase) Close() error {
+	sqlDb, err := s.Db.DB()
+	if err != nil {
+		return err
+	}
+	return sqlDb.Close()
+}
+
+// utcConnPool converts time arguments to UTC. SQLite stores times as text in
+// the zone they were given in, and text in different zones does not compare
+// the way the times do.
+type utcConnPool struct {
+	db *sql.DB
+}
+
+func (p *utcConnPool) PrepareContext(ctx context.Context, query string) (*sql.Stmt, error) {
+	return p.db.PrepareContext(ctx, query)
+}
+
+func (p *utcConnPool) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	return p.db.ExecContext(ctx, query, utcArgs(args)...)
+}
+
+func (p *utcConnPool) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	return p.db.QueryContext(ctx, query, utcArgs(args)...)
+}
+
+func (p *utcConnPool) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	return p.db.QueryRowContext(ctx, query, utcArgs(args)...)
+}
+
+func (p *utcConnPool) BeginTx(ctx context.Context, opts *sql.TxOptions) (gorm.ConnPool, error) {
+	tx, err := p.db.BeginTx(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+	return &utcTx{tx}, nil
+}
+
+func (p *utcConnPool) GetDBConn() (*sql.DB, error) {
+	return p.db, nil
+}
+
+type utcTx struct {
+	*sql.Tx
+}
+
+func (t *utcTx) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	return t.Tx.ExecContext(ctx, query, utcArgs(args)...)
+}
+
+func (t *utcTx) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	return t.Tx.QueryContext(ctx, query, utcArgs(args)...)
+}
+
+func (t *utcTx) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	return t.Tx.QueryRowContext(ctx, query, utcArgs(args)...)
+}
+
+func utcArgs(args []interface{}) []interface{} {
+	for i, arg := range args {
+		switch t := arg.(type) {
+		case time.Time:
+			args[i] = t.UTC()
+		case *time.Time:
+			if t != nil {
+				args[i] = t.UTC()
+			}
+		}
+	}
+	return args
+}
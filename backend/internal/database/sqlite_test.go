@@ -0,0 +1,78 @@
+package database
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/HermanPlay/web-app-backend/internal/config"
+	"gorm.io/gorm"
+)
+
+func TestNewSQLiteDatabase(t *testing.T) {
+	t.Run("file", func(t *testing.T) {
+		cfg := &config.Config{Db: config.Db{Driver: "sqlite", Path: filepath.Join(t.TempDir(), "test.db")}}
+		db, err := New(cfg)
+		if err != nil {
+			t.Fatalf("could not open sqlite database %s", err)
+		}
+		err = db.Connect().Exec("CREATE TABLE notes (id integer PRIMARY KEY)").Error
+		if err != nil {
+			t.Errorf("could not create table %s", err)
+		}
+		var foreignKeys int
+		db.Connect().Raw("PRAGMA foreign_keys").Scan(&foreignKeys)
+		if foreignKeys != 1 {
+			t.Errorf("foreign keys are not enforced")
+		}
+	})
+	t.Run("close", func(t *testing.T) {
+		db, err := New(&config.Config{Db: config.Db{Driver: "sqlite", Path: ":memory:"}})
+		if err != nil {
+			t.Fatalf("could not open sqlite database %s", err)
+		}
+		err = db.Close()
+		if err != nil {
+			t.Errorf("could not close sqlite database %s", err)
+		}
+		err = db.Connect().Exec("SELECT 1").Error
+		assertError(t, err)
+	})
+	t.Run("unknown driver", func(t *testing.T) {
+		_, err := New(&config.Config{Db: config.Db{Driver: "mysql"}})
+		assertError(t, err)
+	})
+}
+
+func TestSQLiteTimesInUTC(t *testing.T) {
+	db, err := NewSQLiteDatabase(&config.Config{Db: config.Db{Path: ":memory:"}})
+	if err != nil {
+		t.Fatalf("could not open sqlite database %s", err)
+	}
+	conn := db.Connect()
+	err = conn.Exec("CREATE TABLE slots (id integer PRIMARY KEY, starts_at datetime)").Error
+	if err != nil {
+		t.Fatalf("could not create table %s", err)
+	}
+
+	// 10:00 in Warsaw is before 09:30 UTC, which text comparison gets wrong
+	// unless both are stored in the same zone
+	warsaw := time.FixedZone("CET", 3600)
+	err = conn.Transaction(func(tx *gorm.DB) error {
+		return tx.Exec("INSERT INTO slots (starts_at) VALUES (?)", time.Date(2024, 1, 1, 10, 0, 0, 0, warsaw)).Error
+	})
+	if err != nil {
+		t.Fatalf("could not insert slot %s", err)
+	}
+	var count int64
+	err = conn.Table("slots").Where("starts_at < ?", time.Date(2024, 1, 1, 9, 30, 0, 0, time.UTC)).Count(&count).Error
+	if err != nil || count != 1 {
+		t.Errorf("slots before 09:30 UTC, got: %d, want: 1. Error: %v", count, err)
+	}
+
+	var startsAt time.Time
+	err = conn.Raw("SELECT starts_at FROM slots").Row().Scan(&startsAt)
+	if err != nil || !startsAt.Equal(time.Date(2024, 1, 1, 9, 0, 0, 0, time.UTC)) {
+		t.Errorf("starts_at is not the same, got: %v. Error: %v", startsAt, err)
+	}
+}
@@ -0,0 +1,82 @@
+package metrics
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/HermanPlay/web-app-backend/internal/config"
+	"github.com/HermanPlay/web-app-backend/internal/database"
+	"github.com/gin-gonic/gin"
+)
+
+func TestMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(Middleware())
+	router.GET("/api/event/:eventID", func(c *gin.Context) {
+		c.Status(http.StatusNoContent)
+	})
+
+	for _, path := range []string{"/api/event/1", "/api/event/2", "/missing"} {
+		router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+	}
+
+	body := scrape()
+	for _, sample := range []string{
+		`http_requests_total{method="GET",route="/api/event/:eventID",status="204"} 2`,
+		`http_requests_total{method="GET",route="unmatched",status="404"} 1`,
+		`http_request_duration_seconds_count{method="GET",route="/api/event/:eventID",status="204"} 2`,
+	} {
+		if !strings.Contains(body, sample) {
+			t.Errorf("Expected %s in the metrics", sample)
+		}
+	}
+}
+
+func TestInstrumentDatabase(t *testing.T) {
+	db, err := database.NewSQLiteDatabase(&config.Config{Db: config.Db{Path: ":memory:"}})
+	if err != nil {
+		t.Fatalf("Error when open database, when not expected. Error: %v", err)
+	}
+	defer db.Close()
+	gormDb := db.Connect()
+	err = InstrumentDatabase(gormDb)
+	if err != nil {
+		t.Fatalf("Error when instrument database, when not expected. Error: %v", err)
+	}
+
+	err = gormDb.Exec("CREATE TABLE notes (id integer PRIMARY KEY, body text)").Error
+	if err != nil {
+		t.Fatalf("Error when create table, when not expected. Error: %v", err)
+	}
+	err = gormDb.Table("notes").Create(map[string]interface{}{"body": "hello"}).Error
+	if err != nil {
+		t.Fatalf("Error when insert note, when not expected. Error: %v", err)
+	}
+	var bodies []string
+	err = gormDb.Table("notes").Pluck("body", &bodies).Error
+	if err != nil {
+		t.Fatalf("Error when query notes, when not expected. Error: %v", err)
+	}
+
+	body := scrape()
+	for _, sample := range []string{
+		`db_query_duration_seconds_count{operation="raw",table=""} 1`,
+		`db_query_duration_seconds_count{operation="create",table="notes"} 1`,
+		`db_query_duration_seconds_count{operation="query",table="notes"} 1`,
+		`go_sql_open_connections{db_name="sqlite"} 1`,
+		"go_goroutines",
+	} {
+		if !strings.Contains(body, sample) {
+			t.Errorf("Expected %s in the metrics", sample)
+		}
+	}
+}
+
+func scrape() string {
+	recorder := httptest.NewRecorder()
+	Handler().ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	return recorder.Body.String()
+}
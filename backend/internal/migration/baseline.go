@@ -0,0 +1,129 @@
+package migration
+
+import (
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+// Layouts the free-form date and time columns of the baseline used to be
+// filled with
+var (
+	legacyDateLayouts = []string{"2006-01-02", "02.01.2006", "01/02/2006"}
+	legacyTimeLayouts = []string{"03:04 PM", "3:04 PM", "15:04", "15:04:05"}
+)
+
+// The baseline had no end time, so migrated events get a default duration
+const legacyEventDuration = time.Hour
+
+// The tables of the baseline, with the columns added to them before
+// migrations were versioned.
+type baselineUser struct {
+	RemindersDisabled bool `gorm:"not null; default:false"`
+}
+
+func (baselineUser) TableName() string {
+	return "users"
+}
+
+type baselineEvent struct {
+	ID              int
+	Date            string
+	Time            string
+	StartsAt        *time.Time
+	EndsAt          *time.Time
+	TimeZone        string `gorm:"not null; default:UTC"`
+	Capacity        int64  `gorm:"not null; default:0"`
+	WaitlistEnabled bool   `gorm:"not null; default:false"`
+	Sequence        int64  `gorm:"not null; default:0"`
+	CreatedAt       time.Time
+}
+
+func (baselineEvent) TableName() string {
+	return "events"
+}
+
+type baselineEventUser struct {
+	Status string `gorm:"not null; default:confirmed"`
+}
+
+func (baselineEventUser) TableName() string {
+	return "event_users"
+}
+
+// upgradeBaseline prepares the initial migration on a database AutoMigrate
+// created from the baseline, whose events still keep their schedule in the
+// date and time text columns. It adds the columns the initial migration
+// expects, moves the schedules into starts_at and ends_at and drops the old
+// columns. Old values carry no zone, so they are taken as UTC. It does nothing
+// on an empty database.
+func upgradeBaseline(tx *gorm.DB) error {
+	if !tx.Migrator().HasColumn(&baselineEvent{}, "date") {
+		return nil
+	}
+
+	columns := []struct {
+		model  any
+		fields []string
+	}{
+		{&baselineUser{}, []string{"RemindersDisabled"}},
+		{&baselineEvent{}, []string{"StartsAt", "EndsAt", "TimeZone", "Capacity", "WaitlistEnabled", "Sequence"}},
+		{&baselineEventUser{}, []string{"Status"}},
+	}
+	for _, table := range columns {
+		for _, field := range table.fields {
+			if tx.Migrator().HasColumn(table.model, field) {
+				continue
+			}
+			err := tx.Migrator().AddColumn(table.model, field)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	var rows []baselineEvent
+	err := tx.Select("id", "date", "time", "created_at").Where("starts_at IS NULL").Find(&rows).Error
+	if err != nil {
+		return err
+	}
+	for _, row := range rows {
+		startsAt, ok := parseLegacySchedule(row.Date, row.Time)
+		if !ok {
+			logrus.Warnf("Could not parse schedule %q %q of event %d, using its creation time", row.Date, row.Time, row.ID)
+			startsAt = row.CreatedAt.UTC()
+		}
+		err := tx.Table("events").Where("id = ?", row.ID).Updates(map[string]interface{}{
+			"starts_at": startsAt,
+			"ends_at":   startsAt.Add(legacyEventDuration),
+			"time_zone": "UTC",
+		}).Error
+		if err != nil {
+			return err
+		}
+	}
+
+	// Not Migrator().DropColumn, which copies the table on SQLite and trips the
+	// foreign keys pointing at events
+	return tx.Exec(`ALTER TABLE events DROP COLUMN "date"; ALTER TABLE events DROP COLUMN "time"`).Error
+}
+
+func parseLegacySchedule(date, clock string) (time.Time, bool) {
+	date = strings.TrimSpace(date)
+	clock = strings.TrimSpace(clock)
+	for _, dateLayout := range legacyDateLayouts {
+		for _, timeLayout := range legacyTimeLayouts {
+			parsed, err := time.Parse(dateLayout+" "+timeLayout, date+" "+clock)
+			if err == nil {
+				return parsed, true
+			}
+		}
+		parsed, err := time.Parse(dateLayout, date)
+		if err == nil {
+			return parsed, true
+		}
+	}
+	return time.Time{}, false
+}
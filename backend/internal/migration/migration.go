@@ -0,0 +1,332 @@
+// Package migration applies versioned SQL migrations. A migration is a pair
+// of files, <version>_<name>.up.sql and <version>_<name>.down.sql, and the
+// versions applied to a database are kept in its schema_migrations table.
+package migration
+
+import (
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// files holds the migrations shipped with the app, in a directory per dialect.
+//
+//go:embed sql
+var files embed.FS
+
+var (
+	ErrUnknownVersion = errors.New("unknown migration version")
+	ErrIrreversible   = errors.New("migration has no down file")
+	ErrNoDialect      = errors.New("no migrations for database dialect")
+)
+
+type Migration struct {
+	Version int64
+	Name    string
+	Up      string
+	Down    string // empty when the migration cannot be reverted
+	// Prepare, when set, runs before Up in the same transaction, for changes
+	// that need more than SQL.
+	Prepare func(tx *gorm.DB) error
+}
+
+// Status tells whether a migration was applied, and when.
+type Status struct {
+	Version   int64
+	Name      string
+	AppliedAt *time.Time
+	Unknown   bool // applied, but not shipped with this build
+}
+
+// appliedMigration is a row of the state table.
+type appliedMigration struct {
+	Version   int64     `gorm:"column:version; primaryKey; autoIncrement:false"`
+	Name      string    `gorm:"column:name; not null"`
+	AppliedAt time.Time `gorm:"column:applied_at; not null"`
+}
+
+func (appliedMigration) TableName() string {
+	return "schema_migrations"
+}
+
+// Load reads the migrations in the root of fsys, ordered by version. Every
+// migration needs an up file, the down file is optional.
+func Load(fsys fs.FS) ([]Migration, error) {
+	names, err := fs.Glob(fsys, "*.sql")
+	if err != nil {
+		return nil, err
+	}
+	byVersion := map[int64]*Migration{}
+	for _, name := range names {
+		base, direction, ok := strings.Cut(strings.TrimSuffix(name, ".sql"), ".")
+		if !ok || (direction != "up" && direction != "down") {
+			return nil, fmt.Errorf("migration file %s must end in .up.sql or .down.sql", name)
+		}
+		number, title, _ := strings.Cut(base, "_")
+		version, err := strconv.ParseInt(number, 10, 64)
+		if err != nil || version <= 0 {
+			return nil, fmt.Errorf("migration file %s must start with a positive version number", name)
+		}
+		content, err := fs.ReadFile(fsys, name)
+		if err != nil {
+			return nil, err
+		}
+
+		migration, ok := byVersion[version]
+		if !ok {
+			migration = &Migration{Version: version, Name: title}
+			byVersion[version] = migration
+		}
+		if migration.Name != title {
+			return nil, fmt.Errorf("migration %d is named both %s and %s", version, migration.Name, title)
+		}
+		if direction == "up" {
+			migration.Up = string(content)
+		} else {
+			migration.Down = string(content)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, migration := range byVersion {
+		if migration.Up == "" {
+			return nil, fmt.Errorf("migration %d has no up file", migration.Version)
+		}
+		migrations = append(migrations, *migration)
+	}
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+	return migrations, nil
+}
+
+// Migrator moves the schema of one database between versions. Every
+// migration runs in its own transaction together with its state change, so
+// a failing migration leaves the database at the previous version. It does
+// not lock against other migrators, migrations are meant to be run once per
+// deploy rather than by every replica.
+type Migrator struct {
+	db         *gorm.DB
+	migrations []Migration
+}
+
+func NewMigrator(db *gorm.DB, migrations []Migration) *Migrator {
+	return &Migrator{db: db, migrations: migrations}
+}
+
+// ForDatabase returns a migrator with the migrations shipped for the dialect
+// of db.
+func ForDatabase(db *gorm.DB) (*Migrator, error) {
+	migrations, err := Migrations(db.Dialector.Name())
+	if err != nil {
+		return nil, err
+	}
+	return NewMigrator(db, migrations), nil
+}
+
+// Status lists all known migrations in order, followed by versions that are
+// applied but unknown to this build, e.g. after rolling back a deploy.
+func (m *Migrator) Status() ([]Status, error) {
+	applied, err := m.applied()
+	if err != nil {
+		return nil, err
+	}
+	statuses := make([]Status, 0, len(m.migrations))
+	for _, migration := range m.migrations {
+		status := Status{Version: migration.Version, Name: migration.Name}
+		if row, ok := applied[migration.Version]; ok {
+			appliedAt := row.AppliedAt
+			status.AppliedAt = &appliedAt
+			delete(applied, migration.Version)
+		}
+		statuses = append(statuses, status)
+	}
+	var unknown []Status
+	for _, row := range applied {
+		appliedAt := row.AppliedAt
+		unknown = append(unknown, Status{Version: row.Version, Name: row.Name, AppliedAt: &appliedAt, Unknown: true})
+	}
+	sort.Slice(unknown, func(i, j int) bool {
+		return unknown[i].Version < unknown[j].Version
+	})
+	return append(statuses, unknown...), nil
+}
+
+// Pending returns the known migrations that are not applied yet, in order.
+// Like Status, it does not change the database.
+func (m *Migrator) Pending() ([]Migration, error) {
+	applied, err := m.applied()
+	if err != nil {
+		return nil, err
+	}
+	var pending []Migration
+	for _, migration := range m.migrations {
+		if _, ok := applied[migration.Version]; !ok {
+			pending = append(pending, migration)
+		}
+	}
+	return pending, nil
+}
+
+// Up applies all pending migrations and returns them.
+func (m *Migrator) Up() ([]Migration, error) {
+	return m.To(m.latest())
+}
+
+// Down reverts the most recently applied migration and returns it, or
+// nothing when no migration is applied.
+func (m *Migrator) Down() ([]Migration, error) {
+	applied, err := m.applied()
+	if err != nil {
+		return nil, err
+	}
+	for i := len(m.migrations) - 1; i >= 0; i-- {
+		if _, ok := applied[m.migrations[i].Version]; ok {
+			err = m.revert(m.migrations[i])
+			if err != nil {
+				return nil, err
+			}
+			return m.migrations[i : i+1], nil
+		}
+	}
+	return nil, nil
+}
+
+// To applies the pending migrations up to and including version and reverts
+// the applied ones after it, so 0 reverts everything. It returns the
+// migrations it ran, in the order it ran them.
+func (m *Migrator) To(version int64) ([]Migration, error) {
+	if version != 0 && !m.known(version) {
+		return nil, fmt.Errorf("%w: %d", ErrUnknownVersion, version)
+	}
+	applied, err := m.applied()
+	if err != nil {
+		return nil, err
+	}
+
+	var ran []Migration
+	for i := len(m.migrations) - 1; i >= 0; i-- {
+		migration := m.migrations[i]
+		if _, ok := applied[migration.Version]; !ok || migration.Version <= version {
+			continue
+		}
+		err = m.revert(migration)
+		if err != nil {
+			return ran, err
+		}
+		ran = append(ran, migration)
+	}
+	for _, migration := range m.migrations {
+		if _, ok := applied[migration.Version]; ok || migration.Version > version {
+			continue
+		}
+		if !m.db.Migrator().HasTable(&appliedMigration{}) {
+			err = m.db.Migrator().CreateTable(&appliedMigration{})
+			if err != nil {
+				return ran, err
+			}
+		}
+		err = m.apply(migration)
+		if err != nil {
+			return ran, err
+		}
+		ran = append(ran, migration)
+	}
+	return ran, nil
+}
+
+func (m *Migrator) apply(migration Migration) error {
+	return m.db.Transaction(func(tx *gorm.DB) error {
+		if migration.Prepare != nil {
+			err := migration.Prepare(tx)
+			if err != nil {
+				return fmt.Errorf("migration %d_%s: %w", migration.Version, migration.Name, err)
+			}
+		}
+		err := tx.Exec(migration.Up).Error
+		if err != nil {
+			return fmt.Errorf("migration %d_%s: %w", migration.Version, migration.Name, err)
+		}
+		return tx.Create(&appliedMigration{
+			Version:   migration.Version,
+			Name:      migration.Name,
+			AppliedAt: time.Now().UTC(),
+		}).Error
+	})
+}
+
+func (m *Migrator) revert(migration Migration) error {
+	if migration.Down == "" {
+		return fmt.Errorf("%w: %d_%s", ErrIrreversible, migration.Version, migration.Name)
+	}
+	return m.db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Exec(migration.Down).Error
+		if err != nil {
+			return fmt.Errorf("migration %d_%s: %w", migration.Version, migration.Name, err)
+		}
+		return tx.Delete(&appliedMigration{Version: migration.Version}).Error
+	})
+}
+
+// applied returns the rows of the state table by version. It only reads, a
+// database without the table has nothing applied.
+func (m *Migrator) applied() (map[int64]appliedMigration, error) {
+	if !m.db.Migrator().HasTable(&appliedMigration{}) {
+		return map[int64]appliedMigration{}, nil
+	}
+	var rows []appliedMigration
+	err := m.db.Find(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+	applied := make(map[int64]appliedMigration, len(rows))
+	for _, row := range rows {
+		applied[row.Version] = row
+	}
+	return applied, nil
+}
+
+func (m *Migrator) known(version int64) bool {
+	for _, migration := range m.migrations {
+		if migration.Version == version {
+			return true
+		}
+	}
+	return false
+}
+
+func (m *Migrator) latest() int64 {
+	if len(m.migrations) == 0 {
+		return 0
+	}
+	return m.migrations[len(m.migrations)-1].Version
+}
+
+// Migrations returns the migrations shipped for a database dialect, as
+// named by gorm. The first one also upgrades databases AutoMigrate created
+// from the baseline schema.
+func Migrations(dialect string) ([]Migration, error) {
+	fsys, err := fs.Sub(files, path.Join("sql", dialect))
+	if err != nil {
+		return nil, err
+	}
+	migrations, err := Load(fsys)
+	if err != nil {
+		return nil, err
+	}
+	if len(migrations) == 0 {
+		return nil, fmt.Errorf("%w %s", ErrNoDialect, dialect)
+	}
+	if migrations[0].Version == 1 {
+		migrations[0].Prepare = upgradeBaseline
+	}
+	return migrations, nil
+}
@@ -0,0 +1,321 @@
+package migration_test
+
+import (
+	"errors"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/HermanPlay/web-app-backend/internal/migration"
+	"github.com/HermanPlay/web-app-backend/package/domain/models"
+	"github.com/HermanPlay/web-app-backend/package/utils"
+	"gorm.io/gorm"
+)
+
+var testFiles = fstest.MapFS{
+	"0001_notes.up.sql":        {Data: []byte("CREATE TABLE migration_notes (id integer PRIMARY KEY, body text);")},
+	"0001_notes.down.sql":      {Data: []byte("DROP TABLE migration_notes;")},
+	"0002_tags.up.sql":         {Data: []byte("CREATE TABLE migration_tags (id integer PRIMARY KEY); CREATE INDEX idx_migration_tags_id ON migration_tags (id);")},
+	"0002_tags.down.sql":       {Data: []byte("DROP TABLE migration_tags;")},
+	"0010_note_title.up.sql":   {Data: []byte("ALTER TABLE migration_notes ADD COLUMN title text;")},
+	"0010_note_title.down.sql": {Data: []byte("ALTER TABLE migration_notes DROP COLUMN title;")},
+}
+
+func newTestMigrator(t *testing.T, files fstest.MapFS) (*migration.Migrator, *gorm.DB) {
+	db := utils.ConnectToTestDatabase()
+	db.Migrator().DropTable("migration_tags", "migration_notes", "schema_migrations")
+	migrations, err := migration.Load(files)
+	if err != nil {
+		t.Fatalf("Error when load migrations, when not expected. Error: %v", err)
+	}
+	return migration.NewMigrator(db, migrations), db
+}
+
+func versions(migrations []migration.Migration) []int64 {
+	var result []int64
+	for _, m := range migrations {
+		result = append(result, m.Version)
+	}
+	return result
+}
+
+func sameVersions(got []migration.Migration, want ...int64) bool {
+	if len(got) != len(want) {
+		return false
+	}
+	for i := range want {
+		if got[i].Version != want[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestLoad(t *testing.T) {
+	migrations, err := migration.Load(testFiles)
+	if err != nil {
+		t.Fatalf("Error when load migrations, when not expected. Error: %v", err)
+	}
+	if !sameVersions(migrations, 1, 2, 10) {
+		t.Fatalf("Versions are not the same, got: %v", versions(migrations))
+	}
+	if migrations[2].Name != "note_title" || migrations[2].Up == "" || migrations[2].Down == "" {
+		t.Errorf("Migration is not the same, got: %+v", migrations[2])
+	}
+
+	for name, files := range map[string]fstest.MapFS{
+		"no version":     {"notes.up.sql": {Data: []byte("SELECT 1;")}},
+		"no direction":   {"0001_notes.sql": {Data: []byte("SELECT 1;")}},
+		"no up file":     {"0001_notes.down.sql": {Data: []byte("SELECT 1;")}},
+		"name mismatch":  {"0001_notes.up.sql": {Data: []byte("SELECT 1;")}, "0001_tags.down.sql": {Data: []byte("SELECT 1;")}},
+		"version zero":   {"0000_notes.up.sql": {Data: []byte("SELECT 1;")}},
+		"wrong sequence": {"0001_notes.sideways.sql": {Data: []byte("SELECT 1;")}},
+	} {
+		_, err := migration.Load(files)
+		if err == nil {
+			t.Errorf("%s: no error when load migrations, when expected", name)
+		}
+	}
+}
+
+func TestMigrator(t *testing.T) {
+	migrator, db := newTestMigrator(t, testFiles)
+
+	pending, err := migrator.Pending()
+	if err != nil || !sameVersions(pending, 1, 2, 10) {
+		t.Errorf("Pending migrations are not the same, got: %v. Error: %v", versions(pending), err)
+	}
+	_, err = migrator.Status()
+	if err != nil {
+		t.Errorf("Error when get status, when not expected. Error: %v", err)
+	}
+	if db.Migrator().HasTable("schema_migrations") {
+		t.Errorf("State table exists before migrating, when not expected")
+	}
+
+	ran, err := migrator.To(2)
+	if err != nil || !sameVersions(ran, 1, 2) {
+		t.Errorf("Migrations run to version 2 are not the same, got: %v. Error: %v", versions(ran), err)
+	}
+	if !db.Migrator().HasTable("migration_tags") || db.Migrator().HasColumn("migration_notes", "title") {
+		t.Errorf("Schema is not at version 2")
+	}
+
+	ran, err = migrator.Up()
+	if err != nil || !sameVersions(ran, 10) {
+		t.Errorf("Migrations run up are not the same, got: %v. Error: %v", versions(ran), err)
+	}
+	if !db.Migrator().HasColumn("migration_notes", "title") {
+		t.Errorf("Column title is missing after up, when expected")
+	}
+	ran, err = migrator.Up()
+	if err != nil || len(ran) != 0 {
+		t.Errorf("Migrations run up twice are not the same, got: %v. Error: %v", versions(ran), err)
+	}
+
+	statuses, err := migrator.Status()
+	if err != nil {
+		t.Fatalf("Error when get status, when not expected. Error: %v", err)
+	}
+	if len(statuses) != 3 {
+		t.Fatalf("Status count is not the same, got: %d, want: %d", len(statuses), 3)
+	}
+	for _, status := range statuses {
+		if status.AppliedAt == nil {
+			t.Errorf("Migration %d is not applied, when expected", status.Version)
+		}
+	}
+
+	ran, err = migrator.Down()
+	if err != nil || !sameVersions(ran, 10) {
+		t.Errorf("Migrations run down are not the same, got: %v. Error: %v", versions(ran), err)
+	}
+	if db.Migrator().HasColumn("migration_notes", "title") {
+		t.Errorf("Column title exists after down, when not expected")
+	}
+
+	ran, err = migrator.To(0)
+	if err != nil || !sameVersions(ran, 2, 1) {
+		t.Errorf("Migrations run to version 0 are not the same, got: %v. Error: %v", versions(ran), err)
+	}
+	if db.Migrator().HasTable("migration_notes") || db.Migrator().HasTable("migration_tags") {
+		t.Errorf("Tables exist after reverting everything, when not expected")
+	}
+	ran, err = migrator.Down()
+	if err != nil || len(ran) != 0 {
+		t.Errorf("Migrations run down with nothing applied are not the same, got: %v. Error: %v", versions(ran), err)
+	}
+
+	_, err = migrator.To(3)
+	if !errors.Is(err, migration.ErrUnknownVersion) {
+		t.Errorf("Error is not migration.ErrUnknownVersion, when expected. Error: %v", err)
+	}
+}
+
+func TestMigratorFailure(t *testing.T) {
+	files := fstest.MapFS{
+		"0001_notes.up.sql":  testFiles["0001_notes.up.sql"],
+		"0002_broken.up.sql": {Data: []byte("CREATE TABLE migration_tags (id integer PRIMARY KEY); THIS IS NOT SQL;")},
+	}
+	migrator, db := newTestMigrator(t, files)
+
+	ran, err := migrator.Up()
+	if err == nil {
+		t.Fatalf("No error when run a broken migration, when expected")
+	}
+	if !sameVersions(ran, 1) {
+		t.Errorf("Migrations run are not the same, got: %v", versions(ran))
+	}
+	pending, err := migrator.Pending()
+	if err != nil || !sameVersions(pending, 2) {
+		t.Errorf("Pending migrations are not the same, got: %v. Error: %v", versions(pending), err)
+	}
+	if db.Migrator().HasTable("migration_tags") {
+		t.Errorf("Broken migration was partly applied, when not expected")
+	}
+
+	_, err = migrator.To(0)
+	if !errors.Is(err, migration.ErrIrreversible) {
+		t.Errorf("Error is not migration.ErrIrreversible, when expected. Error: %v", err)
+	}
+}
+
+func TestStatusUnknownVersion(t *testing.T) {
+	migrator, db := newTestMigrator(t, testFiles)
+	_, err := migrator.Up()
+	if err != nil {
+		t.Fatalf("Error when run migrations up, when not expected. Error: %v", err)
+	}
+
+	// An older build knows only the first migration
+	migrations, _ := migration.Load(testFiles)
+	older := migration.NewMigrator(db, migrations[:1])
+	statuses, err := older.Status()
+	if err != nil {
+		t.Fatalf("Error when get status, when not expected. Error: %v", err)
+	}
+	if len(statuses) != 3 || statuses[0].Unknown || statuses[1].Version != 2 || statuses[1].Name != "tags" || !statuses[1].Unknown || statuses[2].Version != 10 {
+		t.Errorf("Statuses are not the same, got: %+v", statuses)
+	}
+	pending, err := older.Pending()
+	if err != nil || len(pending) != 0 {
+		t.Errorf("Pending migrations are not the same, got: %v. Error: %v", versions(pending), err)
+	}
+}
+
+func TestShippedMigrations(t *testing.T) {
+	db := utils.ConnectToTestDatabase()
+	migrator, err := migration.ForDatabase(db)
+	if err != nil {
+		t.Fatalf("Error when load shipped migrations, when not expected. Error: %v", err)
+	}
+
+	// The test database comes with every migration applied
+	pending, err := migrator.Pending()
+	if err != nil || len(pending) != 0 {
+		t.Errorf("Pending migrations are not the same, got: %v. Error: %v", versions(pending), err)
+	}
+	_, err = migrator.To(0)
+	if err != nil {
+		t.Fatalf("Error when revert all migrations, when not expected. Error: %v", err)
+	}
+	for _, table := range []string{"users", "events", "event_users", "jobs", "webhooks"} {
+		if db.Migrator().HasTable(table) {
+			t.Errorf("Table %s exists after reverting all migrations, when not expected", table)
+		}
+	}
+	_, err = migrator.Up()
+	if err != nil {
+		t.Errorf("Error when run migrations up, when not expected. Error: %v", err)
+	}
+}
+
+// The models as AutoMigrate created them before migrations were versioned
+type baselineUser struct {
+	ID       int `gorm:"column:id; primary_key; not null"`
+	Name     string
+	Email    string
+	Password string
+	Role     string
+	models.BaseModel
+}
+
+type baselineEvent struct {
+	ID               int          `gorm:"column:id; primary_key; not null"`
+	Title            string       `gorm:"not null"`
+	ShortDescription string       `gorm:"not null"`
+	Description      string       `gorm:"not null"`
+	Location         string       `gorm:"not null"`
+	Date             string       `gorm:"not null"`
+	Time             string       `gorm:"not null"`
+	IsFeatured       bool         `gorm:"not null"`
+	CreatedBy        int          `gorm:"not null"`
+	User             baselineUser `gorm:"foreignKey:CreatedBy; references:ID"`
+	models.BaseModel
+}
+
+type baselineEventUser struct {
+	ID      int           `gorm:"column:id; primary_key; not null"`
+	EventID int           `gorm:"not null"`
+	Event   baselineEvent `gorm:"foreignKey:EventID; references:ID"`
+	UserID  int           `gorm:"not null"`
+	User    baselineUser  `gorm:"foreignKey:UserID; references:ID"`
+	models.BaseModel
+}
+
+func (baselineUser) TableName() string      { return "users" }
+func (baselineEvent) TableName() string     { return "events" }
+func (baselineEventUser) TableName() string { return "event_users" }
+
+func TestUpgradeBaseline(t *testing.T) {
+	db := utils.ConnectToTestDatabase()
+	migrator, err := migration.ForDatabase(db)
+	if err != nil {
+		t.Fatalf("Error when load shipped migrations, when not expected. Error: %v", err)
+	}
+	_, err = migrator.To(0)
+	if err != nil {
+		t.Fatalf("Error when revert all migrations, when not expected. Error: %v", err)
+	}
+	db.Migrator().DropTable("schema_migrations")
+	err = db.AutoMigrate(&baselineUser{}, &baselineEvent{}, &baselineEventUser{})
+	if err != nil {
+		t.Fatalf("Error when create baseline schema, when not expected. Error: %v", err)
+	}
+	createdAt := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	db.Create(&baselineUser{ID: 1, Name: "user"})
+	db.Create(&baselineEvent{ID: 1, Title: "parsed", Date: "2024-11-15", Time: "09:00 AM", CreatedBy: 1, BaseModel: models.BaseModel{CreatedAt: createdAt}})
+	db.Create(&baselineEvent{ID: 2, Title: "day first", Date: "05.12.2024", Time: "18:30", CreatedBy: 1, BaseModel: models.BaseModel{CreatedAt: createdAt}})
+	db.Create(&baselineEvent{ID: 3, Title: "unparsed", Date: "soon", Time: "whenever", CreatedBy: 1, BaseModel: models.BaseModel{CreatedAt: createdAt}})
+	db.Create(&baselineEventUser{EventID: 1, UserID: 1})
+
+	_, err = migrator.Up()
+	if err != nil {
+		t.Fatalf("Error when run migrations up on the baseline, when not expected. Error: %v", err)
+	}
+	for _, column := range []string{"date", "time"} {
+		if db.Migrator().HasColumn(&models.Event{}, column) {
+			t.Errorf("Legacy column %s still exists, when not expected", column)
+		}
+	}
+
+	var events []models.Event
+	db.Order("id").Find(&events)
+	if len(events) != 3 {
+		t.Fatalf("Events count is not same, got: %d, want: %d", len(events), 3)
+	}
+	for i, want := range []time.Time{
+		time.Date(2024, 11, 15, 9, 0, 0, 0, time.UTC),
+		time.Date(2024, 12, 5, 18, 30, 0, 0, time.UTC),
+		createdAt,
+	} {
+		if !events[i].StartsAt.Equal(want) || !events[i].EndsAt.Equal(want.Add(time.Hour)) || events[i].TimeZone != "UTC" {
+			t.Errorf("Schedule of event %d is not same, got: %s - %s %s, want: %s", events[i].ID, events[i].StartsAt, events[i].EndsAt, events[i].TimeZone, want)
+		}
+	}
+	var booking models.EventUser
+	db.First(&booking)
+	if booking.Status != models.BookingConfirmed {
+		t.Errorf("Booking status is not same, got: %s, want: %s", booking.Status, models.BookingConfirmed)
+	}
+}
@@ -0,0 +1,164 @@
+-- The schema as AutoMigrate left it before migrations were versioned. Every
+-- statement is conditional, so databases created by AutoMigrate adopt this
+-- version as they are.
+
+CREATE TABLE IF NOT EXISTS users (
+    id bigserial PRIMARY KEY,
+    name text,
+    email text,
+    password text,
+    role text,
+    reminders_disabled boolean NOT NULL DEFAULT false,
+    created_at timestamptz,
+    updated_at timestamptz,
+    deleted_at timestamptz
+);
+CREATE INDEX IF NOT EXISTS idx_users_deleted_at ON users (deleted_at);
+
+CREATE TABLE IF NOT EXISTS events (
+    id bigserial PRIMARY KEY,
+    title text NOT NULL,
+    short_description text NOT NULL,
+    description text NOT NULL,
+    location text NOT NULL,
+    starts_at timestamptz,
+    ends_at timestamptz,
+    time_zone text NOT NULL DEFAULT 'UTC',
+    is_featured boolean NOT NULL,
+    capacity bigint NOT NULL DEFAULT 0,
+    waitlist_enabled boolean NOT NULL DEFAULT false,
+    sequence bigint NOT NULL DEFAULT 0,
+    created_by bigint NOT NULL,
+    created_at timestamptz,
+    updated_at timestamptz,
+    deleted_at timestamptz,
+    CONSTRAINT fk_events_user FOREIGN KEY (created_by) REFERENCES users (id)
+);
+CREATE INDEX IF NOT EXISTS idx_events_starts_at ON events (starts_at);
+CREATE INDEX IF NOT EXISTS idx_events_deleted_at ON events (deleted_at);
+
+CREATE TABLE IF NOT EXISTS event_users (
+    id bigserial PRIMARY KEY,
+    event_id bigint NOT NULL,
+    user_id bigint NOT NULL,
+    status text NOT NULL DEFAULT 'confirmed',
+    created_at timestamptz,
+    updated_at timestamptz,
+    deleted_at timestamptz,
+    CONSTRAINT fk_event_users_event FOREIGN KEY (event_id) REFERENCES events (id),
+    CONSTRAINT fk_event_users_user FOREIGN KEY (user_id) REFERENCES users (id)
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_event_users_event_user ON event_users (event_id, user_id);
+CREATE INDEX IF NOT EXISTS idx_event_users_deleted_at ON event_users (deleted_at);
+
+CREATE TABLE IF NOT EXISTS event_organizers (
+    id bigserial PRIMARY KEY,
+    event_id bigint NOT NULL,
+    user_id bigint NOT NULL,
+    created_at timestamptz,
+    updated_at timestamptz,
+    deleted_at timestamptz,
+    CONSTRAINT fk_event_organizers_event FOREIGN KEY (event_id) REFERENCES events (id),
+    CONSTRAINT fk_event_organizers_user FOREIGN KEY (user_id) REFERENCES users (id)
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_event_organizers_event_user ON event_organizers (event_id, user_id);
+CREATE INDEX IF NOT EXISTS idx_event_organizers_deleted_at ON event_organizers (deleted_at);
+
+CREATE TABLE IF NOT EXISTS event_reminders (
+    id bigserial PRIMARY KEY,
+    event_id bigint NOT NULL,
+    user_id bigint NOT NULL,
+    kind text NOT NULL,
+    sent_at timestamptz NOT NULL,
+    CONSTRAINT fk_event_reminders_event FOREIGN KEY (event_id) REFERENCES events (id),
+    CONSTRAINT fk_event_reminders_user FOREIGN KEY (user_id) REFERENCES users (id)
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_event_reminders_event_user_kind ON event_reminders (event_id, user_id, kind);
+
+CREATE TABLE IF NOT EXISTS refresh_tokens (
+    id bigserial PRIMARY KEY,
+    user_id bigint NOT NULL,
+    family_id text NOT NULL,
+    token_hash text NOT NULL,
+    expires_at timestamptz NOT NULL,
+    used_at timestamptz,
+    revoked_at timestamptz,
+    created_at timestamptz,
+    updated_at timestamptz,
+    deleted_at timestamptz,
+    CONSTRAINT fk_refresh_tokens_user FOREIGN KEY (user_id) REFERENCES users (id)
+);
+CREATE INDEX IF NOT EXISTS idx_refresh_tokens_user_id ON refresh_tokens (user_id);
+CREATE INDEX IF NOT EXISTS idx_refresh_tokens_family_id ON refresh_tokens (family_id);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_refresh_tokens_token_hash ON refresh_tokens (token_hash);
+CREATE INDEX IF NOT EXISTS idx_refresh_tokens_deleted_at ON refresh_tokens (deleted_at);
+
+CREATE TABLE IF NOT EXISTS password_reset_tokens (
+    id bigserial PRIMARY KEY,
+    user_id bigint NOT NULL,
+    token_hash text NOT NULL,
+    expires_at timestamptz NOT NULL,
+    used_at timestamptz,
+    created_at timestamptz,
+    updated_at timestamptz,
+    deleted_at timestamptz,
+    CONSTRAINT fk_password_reset_tokens_user FOREIGN KEY (user_id) REFERENCES users (id)
+);
+CREATE INDEX IF NOT EXISTS idx_password_reset_tokens_user_id ON password_reset_tokens (user_id);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_password_reset_tokens_token_hash ON password_reset_tokens (token_hash);
+CREATE INDEX IF NOT EXISTS idx_password_reset_tokens_deleted_at ON password_reset_tokens (deleted_at);
+
+CREATE TABLE IF NOT EXISTS feed_tokens (
+    id bigserial PRIMARY KEY,
+    user_id bigint NOT NULL,
+    token_hash text NOT NULL,
+    created_at timestamptz,
+    CONSTRAINT fk_feed_tokens_user FOREIGN KEY (user_id) REFERENCES users (id)
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_feed_tokens_user_id ON feed_tokens (user_id);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_feed_tokens_token_hash ON feed_tokens (token_hash);
+
+CREATE TABLE IF NOT EXISTS jobs (
+    id bigserial PRIMARY KEY,
+    kind text NOT NULL,
+    payload text NOT NULL,
+    status text NOT NULL DEFAULT 'pending',
+    run_at timestamptz NOT NULL,
+    attempts bigint NOT NULL DEFAULT 0,
+    max_attempts bigint NOT NULL,
+    locked_until timestamptz,
+    last_error text,
+    created_at timestamptz,
+    updated_at timestamptz
+);
+CREATE INDEX IF NOT EXISTS idx_jobs_kind ON jobs (kind);
+CREATE INDEX IF NOT EXISTS idx_jobs_status_run_at ON jobs (status, run_at);
+
+CREATE TABLE IF NOT EXISTS webhooks (
+    id bigserial PRIMARY KEY,
+    url text NOT NULL,
+    secret text NOT NULL,
+    events text NOT NULL,
+    active boolean NOT NULL DEFAULT true,
+    created_by bigint NOT NULL,
+    created_at timestamptz,
+    updated_at timestamptz,
+    deleted_at timestamptz
+);
+CREATE INDEX IF NOT EXISTS idx_webhooks_deleted_at ON webhooks (deleted_at);
+
+CREATE TABLE IF NOT EXISTS webhook_deliveries (
+    id bigserial PRIMARY KEY,
+    webhook_id bigint NOT NULL,
+    delivery_id text NOT NULL,
+    event text NOT NULL,
+    payload text NOT NULL,
+    status_code bigint,
+    response text,
+    error text,
+    duration_ms bigint,
+    created_at timestamptz,
+    CONSTRAINT fk_webhook_deliveries_webhook FOREIGN KEY (webhook_id) REFERENCES webhooks (id)
+);
+CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_webhook_id ON webhook_deliveries (webhook_id);
+CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_delivery_id ON webhook_deliveries (delivery_id);
@@ -0,0 +1,11 @@
+DROP TABLE IF EXISTS webhook_deliveries;
+DROP TABLE IF EXISTS webhooks;
+DROP TABLE IF EXISTS jobs;
+DROP TABLE IF EXISTS feed_tokens;
+DROP TABLE IF EXISTS password_reset_tokens;
+DROP TABLE IF EXISTS refresh_tokens;
+DROP TABLE IF EXISTS event_reminders;
+DROP TABLE IF EXISTS event_organizers;
+DROP TABLE IF EXISTS event_users;
+DROP TABLE IF EXISTS events;
+DROP TABLE IF EXISTS users;
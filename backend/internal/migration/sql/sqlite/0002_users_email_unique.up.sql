@@ -0,0 +1,3 @@
+-- Emails identify users at login, deleted users free theirs for a new account.
+-- Fails while live users share an email; merge or rename them first.
+CREATE UNIQUE INDEX idx_users_email ON users (email) WHERE deleted_at IS NULL;
@@ -0,0 +1,193 @@
+package notification
+
+import (
+	"bytes"
+	"fmt"
+	"mime/multipart"
+	"net/smtp"
+	"net/textproto"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/HermanPlay/web-app-backend/internal/config"
+)
+
+// Notifier delivers templated messages to users. Send delivers a message
+// that was rendered earlier, e.g. when it was queued as a job.
+type Notifier interface {
+	Notify(to string, template Template, data any) error
+	Send(msg Message) error
+}
+
+type Message struct {
+	To      string `json:"to"`
+	Subject string `json:"subject"`
+	Text    string `json:"text"`
+	HTML    string `json:"html"`
+}
+
+// NewNotifier picks the implementation configured by cfg.Mail.Driver.
+func NewNotifier(cfg *config.Config) (Notifier, error) {
+	switch cfg.Mail.Driver {
+	case "smtp":
+		return NewSMTPNotifier(cfg), nil
+	case "file":
+		return NewFileNotifier(cfg.Mail.Dir, cfg.Mail.From)
+	case "memory":
+		return NewMemoryNotifier(), nil
+	default:
+		return nil, fmt.Errorf("unknown mail driver %q", cfg.Mail.Driver)
+	}
+}
+
+type SMTPNotifier struct {
+	addr string
+	from string
+	auth smtp.Auth
+}
+
+func (s SMTPNotifier) Notify(to string, template Template, data any) error {
+	msg, err := Render(to, template, data)
+	if err != nil {
+		return err
+	}
+	return s.Send(msg)
+}
+
+func (s SMTPNotifier) Send(msg Message) error {
+	body, err := formatMessage(s.from, msg)
+	if err != nil {
+		return err
+	}
+	return smtp.SendMail(s.addr, s.auth, s.from, []string{msg.To}, body)
+}
+
+func NewSMTPNotifier(cfg *config.Config) *SMTPNotifier {
+	var auth smtp.Auth
+	if cfg.Mail.Username != "" {
+		auth = smtp.PlainAuth("", cfg.Mail.Username, cfg.Mail.Password, cfg.Mail.Host)
+	}
+	return &SMTPNotifier{
+		addr: cfg.Mail.Host + ":" + strconv.Itoa(cfg.Mail.Port),
+		from: cfg.Mail.From,
+		auth: auth,
+	}
+}
+
+// FileNotifier writes every message to its own .eml file instead of sending
+// it, for local development.
+type FileNotifier struct {
+	dir  string
+	from string
+}
+
+func (f FileNotifier) Notify(to string, template Template, data any) error {
+	msg, err := Render(to, template, data)
+	if err != nil {
+		return err
+	}
+	return f.Send(msg)
+}
+
+func (f FileNotifier) Send(msg Message) error {
+	body, err := formatMessage(f.from, msg)
+	if err != nil {
+		return err
+	}
+	name := fmt.Sprintf("%d-%s.eml", time.Now().UnixNano(), sanitize(msg.To))
+	return os.WriteFile(filepath.Join(f.dir, name), body, 0o600)
+}
+
+func NewFileNotifier(dir, from string) (*FileNotifier, error) {
+	err := os.MkdirAll(dir, 0o700)
+	if err != nil {
+		return nil, err
+	}
+	return &FileNotifier{
+		dir:  dir,
+		from: from,
+	}, nil
+}
+
+// MemoryNotifier keeps rendered messages in memory, for tests.
+type MemoryNotifier struct {
+	mu       sync.Mutex
+	messages []Message
+}
+
+func (m *MemoryNotifier) Notify(to string, template Template, data any) error {
+	msg, err := Render(to, template, data)
+	if err != nil {
+		return err
+	}
+	return m.Send(msg)
+}
+
+func (m *MemoryNotifier) Send(msg Message) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.messages = append(m.messages, msg)
+	return nil
+}
+
+// Messages returns a copy of everything sent so far.
+func (m *MemoryNotifier) Messages() []Message {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]Message(nil), m.messages...)
+}
+
+func NewMemoryNotifier() *MemoryNotifier {
+	return &MemoryNotifier{}
+}
+
+// formatMessage builds a multipart/alternative email with the text and HTML
+// versions of msg.
+func formatMessage(from string, msg Message) ([]byte, error) {
+	var body bytes.Buffer
+	parts := multipart.NewWriter(&body)
+	for _, part := range []struct{ contentType, content string }{
+		{"text/plain; charset=UTF-8", msg.Text},
+		{"text/html; charset=UTF-8", msg.HTML},
+	} {
+		w, err := parts.CreatePart(textproto.MIMEHeader{"Content-Type": {part.contentType}})
+		if err != nil {
+			return nil, err
+		}
+		_, err = w.Write([]byte(strings.ReplaceAll(part.content, "\n", "\r\n")))
+		if err != nil {
+			return nil, err
+		}
+	}
+	err := parts.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	var b bytes.Buffer
+	// Strip line breaks so user input cannot inject headers
+	header := strings.NewReplacer("\r", "", "\n", "")
+	fmt.Fprintf(&b, "From: %s\r\n", header.Replace(from))
+	fmt.Fprintf(&b, "To: %s\r\n", header.Replace(msg.To))
+	fmt.Fprintf(&b, "Subject: %s\r\n", header.Replace(msg.Subject))
+	fmt.Fprintf(&b, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	b.WriteString("MIME-Version: 1.0\r\n")
+	fmt.Fprintf(&b, "Content-Type: multipart/alternative; boundary=%s\r\n", parts.Boundary())
+	b.WriteString("\r\n")
+	b.Write(body.Bytes())
+	return b.Bytes(), nil
+}
+
+// sanitize keeps addresses usable as part of a file name.
+func sanitize(s string) string {
+	return strings.Map(func(r rune) rune {
+		if r == '/' || r == '\\' || r == os.PathSeparator {
+			return '_'
+		}
+		return r
+	}, s)
+}
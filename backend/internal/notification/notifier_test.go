@@ -0,0 +1,138 @@
+package notification
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/HermanPlay/web-app-backend/internal/config"
+	"github.com/HermanPlay/web-app-backend/package/domain/schemas"
+)
+
+func TestRender(t *testing.T) {
+	warsaw, _ := time.LoadLocation("Europe/Warsaw")
+	event := schemas.Event{
+		Title:    "Jazz <Night>",
+		Location: "Warsaw",
+		StartsAt: time.Date(2024, 11, 15, 19, 0, 0, 0, warsaw),
+		EndsAt:   time.Date(2024, 11, 15, 22, 0, 0, 0, warsaw),
+		TimeZone: "Europe/Warsaw",
+	}
+	msg, err := Render("to@email.com", BookingConfirmed, EventData{Name: "Ann", Event: event, Link: "http://frontend/events/1"})
+	if err != nil {
+		t.Fatalf("Error when render template, when not expected. Error: %v", err)
+	}
+	if msg.To != "to@email.com" || msg.Subject != "You are going to Jazz <Night>" {
+		t.Errorf("Message header is not the same, got: %v %q", msg.To, msg.Subject)
+	}
+	if !strings.Contains(msg.Text, "When: Fri, 15 Nov 2024 19:00 - 22:00 (Europe/Warsaw)") {
+		t.Errorf("Text does not show the local event time, got: %v", msg.Text)
+	}
+	if !strings.Contains(msg.HTML, "Jazz &lt;Night&gt;") || !strings.Contains(msg.HTML, `href="http://frontend/events/1"`) {
+		t.Errorf("HTML is not escaped or misses the link, got: %v", msg.HTML)
+	}
+
+	for _, template := range []Template{Welcome, PasswordReset, BookingWaitlisted, BookingPromoted, EventUpdated, EventReminder} {
+		data := map[Template]any{
+			Welcome:           WelcomeData{Name: "Ann"},
+			PasswordReset:     PasswordResetData{Name: "Ann", ExpiresIn: time.Hour},
+			BookingWaitlisted: EventData{Name: "Ann", Event: event, WaitlistPosition: 2},
+			BookingPromoted:   EventData{Name: "Ann", Event: event},
+			EventUpdated:      EventData{Name: "Ann", Event: event},
+			EventReminder:     EventData{Name: "Ann", Event: event},
+		}[template]
+		msg, err := Render("to@email.com", template, data)
+		if err != nil {
+			t.Errorf("Error when render %v, when not expected. Error: %v", template, err)
+		}
+		if msg.Subject == "" || msg.Text == "" || msg.HTML == "" {
+			t.Errorf("Message %v has empty parts: %+v", template, msg)
+		}
+	}
+
+	_, err = Render("to@email.com", "unknown", nil)
+	if err == nil {
+		t.Errorf("Error is nil for an unknown template, when expected")
+	}
+}
+
+func TestFileNotifier(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "mail")
+	notifier, err := NewFileNotifier(dir, "from@email.com")
+	if err != nil {
+		t.Fatalf("Error when create file notifier, when not expected. Error: %v", err)
+	}
+	err = notifier.Notify("to@email.com\r\nBcc: everyone@email.com", Welcome, WelcomeData{Name: "Ann", Link: "http://frontend"})
+	if err != nil {
+		t.Fatalf("Error when notify, when not expected. Error: %v", err)
+	}
+
+	files, _ := os.ReadDir(dir)
+	if len(files) != 1 {
+		t.Fatalf("Found %d files, when expected 1", len(files))
+	}
+	content, _ := os.ReadFile(filepath.Join(dir, files[0].Name()))
+	for _, want := range []string{
+		"From: from@email.com\r\n",
+		"To: to@email.comBcc: everyone@email.com\r\n",
+		"Subject: Welcome to Event Manager\r\n",
+		"Content-Type: multipart/alternative; boundary=",
+		"Content-Type: text/plain; charset=UTF-8",
+		"Content-Type: text/html; charset=UTF-8",
+		"Hi Ann,\r\n",
+	} {
+		if !strings.Contains(string(content), want) {
+			t.Errorf("Message does not contain %q, got: %q", want, content)
+		}
+	}
+}
+
+func TestMemoryNotifier(t *testing.T) {
+	notifier := NewMemoryNotifier()
+	err := notifier.Notify("to@email.com", Welcome, WelcomeData{Name: "Ann"})
+	if err != nil {
+		t.Fatalf("Error when notify, when not expected. Error: %v", err)
+	}
+	messages := notifier.Messages()
+	if len(messages) != 1 || messages[0].To != "to@email.com" {
+		t.Errorf("Messages are %v, when expected one to to@email.com", messages)
+	}
+}
+
+func TestNewNotifier(t *testing.T) {
+	t.Run("file", func(t *testing.T) {
+		notifier, err := NewNotifier(&config.Config{Mail: config.Mail{Driver: "file", Dir: t.TempDir()}})
+		if err != nil {
+			t.Errorf("Error when create notifier, when not expected. Error: %v", err)
+		}
+		if _, ok := notifier.(*FileNotifier); !ok {
+			t.Errorf("Notifier is %T, when expected *FileNotifier", notifier)
+		}
+	})
+	t.Run("smtp", func(t *testing.T) {
+		notifier, err := NewNotifier(&config.Config{Mail: config.Mail{Driver: "smtp", Host: "localhost", Port: 25}})
+		if err != nil {
+			t.Errorf("Error when create notifier, when not expected. Error: %v", err)
+		}
+		if smtpNotifier, ok := notifier.(*SMTPNotifier); !ok || smtpNotifier.addr != "localhost:25" {
+			t.Errorf("Notifier is %#v, when expected SMTP notifier for localhost:25", notifier)
+		}
+	})
+	t.Run("memory", func(t *testing.T) {
+		notifier, err := NewNotifier(&config.Config{Mail: config.Mail{Driver: "memory"}})
+		if err != nil {
+			t.Errorf("Error when create notifier, when not expected. Error: %v", err)
+		}
+		if _, ok := notifier.(*MemoryNotifier); !ok {
+			t.Errorf("Notifier is %T, when expected *MemoryNotifier", notifier)
+		}
+	})
+	t.Run("unknown", func(t *testing.T) {
+		_, err := NewNotifier(&config.Config{Mail: config.Mail{Driver: "pigeon"}})
+		if err == nil {
+			t.Errorf("Error is nil, when expected")
+		}
+	})
+}
@@ -0,0 +1,90 @@
+package notification
+
+import (
+	"bytes"
+	"embed"
+	"fmt"
+	htmltemplate "html/template"
+	"strings"
+	texttemplate "text/template"
+	"time"
+
+	"github.com/HermanPlay/web-app-backend/package/domain/schemas"
+)
+
+// Template names a message kind. Each has a <name>.txt template defining
+// "subject" and the plain text body, and a <name>.html template defining
+// "content", which is rendered inside layout.html.
+type Template string
+
+const (
+	Welcome           Template = "welcome"
+	PasswordReset     Template = "password_reset"
+	BookingConfirmed  Template = "booking_confirmed"
+	BookingWaitlisted Template = "booking_waitlisted"
+	BookingPromoted   Template = "booking_promoted"
+	EventUpdated      Template = "event_updated"
+	EventReminder     Template = "event_reminder"
+)
+
+//go:embed templates
+var templateFS embed.FS
+
+var (
+	textTemplates = map[Template]*texttemplate.Template{}
+	htmlTemplates = map[Template]*htmltemplate.Template{}
+)
+
+func init() {
+	for _, template := range []Template{Welcome, PasswordReset, BookingConfirmed, BookingWaitlisted, BookingPromoted, EventUpdated, EventReminder} {
+		textTemplates[template] = texttemplate.Must(texttemplate.ParseFS(templateFS, "templates/"+string(template)+".txt"))
+		htmlTemplates[template] = htmltemplate.Must(htmltemplate.ParseFS(templateFS, "templates/layout.html", "templates/"+string(template)+".html"))
+	}
+}
+
+// Render fills in the text and HTML versions of template with data.
+func Render(to string, template Template, data any) (Message, error) {
+	text, ok := textTemplates[template]
+	if !ok {
+		return Message{}, fmt.Errorf("unknown template %q", template)
+	}
+
+	var subject, textBody, htmlBody bytes.Buffer
+	err := text.ExecuteTemplate(&subject, "subject", data)
+	if err != nil {
+		return Message{}, err
+	}
+	err = text.Execute(&textBody, data)
+	if err != nil {
+		return Message{}, err
+	}
+	err = htmlTemplates[template].ExecuteTemplate(&htmlBody, "layout.html", data)
+	if err != nil {
+		return Message{}, err
+	}
+	return Message{
+		To:      to,
+		Subject: strings.TrimSpace(subject.String()),
+		Text:    strings.TrimSpace(textBody.String()) + "\n",
+		HTML:    htmlBody.String(),
+	}, nil
+}
+
+type WelcomeData struct {
+	Name string
+	Link string
+}
+
+type PasswordResetData struct {
+	Name      string
+	Link      string
+	ExpiresIn time.Duration
+}
+
+// EventData feeds the booking, event change and reminder templates.
+type EventData struct {
+	Name             string
+	Event            schemas.Event
+	Link             string
+	WaitlistPosition int
+}
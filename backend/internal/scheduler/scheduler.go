@@ -0,0 +1,59 @@
+package scheduler
+
+import (
+	"context"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Task is one piece of periodic background work. It gets the time of the tick.
+// ctx carries the values of the scheduler's context but is not cancelled on
+// shutdown, so a running task can finish.
+type Task func(ctx context.Context, now time.Time) error
+
+// Scheduler runs tasks inside the server process at a fixed interval. Tasks
+// must be safe to run on several replicas at once, the scheduler does not
+// coordinate between processes.
+type Scheduler struct {
+	interval time.Duration
+	tasks    map[string]Task
+}
+
+func NewScheduler(interval time.Duration) *Scheduler {
+	return &Scheduler{
+		interval: interval,
+		tasks:    map[string]Task{},
+	}
+}
+
+func (s *Scheduler) Add(name string, task Task) {
+	s.tasks[name] = task
+}
+
+// Run runs every task right away and then on each tick, until ctx is done.
+// A failing task is logged and retried on the next tick.
+func (s *Scheduler) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	taskCtx := context.WithoutCancel(ctx)
+	s.runTasks(taskCtx, time.Now())
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			s.runTasks(taskCtx, now)
+		}
+	}
+}
+
+func (s *Scheduler) runTasks(ctx context.Context, now time.Time) {
+	for name, task := range s.tasks {
+		err := task(ctx, now)
+		if err != nil {
+			logrus.Errorf("Scheduled task %s failed. Error: %v", name, err)
+		}
+	}
+}
@@ -0,0 +1,43 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestScheduler(t *testing.T) {
+	var runs, failures atomic.Int32
+	s := NewScheduler(10 * time.Millisecond)
+	s.Add("count", func(ctx context.Context, now time.Time) error {
+		runs.Add(1)
+		return nil
+	})
+	s.Add("fail", func(ctx context.Context, now time.Time) error {
+		failures.Add(1)
+		return errors.New("failed")
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		s.Run(ctx)
+		close(done)
+	}()
+	time.Sleep(55 * time.Millisecond)
+	cancel()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatalf("Run did not return after the context was cancelled")
+	}
+	if runs.Load() < 3 {
+		t.Errorf("Task ran %d times, when expected at least 3", runs.Load())
+	}
+	if failures.Load() < 3 {
+		t.Errorf("Failing task ran %d times, when expected to be retried on every tick", failures.Load())
+	}
+}
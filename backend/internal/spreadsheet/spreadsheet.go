@@ -0,0 +1,114 @@
+// Package spreadsheet streams tables as CSV or XLSX files. Rows are written
+// as they come, so exports of any size need no more memory than one row.
+package spreadsheet
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type Format string
+
+const (
+	CSV  Format = "csv"
+	XLSX Format = "xlsx"
+)
+
+var ErrUnknownFormat = errors.New("spreadsheet format must be csv or xlsx")
+
+func (f Format) IsValid() bool {
+	return f == CSV || f == XLSX
+}
+
+func (f Format) ContentType() string {
+	if f == XLSX {
+		return "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+	}
+	return "text/csv; charset=utf-8"
+}
+
+// Writer writes one table. Values may be strings, integers, booleans,
+// times, which are written in their own zone, or nil for an empty cell.
+// Close finishes the file and must be called once the last row is written.
+type Writer interface {
+	WriteHeader(columns ...string) error
+	WriteRow(values ...any) error
+	Close() error
+}
+
+// NewWriter starts a spreadsheet in the given format on w. sheet names the
+// worksheet of an XLSX file.
+func NewWriter(format Format, w io.Writer, sheet string) (Writer, error) {
+	switch format {
+	case CSV:
+		return &csvWriter{w: csv.NewWriter(w)}, nil
+	case XLSX:
+		return newXLSXWriter(w, sheet)
+	}
+	return nil, ErrUnknownFormat
+}
+
+const timeLayout = "2006-01-02 15:04:05"
+
+type csvWriter struct {
+	w *csv.Writer
+}
+
+func (c *csvWriter) WriteHeader(columns ...string) error {
+	return c.w.Write(columns)
+}
+
+func (c *csvWriter) WriteRow(values ...any) error {
+	record := make([]string, len(values))
+	for i, value := range values {
+		switch v := value.(type) {
+		case nil:
+		case string:
+			record[i] = escapeFormula(v)
+		case time.Time:
+			if !v.IsZero() {
+				record[i] = v.Format(timeLayout)
+			}
+		default:
+			record[i] = fmt.Sprint(v)
+		}
+	}
+	return c.w.Write(record)
+}
+
+func (c *csvWriter) Close() error {
+	c.w.Flush()
+	return c.w.Error()
+}
+
+// escapeFormula keeps spreadsheet apps from running user-provided text that
+// looks like a formula when the CSV is opened.
+func escapeFormula(s string) string {
+	if s != "" && strings.ContainsRune("=+-@\t\r", rune(s[0])) {
+		return "'" + s
+	}
+	return s
+}
+
+// columnName turns a zero-based column index into its letters, e.g. 27 is AB.
+func columnName(i int) string {
+	name := ""
+	for i++; i > 0; i = (i - 1) / 26 {
+		name = string(rune('A'+(i-1)%26)) + name
+	}
+	return name
+}
+
+// excelEpoch is day zero of the serial dates XLSX cells hold.
+var excelEpoch = time.Date(1899, 12, 30, 0, 0, 0, 0, time.UTC)
+
+// serialDate converts the wall clock of t into an XLSX serial date.
+func serialDate(t time.Time) string {
+	wall := time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), 0, time.UTC)
+	return strconv.FormatFloat(wall.Sub(excelEpoch).Hours()/24, 'f', -1, 64)
+}
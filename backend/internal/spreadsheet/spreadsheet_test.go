@@ -0,0 +1,174 @@
+package spreadsheet
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/csv"
+	"encoding/xml"
+	"io"
+	"strings"
+	"testing"
+	"time"
+)
+
+func writeTable(t *testing.T, format Format, rows [][]any) []byte {
+	var buffer bytes.Buffer
+	writer, err := NewWriter(format, &buffer, "Attendees")
+	if err != nil {
+		t.Fatalf("Error when create writer, when not expected. Error: %v", err)
+	}
+	err = writer.WriteHeader("Name", "Booked at", "Seats", "Paid")
+	if err != nil {
+		t.Fatalf("Error when write header, when not expected. Error: %v", err)
+	}
+	for _, row := range rows {
+		err = writer.WriteRow(row...)
+		if err != nil {
+			t.Fatalf("Error when write row, when not expected. Error: %v", err)
+		}
+	}
+	err = writer.Close()
+	if err != nil {
+		t.Fatalf("Error when close writer, when not expected. Error: %v", err)
+	}
+	return buffer.Bytes()
+}
+
+func TestCSVWriter(t *testing.T) {
+	warsaw, _ := time.LoadLocation("Europe/Warsaw")
+	out := writeTable(t, CSV, [][]any{
+		{"Ann, \"the\" host", time.Date(2024, 11, 15, 19, 0, 0, 0, warsaw), 2, true},
+		{"=HYPERLINK(\"http://example.com\")", time.Time{}, nil, false},
+	})
+	records, err := csv.NewReader(bytes.NewReader(out)).ReadAll()
+	if err != nil {
+		t.Fatalf("Error when read csv, when not expected. Error: %v", err)
+	}
+	want := [][]string{
+		{"Name", "Booked at", "Seats", "Paid"},
+		{"Ann, \"the\" host", "2024-11-15 19:00:00", "2", "true"},
+		{"'=HYPERLINK(\"http://example.com\")", "", "", "false"},
+	}
+	if len(records) != len(want) {
+		t.Fatalf("Read %d records, when expected %d", len(records), len(want))
+	}
+	for i := range want {
+		if strings.Join(records[i], "|") != strings.Join(want[i], "|") {
+			t.Errorf("Record %d is %q, when expected %q", i, records[i], want[i])
+		}
+	}
+}
+
+type xlsxCell struct {
+	Ref    string `xml:"r,attr"`
+	Type   string `xml:"t,attr"`
+	Style  string `xml:"s,attr"`
+	Value  string `xml:"v"`
+	Inline string `xml:"is>t"`
+}
+
+type xlsxSheet struct {
+	Rows []struct {
+		Ref   string     `xml:"r,attr"`
+		Cells []xlsxCell `xml:"c"`
+	} `xml:"sheetData>row"`
+}
+
+func TestXLSXWriter(t *testing.T) {
+	warsaw, _ := time.LoadLocation("Europe/Warsaw")
+	out := writeTable(t, XLSX, [][]any{
+		{"Ann <host> & co", time.Date(2024, 11, 15, 18, 0, 0, 0, warsaw), 2, true},
+		{"=1+1", nil, int64(-3), false},
+	})
+
+	archive, err := zip.NewReader(bytes.NewReader(out), int64(len(out)))
+	if err != nil {
+		t.Fatalf("Error when open xlsx, when not expected. Error: %v", err)
+	}
+	files := map[string]*zip.File{}
+	for _, file := range archive.File {
+		files[file.Name] = file
+	}
+	for _, name := range []string{"[Content_Types].xml", "_rels/.rels", "xl/workbook.xml", "xl/_rels/workbook.xml.rels", "xl/styles.xml", "xl/worksheets/sheet1.xml"} {
+		if files[name] == nil {
+			t.Errorf("Part %s is missing, when expected", name)
+		}
+	}
+	read := func(name string) []byte {
+		f, err := files[name].Open()
+		if err != nil {
+			t.Fatalf("Error when open %s, when not expected. Error: %v", name, err)
+		}
+		defer f.Close()
+		content, err := io.ReadAll(f)
+		if err != nil {
+			t.Fatalf("Error when read %s, when not expected. Error: %v", name, err)
+		}
+		return content
+	}
+	if !bytes.Contains(read("xl/workbook.xml"), []byte(`name="Attendees"`)) {
+		t.Errorf("Workbook does not name the sheet, when expected")
+	}
+
+	var sheet xlsxSheet
+	err = xml.Unmarshal(read("xl/worksheets/sheet1.xml"), &sheet)
+	if err != nil {
+		t.Fatalf("Error when parse sheet, when not expected. Error: %v", err)
+	}
+	if len(sheet.Rows) != 3 {
+		t.Fatalf("Sheet has %d rows, when expected 3", len(sheet.Rows))
+	}
+	header := sheet.Rows[0].Cells
+	if len(header) != 4 || header[0].Inline != "Name" || header[0].Style != "2" {
+		t.Errorf("Header is %+v, when expected bold text cells", header)
+	}
+	row := sheet.Rows[1].Cells
+	want := []xlsxCell{
+		{Ref: "A2", Type: "inlineStr", Inline: "Ann <host> & co"},
+		{Ref: "B2", Style: "1", Value: "45611.75"},
+		{Ref: "C2", Value: "2"},
+		{Ref: "D2", Type: "b", Value: "1"},
+	}
+	if len(row) != len(want) {
+		t.Fatalf("Row has %d cells, when expected %d", len(row), len(want))
+	}
+	for i := range want {
+		if row[i] != want[i] {
+			t.Errorf("Cell %d is %+v, when expected %+v", i, row[i], want[i])
+		}
+	}
+	row = sheet.Rows[2].Cells
+	if len(row) != 3 || row[0].Inline != "=1+1" || row[1].Ref != "C3" || row[1].Value != "-3" {
+		t.Errorf("Row is %+v, when expected text, a skipped empty cell and a number", row)
+	}
+}
+
+func TestNewWriterUnknownFormat(t *testing.T) {
+	_, err := NewWriter(Format("pdf"), io.Discard, "")
+	if err != ErrUnknownFormat {
+		t.Errorf("Error is %v, when expected ErrUnknownFormat", err)
+	}
+}
+
+func TestColumnName(t *testing.T) {
+	for i, want := range map[int]string{0: "A", 25: "Z", 26: "AA", 27: "AB", 701: "ZZ", 702: "AAA"} {
+		if got := columnName(i); got != want {
+			t.Errorf("Column %d is %s, when expected %s", i, got, want)
+		}
+	}
+}
+
+func TestSheetName(t *testing.T) {
+	for name, want := range map[string]string{
+		"Attendees":                           "Attendees",
+		"Jazz: live [2024]":                   "Jazz live 2024",
+		"":                                    "Sheet1",
+		strings.Repeat("ą", 40):               strings.Repeat("ą", 31),
+		"Q&A / Panel":                         "Q&A  Panel",
+		"An event with a very long name here": "An event with a very long name",
+	} {
+		if got := sheetName(name); got != want {
+			t.Errorf("Sheet name of %q is %q, when expected %q", name, got, want)
+		}
+	}
+}
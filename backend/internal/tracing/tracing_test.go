@@ -0,0 +1,156 @@
+package tracing
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/HermanPlay/web-app-backend/internal/config"
+	"github.com/HermanPlay/web-app-backend/internal/database"
+	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestMiddleware(t *testing.T) {
+	recorder := record()
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(Middleware())
+	var handlerSpan trace.SpanContext
+	router.GET("/api/event/:eventID", func(c *gin.Context) {
+		handlerSpan = trace.SpanContextFromContext(c.Request.Context())
+		c.Status(http.StatusInternalServerError)
+	})
+
+	request := httptest.NewRequest(http.MethodGet, "/api/event/1", nil)
+	request.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	router.ServeHTTP(httptest.NewRecorder(), request)
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/missing", nil))
+
+	spans := recorder.Ended()
+	if len(spans) != 2 {
+		t.Fatalf("Got %d spans, when expected 2", len(spans))
+	}
+	span := spans[0]
+	if span.Name() != "GET /api/event/:eventID" {
+		t.Errorf("Got span %q, when expected it named after the route", span.Name())
+	}
+	if span.SpanContext().TraceID().String() != "4bf92f3577b34da6a3ce929d0e0e4736" || span.Parent().SpanID().String() != "00f067aa0ba902b7" {
+		t.Errorf("Span does not continue the trace of the traceparent header")
+	}
+	if handlerSpan.SpanID() != span.SpanContext().SpanID() {
+		t.Errorf("Handler does not see the request span in its context")
+	}
+	if span.Status().Code != codes.Error {
+		t.Errorf("Expected a failed request to mark its span as an error")
+	}
+	assertAttribute(t, span, "http.response.status_code", attribute.IntValue(http.StatusInternalServerError))
+	assertAttribute(t, span, "http.route", attribute.StringValue("/api/event/:eventID"))
+	for _, kv := range span.Attributes() {
+		if kv.Key == "url.path" {
+			t.Errorf("Span records the path %s, when expected only the route", kv.Value.Emit())
+		}
+	}
+	if spans[1].Name() != "GET" {
+		t.Errorf("Got span %q for an unmatched request, when expected the method only", spans[1].Name())
+	}
+}
+
+func TestInstrumentDatabase(t *testing.T) {
+	recorder := record()
+	db, err := database.NewSQLiteDatabase(&config.Config{Db: config.Db{Path: ":memory:"}})
+	if err != nil {
+		t.Fatalf("Error when open database, when not expected. Error: %v", err)
+	}
+	defer db.Close()
+	gormDb := db.Connect()
+	err = InstrumentDatabase(gormDb)
+	if err != nil {
+		t.Fatalf("Error when instrument database, when not expected. Error: %v", err)
+	}
+	err = gormDb.Exec("CREATE TABLE notes (id integer PRIMARY KEY, body text)").Error
+	if err != nil {
+		t.Fatalf("Error when create table, when not expected. Error: %v", err)
+	}
+
+	ctx, parent := otel.Tracer("test").Start(context.Background(), "parent")
+	var note struct{ Body string }
+	err = gormDb.WithContext(ctx).Table("notes").Where("id = ?", 1).Take(&note).Error
+	if err == nil {
+		t.Fatalf("Expected error when find missing note, got nil")
+	}
+	gormDb.WithContext(ctx).Table("missing").Create(map[string]interface{}{"body": "hello"})
+	parent.End()
+
+	var spans []sdktrace.ReadOnlySpan
+	for _, span := range recorder.Ended() {
+		if span.Parent().SpanID() == parent.SpanContext().SpanID() {
+			spans = append(spans, span)
+		}
+	}
+	if len(spans) != 2 {
+		t.Fatalf("Got %d query spans under the parent, when expected 2", len(spans))
+	}
+	if spans[0].Name() != "query notes" || spans[1].Name() != "create missing" {
+		t.Errorf("Got spans %q and %q, when expected them named after the operation and table", spans[0].Name(), spans[1].Name())
+	}
+	assertAttribute(t, spans[0], "db.query.text", attribute.StringValue("SELECT * FROM `notes` WHERE id = ? LIMIT 1"))
+	if spans[0].Status().Code == codes.Error {
+		t.Errorf("Expected a lookup finding nothing not to be an error")
+	}
+	if spans[1].Status().Code != codes.Error {
+		t.Errorf("Expected a failed insert to mark its span as an error")
+	}
+}
+
+func TestSetup(t *testing.T) {
+	t.Run("none", func(t *testing.T) {
+		shutdown, err := Setup(&config.Config{Tracing: config.Tracing{Exporter: "none"}})
+		if err != nil {
+			t.Fatalf("Error when setup tracing, when not expected. Error: %v", err)
+		}
+		err = shutdown(context.Background())
+		if err != nil {
+			t.Errorf("Error when shutdown tracing, when not expected. Error: %v", err)
+		}
+	})
+	t.Run("otlp", func(t *testing.T) {
+		shutdown, err := Setup(&config.Config{Tracing: config.Tracing{Exporter: "otlp", Endpoint: "http://localhost:4318", SampleRatio: 1, ServiceName: "test"}})
+		if err != nil {
+			t.Fatalf("Error when setup tracing, when not expected. Error: %v", err)
+		}
+		// Nothing was recorded, so there is nothing to send
+		err = shutdown(context.Background())
+		if err != nil {
+			t.Errorf("Error when shutdown tracing, when not expected. Error: %v", err)
+		}
+	})
+}
+
+// record installs a tracer provider keeping the ended spans in memory.
+func record() *tracetest.SpanRecorder {
+	recorder := tracetest.NewSpanRecorder()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	return recorder
+}
+
+func assertAttribute(t *testing.T, span sdktrace.ReadOnlySpan, key string, want attribute.Value) {
+	t.Helper()
+	for _, kv := range span.Attributes() {
+		if string(kv.Key) == key {
+			if kv.Value != want {
+				t.Errorf("Got %s %v, when expected %v", key, kv.Value.Emit(), want.Emit())
+			}
+			return
+		}
+	}
+	t.Errorf("Expected attribute %s on span %q", key, span.Name())
+}
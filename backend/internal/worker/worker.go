@@ -0,0 +1,59 @@
+package worker
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/HermanPlay/web-app-backend/package/service"
+	"github.com/sirupsen/logrus"
+)
+
+// Pool runs queued background jobs. Several pools, in one process or across
+// replicas, can work the same queue; the job table hands every job to one
+// worker at a time.
+type Pool struct {
+	jobService   service.JobService
+	workers      int
+	pollInterval time.Duration
+}
+
+func NewPool(jobService service.JobService, workers int, pollInterval time.Duration) *Pool {
+	return &Pool{
+		jobService:   jobService,
+		workers:      workers,
+		pollInterval: pollInterval,
+	}
+}
+
+// Run works the queue until ctx is done and the jobs in progress finished.
+func (p *Pool) Run(ctx context.Context) {
+	var wg sync.WaitGroup
+	for i := 0; i < p.workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			p.work(ctx)
+		}()
+	}
+	wg.Wait()
+}
+
+func (p *Pool) work(ctx context.Context) {
+	// A job that started keeps its queries running through the shutdown
+	jobCtx := context.WithoutCancel(ctx)
+	for ctx.Err() == nil {
+		ran, err := p.jobService.RunNext(jobCtx, time.Now())
+		if err != nil {
+			logrus.Error("Could not run job. Error: ", err)
+		}
+		if ran && err == nil {
+			// Keep going while there is work
+			continue
+		}
+		select {
+		case <-ctx.Done():
+		case <-time.After(p.pollInterval):
+		}
+	}
+}
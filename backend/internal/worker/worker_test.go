@@ -0,0 +1,71 @@
+package worker
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/HermanPlay/web-app-backend/package/domain/schemas"
+)
+
+// queue hands out a fixed number of jobs.
+type queue struct {
+	mu   sync.Mutex
+	left int
+	ran  int
+}
+
+func (q *queue) RunNext(ctx context.Context, now time.Time) (bool, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.left == 0 {
+		return false, nil
+	}
+	q.left--
+	q.ran++
+	return true, nil
+}
+
+func (q *queue) GetJobs(ctx context.Context, filter schemas.JobFilter) ([]schemas.Job, *schemas.Pagination, error) {
+	return nil, nil, nil
+}
+
+func (q *queue) GetJob(ctx context.Context, id int) (*schemas.Job, error) {
+	return nil, nil
+}
+
+func (q *queue) RetryJob(ctx context.Context, id int) (*schemas.Job, error) {
+	return nil, nil
+}
+
+func (q *queue) PurgeDoneJobs(ctx context.Context, now time.Time) (int64, error) {
+	return 0, nil
+}
+
+func TestPool(t *testing.T) {
+	jobs := &queue{left: 10}
+	pool := NewPool(jobs, 3, 5*time.Millisecond)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		pool.Run(ctx)
+		close(done)
+	}()
+	time.Sleep(20 * time.Millisecond)
+	jobs.mu.Lock()
+	jobs.left = 5
+	jobs.mu.Unlock()
+	time.Sleep(20 * time.Millisecond)
+	cancel()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatalf("Run did not return after the context was cancelled")
+	}
+	if jobs.ran != 15 {
+		t.Errorf("Ran %d jobs, when expected 15", jobs.ran)
+	}
+}
@@ -0,0 +1,42 @@
+package models
+
+import "time"
+
+// RefreshToken is one link in a chain of rotated refresh tokens. Tokens that
+// descend from the same login share a FamilyID, so a replayed token can take
+// the whole chain down. Only a SHA-256 hash of the token is stored.
+type RefreshToken struct {
+	ID        int        `gorm:"column:id; primary_key; not null" json:"id"`
+	UserID    int        `gorm:"column:user_id; not null; index" json:"user_id"`
+	User      User       `gorm:"foreignKey:UserID; references:ID"`
+	FamilyID  string     `gorm:"column:family_id; not null; index" json:"family_id"`
+	TokenHash string     `gorm:"column:token_hash; not null; uniqueIndex" json:"-"`
+	ExpiresAt time.Time  `gorm:"column:expires_at; not null" json:"expires_at"`
+	UsedAt    *time.Time `gorm:"column:used_at" json:"used_at"`       // set once the token was exchanged
+	RevokedAt *time.Time `gorm:"column:revoked_at" json:"revoked_at"` // set on logout or reuse
+	BaseModel
+}
+
+// PasswordResetToken is a single-use token mailed to a user who forgot their
+// password. Only a SHA-256 hash of the token is stored.
+type PasswordResetToken struct {
+	ID        int        `gorm:"column:id; primary_key; not null" json:"id"`
+	UserID    int        `gorm:"column:user_id; not null; index" json:"user_id"`
+	User      User       `gorm:"foreignKey:UserID; references:ID"`
+	TokenHash string     `gorm:"column:token_hash; not null; uniqueIndex" json:"-"`
+	ExpiresAt time.Time  `gorm:"column:expires_at; not null" json:"expires_at"`
+	UsedAt    *time.Time `gorm:"column:used_at" json:"used_at"`
+	BaseModel
+}
+
+// FeedToken authenticates the calendar feed of a user. Calendar apps cannot
+// send a JWT, so the token is part of the feed URL. A user has at most one;
+// creating a new one or deleting it revokes the old URL. Only a SHA-256 hash
+// of the token is stored.
+type FeedToken struct {
+	ID        int       `gorm:"column:id; primary_key; not null" json:"id"`
+	UserID    int       `gorm:"column:user_id; not null; uniqueIndex" json:"user_id"`
+	User      User      `gorm:"foreignKey:UserID; references:ID"`
+	TokenHash string    `gorm:"column:token_hash; not null; uniqueIndex" json:"-"`
+	CreatedAt time.Time `json:"created_at"`
+}
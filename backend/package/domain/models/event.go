@@ -1,24 +1,48 @@
 package models
 
+import "time"
+
+type BookingStatus string
+
+const (
+	BookingConfirmed  BookingStatus = "confirmed"
+	BookingWaitlisted BookingStatus = "waitlisted"
+)
+
 type Event struct {
-	ID               int    `gorm:"column:id; primary_key; not null" json:"id"`
-	Title            string `gorm:"column:title; not null" json:"title"`
-	ShortDescription string `gorm:"column:short_description; not null" json:"short_description"`
-	Description      string `gorm:"column:description; not null" json:"description"`
-	Location         string `gorm:"column:location; not null" json:"location"`
-	Date             string `gorm:"column:date; not null" json:"date"`
-	Time             string `gorm:"column:time; not null" json:"time"`
-	IsFeatured       bool   `gorm:"column:is_featured; not null" json:"is_featured"`
-	CreatedBy        int    `gorm:"column:created_by; not null" json:"created_by"`
-	User             User   `gorm:"foreignKey:CreatedBy; references:ID"`
+	ID               int       `gorm:"column:id; primary_key; not null" json:"id"`
+	Title            string    `gorm:"column:title; not null" json:"title"`
+	ShortDescription string    `gorm:"column:short_description; not null" json:"short_description"`
+	Description      string    `gorm:"column:description; not null" json:"description"`
+	Location         string    `gorm:"column:location; not null" json:"location"`
+	StartsAt         time.Time `gorm:"column:starts_at; index" json:"starts_at"` // UTC, timestamptz on Postgres
+	EndsAt           time.Time `gorm:"column:ends_at" json:"ends_at"`
+	TimeZone         string    `gorm:"column:time_zone; not null; default:UTC" json:"time_zone"` // IANA name, e.g. Europe/Warsaw
+	IsFeatured       bool      `gorm:"column:is_featured; not null" json:"is_featured"`
+	Capacity         int       `gorm:"column:capacity; not null; default:0" json:"capacity"` // 0 means unlimited
+	WaitlistEnabled  bool      `gorm:"column:waitlist_enabled; not null; default:false" json:"waitlist_enabled"`
+	Sequence         int       `gorm:"column:sequence; not null; default:0" json:"sequence"` // iCalendar revision, raised on every update
+	CreatedBy        int       `gorm:"column:created_by; not null" json:"created_by"`
+	User             User      `gorm:"foreignKey:CreatedBy; references:ID"`
 	BaseModel
 }
 
 type EventUser struct {
+	ID      int           `gorm:"column:id; primary_key; not null" json:"id"`
+	EventID int           `gorm:"column:event_id; not null; uniqueIndex:idx_event_users_event_user" json:"event_id"`
+	Event   Event         `gorm:"foreignKey:EventID; references:ID"`
+	UserID  int           `gorm:"column:user_id; not null; uniqueIndex:idx_event_users_event_user" json:"user_id"`
+	User    User          `gorm:"foreignKey:UserID; references:ID"`
+	Status  BookingStatus `gorm:"column:status; not null; default:confirmed" json:"status"`
+	BaseModel
+}
+
+// EventOrganizer designates a user who may manage an event next to its creator.
+type EventOrganizer struct {
 	ID      int   `gorm:"column:id; primary_key; not null" json:"id"`
-	EventID int   `gorm:"column:event_id; not null" json:"event_id"`
+	EventID int   `gorm:"column:event_id; not null; uniqueIndex:idx_event_organizers_event_user" json:"event_id"`
 	Event   Event `gorm:"foreignKey:EventID; references:ID"`
-	UserID  int   `gorm:"column:user_id; not null" json:"user_id"`
+	UserID  int   `gorm:"column:user_id; not null; uniqueIndex:idx_event_organizers_event_user" json:"user_id"`
 	User    User  `gorm:"foreignKey:UserID; references:ID"`
 	BaseModel
 }
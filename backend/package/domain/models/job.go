@@ -0,0 +1,29 @@
+package models
+
+import "time"
+
+type JobStatus string
+
+const (
+	JobPending JobStatus = "pending"
+	JobRunning JobStatus = "running"
+	JobDone    JobStatus = "done"
+	JobDead    JobStatus = "dead" // gave up after MaxAttempts, waits for an admin
+)
+
+// Job is a unit of background work, such as sending an email. Jobs are
+// inserted in the same transaction as the data they belong to, so a side
+// effect is never lost or sent for a write that was rolled back.
+type Job struct {
+	ID          int        `gorm:"column:id; primary_key; not null" json:"id"`
+	Kind        string     `gorm:"column:kind; not null; index" json:"kind"`
+	Payload     string     `gorm:"column:payload; not null" json:"payload"` // JSON, decoded by the handler of Kind
+	Status      JobStatus  `gorm:"column:status; not null; default:pending; index:idx_jobs_status_run_at" json:"status"`
+	RunAt       time.Time  `gorm:"column:run_at; not null; index:idx_jobs_status_run_at" json:"run_at"`
+	Attempts    int        `gorm:"column:attempts; not null; default:0" json:"attempts"`
+	MaxAttempts int        `gorm:"column:max_attempts; not null" json:"max_attempts"`
+	LockedUntil *time.Time `gorm:"column:locked_until" json:"locked_until"` // a running job past this is taken over by another worker
+	LastError   string     `gorm:"column:last_error" json:"last_error"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+}
@@ -0,0 +1,24 @@
+package models
+
+import "time"
+
+// ReminderKind names one of the reminders sent ahead of an event.
+type ReminderKind string
+
+const (
+	DayReminder  ReminderKind = "24h"
+	HourReminder ReminderKind = "1h"
+)
+
+// EventReminder records that a reminder went out for a booking. The unique
+// index makes the row a claim: of several backend replicas sweeping at the
+// same time, only the one whose insert succeeds sends the reminder.
+type EventReminder struct {
+	ID      int          `gorm:"column:id; primary_key; not null" json:"id"`
+	EventID int          `gorm:"column:event_id; not null; uniqueIndex:idx_event_reminders_event_user_kind" json:"event_id"`
+	Event   Event        `gorm:"foreignKey:EventID; references:ID"`
+	UserID  int          `gorm:"column:user_id; not null; uniqueIndex:idx_event_reminders_event_user_kind" json:"user_id"`
+	User    User         `gorm:"foreignKey:UserID; references:ID"`
+	Kind    ReminderKind `gorm:"column:kind; not null; uniqueIndex:idx_event_reminders_event_user_kind" json:"kind"`
+	SentAt  time.Time    `gorm:"column:sent_at; not null" json:"sent_at"`
+}
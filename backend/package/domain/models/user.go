@@ -13,22 +13,42 @@ const (
 	AdminRole   Role = "admin"
 )
 
+// IsValid reports whether r is one of the roles above.
+func (r Role) IsValid() bool {
+	return r == UserRole || r == ManagerRole || r == AdminRole
+}
+
 type User struct {
 	ID       int    `gorm:"column:id; primary_key; not null" json:"id"`
 	Name     string `gorm:"column:name" json:"name"`
 	Email    string `gorm:"column:email" json:"email"`
 	Password string `gorm:"column:password" json:"-"`
 	Role     Role   `gorm:"column:role" json:"role"`
+	// Users who opted out get no event reminders, booking emails are still sent
+	RemindersDisabled bool `gorm:"column:reminders_disabled; not null; default:false" json:"reminders_disabled"`
 	BaseModel
+	// storedPassword is the hash as loaded or last saved. Password differs
+	// from it once a new password was set.
+	storedPassword string
+}
+
+func (u *User) AfterFind(tx *gorm.DB) (err error) {
+	u.storedPassword = u.Password
+	return nil
 }
 
+// BeforeSave hashes the password when it was set since the user was loaded or
+// saved, so saving a loaded user does not hash the hash again.
 func (u *User) BeforeSave(tx *gorm.DB) (err error) {
-	// Hash the password
+	if u.storedPassword != "" && u.Password == u.storedPassword {
+		return nil
+	}
 	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(u.Password), bcrypt.DefaultCost)
 	if err != nil {
 		return err
 	}
 	u.Password = string(hashedPassword)
+	u.storedPassword = u.Password
 
 	return nil
 }
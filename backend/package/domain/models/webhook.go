@@ -0,0 +1,70 @@
+package models
+
+import (
+	"strings"
+	"time"
+)
+
+type WebhookEvent string
+
+const (
+	WebhookEventCreated     WebhookEvent = "event.created"
+	WebhookEventUpdated     WebhookEvent = "event.updated"
+	WebhookEventDeleted     WebhookEvent = "event.deleted"
+	WebhookBookingCreated   WebhookEvent = "booking.created"
+	WebhookBookingCancelled WebhookEvent = "booking.cancelled"
+)
+
+// IsValid reports whether e is one of the webhook events above.
+func (e WebhookEvent) IsValid() bool {
+	switch e {
+	case WebhookEventCreated, WebhookEventUpdated, WebhookEventDeleted, WebhookBookingCreated, WebhookBookingCancelled:
+		return true
+	}
+	return false
+}
+
+// Webhook is an endpoint that gets a signed POST for each subscribed event.
+type Webhook struct {
+	ID        int    `gorm:"column:id; primary_key; not null" json:"id"`
+	URL       string `gorm:"column:url; not null" json:"url"`
+	Secret    string `gorm:"column:secret; not null" json:"-"`      // HMAC key, shared with the receiver
+	Events    string `gorm:"column:events; not null" json:"events"` // comma separated WebhookEvent values
+	Active    bool   `gorm:"column:active; not null; default:true" json:"active"`
+	CreatedBy int    `gorm:"column:created_by; not null" json:"created_by"`
+	BaseModel
+}
+
+func (w Webhook) EventList() []WebhookEvent {
+	var events []WebhookEvent
+	for _, event := range strings.Split(w.Events, ",") {
+		if event != "" {
+			events = append(events, WebhookEvent(event))
+		}
+	}
+	return events
+}
+
+func (w Webhook) Subscribes(event WebhookEvent) bool {
+	for _, subscribed := range w.EventList() {
+		if subscribed == event {
+			return true
+		}
+	}
+	return false
+}
+
+// WebhookDelivery records one attempt to deliver an event to a webhook.
+type WebhookDelivery struct {
+	ID         int          `gorm:"column:id; primary_key; not null" json:"id"`
+	WebhookID  int          `gorm:"column:webhook_id; not null; index" json:"webhook_id"`
+	Webhook    Webhook      `gorm:"foreignKey:WebhookID; references:ID"`
+	DeliveryID string       `gorm:"column:delivery_id; not null; index" json:"delivery_id"` // the same for every attempt of a delivery
+	Event      WebhookEvent `gorm:"column:event; not null" json:"event"`
+	Payload    string       `gorm:"column:payload; not null" json:"payload"`
+	StatusCode int          `gorm:"column:status_code" json:"status_code"` // 0 when no response was received
+	Response   string       `gorm:"column:response" json:"response"`       // start of the response body
+	Error      string       `gorm:"column:error" json:"error"`
+	Duration   int64        `gorm:"column:duration_ms" json:"duration_ms"`
+	CreatedAt  time.Time    `json:"created_at"`
+}
@@ -1,8 +1,16 @@
 package schemas
 
 type ApiResponse[T any] struct {
-	StatusCode      int    `json:"status_code"`
-	ResponseKey     string `json:"response_key"`
-	ResponseMessage string `json:"response_message"`
-	Data            T      `json:"data"`
+	StatusCode      int         `json:"status_code"`
+	ResponseKey     string      `json:"response_key"`
+	ResponseMessage string      `json:"response_message"`
+	Data            T           `json:"data"`
+	Pagination      *Pagination `json:"pagination,omitempty"`
+}
+
+type Pagination struct {
+	Page       int   `json:"page"`
+	PageSize   int   `json:"page_size"`
+	Total      int64 `json:"total"`
+	TotalPages int   `json:"total_pages"`
 }
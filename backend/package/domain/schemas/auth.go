@@ -14,3 +14,18 @@ type UserLogin struct {
 type UserResetPassword struct {
 	Email string `json:"email" binding:"required"`
 }
+
+type UserResetPasswordConfirm struct {
+	Token    string `json:"token" binding:"required"`
+	Password string `json:"password" binding:"required"`
+}
+
+type RefreshTokenInput struct {
+	RefreshToken string `json:"refresh_token"`
+}
+
+type TokenPair struct {
+	AccessToken  string `json:"access_token"`
+	RefreshToken string `json:"refresh_token"`
+	ExpiresIn    int    `json:"expires_in"` // seconds until the access token expires
+}
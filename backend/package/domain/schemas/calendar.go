@@ -0,0 +1,8 @@
+package schemas
+
+// FeedToken is returned once when a calendar feed token is created. URL is
+// what users paste into their calendar app.
+type FeedToken struct {
+	Token string `json:"token"`
+	URL   string `json:"url"`
+}
@@ -1,33 +1,101 @@
 package schemas
 
+import (
+	"time"
+
+	"github.com/HermanPlay/web-app-backend/package/domain/models"
+)
+
 type EventInput struct {
-	Title            string `json:"title" binding:"required"`
-	ShortDescription string `json:"short_description" binding:"required"`
-	Description      string `json:"description" binding:"required"`
-	Location         string `json:"location" binding:"required"`
-	Date             string `json:"date" binding:"required"`
-	Time             string `json:"time" binding:"required"`
-	IsFeatured       bool   `json:"is_featured"`
+	Title            string    `json:"title" binding:"required"`
+	ShortDescription string    `json:"short_description" binding:"required"`
+	Description      string    `json:"description" binding:"required"`
+	Location         string    `json:"location" binding:"required"`
+	StartsAt         time.Time `json:"starts_at" binding:"required"`
+	EndsAt           time.Time `json:"ends_at" binding:"required"`
+	TimeZone         string    `json:"time_zone" binding:"required"`
+	IsFeatured       bool      `json:"is_featured"`
+	Capacity         int       `json:"capacity"`
+	WaitlistEnabled  bool      `json:"waitlist_enabled"`
 }
 
 type EventUpdate struct {
-	Title            string `json:"title"`
-	ShortDescription string `json:"short_description"`
-	Description      string `json:"description"`
-	Location         string `json:"location"`
-	Date             string `json:"date"`
-	Time             string `json:"time"`
-	IsFeatured       bool   `json:"is_featured"`
+	Title            string     `json:"title"`
+	ShortDescription string     `json:"short_description"`
+	Description      string     `json:"description"`
+	Location         string     `json:"location"`
+	StartsAt         *time.Time `json:"starts_at"`
+	EndsAt           *time.Time `json:"ends_at"`
+	TimeZone         string     `json:"time_zone"`
+	IsFeatured       bool       `json:"is_featured"`
+	Capacity         *int       `json:"capacity"`
+	WaitlistEnabled  *bool      `json:"waitlist_enabled"`
+}
+
+// EventFilter holds the query string of the event list endpoint.
+type EventFilter struct {
+	Search   string     `form:"q"`
+	Location string     `form:"location"`
+	From     *time.Time `form:"from" time_format:"2006-01-02T15:04:05Z07:00"`
+	To       *time.Time `form:"to" time_format:"2006-01-02T15:04:05Z07:00"`
+	Featured *bool      `form:"featured"`
+	Sort     string     `form:"sort"` // starts_at, title or created_at; prefix with - for descending
+	Page     int        `form:"page"`
+	PageSize int        `form:"page_size"`
 }
 
 type Event struct {
-	ID               int    `json:"id"`
-	Title            string `json:"title"`
-	ShortDescription string `json:"short_description"`
-	Description      string `json:"description"`
-	Location         string `json:"location"`
-	Date             string `json:"date"`
-	Time             string `json:"time"`
-	IsFeatured       bool   `json:"is_featured"`
-	CreatedBy        int    `json:"created_by"`
+	ID               int       `json:"id"`
+	Title            string    `json:"title"`
+	ShortDescription string    `json:"short_description"`
+	Description      string    `json:"description"`
+	Location         string    `json:"location"`
+	StartsAt         time.Time `json:"starts_at"` // in the event's time zone
+	EndsAt           time.Time `json:"ends_at"`
+	TimeZone         string    `json:"time_zone"`
+	IsFeatured       bool      `json:"is_featured"`
+	Capacity         int       `json:"capacity"`
+	WaitlistEnabled  bool      `json:"waitlist_enabled"`
+	CreatedBy        int       `json:"created_by"`
+}
+
+type OrganizerInput struct {
+	UserID int `json:"user_id" binding:"required"`
+}
+
+type Booking struct {
+	EventID          int                  `json:"event_id"`
+	UserID           int                  `json:"user_id"`
+	Status           models.BookingStatus `json:"status"`
+	WaitlistPosition int                  `json:"waitlist_position,omitempty"`
+}
+
+type Attendee struct {
+	UserID           int                  `json:"user_id"`
+	Name             string               `json:"name"`
+	Email            string               `json:"email"`
+	Status           models.BookingStatus `json:"status"`
+	WaitlistPosition int                  `json:"waitlist_position,omitempty"`
+	BookedAt         time.Time            `json:"booked_at"`
+}
+
+// EventImport holds the options of an event import upload.
+type EventImport struct {
+	Format   string            `form:"format"`    // csv or ics, taken from the file name when empty
+	Mapping  map[string]string `form:"-"`         // event field to CSV column, fields default to the column of the same name
+	TimeZone string            `form:"time_zone"` // zone of times given without one, e.g. "2024-11-15 19:00" or floating iCalendar times
+	Commit   bool              `form:"commit"`    // without it the file is only validated
+}
+
+type ImportError struct {
+	Row     int    `json:"row"` // line in a CSV file, position of the event in an iCalendar file
+	Field   string `json:"field,omitempty"`
+	Message string `json:"message"`
+}
+
+type EventImportResult struct {
+	Committed bool          `json:"committed"`
+	Total     int           `json:"total"`
+	Errors    []ImportError `json:"errors"`
+	Events    []*Event      `json:"events"` // the valid events as they will be created, with ids once committed
 }
@@ -0,0 +1,11 @@
+package schemas
+
+// Export is a table that is written out row by row, so it can be streamed
+// without holding all of it in memory.
+type Export struct {
+	Name    string // file name without extension
+	Columns []string
+	// Rows calls write with the values of each row, in order. It stops at
+	// and returns the first error.
+	Rows func(write func(values ...any) error) error
+}
@@ -0,0 +1,14 @@
+package schemas
+
+// Readiness is the result of the readiness checks, Status is "ok" when all
+// of them passed and "unavailable" otherwise.
+type Readiness struct {
+	Status string                      `json:"status"`
+	Checks map[string]DependencyStatus `json:"checks"`
+}
+
+type DependencyStatus struct {
+	Status    string  `json:"status"`
+	LatencyMs float64 `json:"latency_ms"`
+	Error     string  `json:"error,omitempty"`
+}
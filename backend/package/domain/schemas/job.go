@@ -0,0 +1,28 @@
+package schemas
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/HermanPlay/web-app-backend/package/domain/models"
+)
+
+type Job struct {
+	ID          int              `json:"id"`
+	Kind        string           `json:"kind"`
+	Payload     json.RawMessage  `json:"payload,omitempty"` // left out for notifications and done jobs
+	Status      models.JobStatus `json:"status"`
+	RunAt       time.Time        `json:"run_at"`
+	Attempts    int              `json:"attempts"`
+	MaxAttempts int              `json:"max_attempts"`
+	LastError   string           `json:"last_error"`
+	CreatedAt   time.Time        `json:"created_at"`
+	UpdatedAt   time.Time        `json:"updated_at"`
+}
+
+// JobFilter holds the query string of the job list endpoint.
+type JobFilter struct {
+	Status   models.JobStatus `form:"status"`
+	Page     int              `form:"page"`
+	PageSize int              `form:"page_size"`
+}
@@ -3,16 +3,18 @@ package schemas
 import "github.com/HermanPlay/web-app-backend/package/domain/models"
 
 type UserUpdate struct {
-	Name  string      `json:"name"`
-	Email string      `json:"email"`
-	Role  models.Role `json:"role"`
+	Name              string      `json:"name"`
+	Email             string      `json:"email"`
+	Role              models.Role `json:"role"`
+	RemindersDisabled *bool       `json:"reminders_disabled"`
 }
 
 type User struct {
-	ID    int         `json:"id"`
-	Name  string      `json:"name"`
-	Email string      `json:"email"`
-	Role  models.Role `json:"role"`
+	ID                int         `json:"id"`
+	Name              string      `json:"name"`
+	Email             string      `json:"email"`
+	Role              models.Role `json:"role"`
+	RemindersDisabled bool        `json:"reminders_disabled"`
 }
 
 type UserInput struct {
@@ -0,0 +1,55 @@
+package schemas
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/HermanPlay/web-app-backend/package/domain/models"
+)
+
+type WebhookInput struct {
+	URL    string                `json:"url" binding:"required"`
+	Events []models.WebhookEvent `json:"events" binding:"required"`
+	Secret string                `json:"secret"` // generated when empty
+}
+
+type WebhookUpdate struct {
+	URL    string                `json:"url"`
+	Events []models.WebhookEvent `json:"events"`
+	Active *bool                 `json:"active"`
+}
+
+type Webhook struct {
+	ID     int                   `json:"id"`
+	URL    string                `json:"url"`
+	Events []models.WebhookEvent `json:"events"`
+	Active bool                  `json:"active"`
+	Secret string                `json:"secret,omitempty"` // only returned when the webhook is created
+}
+
+type WebhookDelivery struct {
+	ID         int                 `json:"id"`
+	DeliveryID string              `json:"delivery_id"`
+	Event      models.WebhookEvent `json:"event"`
+	Payload    json.RawMessage     `json:"payload"`
+	StatusCode int                 `json:"status_code"`
+	Response   string              `json:"response"`
+	Error      string              `json:"error"`
+	Duration   int64               `json:"duration_ms"`
+	CreatedAt  time.Time           `json:"created_at"`
+}
+
+// DeliveryFilter holds the query string of the delivery history endpoint.
+type DeliveryFilter struct {
+	Page     int `form:"page"`
+	PageSize int `form:"page_size"`
+}
+
+// WebhookPayload is the body POSTed to webhooks. ID stays the same across
+// retries, so receivers can drop duplicates.
+type WebhookPayload struct {
+	ID        string              `json:"id"`
+	Event     models.WebhookEvent `json:"event"`
+	CreatedAt time.Time           `json:"created_at"`
+	Data      any                 `json:"data"`
+}
@@ -1,10 +1,9 @@
 package repository
 
 import (
-	"log"
+	"context"
+	"time"
 
-	"github.com/HermanPlay/web-app-backend/internal/api/http/util/token"
-	"github.com/HermanPlay/web-app-backend/internal/config"
 	"github.com/HermanPlay/web-app-backend/package/domain/models"
 	"github.com/sirupsen/logrus"
 	"golang.org/x/crypto/bcrypt"
@@ -12,48 +11,187 @@ import (
 )
 
 type AuthRepository interface {
-	LoginUser(email, password string) (string, error)
+	WithTx(tx *gorm.DB) AuthRepository
+	LoginUser(ctx context.Context, email, password string) (models.User, error)
+	SaveRefreshToken(ctx context.Context, token *models.RefreshToken) error
+	ConsumeRefreshToken(ctx context.Context, tokenHash string) (models.RefreshToken, bool, error)
+	RevokeTokenFamily(ctx context.Context, familyID string) error
+	SavePasswordResetToken(ctx context.Context, token *models.PasswordResetToken) error
+	ResetPassword(ctx context.Context, tokenHash string, password string) (models.User, error)
+	SaveFeedToken(ctx context.Context, token *models.FeedToken) error
+	GetFeedToken(ctx context.Context, tokenHash string) (models.FeedToken, error)
+	DeleteFeedToken(ctx context.Context, userID int) error
 }
 
 type AuthRepositoryImpl struct {
-	db  *gorm.DB
-	cfg *config.Config
+	db *gorm.DB
+}
+
+func (a AuthRepositoryImpl) WithTx(tx *gorm.DB) AuthRepository {
+	return &AuthRepositoryImpl{db: tx}
 }
 
 func verifyPassword(inputPassword, validPassword string) error {
 	return bcrypt.CompareHashAndPassword([]byte(validPassword), []byte(inputPassword))
 }
 
-func (a AuthRepositoryImpl) LoginUser(email, password string) (string, error) {
+// LoginUser returns the user matching the credentials.
+func (a AuthRepositoryImpl) LoginUser(ctx context.Context, email, password string) (models.User, error) {
+	ctx, span := tracer.Start(ctx, "AuthRepository.LoginUser")
+	defer span.End()
+	db := a.db.WithContext(ctx)
 	var user models.User
-	err := a.db.Model(&user).Where("email = ?", email).First(&user).Error
+	err := db.Model(&user).Where("email = ?", email).First(&user).Error
 	if err != nil {
 		if err == gorm.ErrRecordNotFound {
-			return "", err
+			return models.User{}, err
 		}
 		logrus.Error("Error getting user from db, err: ", err.Error())
-		return "", err
+		return models.User{}, err
 	}
 
-	log.Printf("user: %v", user)
+	err = verifyPassword(password, user.Password)
+	if err != nil {
+		return models.User{}, err
+	}
+	return user, nil
+}
 
-	logrus.Printf("user: %v", user)
+func (a AuthRepositoryImpl) SaveRefreshToken(ctx context.Context, token *models.RefreshToken) error {
+	ctx, span := tracer.Start(ctx, "AuthRepository.SaveRefreshToken")
+	defer span.End()
+	db := a.db.WithContext(ctx)
+	return db.Create(token).Error
+}
 
-	err = verifyPassword(password, user.Password)
+// ConsumeRefreshToken marks the token as used and reports whether this call
+// was the one to do it. A token that was already used or revoked is returned
+// unchanged with false, so the caller can tell a replay from a fresh token.
+func (a AuthRepositoryImpl) ConsumeRefreshToken(ctx context.Context, tokenHash string) (models.RefreshToken, bool, error) {
+	ctx, span := tracer.Start(ctx, "AuthRepository.ConsumeRefreshToken")
+	defer span.End()
+	db := a.db.WithContext(ctx)
+	// The conditional update is atomic, so of two concurrent refreshes with
+	// the same token only one wins
+	result := db.Model(&models.RefreshToken{}).
+		Where("token_hash = ? AND used_at IS NULL AND revoked_at IS NULL", tokenHash).
+		Update("used_at", time.Now())
+	if result.Error != nil {
+		return models.RefreshToken{}, false, result.Error
+	}
+
+	var token models.RefreshToken
+	err := db.Where("token_hash = ?", tokenHash).First(&token).Error
+	if err != nil {
+		return models.RefreshToken{}, false, err
+	}
+	return token, result.RowsAffected == 1, nil
+}
+
+func (a AuthRepositoryImpl) RevokeTokenFamily(ctx context.Context, familyID string) error {
+	ctx, span := tracer.Start(ctx, "AuthRepository.RevokeTokenFamily")
+	defer span.End()
+	db := a.db.WithContext(ctx)
+	return db.Model(&models.RefreshToken{}).
+		Where("family_id = ? AND revoked_at IS NULL", familyID).
+		Update("revoked_at", time.Now()).Error
+}
+
+func (a AuthRepositoryImpl) SavePasswordResetToken(ctx context.Context, token *models.PasswordResetToken) error {
+	ctx, span := tracer.Start(ctx, "AuthRepository.SavePasswordResetToken")
+	defer span.End()
+	db := a.db.WithContext(ctx)
+	return db.Create(token).Error
+}
+
+// ResetPassword uses up the reset token and sets the password of its user.
+// All refresh tokens of the user are revoked, so other sessions have to log
+// in again. Unknown, used and expired tokens yield gorm.ErrRecordNotFound.
+func (a AuthRepositoryImpl) ResetPassword(ctx context.Context, tokenHash string, password string) (models.User, error) {
+	ctx, span := tracer.Start(ctx, "AuthRepository.ResetPassword")
+	defer span.End()
+	db := a.db.WithContext(ctx)
+	var user models.User
+	err := db.Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+		result := tx.Model(&models.PasswordResetToken{}).
+			Where("token_hash = ? AND used_at IS NULL AND expires_at > ?", tokenHash, now).
+			Update("used_at", now)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+
+		var token models.PasswordResetToken
+		err := tx.Where("token_hash = ?", tokenHash).First(&token).Error
+		if err != nil {
+			return err
+		}
+		err = tx.First(&user, token.UserID).Error
+		if err != nil {
+			return err
+		}
+		user.Password = password
+		err = tx.Save(&user).Error
+		if err != nil {
+			return err
+		}
+		return tx.Model(&models.RefreshToken{}).
+			Where("user_id = ? AND revoked_at IS NULL", user.ID).
+			Update("revoked_at", now).Error
+	})
 	if err != nil {
-		return "", err
+		return models.User{}, err
 	}
+	return user, nil
+}
 
-	token, err := token.GenerateToken(user.ID, a.cfg)
+// SaveFeedToken stores the feed token of a user in place of the previous one.
+func (a AuthRepositoryImpl) SaveFeedToken(ctx context.Context, token *models.FeedToken) error {
+	ctx, span := tracer.Start(ctx, "AuthRepository.SaveFeedToken")
+	defer span.End()
+	db := a.db.WithContext(ctx)
+	return db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Where("user_id = ?", token.UserID).Delete(&models.FeedToken{}).Error
+		if err != nil {
+			return err
+		}
+		return tx.Create(token).Error
+	})
+}
+
+func (a AuthRepositoryImpl) GetFeedToken(ctx context.Context, tokenHash string) (models.FeedToken, error) {
+	ctx, span := tracer.Start(ctx, "AuthRepository.GetFeedToken")
+	defer span.End()
+	db := a.db.WithContext(ctx)
+	var token models.FeedToken
+	err := db.Where("token_hash = ?", tokenHash).First(&token).Error
 	if err != nil {
-		return "", err
+		return models.FeedToken{}, err
 	}
 	return token, nil
 }
 
-func NewAuthRepository(db *gorm.DB, cfg *config.Config) *AuthRepositoryImpl {
-	return &AuthRepositoryImpl{
-		db:  db,
-		cfg: cfg,
+// DeleteFeedToken revokes the feed token of a user. A user without one yields
+// gorm.ErrRecordNotFound.
+func (a AuthRepositoryImpl) DeleteFeedToken(ctx context.Context, userID int) error {
+	ctx, span := tracer.Start(ctx, "AuthRepository.DeleteFeedToken")
+	defer span.End()
+	db := a.db.WithContext(ctx)
+	result := db.Where("user_id = ?", userID).Delete(&models.FeedToken{})
+	if result.Error != nil {
+		return result.Error
 	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+func NewAuthRepository(db *gorm.DB) (*AuthRepositoryImpl, error) {
+	return &AuthRepositoryImpl{
+		db: db,
+	}, nil
 }
@@ -0,0 +1,216 @@
+package repository
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/HermanPlay/web-app-backend/package/domain/models"
+	"gorm.io/gorm"
+)
+
+// MemoryAuthRepository keeps tokens in memory, for unit tests that need no
+// database. It is safe for concurrent use and behaves like
+// AuthRepositoryImpl, users are looked up in the given MemoryUserRepository.
+// Like there, WithTx returns the repository itself.
+type MemoryAuthRepository struct {
+	mu            sync.Mutex
+	users         *MemoryUserRepository
+	refreshTokens []models.RefreshToken
+	resetTokens   []models.PasswordResetToken
+	feedTokens    []models.FeedToken
+	nextID        int // shared by all token kinds
+}
+
+func (a *MemoryAuthRepository) WithTx(tx *gorm.DB) AuthRepository {
+	return a
+}
+
+func (a *MemoryAuthRepository) LoginUser(ctx context.Context, email, password string) (models.User, error) {
+	user, err := a.users.GetUserByEmail(ctx, email)
+	if err != nil {
+		return models.User{}, err
+	}
+	err = verifyPassword(password, user.Password)
+	if err != nil {
+		return models.User{}, err
+	}
+	return user, nil
+}
+
+func (a *MemoryAuthRepository) SaveRefreshToken(ctx context.Context, token *models.RefreshToken) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	err := a.checkUser(token.UserID)
+	if err != nil {
+		return err
+	}
+	for _, other := range a.refreshTokens {
+		if other.TokenHash == token.TokenHash {
+			return gorm.ErrDuplicatedKey
+		}
+	}
+	now := time.Now()
+	token.ID = a.newID()
+	token.CreatedAt = now
+	token.UpdatedAt = now
+	a.refreshTokens = append(a.refreshTokens, *token)
+	return nil
+}
+
+func (a *MemoryAuthRepository) ConsumeRefreshToken(ctx context.Context, tokenHash string) (models.RefreshToken, bool, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	for i := range a.refreshTokens {
+		token := &a.refreshTokens[i]
+		if token.TokenHash != tokenHash {
+			continue
+		}
+		if token.UsedAt != nil || token.RevokedAt != nil {
+			return *token, false, nil
+		}
+		now := time.Now()
+		token.UsedAt = &now
+		token.UpdatedAt = now
+		return *token, true, nil
+	}
+	return models.RefreshToken{}, false, gorm.ErrRecordNotFound
+}
+
+func (a *MemoryAuthRepository) RevokeTokenFamily(ctx context.Context, familyID string) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.revokeRefreshTokens(func(token models.RefreshToken) bool { return token.FamilyID == familyID })
+	return nil
+}
+
+func (a *MemoryAuthRepository) SavePasswordResetToken(ctx context.Context, token *models.PasswordResetToken) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	err := a.checkUser(token.UserID)
+	if err != nil {
+		return err
+	}
+	for _, other := range a.resetTokens {
+		if other.TokenHash == token.TokenHash {
+			return gorm.ErrDuplicatedKey
+		}
+	}
+	now := time.Now()
+	token.ID = a.newID()
+	token.CreatedAt = now
+	token.UpdatedAt = now
+	a.resetTokens = append(a.resetTokens, *token)
+	return nil
+}
+
+func (a *MemoryAuthRepository) ResetPassword(ctx context.Context, tokenHash string, password string) (models.User, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	now := time.Now()
+	var token *models.PasswordResetToken
+	for i := range a.resetTokens {
+		if a.resetTokens[i].TokenHash == tokenHash {
+			token = &a.resetTokens[i]
+		}
+	}
+	if token == nil || token.UsedAt != nil || !token.ExpiresAt.After(now) {
+		return models.User{}, gorm.ErrRecordNotFound
+	}
+
+	a.users.mu.Lock()
+	user, ok := a.users.liveUser(token.UserID)
+	if !ok {
+		a.users.mu.Unlock()
+		return models.User{}, gorm.ErrRecordNotFound
+	}
+	user.Password = password
+	user, err := a.users.save(&user)
+	a.users.mu.Unlock()
+	if err != nil {
+		return models.User{}, err
+	}
+
+	token.UsedAt = &now
+	token.UpdatedAt = now
+	a.revokeRefreshTokens(func(token models.RefreshToken) bool { return token.UserID == user.ID })
+	return user, nil
+}
+
+func (a *MemoryAuthRepository) SaveFeedToken(ctx context.Context, token *models.FeedToken) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	err := a.checkUser(token.UserID)
+	if err != nil {
+		return err
+	}
+	var kept []models.FeedToken
+	for _, other := range a.feedTokens {
+		if other.UserID == token.UserID {
+			continue
+		}
+		if other.TokenHash == token.TokenHash {
+			return gorm.ErrDuplicatedKey
+		}
+		kept = append(kept, other)
+	}
+	token.ID = a.newID()
+	token.CreatedAt = time.Now()
+	a.feedTokens = append(kept, *token)
+	return nil
+}
+
+func (a *MemoryAuthRepository) GetFeedToken(ctx context.Context, tokenHash string) (models.FeedToken, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	for _, token := range a.feedTokens {
+		if token.TokenHash == tokenHash {
+			return token, nil
+		}
+	}
+	return models.FeedToken{}, gorm.ErrRecordNotFound
+}
+
+func (a *MemoryAuthRepository) DeleteFeedToken(ctx context.Context, userID int) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	for i, token := range a.feedTokens {
+		if token.UserID == userID {
+			a.feedTokens = append(a.feedTokens[:i], a.feedTokens[i+1:]...)
+			return nil
+		}
+	}
+	return gorm.ErrRecordNotFound
+}
+
+// The helpers below expect a.mu to be held.
+
+// checkUser stands in for the foreign key on user_id.
+func (a *MemoryAuthRepository) checkUser(userID int) error {
+	if _, _, exists := a.users.lookup(userID); !exists {
+		return gorm.ErrForeignKeyViolated
+	}
+	return nil
+}
+
+func (a *MemoryAuthRepository) revokeRefreshTokens(match func(token models.RefreshToken) bool) {
+	now := time.Now()
+	for i := range a.refreshTokens {
+		token := &a.refreshTokens[i]
+		if token.RevokedAt == nil && match(*token) {
+			token.RevokedAt = &now
+			token.UpdatedAt = now
+		}
+	}
+}
+
+func (a *MemoryAuthRepository) newID() int {
+	a.nextID++
+	return a.nextID
+}
+
+func NewMemoryAuthRepository(users *MemoryUserRepository) *MemoryAuthRepository {
+	return &MemoryAuthRepository{
+		users: users,
+	}
+}
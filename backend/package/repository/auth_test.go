@@ -1,9 +1,10 @@
 package repository
 
 import (
+	"context"
 	"testing"
+	"time"
 
-	"github.com/HermanPlay/web-app-backend/internal/config"
 	"github.com/HermanPlay/web-app-backend/package/domain/models"
 	"github.com/HermanPlay/web-app-backend/package/utils"
 	"gorm.io/gorm"
@@ -11,23 +12,19 @@ import (
 
 func TestNewAuthRepository(t *testing.T) {
 	db := utils.ConnectToTestDatabase()
-	cfg := config.Config{
-		Db:  config.Db{},
-		App: config.App{},
+	authRepositoryImpl, err := NewAuthRepository(db)
+	if err != nil {
+		t.Errorf("Error when create new auth repository, when not expected. Error: %v", err)
 	}
-	authRepositoryImpl := NewAuthRepository(db, &cfg)
 	if authRepositoryImpl == nil {
 		t.Errorf("Auth repository is nil, when not expected")
 	}
 }
 
 func TestLoginUser(t *testing.T) {
+	ctx := context.Background()
 	db := utils.ConnectToTestDatabase()
-	cfg := config.Config{
-		Db:  config.Db{},
-		App: config.App{},
-	}
-	authRepositoryImpl := NewAuthRepository(db, &cfg)
+	authRepositoryImpl, _ := NewAuthRepository(db)
 	password := "passwordlong"
 	want := models.User{
 		Email:    "email@email.com",
@@ -38,35 +35,178 @@ func TestLoginUser(t *testing.T) {
 	if err != nil {
 		t.Errorf("Error when create new user repository, when not expected. Error: %v", err)
 	}
-	_, err = userRepository.Save(&want)
+	_, err = userRepository.Save(ctx, &want)
 	if err != nil {
 		t.Errorf("Error when save user, when not expected. Error: %v", err)
 	}
 	t.Run("Invalid email", func(t *testing.T) {
-		got, err := authRepositoryImpl.LoginUser("invalid", password)
+		got, err := authRepositoryImpl.LoginUser(ctx, "invalid", password)
 		if err != gorm.ErrRecordNotFound {
 			t.Errorf("Error is not gorm.ErrRecordNotFound, when expected. Error: %v", err)
 		}
-		if got != "" {
-			t.Errorf("Token is not empty, when expected, got: %v", got)
+		if got.ID != 0 {
+			t.Errorf("User is not empty, when expected, got: %v", got)
 		}
 
 	})
 	t.Run("Invalid password", func(t *testing.T) {
-		_, err := authRepositoryImpl.LoginUser(want.Email, "invalid")
+		_, err := authRepositoryImpl.LoginUser(ctx, want.Email, "invalid")
 		if err == nil {
 			t.Errorf("Error is nil, when expected")
 		}
 	})
 	t.Run("Valid login", func(t *testing.T) {
-		token, err := authRepositoryImpl.LoginUser(want.Email, password)
+		user, err := authRepositoryImpl.LoginUser(ctx, want.Email, password)
 		if err != nil {
 			t.Errorf("Error when login, when not expected. Error: %v", err)
 			return
 		}
-		if token == "" {
-			t.Errorf("Token is empty, when not expected")
+		if user.ID != want.ID {
+			t.Errorf("User id is not the same, got: %v, want: %v", user.ID, want.ID)
 		}
 	})
 
 }
+
+func TestConsumeRefreshToken(t *testing.T) {
+	ctx := context.Background()
+	db := utils.ConnectToTestDatabase()
+	authRepositoryImpl, _ := NewAuthRepository(db)
+	users := createUsers(db, 1)
+	token := models.RefreshToken{UserID: users[0].ID, FamilyID: "family", TokenHash: "hash", ExpiresAt: time.Now().Add(time.Hour)}
+	err := authRepositoryImpl.SaveRefreshToken(ctx, &token)
+	if err != nil {
+		t.Fatalf("Error when save refresh token, when not expected. Error: %v", err)
+	}
+	t.Run("Unknown token", func(t *testing.T) {
+		_, _, err := authRepositoryImpl.ConsumeRefreshToken(ctx, "unknown")
+		if err != gorm.ErrRecordNotFound {
+			t.Errorf("Error is not gorm.ErrRecordNotFound, when expected. Error: %v", err)
+		}
+	})
+	t.Run("First use", func(t *testing.T) {
+		got, consumed, err := authRepositoryImpl.ConsumeRefreshToken(ctx, token.TokenHash)
+		if err != nil {
+			t.Fatalf("Error when consume refresh token, when not expected. Error: %v", err)
+		}
+		if !consumed || got.UsedAt == nil {
+			t.Errorf("Token is not consumed, when expected")
+		}
+	})
+	t.Run("Second use", func(t *testing.T) {
+		got, consumed, err := authRepositoryImpl.ConsumeRefreshToken(ctx, token.TokenHash)
+		if err != nil {
+			t.Fatalf("Error when consume refresh token, when not expected. Error: %v", err)
+		}
+		if consumed {
+			t.Errorf("Token is consumed twice, when not expected")
+		}
+		if got.FamilyID != token.FamilyID {
+			t.Errorf("FamilyID is not the same, got: %v, want: %v", got.FamilyID, token.FamilyID)
+		}
+	})
+}
+
+func TestRevokeTokenFamily(t *testing.T) {
+	ctx := context.Background()
+	db := utils.ConnectToTestDatabase()
+	authRepositoryImpl, _ := NewAuthRepository(db)
+	users := createUsers(db, 1)
+	tokens := []models.RefreshToken{
+		{UserID: users[0].ID, FamilyID: "family", TokenHash: "first"},
+		{UserID: users[0].ID, FamilyID: "family", TokenHash: "second"},
+		{UserID: users[0].ID, FamilyID: "other", TokenHash: "third"},
+	}
+	for i := range tokens {
+		tokens[i].ExpiresAt = time.Now().Add(time.Hour)
+		authRepositoryImpl.SaveRefreshToken(ctx, &tokens[i])
+	}
+	err := authRepositoryImpl.RevokeTokenFamily(ctx, "family")
+	if err != nil {
+		t.Fatalf("Error when revoke token family, when not expected. Error: %v", err)
+	}
+	for _, token := range tokens {
+		got, consumed, err := authRepositoryImpl.ConsumeRefreshToken(ctx, token.TokenHash)
+		if err != nil {
+			t.Fatalf("Error when consume refresh token, when not expected. Error: %v", err)
+		}
+		revoked := token.FamilyID == "family"
+		if (got.RevokedAt != nil) != revoked || consumed == revoked {
+			t.Errorf("Token %v revoked: %v, consumed: %v, when expected revoked: %v", token.TokenHash, got.RevokedAt != nil, consumed, revoked)
+		}
+	}
+}
+
+func TestResetPassword(t *testing.T) {
+	ctx := context.Background()
+	db := utils.ConnectToTestDatabase()
+	authRepositoryImpl, _ := NewAuthRepository(db)
+	users := createUsers(db, 1)
+	tokens := []models.PasswordResetToken{
+		{UserID: users[0].ID, TokenHash: "valid", ExpiresAt: time.Now().Add(time.Hour)},
+		{UserID: users[0].ID, TokenHash: "expired", ExpiresAt: time.Now().Add(-time.Minute)},
+	}
+	for i := range tokens {
+		err := authRepositoryImpl.SavePasswordResetToken(ctx, &tokens[i])
+		if err != nil {
+			t.Fatalf("Error when save reset token, when not expected. Error: %v", err)
+		}
+	}
+	for _, hash := range []string{"unknown", "expired"} {
+		_, err := authRepositoryImpl.ResetPassword(ctx, hash, "new password")
+		if err != gorm.ErrRecordNotFound {
+			t.Errorf("Error for %v token is not gorm.ErrRecordNotFound, when expected. Error: %v", hash, err)
+		}
+	}
+	user, err := authRepositoryImpl.ResetPassword(ctx, "valid", "new password")
+	if err != nil {
+		t.Fatalf("Error when reset password, when not expected. Error: %v", err)
+	}
+	if user.ID != users[0].ID {
+		t.Errorf("User id is not the same, got: %v, want: %v", user.ID, users[0].ID)
+	}
+	_, err = authRepositoryImpl.LoginUser(ctx, users[0].Email, "new password")
+	if err != nil {
+		t.Errorf("Error when login with the new password, when not expected. Error: %v", err)
+	}
+	_, err = authRepositoryImpl.ResetPassword(ctx, "valid", "other password")
+	if err != gorm.ErrRecordNotFound {
+		t.Errorf("Error for a used token is not gorm.ErrRecordNotFound, when expected. Error: %v", err)
+	}
+}
+
+func TestFeedToken(t *testing.T) {
+	ctx := context.Background()
+	db := utils.ConnectToTestDatabase()
+	authRepositoryImpl, _ := NewAuthRepository(db)
+	users := createUsers(db, 1)
+	for _, hash := range []string{"first", "second"} {
+		err := authRepositoryImpl.SaveFeedToken(ctx, &models.FeedToken{UserID: users[0].ID, TokenHash: hash})
+		if err != nil {
+			t.Fatalf("Error when save feed token, when not expected. Error: %v", err)
+		}
+	}
+	_, err := authRepositoryImpl.GetFeedToken(ctx, "first")
+	if err != gorm.ErrRecordNotFound {
+		t.Errorf("Error for a replaced token is not gorm.ErrRecordNotFound, when expected. Error: %v", err)
+	}
+	token, err := authRepositoryImpl.GetFeedToken(ctx, "second")
+	if err != nil {
+		t.Fatalf("Error when get feed token, when not expected. Error: %v", err)
+	}
+	if token.UserID != users[0].ID {
+		t.Errorf("User id is not the same, got: %v, want: %v", token.UserID, users[0].ID)
+	}
+	err = authRepositoryImpl.DeleteFeedToken(ctx, users[0].ID)
+	if err != nil {
+		t.Errorf("Error when delete feed token, when not expected. Error: %v", err)
+	}
+	_, err = authRepositoryImpl.GetFeedToken(ctx, "second")
+	if err != gorm.ErrRecordNotFound {
+		t.Errorf("Error for a revoked token is not gorm.ErrRecordNotFound, when expected. Error: %v", err)
+	}
+	err = authRepositoryImpl.DeleteFeedToken(ctx, users[0].ID)
+	if err != gorm.ErrRecordNotFound {
+		t.Errorf("Error when nothing to revoke is not gorm.ErrRecordNotFound, when expected. Error: %v", err)
+	}
+}
@@ -0,0 +1,591 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/HermanPlay/web-app-backend/package/domain/models"
+	"github.com/HermanPlay/web-app-backend/package/utils"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+)
+
+// The tests below run against every implementation of the repositories, so
+// the in-memory ones stay true to the database.
+
+type repositories struct {
+	users  UserRepository
+	events EventRepository
+	auth   AuthRepository
+}
+
+var implementations = []struct {
+	name string
+	new  func() repositories
+}{
+	{"gorm", func() repositories {
+		db := utils.ConnectToTestDatabase()
+		users, _ := NewUserRepository(db)
+		events, _ := NewEventRepository(db)
+		auth, _ := NewAuthRepository(db)
+		return repositories{users, events, auth}
+	}},
+	{"memory", func() repositories {
+		users := NewMemoryUserRepository()
+		return repositories{users, NewMemoryEventRepository(users), NewMemoryAuthRepository(users)}
+	}},
+}
+
+func forEachImplementation(t *testing.T, test func(t *testing.T, repos repositories)) {
+	for _, implementation := range implementations {
+		t.Run(implementation.name, func(t *testing.T) {
+			test(t, implementation.new())
+		})
+	}
+}
+
+func TestUserRepositoryConformance(t *testing.T) {
+	ctx := context.Background()
+	forEachImplementation(t, func(t *testing.T, repos repositories) {
+		_, err := repos.users.FindUserById(ctx, 1)
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			t.Errorf("Error is not ErrRecordNotFound, when expected. Error: %v", err)
+		}
+		_, err = repos.users.GetUserByEmail(ctx, "first@email")
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			t.Errorf("Error is not ErrRecordNotFound, when expected. Error: %v", err)
+		}
+
+		first := models.User{Name: "first", Email: "first@email", Password: "password", Role: models.UserRole}
+		saved, err := repos.users.Save(ctx, &first)
+		if err != nil {
+			t.Fatalf("Error when save user, when not expected. Error: %v", err)
+		}
+		if saved.ID == 0 || saved.ID != first.ID {
+			t.Errorf("User ID is not set, got: %d", saved.ID)
+		}
+		if saved.Password == "password" || verifyPassword("password", saved.Password) != nil {
+			t.Errorf("Password is not hashed, got: %s", saved.Password)
+		}
+		found, err := repos.users.FindUserById(ctx, first.ID)
+		if err != nil || found.Email != first.Email || found.Name != first.Name {
+			t.Errorf("User is not the same, got: %+v. Error: %v", found, err)
+		}
+		found, err = repos.users.GetUserByEmail(ctx, first.Email)
+		if err != nil || found.ID != first.ID {
+			t.Errorf("User is not the same, got: %+v. Error: %v", found, err)
+		}
+		exists, err := repos.users.CheckUserExist(ctx, first.Email)
+		if err != nil || !exists {
+			t.Errorf("User does not exist, when expected. Error: %v", err)
+		}
+
+		duplicate := models.User{Name: "duplicate", Email: first.Email, Password: "password", Role: models.UserRole}
+		_, err = repos.users.Save(ctx, &duplicate)
+		if !errors.Is(err, gorm.ErrDuplicatedKey) {
+			t.Errorf("Error is not ErrDuplicatedKey, when expected. Error: %v", err)
+		}
+
+		second := models.User{Name: "second", Email: "second@email", Password: "password", Role: models.UserRole}
+		_, err = repos.users.Save(ctx, &second)
+		if err != nil {
+			t.Fatalf("Error when save user, when not expected. Error: %v", err)
+		}
+		second.Email = first.Email
+		_, err = repos.users.Update(ctx, &second)
+		if !errors.Is(err, gorm.ErrDuplicatedKey) {
+			t.Errorf("Error is not ErrDuplicatedKey, when expected. Error: %v", err)
+		}
+		second.Email = "renamed@email"
+		second.RemindersDisabled = true
+		_, err = repos.users.Update(ctx, &second)
+		if err != nil {
+			t.Errorf("Error when update user, when not expected. Error: %v", err)
+		}
+		found, err = repos.users.FindUserById(ctx, second.ID)
+		if err != nil || found.Email != "renamed@email" || !found.RemindersDisabled {
+			t.Errorf("User is not updated, got: %+v. Error: %v", found, err)
+		}
+
+		users, err := repos.users.FindAllUser(ctx)
+		if err != nil || len(users) != 2 {
+			t.Errorf("Users are not the same, got: %+v. Error: %v", users, err)
+		}
+
+		err = repos.users.DeleteUserById(ctx, first.ID)
+		if err != nil {
+			t.Errorf("Error when delete user, when not expected. Error: %v", err)
+		}
+		_, err = repos.users.FindUserById(ctx, first.ID)
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			t.Errorf("Error is not ErrRecordNotFound, when expected. Error: %v", err)
+		}
+		exists, err = repos.users.CheckUserExist(ctx, first.Email)
+		if err != nil || exists {
+			t.Errorf("Deleted user exists, when not expected. Error: %v", err)
+		}
+		users, _ = repos.users.FindAllUser(ctx)
+		if len(users) != 1 || users[0].ID != second.ID {
+			t.Errorf("Users are not the same, got: %+v", users)
+		}
+		// A deleted user frees the email
+		_, err = repos.users.Save(ctx, &duplicate)
+		if err != nil {
+			t.Errorf("Error when save user with the email of a deleted one, when not expected. Error: %v", err)
+		}
+	})
+}
+
+func TestEventRepositoryConformance(t *testing.T) {
+	ctx := context.Background()
+	forEachImplementation(t, func(t *testing.T, repos repositories) {
+		users := saveUsers(t, repos.users, 3)
+		creator, organizer, attendee := users[0], users[1], users[2]
+		start := time.Date(2024, 6, 1, 18, 0, 0, 0, time.UTC)
+
+		_, err := repos.events.GetByID(ctx, 1)
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			t.Errorf("Error is not ErrRecordNotFound, when expected. Error: %v", err)
+		}
+		_, err = repos.events.LockByID(ctx, 1)
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			t.Errorf("Error is not ErrRecordNotFound, when expected. Error: %v", err)
+		}
+		_, err = repos.events.Save(ctx, &models.Event{Title: "orphan", CreatedBy: 1000, StartsAt: start, EndsAt: start})
+		if !errors.Is(err, gorm.ErrForeignKeyViolated) {
+			t.Errorf("Error is not ErrForeignKeyViolated, when expected. Error: %v", err)
+		}
+
+		events := []models.Event{
+			{Title: "Jazz Night", Description: "Live music", Location: "Warsaw", StartsAt: start, IsFeatured: true},
+			{Title: "Go meetup", Description: "Talks about 100% JAZZ-free code", Location: "Krakow", StartsAt: start.AddDate(0, 0, 1)},
+			{Title: "Art fair", Description: "Paintings", Location: "warsaw old town", StartsAt: start.AddDate(0, 0, 2)},
+		}
+		for i := range events {
+			events[i].ShortDescription = "short description"
+			events[i].EndsAt = events[i].StartsAt.Add(time.Hour)
+			events[i].CreatedBy = creator.ID
+			_, err := repos.events.Save(ctx, &events[i])
+			if err != nil {
+				t.Fatalf("Error when save event, when not expected. Error: %v", err)
+			}
+		}
+		got, err := repos.events.GetByID(ctx, events[0].ID)
+		if err != nil {
+			t.Fatalf("Error when get event, when not expected. Error: %v", err)
+		}
+		compareEvent(t, got, models.Event{
+			Title:            "Jazz Night",
+			ShortDescription: "short description",
+			Description:      "Live music",
+			Location:         "Warsaw",
+			StartsAt:         start,
+			EndsAt:           start.Add(time.Hour),
+			TimeZone:         "UTC",
+			CreatedBy:        creator.ID,
+		})
+
+		events[2].Title = "Art Fair"
+		_, err = repos.events.Update(ctx, &events[2])
+		if err != nil {
+			t.Errorf("Error when update event, when not expected. Error: %v", err)
+		}
+		_, err = repos.events.AddOrganizer(ctx, events[1].ID, organizer.ID)
+		if err != nil {
+			t.Errorf("Error when add organizer, when not expected. Error: %v", err)
+		}
+
+		featured := true
+		to := start.AddDate(0, 0, 2)
+		for _, tt := range []struct {
+			name   string
+			query  EventQuery
+			titles []string
+			total  int64
+		}{
+			{"No filters", EventQuery{}, []string{"Jazz Night", "Go meetup", "Art Fair"}, 3},
+			{"Search", EventQuery{Search: "jazz"}, []string{"Jazz Night", "Go meetup"}, 2},
+			{"Location", EventQuery{Location: "WARSAW"}, []string{"Jazz Night", "Art Fair"}, 2},
+			{"Date range", EventQuery{From: &start, To: &to}, []string{"Jazz Night", "Go meetup"}, 2},
+			{"Featured", EventQuery{Featured: &featured}, []string{"Jazz Night"}, 1},
+			{"Managed by organizer", EventQuery{ManagedBy: organizer.ID}, []string{"Go meetup"}, 1},
+			{"Order by title descending", EventQuery{OrderBy: "title", Descending: true}, []string{"Jazz Night", "Go meetup", "Art Fair"}, 3},
+			{"Second page", EventQuery{Limit: 2, Offset: 2}, []string{"Art Fair"}, 3},
+		} {
+			page, total, err := repos.events.GetAll(ctx, tt.query)
+			if err != nil {
+				t.Errorf("%s: error when get all events, when not expected. Error: %v", tt.name, err)
+				continue
+			}
+			if fmt.Sprint(eventTitles(page)) != fmt.Sprint(tt.titles) || total != tt.total {
+				t.Errorf("%s: events are not the same, got: %v of %d, want: %v of %d", tt.name, eventTitles(page), total, tt.titles, tt.total)
+			}
+			var streamed []models.Event
+			err = repos.events.StreamEvents(ctx, tt.query, func(event models.Event) error {
+				streamed = append(streamed, event)
+				return nil
+			})
+			if err != nil || fmt.Sprint(eventTitles(streamed)) != fmt.Sprint(tt.titles) {
+				t.Errorf("%s: streamed events are not the same, got: %v. Error: %v", tt.name, eventTitles(streamed), err)
+			}
+		}
+
+		featuredEvents, _ := repos.events.GetFeaturedEvents(ctx)
+		createdEvents, _ := repos.events.GetCreatedEvents(ctx, creator.ID)
+		if len(featuredEvents) != 1 || len(createdEvents) != 3 {
+			t.Errorf("Featured and created events are not the same, got: %v and %v", eventTitles(featuredEvents), eventTitles(createdEvents))
+		}
+
+		err = repos.events.Delete(ctx, events[2].ID)
+		if err != nil {
+			t.Errorf("Error when delete event, when not expected. Error: %v", err)
+		}
+		_, err = repos.events.GetByID(ctx, events[2].ID)
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			t.Errorf("Error is not ErrRecordNotFound, when expected. Error: %v", err)
+		}
+		_, total, _ := repos.events.GetAll(ctx, EventQuery{})
+		if total != 2 {
+			t.Errorf("Event count is not the same, got: %d, want: %d", total, 2)
+		}
+		_, err = repos.events.BookEvent(ctx, events[2].ID, attendee.ID, withStatus(models.BookingConfirmed))
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			t.Errorf("Error is not ErrRecordNotFound, when expected. Error: %v", err)
+		}
+		myEvents, _ := repos.events.GetMyEvents(ctx, attendee.ID)
+		if len(myEvents) != 0 {
+			t.Errorf("Events of attendee are not empty, got: %v", eventTitles(myEvents))
+		}
+	})
+}
+
+func TestBookingConformance(t *testing.T) {
+	ctx := context.Background()
+	forEachImplementation(t, func(t *testing.T, repos repositories) {
+		users := saveUsers(t, repos.users, 4)
+		event := saveEvent(t, repos.events, users[0].ID, 1)
+
+		booking, err := repos.events.BookEvent(ctx, event.ID, users[1].ID, withStatus(models.BookingConfirmed))
+		if err != nil {
+			t.Fatalf("Error when book event, when not expected. Error: %v", err)
+		}
+		if booking.ID == 0 || booking.Event.ID != event.ID || booking.User.Email != users[1].Email {
+			t.Errorf("Booking is not loaded, got: %+v", booking)
+		}
+		_, err = repos.events.BookEvent(ctx, event.ID, users[1].ID, withStatus(models.BookingConfirmed))
+		if !errors.Is(err, gorm.ErrDuplicatedKey) {
+			t.Errorf("Error is not ErrDuplicatedKey, when expected. Error: %v", err)
+		}
+		_, err = repos.events.BookEvent(ctx, event.ID, users[2].ID, capacityPolicy)
+		if !errors.Is(err, errEventFull) {
+			t.Errorf("Error is not errEventFull, when expected. Error: %v", err)
+		}
+		_, err = repos.events.GetBooking(ctx, event.ID, users[2].ID)
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			t.Errorf("Error is not ErrRecordNotFound, when expected. Error: %v", err)
+		}
+
+		var waitlisted []models.EventUser
+		for _, user := range users[2:] {
+			booking, err := repos.events.BookEvent(ctx, event.ID, user.ID, withStatus(models.BookingWaitlisted))
+			if err != nil {
+				t.Fatalf("Error when book event, when not expected. Error: %v", err)
+			}
+			waitlisted = append(waitlisted, booking)
+		}
+		position, err := repos.events.GetWaitlistPosition(ctx, waitlisted[1])
+		if err != nil || position != 2 {
+			t.Errorf("Waitlist position is not the same, got: %d, want: %d. Error: %v", position, 2, err)
+		}
+		confirmed, _ := repos.events.CountBookings(ctx, event.ID, models.BookingConfirmed)
+		onWaitlist, _ := repos.events.CountBookings(ctx, event.ID, models.BookingWaitlisted)
+		if confirmed != 1 || onWaitlist != 2 {
+			t.Errorf("Booking counts are not the same, got: %d confirmed and %d waitlisted", confirmed, onWaitlist)
+		}
+
+		promoted, err := repos.events.CancelBooking(ctx, event.ID, users[1].ID)
+		if err != nil {
+			t.Fatalf("Error when cancel booking, when not expected. Error: %v", err)
+		}
+		if len(promoted) != 1 || promoted[0].UserID != users[2].ID || promoted[0].Status != models.BookingConfirmed || promoted[0].User.Email != users[2].Email {
+			t.Errorf("Promoted bookings are not the same, got: %+v", promoted)
+		}
+		_, err = repos.events.CancelBooking(ctx, event.ID, users[1].ID)
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			t.Errorf("Error is not ErrRecordNotFound, when expected. Error: %v", err)
+		}
+		position, _ = repos.events.GetWaitlistPosition(ctx, waitlisted[1])
+		if position != 1 {
+			t.Errorf("Waitlist position is not the same, got: %d, want: %d", position, 1)
+		}
+
+		// Cancelled bookings can be made again
+		_, err = repos.events.BookEvent(ctx, event.ID, users[1].ID, withStatus(models.BookingWaitlisted))
+		if err != nil {
+			t.Errorf("Error when book event again, when not expected. Error: %v", err)
+		}
+		myEvents, _ := repos.events.GetMyEvents(ctx, users[1].ID)
+		if len(myEvents) != 1 || myEvents[0].ID != event.ID {
+			t.Errorf("Events of user are not the same, got: %v", eventTitles(myEvents))
+		}
+
+		event.Capacity = 0
+		repos.events.Update(ctx, &event)
+		promoted, err = repos.events.PromoteWaitlisted(ctx, event.ID)
+		if err != nil || len(promoted) != 2 {
+			t.Errorf("Promoted bookings are not the same, got: %+v. Error: %v", promoted, err)
+		}
+
+		attendees, err := repos.events.GetAttendees(ctx, event.ID)
+		if err != nil || len(attendees) != 3 || attendees[0].UserID != users[2].ID || attendees[0].User.Name != users[2].Name {
+			t.Errorf("Attendees are not the same, got: %+v. Error: %v", attendees, err)
+		}
+		repos.users.DeleteUserById(ctx, users[3].ID)
+		var streamed []models.EventUser
+		err = repos.events.StreamAttendees(ctx, event.ID, func(booking models.EventUser) error {
+			streamed = append(streamed, booking)
+			return nil
+		})
+		if err != nil || len(streamed) != 2 || streamed[1].UserID != users[1].ID || streamed[1].User.Email != users[1].Email {
+			t.Errorf("Streamed attendees are not the same, got: %+v. Error: %v", streamed, err)
+		}
+	})
+}
+
+func TestBookingConcurrencyConformance(t *testing.T) {
+	ctx := context.Background()
+	const capacity = 3
+	forEachImplementation(t, func(t *testing.T, repos repositories) {
+		users := saveUsers(t, repos.users, 10)
+		event := saveEvent(t, repos.events, users[0].ID, capacity)
+
+		var wg sync.WaitGroup
+		for _, user := range users {
+			wg.Add(1)
+			go func(userID int) {
+				defer wg.Done()
+				_, err := repos.events.BookEvent(ctx, event.ID, userID, capacityPolicy)
+				if err != nil && !errors.Is(err, errEventFull) {
+					t.Errorf("Error when book event, when not expected. Error: %v", err)
+				}
+			}(user.ID)
+		}
+		wg.Wait()
+		confirmed, _ := repos.events.CountBookings(ctx, event.ID, models.BookingConfirmed)
+		if confirmed != capacity {
+			t.Errorf("Booked count is not same, got: %d, want: %d", confirmed, capacity)
+		}
+	})
+}
+
+func TestOrganizerConformance(t *testing.T) {
+	ctx := context.Background()
+	forEachImplementation(t, func(t *testing.T, repos repositories) {
+		users := saveUsers(t, repos.users, 2)
+		event := saveEvent(t, repos.events, users[0].ID, 0)
+
+		_, err := repos.events.AddOrganizer(ctx, event.ID, 1000)
+		if !errors.Is(err, gorm.ErrForeignKeyViolated) {
+			t.Errorf("Error is not ErrForeignKeyViolated, when expected. Error: %v", err)
+		}
+		_, err = repos.events.AddOrganizer(ctx, event.ID, users[1].ID)
+		if err != nil {
+			t.Fatalf("Error when add organizer, when not expected. Error: %v", err)
+		}
+		_, err = repos.events.AddOrganizer(ctx, event.ID, users[1].ID)
+		if !errors.Is(err, gorm.ErrDuplicatedKey) {
+			t.Errorf("Error is not ErrDuplicatedKey, when expected. Error: %v", err)
+		}
+		isOrganizer, _ := repos.events.IsOrganizer(ctx, event.ID, users[1].ID)
+		if !isOrganizer {
+			t.Errorf("User is not an organizer, when expected")
+		}
+		organizers, err := repos.events.GetOrganizers(ctx, event.ID)
+		if err != nil || len(organizers) != 1 || organizers[0].User.Email != users[1].Email {
+			t.Errorf("Organizers are not the same, got: %+v. Error: %v", organizers, err)
+		}
+
+		err = repos.events.RemoveOrganizer(ctx, event.ID, users[1].ID)
+		if err != nil {
+			t.Errorf("Error when remove organizer, when not expected. Error: %v", err)
+		}
+		err = repos.events.RemoveOrganizer(ctx, event.ID, users[1].ID)
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			t.Errorf("Error is not ErrRecordNotFound, when expected. Error: %v", err)
+		}
+		isOrganizer, _ = repos.events.IsOrganizer(ctx, event.ID, users[1].ID)
+		if isOrganizer {
+			t.Errorf("User is an organizer, when not expected")
+		}
+	})
+}
+
+func TestAuthRepositoryConformance(t *testing.T) {
+	ctx := context.Background()
+	forEachImplementation(t, func(t *testing.T, repos repositories) {
+		user := saveUsers(t, repos.users, 1)[0]
+		expiresAt := time.Now().Add(time.Hour)
+
+		_, err := repos.auth.LoginUser(ctx, user.Email, "password")
+		if err != nil {
+			t.Errorf("Error when login user, when not expected. Error: %v", err)
+		}
+		_, err = repos.auth.LoginUser(ctx, user.Email, "wrong")
+		if err == nil {
+			t.Errorf("No error when login with a wrong password, when expected")
+		}
+		_, err = repos.auth.LoginUser(ctx, "unknown@email", "password")
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			t.Errorf("Error is not ErrRecordNotFound, when expected. Error: %v", err)
+		}
+		// A password that looks like a bcrypt hash is still hashed
+		hashLike, _ := bcrypt.GenerateFromPassword([]byte("password"), bcrypt.MinCost)
+		other := models.User{Name: "other", Email: "other@email", Password: string(hashLike), Role: models.UserRole}
+		_, err = repos.users.Save(ctx, &other)
+		if err != nil {
+			t.Fatalf("Error when save user, when not expected. Error: %v", err)
+		}
+		_, err = repos.auth.LoginUser(ctx, other.Email, string(hashLike))
+		if err != nil {
+			t.Errorf("Error when login with a hash-like password, when not expected. Error: %v", err)
+		}
+
+		err = repos.auth.SaveRefreshToken(ctx, &models.RefreshToken{UserID: 1000, FamilyID: "family", TokenHash: "orphan", ExpiresAt: expiresAt})
+		if !errors.Is(err, gorm.ErrForeignKeyViolated) {
+			t.Errorf("Error is not ErrForeignKeyViolated, when expected. Error: %v", err)
+		}
+		for _, hash := range []string{"first", "second"} {
+			err = repos.auth.SaveRefreshToken(ctx, &models.RefreshToken{UserID: user.ID, FamilyID: "family", TokenHash: hash, ExpiresAt: expiresAt})
+			if err != nil {
+				t.Fatalf("Error when save refresh token, when not expected. Error: %v", err)
+			}
+		}
+		err = repos.auth.SaveRefreshToken(ctx, &models.RefreshToken{UserID: user.ID, FamilyID: "family", TokenHash: "first", ExpiresAt: expiresAt})
+		if !errors.Is(err, gorm.ErrDuplicatedKey) {
+			t.Errorf("Error is not ErrDuplicatedKey, when expected. Error: %v", err)
+		}
+		token, consumed, err := repos.auth.ConsumeRefreshToken(ctx, "first")
+		if err != nil || !consumed || token.UsedAt == nil || token.UserID != user.ID {
+			t.Errorf("Refresh token is not consumed, got: %+v. Error: %v", token, err)
+		}
+		_, consumed, err = repos.auth.ConsumeRefreshToken(ctx, "first")
+		if err != nil || consumed {
+			t.Errorf("Refresh token is consumed twice, when not expected. Error: %v", err)
+		}
+		_, _, err = repos.auth.ConsumeRefreshToken(ctx, "unknown")
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			t.Errorf("Error is not ErrRecordNotFound, when expected. Error: %v", err)
+		}
+		err = repos.auth.RevokeTokenFamily(ctx, "family")
+		if err != nil {
+			t.Errorf("Error when revoke token family, when not expected. Error: %v", err)
+		}
+		token, consumed, _ = repos.auth.ConsumeRefreshToken(ctx, "second")
+		if consumed || token.RevokedAt == nil {
+			t.Errorf("Refresh token is not revoked, got: %+v", token)
+		}
+
+		repos.auth.SaveRefreshToken(ctx, &models.RefreshToken{UserID: user.ID, FamilyID: "other", TokenHash: "third", ExpiresAt: expiresAt})
+		repos.auth.SavePasswordResetToken(ctx, &models.PasswordResetToken{UserID: user.ID, TokenHash: "expired", ExpiresAt: time.Now().Add(-time.Minute)})
+		err = repos.auth.SavePasswordResetToken(ctx, &models.PasswordResetToken{UserID: user.ID, TokenHash: "reset", ExpiresAt: expiresAt})
+		if err != nil {
+			t.Fatalf("Error when save password reset token, when not expected. Error: %v", err)
+		}
+		_, err = repos.auth.ResetPassword(ctx, "expired", "new password")
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			t.Errorf("Error is not ErrRecordNotFound, when expected. Error: %v", err)
+		}
+		reset, err := repos.auth.ResetPassword(ctx, "reset", "new password")
+		if err != nil || reset.ID != user.ID {
+			t.Errorf("User is not the same, got: %+v. Error: %v", reset, err)
+		}
+		_, err = repos.auth.LoginUser(ctx, user.Email, "new password")
+		if err != nil {
+			t.Errorf("Error when login with the new password, when not expected. Error: %v", err)
+		}
+		token, _, _ = repos.auth.ConsumeRefreshToken(ctx, "third")
+		if token.RevokedAt == nil {
+			t.Errorf("Refresh token is not revoked after password reset, got: %+v", token)
+		}
+		_, err = repos.auth.ResetPassword(ctx, "reset", "another password")
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			t.Errorf("Error is not ErrRecordNotFound, when expected. Error: %v", err)
+		}
+
+		for _, hash := range []string{"old feed", "new feed"} {
+			err = repos.auth.SaveFeedToken(ctx, &models.FeedToken{UserID: user.ID, TokenHash: hash})
+			if err != nil {
+				t.Fatalf("Error when save feed token, when not expected. Error: %v", err)
+			}
+		}
+		_, err = repos.auth.GetFeedToken(ctx, "old feed")
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			t.Errorf("Error is not ErrRecordNotFound, when expected. Error: %v", err)
+		}
+		feedToken, err := repos.auth.GetFeedToken(ctx, "new feed")
+		if err != nil || feedToken.UserID != user.ID {
+			t.Errorf("Feed token is not the same, got: %+v. Error: %v", feedToken, err)
+		}
+		err = repos.auth.DeleteFeedToken(ctx, user.ID)
+		if err != nil {
+			t.Errorf("Error when delete feed token, when not expected. Error: %v", err)
+		}
+		err = repos.auth.DeleteFeedToken(ctx, user.ID)
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			t.Errorf("Error is not ErrRecordNotFound, when expected. Error: %v", err)
+		}
+	})
+}
+
+func saveUsers(t *testing.T, repository UserRepository, n int) []models.User {
+	ctx := context.Background()
+	t.Helper()
+	users := make([]models.User, 0, n)
+	for i := 0; i < n; i++ {
+		user := models.User{
+			Name:     fmt.Sprintf("user%d", i),
+			Email:    fmt.Sprintf("user%d@email", i),
+			Password: "password",
+			Role:     models.UserRole,
+		}
+		_, err := repository.Save(ctx, &user)
+		if err != nil {
+			t.Fatalf("Error when save user, when not expected. Error: %v", err)
+		}
+		users = append(users, user)
+	}
+	return users
+}
+
+func saveEvent(t *testing.T, repository EventRepository, createdBy int, capacity int) models.Event {
+	ctx := context.Background()
+	t.Helper()
+	start := time.Now().UTC().Truncate(time.Second)
+	event := models.Event{
+		Title:            "event",
+		ShortDescription: "short description",
+		Description:      "description",
+		Location:         "location",
+		StartsAt:         start,
+		EndsAt:           start.Add(time.Hour),
+		Capacity:         capacity,
+		CreatedBy:        createdBy,
+	}
+	_, err := repository.Save(ctx, &event)
+	if err != nil {
+		t.Fatalf("Error when save event, when not expected. Error: %v", err)
+	}
+	return event
+}
+
+func eventTitles(events []models.Event) []string {
+	titles := []string{}
+	for _, event := range events {
+		titles = append(titles, event.Title)
+	}
+	return titles
+}
@@ -1,82 +1,240 @@
 package repository
 
 import (
+	"context"
+	"strings"
+	"time"
+
 	"github.com/HermanPlay/web-app-backend/package/domain/models"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type EventRepository interface {
-	GetAll() ([]models.Event, error)
-	GetByID(id int) (models.Event, error)
-	Save(event *models.Event) (models.Event, error)
-	Update(event *models.Event) (models.Event, error)
-	Delete(id int) error
-	GetFeaturedEvents() ([]models.Event, error)
-	GetMyEvents(userId int) ([]models.Event, error)
-	GetCreatedEvents(userId int) ([]models.Event, error)
-	BookEvent(eventID int, userID int) error
-	GetBooking(eventID int, userID int) (models.EventUser, error)
+	WithTx(tx *gorm.DB) EventRepository
+	GetAll(ctx context.Context, query EventQuery) ([]models.Event, int64, error)
+	GetByID(ctx context.Context, id int) (models.Event, error)
+	LockByID(ctx context.Context, id int) (models.Event, error)
+	Save(ctx context.Context, event *models.Event) (models.Event, error)
+	Update(ctx context.Context, event *models.Event) (models.Event, error)
+	Delete(ctx context.Context, id int) error
+	GetFeaturedEvents(ctx context.Context) ([]models.Event, error)
+	GetMyEvents(ctx context.Context, userId int) ([]models.Event, error)
+	GetCreatedEvents(ctx context.Context, userId int) ([]models.Event, error)
+	BookEvent(ctx context.Context, eventID int, userID int, policy BookingPolicy) (models.EventUser, error)
+	GetBooking(ctx context.Context, eventID int, userID int) (models.EventUser, error)
+	CountBookings(ctx context.Context, eventID int, status models.BookingStatus) (int64, error)
+	GetWaitlistPosition(ctx context.Context, booking models.EventUser) (int64, error)
+	CancelBooking(ctx context.Context, eventID int, userID int) ([]models.EventUser, error)
+	PromoteWaitlisted(ctx context.Context, eventID int) ([]models.EventUser, error)
+	GetAttendees(ctx context.Context, eventID int) ([]models.EventUser, error)
+	StreamEvents(ctx context.Context, query EventQuery, fn func(event models.Event) error) error
+	StreamAttendees(ctx context.Context, eventID int, fn func(booking models.EventUser) error) error
+	AddOrganizer(ctx context.Context, eventID int, userID int) (models.EventOrganizer, error)
+	RemoveOrganizer(ctx context.Context, eventID int, userID int) error
+	IsOrganizer(ctx context.Context, eventID int, userID int) (bool, error)
+	GetOrganizers(ctx context.Context, eventID int) ([]models.EventOrganizer, error)
+}
+
+// BookingPolicy decides the status of a new booking from the locked event and
+// its number of confirmed bookings. Returning an error aborts the booking.
+type BookingPolicy func(event models.Event, confirmed int64) (models.BookingStatus, error)
+
+// EventQuery narrows down and orders the event list. Zero values mean no filter.
+type EventQuery struct {
+	Search     string // matched against title and descriptions, case-insensitive
+	Location   string
+	From       *time.Time // events starting at or after
+	To         *time.Time // events starting before
+	Featured   *bool
+	ManagedBy  int    // events created or co-organized by this user
+	OrderBy    string // column name, checked by the caller
+	Descending bool
+	Limit      int
+	Offset     int
 }
 
 type EventRepositoryImpl struct {
 	db *gorm.DB
 }
 
-func (e EventRepositoryImpl) GetAll() ([]models.Event, error) {
+func (e EventRepositoryImpl) WithTx(tx *gorm.DB) EventRepository {
+	return &EventRepositoryImpl{db: tx}
+}
+
+// GetAll returns one page of events matching the query, together with the
+// number of matching events across all pages.
+func (e EventRepositoryImpl) GetAll(ctx context.Context, query EventQuery) ([]models.Event, int64, error) {
+	ctx, span := tracer.Start(ctx, "EventRepository.GetAll")
+	defer span.End()
+	db := e.db.WithContext(ctx)
+	tx := filterEvents(db.Model(&models.Event{}), query).Session(&gorm.Session{})
+
+	var total int64
+	err := tx.Count(&total).Error
+	if err != nil {
+		return nil, 0, err
+	}
+
 	var events []models.Event
-	err := e.db.Find(&events).Error
+	err = orderEvents(tx, query).Find(&events).Error
 	if err != nil {
-		return nil, err
+		return nil, 0, err
 	}
-	return events, nil
+	return events, total, nil
 }
 
-func (e EventRepositoryImpl) GetByID(id int) (models.Event, error) {
+// StreamEvents calls fn with every event matching the query, in its order,
+// reading one row at a time. Limit and Offset apply when set. An error from
+// fn stops the iteration and is returned.
+func (e EventRepositoryImpl) StreamEvents(ctx context.Context, query EventQuery, fn func(event models.Event) error) error {
+	ctx, span := tracer.Start(ctx, "EventRepository.StreamEvents")
+	defer span.End()
+	db := e.db.WithContext(ctx)
+	rows, err := orderEvents(filterEvents(db.Model(&models.Event{}), query), query).Rows()
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var event models.Event
+		err = db.ScanRows(rows, &event)
+		if err != nil {
+			return err
+		}
+		err = fn(event)
+		if err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+func filterEvents(tx *gorm.DB, query EventQuery) *gorm.DB {
+	if query.Search != "" {
+		pattern := likePattern(query.Search)
+		tx = tx.Where(
+			"LOWER(title) LIKE ? ESCAPE '\\' OR LOWER(short_description) LIKE ? ESCAPE '\\' OR LOWER(description) LIKE ? ESCAPE '\\'",
+			pattern, pattern, pattern,
+		)
+	}
+	if query.Location != "" {
+		tx = tx.Where("LOWER(location) LIKE ? ESCAPE '\\'", likePattern(query.Location))
+	}
+	if query.From != nil {
+		tx = tx.Where("starts_at >= ?", query.From.UTC())
+	}
+	if query.To != nil {
+		tx = tx.Where("starts_at < ?", query.To.UTC())
+	}
+	if query.Featured != nil {
+		tx = tx.Where("is_featured = ?", *query.Featured)
+	}
+	if query.ManagedBy != 0 {
+		tx = tx.Where(
+			"created_by = ? OR id IN (?)",
+			query.ManagedBy,
+			tx.Session(&gorm.Session{NewDB: true}).Model(&models.EventOrganizer{}).Select("event_id").Where("user_id = ?", query.ManagedBy),
+		)
+	}
+	return tx
+}
+
+func orderEvents(tx *gorm.DB, query EventQuery) *gorm.DB {
+	orderBy := query.OrderBy
+	if orderBy == "" {
+		orderBy = "starts_at"
+	}
+	tx = tx.
+		Order(clause.OrderByColumn{Column: clause.Column{Name: orderBy}, Desc: query.Descending}).
+		Order("id")
+	if query.Limit > 0 {
+		tx = tx.Limit(query.Limit)
+	}
+	if query.Offset > 0 {
+		tx = tx.Offset(query.Offset)
+	}
+	return tx
+}
+
+// likePattern builds a case-insensitive substring pattern, escaping LIKE wildcards in s.
+func likePattern(s string) string {
+	s = strings.NewReplacer("\\", "\\\\", "%", "\\%", "_", "\\_").Replace(strings.ToLower(s))
+	return "%" + s + "%"
+}
+
+func (e EventRepositoryImpl) GetByID(ctx context.Context, id int) (models.Event, error) {
+	ctx, span := tracer.Start(ctx, "EventRepository.GetByID")
+	defer span.End()
+	db := e.db.WithContext(ctx)
 	var event models.Event
-	err := e.db.Where("id = ?", id).First(&event).Error
+	err := db.Where("id = ?", id).First(&event).Error
 	if err != nil {
 		return models.Event{}, err
 	}
 	return event, nil
 }
 
-func (e EventRepositoryImpl) Save(event *models.Event) (models.Event, error) {
-	err := e.db.Create(event).Error
+// LockByID is GetByID that also locks the row until the transaction ends, so
+// call it on a repository from WithTx.
+func (e EventRepositoryImpl) LockByID(ctx context.Context, id int) (models.Event, error) {
+	ctx, span := tracer.Start(ctx, "EventRepository.LockByID")
+	defer span.End()
+	return lockEvent(e.db.WithContext(ctx), id)
+}
+
+func (e EventRepositoryImpl) Save(ctx context.Context, event *models.Event) (models.Event, error) {
+	ctx, span := tracer.Start(ctx, "EventRepository.Save")
+	defer span.End()
+	db := e.db.WithContext(ctx)
+	err := db.Create(event).Error
 	if err != nil {
 		return models.Event{}, err
 	}
 	return *event, nil
 }
 
-func (e EventRepositoryImpl) Update(event *models.Event) (models.Event, error) {
-	err := e.db.Save(event).Error
+func (e EventRepositoryImpl) Update(ctx context.Context, event *models.Event) (models.Event, error) {
+	ctx, span := tracer.Start(ctx, "EventRepository.Update")
+	defer span.End()
+	db := e.db.WithContext(ctx)
+	err := db.Save(event).Error
 	if err != nil {
 		return models.Event{}, err
 	}
 	return *event, nil
 }
 
-func (e EventRepositoryImpl) Delete(id int) error {
-	err := e.db.Delete(&models.Event{}, id).Error
+func (e EventRepositoryImpl) Delete(ctx context.Context, id int) error {
+	ctx, span := tracer.Start(ctx, "EventRepository.Delete")
+	defer span.End()
+	db := e.db.WithContext(ctx)
+	err := db.Delete(&models.Event{}, id).Error
 	if err != nil {
 		return err
 	}
 	return nil
 }
 
-func (e EventRepositoryImpl) GetFeaturedEvents() ([]models.Event, error) {
+func (e EventRepositoryImpl) GetFeaturedEvents(ctx context.Context) ([]models.Event, error) {
+	ctx, span := tracer.Start(ctx, "EventRepository.GetFeaturedEvents")
+	defer span.End()
+	db := e.db.WithContext(ctx)
 	var events []models.Event
-	err := e.db.Where("is_featured = ?", true).Find(&events).Error
+	err := db.Where("is_featured = ?", true).Find(&events).Error
 	if err != nil {
 		return nil, err
 	}
 	return events, nil
 }
 
-func (e EventRepositoryImpl) GetMyEvents(userId int) ([]models.Event, error) {
+func (e EventRepositoryImpl) GetMyEvents(ctx context.Context, userId int) ([]models.Event, error) {
+	ctx, span := tracer.Start(ctx, "EventRepository.GetMyEvents")
+	defer span.End()
+	db := e.db.WithContext(ctx)
 	// Return all events with userID in table event_users as given
 	var events []models.Event
-	err := e.db.Table("events").Select("events.*").Joins("join event_users on events.id = event_users.event_id").Where("event_users.user_id = ?", userId).Find(&events).Error
+	err := db.Table("events").Select("events.*").Joins("join event_users on events.id = event_users.event_id").Where("event_users.user_id = ?", userId).Find(&events).Error
 	if err != nil {
 		return nil, err
 	}
@@ -84,45 +242,307 @@ func (e EventRepositoryImpl) GetMyEvents(userId int) ([]models.Event, error) {
 
 }
 
-func (e EventRepositoryImpl) GetCreatedEvents(userId int) ([]models.Event, error) {
+func (e EventRepositoryImpl) GetCreatedEvents(ctx context.Context, userId int) ([]models.Event, error) {
+	ctx, span := tracer.Start(ctx, "EventRepository.GetCreatedEvents")
+	defer span.End()
+	db := e.db.WithContext(ctx)
 	var events []models.Event
-	err := e.db.Where("created_by = ?", userId).Find(&events).Error
+	err := db.Where("created_by = ?", userId).Find(&events).Error
 	if err != nil {
 		return nil, err
 	}
 	return events, nil
 }
 
-func (e EventRepositoryImpl) BookEvent(eventID int, userID int) error {
-	eventUser := models.EventUser{
-		EventID: eventID,
-		UserID:  userID,
-	}
-	err := e.db.Create(&eventUser).Error
+// BookEvent creates a booking while holding a lock on the event row, so
+// concurrent bookings for the same event are serialized and cannot overbook it.
+// An existing booking for the user is reported as gorm.ErrDuplicatedKey.
+// The returned booking comes with its Event and User loaded.
+func (e EventRepositoryImpl) BookEvent(ctx context.Context, eventID int, userID int, policy BookingPolicy) (models.EventUser, error) {
+	ctx, span := tracer.Start(ctx, "EventRepository.BookEvent")
+	defer span.End()
+	db := e.db.WithContext(ctx)
+	var eventUser models.EventUser
+	err := db.Transaction(func(tx *gorm.DB) error {
+		event, err := lockEvent(tx, eventID)
+		if err != nil {
+			return err
+		}
+
+		var existing int64
+		err = tx.Model(&models.EventUser{}).Where("event_id = ? AND user_id = ?", eventID, userID).Count(&existing).Error
+		if err != nil {
+			return err
+		}
+		if existing > 0 {
+			return gorm.ErrDuplicatedKey
+		}
+
+		var confirmed int64
+		err = tx.Model(&models.EventUser{}).Where("event_id = ? AND status = ?", eventID, models.BookingConfirmed).Count(&confirmed).Error
+		if err != nil {
+			return err
+		}
+		status, err := policy(event, confirmed)
+		if err != nil {
+			return err
+		}
+
+		eventUser = models.EventUser{
+			EventID: eventID,
+			UserID:  userID,
+			Status:  status,
+		}
+		// The unique index on (event_id, user_id) is the last line of defence
+		err = tx.Create(&eventUser).Error
+		if err != nil {
+			return err
+		}
+		eventUser.Event = event
+		return tx.First(&eventUser.User, userID).Error
+	})
 	if err != nil {
-		return err
+		return models.EventUser{}, err
 	}
-	return nil
+	return eventUser, nil
 }
 
-func (e EventRepositoryImpl) GetBooking(eventID int, userID int) (models.EventUser, error) {
+func (e EventRepositoryImpl) GetBooking(ctx context.Context, eventID int, userID int) (models.EventUser, error) {
+	ctx, span := tracer.Start(ctx, "EventRepository.GetBooking")
+	defer span.End()
+	db := e.db.WithContext(ctx)
 	var eventUser models.EventUser
-	err := e.db.Where("event_id = ? AND user_id = ?", eventID, userID).First(&eventUser).Error
+	err := db.Where("event_id = ? AND user_id = ?", eventID, userID).First(&eventUser).Error
 	if err != nil {
 		return models.EventUser{}, err
 	}
 	return eventUser, nil
 }
 
-func NewEventRepository(db *gorm.DB) (*EventRepositoryImpl, error) {
-	err := db.AutoMigrate(&models.Event{})
+func (e EventRepositoryImpl) CountBookings(ctx context.Context, eventID int, status models.BookingStatus) (int64, error) {
+	ctx, span := tracer.Start(ctx, "EventRepository.CountBookings")
+	defer span.End()
+	db := e.db.WithContext(ctx)
+	var count int64
+	err := db.Model(&models.EventUser{}).Where("event_id = ? AND status = ?", eventID, status).Count(&count).Error
+	if err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+// GetWaitlistPosition returns the 1-based position of a waitlisted booking.
+// Bookings are served in the order they were made.
+func (e EventRepositoryImpl) GetWaitlistPosition(ctx context.Context, booking models.EventUser) (int64, error) {
+	ctx, span := tracer.Start(ctx, "EventRepository.GetWaitlistPosition")
+	defer span.End()
+	db := e.db.WithContext(ctx)
+	var position int64
+	err := db.Model(&models.EventUser{}).
+		Where("event_id = ? AND status = ? AND id <= ?", booking.EventID, models.BookingWaitlisted, booking.ID).
+		Count(&position).Error
+	if err != nil {
+		return 0, err
+	}
+	return position, nil
+}
+
+// CancelBooking removes the user's booking and, if it held a seat, promotes
+// waitlisted bookings into the freed capacity. The promoted bookings are
+// returned with their Event and User loaded.
+func (e EventRepositoryImpl) CancelBooking(ctx context.Context, eventID int, userID int) ([]models.EventUser, error) {
+	ctx, span := tracer.Start(ctx, "EventRepository.CancelBooking")
+	defer span.End()
+	db := e.db.WithContext(ctx)
+	var promoted []models.EventUser
+	err := db.Transaction(func(tx *gorm.DB) error {
+		event, err := lockEvent(tx, eventID)
+		if err != nil {
+			return err
+		}
+
+		var booking models.EventUser
+		err = tx.Where("event_id = ? AND user_id = ?", eventID, userID).First(&booking).Error
+		if err != nil {
+			return err
+		}
+		// Bookings are removed for good so the same user can book again later
+		err = tx.Unscoped().Delete(&booking).Error
+		if err != nil {
+			return err
+		}
+
+		if booking.Status != models.BookingConfirmed {
+			return nil
+		}
+		promoted, err = promoteWaitlisted(tx, event)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return promoted, nil
+}
+
+// PromoteWaitlisted confirms waitlisted bookings, oldest first, until the
+// event is full again. The promoted bookings are returned with their Event and
+// User loaded.
+func (e EventRepositoryImpl) PromoteWaitlisted(ctx context.Context, eventID int) ([]models.EventUser, error) {
+	ctx, span := tracer.Start(ctx, "EventRepository.PromoteWaitlisted")
+	defer span.End()
+	db := e.db.WithContext(ctx)
+	var promoted []models.EventUser
+	err := db.Transaction(func(tx *gorm.DB) error {
+		event, err := lockEvent(tx, eventID)
+		if err != nil {
+			return err
+		}
+		promoted, err = promoteWaitlisted(tx, event)
+		return err
+	})
 	if err != nil {
 		return nil, err
 	}
-	err = db.AutoMigrate(&models.EventUser{})
+	return promoted, nil
+}
+
+// GetAttendees returns all bookings of the event with their users, in booking order.
+func (e EventRepositoryImpl) GetAttendees(ctx context.Context, eventID int) ([]models.EventUser, error) {
+	ctx, span := tracer.Start(ctx, "EventRepository.GetAttendees")
+	defer span.End()
+	db := e.db.WithContext(ctx)
+	var bookings []models.EventUser
+	err := db.Preload("User").Where("event_id = ?", eventID).Order("id").Find(&bookings).Error
 	if err != nil {
 		return nil, err
 	}
+	return bookings, nil
+}
+
+// StreamAttendees calls fn with every booking of the event, in booking order,
+// reading one row at a time. Only the name and email of the booking's user
+// are loaded. An error from fn stops the iteration and is returned.
+func (e EventRepositoryImpl) StreamAttendees(ctx context.Context, eventID int, fn func(booking models.EventUser) error) error {
+	ctx, span := tracer.Start(ctx, "EventRepository.StreamAttendees")
+	defer span.End()
+	db := e.db.WithContext(ctx)
+	rows, err := db.Model(&models.EventUser{}).
+		Select("event_users.id, event_users.user_id, event_users.status, event_users.created_at, users.name, users.email").
+		Joins("join users on users.id = event_users.user_id and users.deleted_at is null").
+		Where("event_users.event_id = ?", eventID).
+		Order("event_users.id").
+		Rows()
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		booking := models.EventUser{EventID: eventID}
+		err = rows.Scan(&booking.ID, &booking.UserID, &booking.Status, &booking.CreatedAt, &booking.User.Name, &booking.User.Email)
+		if err != nil {
+			return err
+		}
+		booking.User.ID = booking.UserID
+		err = fn(booking)
+		if err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+func (e EventRepositoryImpl) AddOrganizer(ctx context.Context, eventID int, userID int) (models.EventOrganizer, error) {
+	ctx, span := tracer.Start(ctx, "EventRepository.AddOrganizer")
+	defer span.End()
+	db := e.db.WithContext(ctx)
+	organizer := models.EventOrganizer{EventID: eventID, UserID: userID}
+	err := db.Create(&organizer).Error
+	if err != nil {
+		return models.EventOrganizer{}, err
+	}
+	return organizer, nil
+}
+
+func (e EventRepositoryImpl) RemoveOrganizer(ctx context.Context, eventID int, userID int) error {
+	ctx, span := tracer.Start(ctx, "EventRepository.RemoveOrganizer")
+	defer span.End()
+	db := e.db.WithContext(ctx)
+	// Hard delete so the user can be designated again later
+	result := db.Unscoped().Where("event_id = ? AND user_id = ?", eventID, userID).Delete(&models.EventOrganizer{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+func (e EventRepositoryImpl) IsOrganizer(ctx context.Context, eventID int, userID int) (bool, error) {
+	ctx, span := tracer.Start(ctx, "EventRepository.IsOrganizer")
+	defer span.End()
+	db := e.db.WithContext(ctx)
+	var count int64
+	err := db.Model(&models.EventOrganizer{}).Where("event_id = ? AND user_id = ?", eventID, userID).Count(&count).Error
+	if err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+func (e EventRepositoryImpl) GetOrganizers(ctx context.Context, eventID int) ([]models.EventOrganizer, error) {
+	ctx, span := tracer.Start(ctx, "EventRepository.GetOrganizers")
+	defer span.End()
+	db := e.db.WithContext(ctx)
+	var organizers []models.EventOrganizer
+	err := db.Preload("User").Where("event_id = ?", eventID).Order("id").Find(&organizers).Error
+	if err != nil {
+		return nil, err
+	}
+	return organizers, nil
+}
+
+func lockEvent(tx *gorm.DB, eventID int) (models.Event, error) {
+	var event models.Event
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("id = ?", eventID).First(&event).Error
+	if err != nil {
+		return models.Event{}, err
+	}
+	return event, nil
+}
+
+// promoteWaitlisted expects the event row to be locked by tx.
+func promoteWaitlisted(tx *gorm.DB, event models.Event) ([]models.EventUser, error) {
+	limit := -1 // unlimited capacity takes everybody off the waitlist
+	if event.Capacity > 0 {
+		var confirmed int64
+		err := tx.Model(&models.EventUser{}).Where("event_id = ? AND status = ?", event.ID, models.BookingConfirmed).Count(&confirmed).Error
+		if err != nil {
+			return nil, err
+		}
+		limit = event.Capacity - int(confirmed)
+		if limit <= 0 {
+			return nil, nil
+		}
+	}
+
+	var waitlisted []models.EventUser
+	err := tx.Preload("User").Where("event_id = ? AND status = ?", event.ID, models.BookingWaitlisted).Order("id").Limit(limit).Find(&waitlisted).Error
+	if err != nil {
+		return nil, err
+	}
+	for i := range waitlisted {
+		waitlisted[i].Event = event
+		waitlisted[i].Status = models.BookingConfirmed
+		err = tx.Model(&waitlisted[i]).Update("status", models.BookingConfirmed).Error
+		if err != nil {
+			return nil, err
+		}
+	}
+	return waitlisted, nil
+}
+
+func NewEventRepository(db *gorm.DB) (*EventRepositoryImpl, error) {
 	return &EventRepositoryImpl{
 		db: db,
 	}, nil
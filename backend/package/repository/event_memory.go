@@ -0,0 +1,511 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/HermanPlay/web-app-backend/package/domain/models"
+	"gorm.io/gorm"
+)
+
+// MemoryEventRepository keeps events, bookings and organizers in memory, for
+// unit tests that need no database. It is safe for concurrent use and
+// behaves like EventRepositoryImpl, users are looked up in the given
+// MemoryUserRepository. Like there, WithTx returns the repository itself.
+type MemoryEventRepository struct {
+	mu              sync.Mutex
+	users           *MemoryUserRepository
+	events          map[int]models.Event // including deleted ones, with DeletedAt set
+	bookings        []models.EventUser   // in booking order
+	organizers      []models.EventOrganizer
+	nextEventID     int
+	nextBookingID   int
+	nextOrganizerID int
+}
+
+func (e *MemoryEventRepository) WithTx(tx *gorm.DB) EventRepository {
+	return e
+}
+
+func (e *MemoryEventRepository) GetAll(ctx context.Context, query EventQuery) ([]models.Event, int64, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	events := e.filterEvents(query)
+	total := int64(len(events))
+	events, err := orderMemoryEvents(events, query)
+	if err != nil {
+		return nil, 0, err
+	}
+	return events, total, nil
+}
+
+func (e *MemoryEventRepository) StreamEvents(ctx context.Context, query EventQuery, fn func(event models.Event) error) error {
+	e.mu.Lock()
+	events, err := orderMemoryEvents(e.filterEvents(query), query)
+	e.mu.Unlock()
+	if err != nil {
+		return err
+	}
+	for _, event := range events {
+		err = fn(event)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (e *MemoryEventRepository) GetByID(ctx context.Context, id int) (models.Event, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	event, ok := e.liveEvent(id)
+	if !ok {
+		return models.Event{}, gorm.ErrRecordNotFound
+	}
+	return event, nil
+}
+
+// LockByID is GetByID, there are no transactions to keep the event locked in.
+func (e *MemoryEventRepository) LockByID(ctx context.Context, id int) (models.Event, error) {
+	return e.GetByID(ctx, id)
+}
+
+func (e *MemoryEventRepository) Save(ctx context.Context, event *models.Event) (models.Event, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if _, ok := e.events[event.ID]; ok {
+		return models.Event{}, gorm.ErrDuplicatedKey
+	}
+	return e.save(event)
+}
+
+// Update replaces the event, or creates it when there is none with its ID.
+func (e *MemoryEventRepository) Update(ctx context.Context, event *models.Event) (models.Event, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.save(event)
+}
+
+func (e *MemoryEventRepository) Delete(ctx context.Context, id int) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	event, ok := e.events[id]
+	if ok && !event.DeletedAt.Valid {
+		event.DeletedAt = gorm.DeletedAt{Time: time.Now(), Valid: true}
+		e.events[id] = event
+	}
+	return nil
+}
+
+func (e *MemoryEventRepository) GetFeaturedEvents(ctx context.Context) ([]models.Event, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.eventsWhere(func(event models.Event) bool { return event.IsFeatured }), nil
+}
+
+func (e *MemoryEventRepository) GetMyEvents(ctx context.Context, userId int) ([]models.Event, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.eventsWhere(func(event models.Event) bool {
+		_, ok := e.booking(event.ID, userId)
+		return ok
+	}), nil
+}
+
+func (e *MemoryEventRepository) GetCreatedEvents(ctx context.Context, userId int) ([]models.Event, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.eventsWhere(func(event models.Event) bool { return event.CreatedBy == userId }), nil
+}
+
+// BookEvent holds the repository lock for the whole booking, so concurrent
+// bookings cannot overbook the event.
+func (e *MemoryEventRepository) BookEvent(ctx context.Context, eventID int, userID int, policy BookingPolicy) (models.EventUser, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	event, ok := e.liveEvent(eventID)
+	if !ok {
+		return models.EventUser{}, gorm.ErrRecordNotFound
+	}
+	if _, ok := e.booking(eventID, userID); ok {
+		return models.EventUser{}, gorm.ErrDuplicatedKey
+	}
+	status, err := policy(event, e.countBookings(eventID, models.BookingConfirmed))
+	if err != nil {
+		return models.EventUser{}, err
+	}
+	user, live, exists := e.users.lookup(userID)
+	if !exists {
+		return models.EventUser{}, gorm.ErrForeignKeyViolated
+	}
+	if !live {
+		return models.EventUser{}, gorm.ErrRecordNotFound
+	}
+
+	if status == "" {
+		status = models.BookingConfirmed
+	}
+	now := time.Now()
+	e.nextBookingID++
+	booking := models.EventUser{
+		ID:        e.nextBookingID,
+		EventID:   eventID,
+		UserID:    userID,
+		Status:    status,
+		BaseModel: models.BaseModel{CreatedAt: now, UpdatedAt: now},
+	}
+	e.bookings = append(e.bookings, booking)
+	booking.Event = event
+	booking.User = user
+	return booking, nil
+}
+
+func (e *MemoryEventRepository) GetBooking(ctx context.Context, eventID int, userID int) (models.EventUser, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	booking, ok := e.booking(eventID, userID)
+	if !ok {
+		return models.EventUser{}, gorm.ErrRecordNotFound
+	}
+	return booking, nil
+}
+
+func (e *MemoryEventRepository) CountBookings(ctx context.Context, eventID int, status models.BookingStatus) (int64, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.countBookings(eventID, status), nil
+}
+
+func (e *MemoryEventRepository) GetWaitlistPosition(ctx context.Context, booking models.EventUser) (int64, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	var position int64
+	for _, other := range e.bookings {
+		if other.EventID == booking.EventID && other.Status == models.BookingWaitlisted && other.ID <= booking.ID {
+			position++
+		}
+	}
+	return position, nil
+}
+
+func (e *MemoryEventRepository) CancelBooking(ctx context.Context, eventID int, userID int) ([]models.EventUser, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	event, ok := e.liveEvent(eventID)
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+	booking, ok := e.booking(eventID, userID)
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+	for i := range e.bookings {
+		if e.bookings[i].ID == booking.ID {
+			e.bookings = append(e.bookings[:i], e.bookings[i+1:]...)
+			break
+		}
+	}
+
+	if booking.Status != models.BookingConfirmed {
+		return nil, nil
+	}
+	return e.promoteWaitlisted(event), nil
+}
+
+func (e *MemoryEventRepository) PromoteWaitlisted(ctx context.Context, eventID int) ([]models.EventUser, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	event, ok := e.liveEvent(eventID)
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return e.promoteWaitlisted(event), nil
+}
+
+func (e *MemoryEventRepository) GetAttendees(ctx context.Context, eventID int) ([]models.EventUser, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	bookings := []models.EventUser{}
+	for _, booking := range e.bookings {
+		if booking.EventID == eventID {
+			booking.User, _, _ = e.users.lookup(booking.UserID)
+			bookings = append(bookings, booking)
+		}
+	}
+	return bookings, nil
+}
+
+// StreamAttendees loads the same fields as EventRepositoryImpl does and
+// skips bookings of deleted users.
+func (e *MemoryEventRepository) StreamAttendees(ctx context.Context, eventID int, fn func(booking models.EventUser) error) error {
+	e.mu.Lock()
+	var bookings []models.EventUser
+	for _, booking := range e.bookings {
+		if booking.EventID != eventID {
+			continue
+		}
+		user, live, _ := e.users.lookup(booking.UserID)
+		if !live {
+			continue
+		}
+		bookings = append(bookings, models.EventUser{
+			ID:        booking.ID,
+			EventID:   eventID,
+			UserID:    booking.UserID,
+			User:      models.User{ID: user.ID, Name: user.Name, Email: user.Email},
+			Status:    booking.Status,
+			BaseModel: models.BaseModel{CreatedAt: booking.CreatedAt},
+		})
+	}
+	e.mu.Unlock()
+
+	for _, booking := range bookings {
+		err := fn(booking)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (e *MemoryEventRepository) AddOrganizer(ctx context.Context, eventID int, userID int) (models.EventOrganizer, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if _, ok := e.events[eventID]; !ok {
+		return models.EventOrganizer{}, gorm.ErrForeignKeyViolated
+	}
+	if _, _, exists := e.users.lookup(userID); !exists {
+		return models.EventOrganizer{}, gorm.ErrForeignKeyViolated
+	}
+	if e.isOrganizer(eventID, userID) {
+		return models.EventOrganizer{}, gorm.ErrDuplicatedKey
+	}
+
+	now := time.Now()
+	e.nextOrganizerID++
+	organizer := models.EventOrganizer{
+		ID:        e.nextOrganizerID,
+		EventID:   eventID,
+		UserID:    userID,
+		BaseModel: models.BaseModel{CreatedAt: now, UpdatedAt: now},
+	}
+	e.organizers = append(e.organizers, organizer)
+	return organizer, nil
+}
+
+func (e *MemoryEventRepository) RemoveOrganizer(ctx context.Context, eventID int, userID int) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	for i, organizer := range e.organizers {
+		if organizer.EventID == eventID && organizer.UserID == userID {
+			e.organizers = append(e.organizers[:i], e.organizers[i+1:]...)
+			return nil
+		}
+	}
+	return gorm.ErrRecordNotFound
+}
+
+func (e *MemoryEventRepository) IsOrganizer(ctx context.Context, eventID int, userID int) (bool, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.isOrganizer(eventID, userID), nil
+}
+
+func (e *MemoryEventRepository) GetOrganizers(ctx context.Context, eventID int) ([]models.EventOrganizer, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	organizers := []models.EventOrganizer{}
+	for _, organizer := range e.organizers {
+		if organizer.EventID == eventID {
+			organizer.User, _, _ = e.users.lookup(organizer.UserID)
+			organizers = append(organizers, organizer)
+		}
+	}
+	return organizers, nil
+}
+
+// The helpers below expect e.mu to be held.
+
+func (e *MemoryEventRepository) save(event *models.Event) (models.Event, error) {
+	if _, _, exists := e.users.lookup(event.CreatedBy); !exists {
+		return models.Event{}, gorm.ErrForeignKeyViolated
+	}
+	if event.TimeZone == "" {
+		event.TimeZone = "UTC"
+	}
+
+	now := time.Now()
+	if event.ID == 0 {
+		e.nextEventID++
+		event.ID = e.nextEventID
+	} else if event.ID > e.nextEventID {
+		e.nextEventID = event.ID
+	}
+	if event.CreatedAt.IsZero() {
+		event.CreatedAt = now
+		if stored, ok := e.events[event.ID]; ok {
+			event.CreatedAt = stored.CreatedAt
+		}
+	}
+	event.UpdatedAt = now
+	stored := *event
+	stored.User = models.User{}
+	stored.DeletedAt = gorm.DeletedAt{}
+	e.events[event.ID] = stored
+	return *event, nil
+}
+
+func (e *MemoryEventRepository) liveEvent(id int) (models.Event, bool) {
+	event, ok := e.events[id]
+	if !ok || event.DeletedAt.Valid {
+		return models.Event{}, false
+	}
+	event.DeletedAt = gorm.DeletedAt{}
+	return event, true
+}
+
+// eventsWhere returns the live events matching keep, by ID.
+func (e *MemoryEventRepository) eventsWhere(keep func(event models.Event) bool) []models.Event {
+	events := []models.Event{}
+	for id := range e.events {
+		event, ok := e.liveEvent(id)
+		if ok && keep(event) {
+			events = append(events, event)
+		}
+	}
+	sort.Slice(events, func(i, j int) bool { return events[i].ID < events[j].ID })
+	return events
+}
+
+func (e *MemoryEventRepository) filterEvents(query EventQuery) []models.Event {
+	search := strings.ToLower(query.Search)
+	location := strings.ToLower(query.Location)
+	return e.eventsWhere(func(event models.Event) bool {
+		if search != "" &&
+			!strings.Contains(strings.ToLower(event.Title), search) &&
+			!strings.Contains(strings.ToLower(event.ShortDescription), search) &&
+			!strings.Contains(strings.ToLower(event.Description), search) {
+			return false
+		}
+		if location != "" && !strings.Contains(strings.ToLower(event.Location), location) {
+			return false
+		}
+		if query.From != nil && event.StartsAt.Before(*query.From) {
+			return false
+		}
+		if query.To != nil && !event.StartsAt.Before(*query.To) {
+			return false
+		}
+		if query.Featured != nil && event.IsFeatured != *query.Featured {
+			return false
+		}
+		if query.ManagedBy != 0 && event.CreatedBy != query.ManagedBy && !e.isOrganizer(event.ID, query.ManagedBy) {
+			return false
+		}
+		return true
+	})
+}
+
+func (e *MemoryEventRepository) booking(eventID int, userID int) (models.EventUser, bool) {
+	for _, booking := range e.bookings {
+		if booking.EventID == eventID && booking.UserID == userID {
+			return booking, true
+		}
+	}
+	return models.EventUser{}, false
+}
+
+func (e *MemoryEventRepository) countBookings(eventID int, status models.BookingStatus) int64 {
+	var count int64
+	for _, booking := range e.bookings {
+		if booking.EventID == eventID && booking.Status == status {
+			count++
+		}
+	}
+	return count
+}
+
+func (e *MemoryEventRepository) isOrganizer(eventID int, userID int) bool {
+	for _, organizer := range e.organizers {
+		if organizer.EventID == eventID && organizer.UserID == userID {
+			return true
+		}
+	}
+	return false
+}
+
+func (e *MemoryEventRepository) promoteWaitlisted(event models.Event) []models.EventUser {
+	limit := -1
+	if event.Capacity > 0 {
+		limit = event.Capacity - int(e.countBookings(event.ID, models.BookingConfirmed))
+		if limit <= 0 {
+			return nil
+		}
+	}
+
+	var promoted []models.EventUser
+	for i := range e.bookings {
+		if len(promoted) == limit {
+			break
+		}
+		booking := &e.bookings[i]
+		if booking.EventID != event.ID || booking.Status != models.BookingWaitlisted {
+			continue
+		}
+		booking.Status = models.BookingConfirmed
+		booking.UpdatedAt = time.Now()
+		result := *booking
+		result.Event = event
+		result.User, _, _ = e.users.lookup(booking.UserID)
+		promoted = append(promoted, result)
+	}
+	return promoted
+}
+
+// memoryEventOrder compares events by the columns EventQuery.OrderBy may name.
+var memoryEventOrder = map[string]func(a, b models.Event) int{
+	"starts_at":  func(a, b models.Event) int { return a.StartsAt.Compare(b.StartsAt) },
+	"title":      func(a, b models.Event) int { return strings.Compare(a.Title, b.Title) },
+	"created_at": func(a, b models.Event) int { return a.CreatedAt.Compare(b.CreatedAt) },
+}
+
+// orderMemoryEvents sorts and pages events like orderEvents does.
+func orderMemoryEvents(events []models.Event, query EventQuery) ([]models.Event, error) {
+	orderBy := query.OrderBy
+	if orderBy == "" {
+		orderBy = "starts_at"
+	}
+	compare, ok := memoryEventOrder[orderBy]
+	if !ok {
+		return nil, fmt.Errorf("cannot order events by %q", orderBy)
+	}
+	sort.SliceStable(events, func(i, j int) bool {
+		c := compare(events[i], events[j])
+		if query.Descending {
+			c = -c
+		}
+		if c != 0 {
+			return c < 0
+		}
+		return events[i].ID < events[j].ID
+	})
+
+	if query.Offset > 0 {
+		events = events[min(query.Offset, len(events)):]
+	}
+	if query.Limit > 0 {
+		events = events[:min(query.Limit, len(events))]
+	}
+	return events, nil
+}
+
+func NewMemoryEventRepository(users *MemoryUserRepository) *MemoryEventRepository {
+	return &MemoryEventRepository{
+		users:  users,
+		events: map[int]models.Event{},
+	}
+}
@@ -1,6 +1,11 @@
 package repository
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -21,21 +26,85 @@ func TestNewEventRepository(t *testing.T) {
 }
 
 func TestGetAll(t *testing.T) {
+	ctx := context.Background()
 	db := utils.ConnectToTestDatabase()
 	eventRepo, _ := NewEventRepository(db)
-	events, err := eventRepo.GetAll()
+	events, total, err := eventRepo.GetAll(ctx, EventQuery{})
 	if err != nil {
 		t.Errorf("Error when get all events, when not expected. Error: %v", err)
 	}
-	if len(events) != 0 {
+	if len(events) != 0 || total != 0 {
 		t.Errorf("Events is not empty, when expected")
 	}
 }
 
+func TestGetAllWithQuery(t *testing.T) {
+	ctx := context.Background()
+	db := utils.ConnectToTestDatabase()
+	eventRepo, _ := NewEventRepository(db)
+	users := createUsers(db, 1)
+	start := time.Date(2024, 6, 1, 18, 0, 0, 0, time.UTC)
+	inputs := []models.Event{
+		{Title: "Jazz Night", Description: "Live music", Location: "Warsaw", StartsAt: start, IsFeatured: true},
+		{Title: "Go meetup", Description: "Talks about 100% jazz-free code", Location: "Krakow", StartsAt: start.AddDate(0, 0, 1)},
+		{Title: "Art fair", Description: "Paintings", Location: "warsaw old town", StartsAt: start.AddDate(0, 0, 2)},
+		{Title: "Book club", Description: "Reading_group", Location: "Gdansk", StartsAt: start.AddDate(0, 0, -1), IsFeatured: true},
+	}
+	for i := range inputs {
+		inputs[i].ShortDescription = "short description"
+		inputs[i].EndsAt = inputs[i].StartsAt.Add(time.Hour)
+		inputs[i].TimeZone = "UTC"
+		inputs[i].CreatedBy = users[0].ID
+		_, err := eventRepo.Save(ctx, &inputs[i])
+		if err != nil {
+			t.Fatalf("Error when save event, when not expected. Error: %v", err)
+		}
+	}
+	featured := true
+	from := start
+	to := start.AddDate(0, 0, 2)
+
+	tests := []struct {
+		name   string
+		query  EventQuery
+		titles []string
+		total  int64
+	}{
+		{"No filters", EventQuery{}, []string{"Book club", "Jazz Night", "Go meetup", "Art fair"}, 4},
+		{"Search is case-insensitive", EventQuery{Search: "JAZZ"}, []string{"Jazz Night", "Go meetup"}, 2},
+		{"Search escapes wildcards", EventQuery{Search: "100%"}, []string{"Go meetup"}, 1},
+		{"Search escapes underscore", EventQuery{Search: "g_oup"}, []string{}, 0},
+		{"Location", EventQuery{Location: "Warsaw"}, []string{"Jazz Night", "Art fair"}, 2},
+		{"Date range", EventQuery{From: &from, To: &to}, []string{"Jazz Night", "Go meetup"}, 2},
+		{"Featured", EventQuery{Featured: &featured}, []string{"Book club", "Jazz Night"}, 2},
+		{"Order by title descending", EventQuery{OrderBy: "title", Descending: true}, []string{"Jazz Night", "Go meetup", "Book club", "Art fair"}, 4},
+		{"Second page", EventQuery{Limit: 3, Offset: 3}, []string{"Art fair"}, 4},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			events, total, err := eventRepo.GetAll(ctx, tt.query)
+			if err != nil {
+				t.Fatalf("Error when get all events, when not expected. Error: %v", err)
+			}
+			if total != tt.total {
+				t.Errorf("Total is %d, when expected %d", total, tt.total)
+			}
+			titles := []string{}
+			for _, event := range events {
+				titles = append(titles, event.Title)
+			}
+			if fmt.Sprint(titles) != fmt.Sprint(tt.titles) {
+				t.Errorf("Events are %v, when expected %v", titles, tt.titles)
+			}
+		})
+	}
+}
+
 func TestGetByID(t *testing.T) {
+	ctx := context.Background()
 	db := utils.ConnectToTestDatabase()
 	eventRepo, _ := NewEventRepository(db)
-	event, err := eventRepo.GetByID(1)
+	event, err := eventRepo.GetByID(ctx, 1)
 	if err == nil {
 		t.Errorf("Error is nil, when expected")
 	}
@@ -45,6 +114,7 @@ func TestGetByID(t *testing.T) {
 }
 
 func TestSaveEvent(t *testing.T) {
+	ctx := context.Background()
 	db := utils.ConnectToTestDatabase()
 	eventRepo, _ := NewEventRepository(db)
 	want := models.Event{
@@ -52,12 +122,13 @@ func TestSaveEvent(t *testing.T) {
 		ShortDescription: "short description",
 		Description:      "description",
 		Location:         "location",
-		Date:             time.Now().Format("2006-01-02"),
-		Time:             time.Now().Format("15:04"),
+		StartsAt:         time.Now().UTC().Truncate(time.Second),
+		EndsAt:           time.Now().UTC().Truncate(time.Second).Add(time.Hour),
+		TimeZone:         "UTC",
 		CreatedBy:        1,
 	}
 	createUser(db)
-	got, err := eventRepo.Save(&want)
+	got, err := eventRepo.Save(ctx, &want)
 	if err != nil {
 		t.Errorf("Ereor when save event, when not expected. Error: %v", err)
 		return
@@ -66,6 +137,7 @@ func TestSaveEvent(t *testing.T) {
 }
 
 func TestUpdateEvent(t *testing.T) {
+	ctx := context.Background()
 	db := utils.ConnectToTestDatabase()
 	eventRepo, _ := NewEventRepository(db)
 	want := models.Event{
@@ -73,12 +145,13 @@ func TestUpdateEvent(t *testing.T) {
 		ShortDescription: "short description",
 		Description:      "description",
 		Location:         "location",
-		Date:             time.Now().Format("2006-01-02"),
-		Time:             time.Now().Format("15:04"),
+		StartsAt:         time.Now().UTC().Truncate(time.Second),
+		EndsAt:           time.Now().UTC().Truncate(time.Second).Add(time.Hour),
+		TimeZone:         "UTC",
 		CreatedBy:        1,
 	}
 	createUser(db)
-	got, err := eventRepo.Save(&want)
+	got, err := eventRepo.Save(ctx, &want)
 	if err != nil {
 		t.Errorf("Error when save event, when not expected. Error: %v", err)
 	}
@@ -87,16 +160,51 @@ func TestUpdateEvent(t *testing.T) {
 	want.ShortDescription = "short description2"
 	want.Description = "description2"
 	want.Location = "location2"
-	want.Date = time.Now().AddDate(0, 0, 1).Format("2006-01-02")
-	want.Time = time.Now().Add(1 * time.Hour).Format("15:04")
-	got, err = eventRepo.Update(&want)
+	want.StartsAt = want.StartsAt.AddDate(0, 0, 1)
+	want.EndsAt = want.EndsAt.AddDate(0, 0, 1)
+	want.TimeZone = "Europe/Warsaw"
+	got, err = eventRepo.Update(ctx, &want)
 	if err != nil {
 		t.Errorf("Error when update event, when not expected. Error: %v", err)
 	}
 	compareEvent(t, got, want)
 }
 
+func TestLockByID(t *testing.T) {
+	ctx := context.Background()
+	db := utils.ConnectToTestDatabase()
+	eventRepo, _ := NewEventRepository(db)
+	want := models.Event{
+		Title:            "event",
+		ShortDescription: "short description",
+		Description:      "description",
+		Location:         "location",
+		StartsAt:         time.Now().UTC().Truncate(time.Second),
+		EndsAt:           time.Now().UTC().Truncate(time.Second).Add(time.Hour),
+		TimeZone:         "UTC",
+		CreatedBy:        1,
+	}
+	createUser(db)
+	eventRepo.Save(ctx, &want)
+	err := NewTransactor(db).Transaction(ctx, func(tx *gorm.DB) error {
+		got, err := eventRepo.WithTx(tx).LockByID(ctx, want.ID)
+		if err != nil {
+			return err
+		}
+		compareEvent(t, got, want)
+		_, err = eventRepo.WithTx(tx).LockByID(ctx, want.ID+1)
+		if err != gorm.ErrRecordNotFound {
+			t.Errorf("Error is not ErrRecordNotFound, when expected. Error: %v", err)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Errorf("Error when lock event, when not expected. Error: %v", err)
+	}
+}
+
 func TestDeleteEvent(t *testing.T) {
+	ctx := context.Background()
 	db := utils.ConnectToTestDatabase()
 	eventRepo, _ := NewEventRepository(db)
 	want := models.Event{
@@ -104,26 +212,28 @@ func TestDeleteEvent(t *testing.T) {
 		ShortDescription: "short description",
 		Description:      "description",
 		Location:         "location",
-		Date:             time.Now().Format("2006-01-02"),
-		Time:             time.Now().Format("15:04"),
+		StartsAt:         time.Now().UTC().Truncate(time.Second),
+		EndsAt:           time.Now().UTC().Truncate(time.Second).Add(time.Hour),
+		TimeZone:         "UTC",
 		CreatedBy:        1,
 	}
 	createUser(db)
-	got, err := eventRepo.Save(&want)
+	got, err := eventRepo.Save(ctx, &want)
 	if err != nil {
 		t.Errorf("Error when save event, when not expected. Error: %v", err)
 	}
 	compareEvent(t, got, want)
-	err = eventRepo.Delete(got.ID)
+	err = eventRepo.Delete(ctx, got.ID)
 	if err != nil {
 		t.Errorf("Error when delete event, when not expected. Error: %v", err)
 	}
 }
 
 func TestGetFeaturedEvents(t *testing.T) {
+	ctx := context.Background()
 	db := utils.ConnectToTestDatabase()
 	eventRepo, _ := NewEventRepository(db)
-	events, err := eventRepo.GetFeaturedEvents()
+	events, err := eventRepo.GetFeaturedEvents(ctx)
 	if err != nil {
 		t.Errorf("Error when get featured events, when not expected. Error: %v", err)
 	}
@@ -133,9 +243,10 @@ func TestGetFeaturedEvents(t *testing.T) {
 }
 
 func TestGetCreatedEvents(t *testing.T) {
+	ctx := context.Background()
 	db := utils.ConnectToTestDatabase()
 	eventRepo, _ := NewEventRepository(db)
-	events, err := eventRepo.GetCreatedEvents(1)
+	events, err := eventRepo.GetCreatedEvents(ctx, 1)
 	if err != nil {
 		t.Errorf("Error when get created events, when not expected. Error: %v", err)
 	}
@@ -145,9 +256,10 @@ func TestGetCreatedEvents(t *testing.T) {
 }
 
 func TestGetMyEvents(t *testing.T) {
+	ctx := context.Background()
 	db := utils.ConnectToTestDatabase()
 	eventRepo, _ := NewEventRepository(db)
-	events, err := eventRepo.GetMyEvents(1)
+	events, err := eventRepo.GetMyEvents(ctx, 1)
 	if err != nil {
 		t.Errorf("Error when get my events, when not expected. Error: %v", err)
 	}
@@ -157,6 +269,7 @@ func TestGetMyEvents(t *testing.T) {
 }
 
 func TestBookEvent(t *testing.T) {
+	ctx := context.Background()
 	db := utils.ConnectToTestDatabase()
 	eventRepo, _ := NewEventRepository(db)
 	event := models.Event{
@@ -164,19 +277,24 @@ func TestBookEvent(t *testing.T) {
 		ShortDescription: "short description",
 		Description:      "description",
 		Location:         "location",
-		Date:             time.Now().Format("2006-01-02"),
-		Time:             time.Now().Format("15:04"),
+		StartsAt:         time.Now().UTC().Truncate(time.Second),
+		EndsAt:           time.Now().UTC().Truncate(time.Second).Add(time.Hour),
+		TimeZone:         "UTC",
 		CreatedBy:        1,
 	}
 	createUser(db)
-	eventRepo.Save(&event)
-	err := eventRepo.BookEvent(1, 1)
+	eventRepo.Save(ctx, &event)
+	booking, err := eventRepo.BookEvent(ctx, 1, 1, withStatus(models.BookingConfirmed))
 	if err != nil {
 		t.Errorf("Error when book event, when not expected. Error: %v", err)
 	}
+	if booking.Status != models.BookingConfirmed {
+		t.Errorf("Booking status is not same, got: %s, want: %s", booking.Status, models.BookingConfirmed)
+	}
 }
 
 func TestGetBooking(t *testing.T) {
+	ctx := context.Background()
 	db := utils.ConnectToTestDatabase()
 	eventRepo, _ := NewEventRepository(db)
 	event := models.Event{
@@ -184,14 +302,15 @@ func TestGetBooking(t *testing.T) {
 		ShortDescription: "short description",
 		Description:      "description",
 		Location:         "location",
-		Date:             time.Now().Format("2006-01-02"),
-		Time:             time.Now().Format("15:04"),
+		StartsAt:         time.Now().UTC().Truncate(time.Second),
+		EndsAt:           time.Now().UTC().Truncate(time.Second).Add(time.Hour),
+		TimeZone:         "UTC",
 		CreatedBy:        1,
 	}
 	createUser(db)
-	eventRepo.Save(&event)
-	eventRepo.BookEvent(1, 1)
-	booking, err := eventRepo.GetBooking(1, 1)
+	eventRepo.Save(ctx, &event)
+	eventRepo.BookEvent(ctx, 1, 1, withStatus(models.BookingConfirmed))
+	booking, err := eventRepo.GetBooking(ctx, 1, 1)
 	if err != nil {
 		t.Errorf("Error when get booking, when not expected. Error: %v", err)
 	}
@@ -205,14 +324,458 @@ func TestGetBooking(t *testing.T) {
 
 }
 
+func TestCountBookings(t *testing.T) {
+	ctx := context.Background()
+	db := utils.ConnectToTestDatabase()
+	eventRepo, _ := NewEventRepository(db)
+	event := models.Event{
+		Title:            "event",
+		ShortDescription: "short description",
+		Description:      "description",
+		Location:         "location",
+		StartsAt:         time.Now().UTC().Truncate(time.Second),
+		EndsAt:           time.Now().UTC().Truncate(time.Second).Add(time.Hour),
+		TimeZone:         "UTC",
+		CreatedBy:        1,
+	}
+	users := createUsers(db, 3)
+	eventRepo.Save(ctx, &event)
+	eventRepo.BookEvent(ctx, event.ID, users[0].ID, withStatus(models.BookingConfirmed))
+	eventRepo.BookEvent(ctx, event.ID, users[1].ID, withStatus(models.BookingConfirmed))
+	eventRepo.BookEvent(ctx, event.ID, users[2].ID, withStatus(models.BookingWaitlisted))
+
+	confirmed, err := eventRepo.CountBookings(ctx, event.ID, models.BookingConfirmed)
+	if err != nil {
+		t.Errorf("Error when count bookings, when not expected. Error: %v", err)
+	}
+	if confirmed != 2 {
+		t.Errorf("Confirmed count is not same, got: %d, want: %d", confirmed, 2)
+	}
+	waitlisted, err := eventRepo.CountBookings(ctx, event.ID, models.BookingWaitlisted)
+	if err != nil {
+		t.Errorf("Error when count bookings, when not expected. Error: %v", err)
+	}
+	if waitlisted != 1 {
+		t.Errorf("Waitlisted count is not same, got: %d, want: %d", waitlisted, 1)
+	}
+}
+
+func TestGetWaitlistPosition(t *testing.T) {
+	ctx := context.Background()
+	db := utils.ConnectToTestDatabase()
+	eventRepo, _ := NewEventRepository(db)
+	event := models.Event{
+		Title:            "event",
+		ShortDescription: "short description",
+		Description:      "description",
+		Location:         "location",
+		StartsAt:         time.Now().UTC().Truncate(time.Second),
+		EndsAt:           time.Now().UTC().Truncate(time.Second).Add(time.Hour),
+		TimeZone:         "UTC",
+		CreatedBy:        1,
+	}
+	users := createUsers(db, 3)
+	eventRepo.Save(ctx, &event)
+	eventRepo.BookEvent(ctx, event.ID, users[0].ID, withStatus(models.BookingWaitlisted))
+	eventRepo.BookEvent(ctx, event.ID, users[1].ID, withStatus(models.BookingConfirmed))
+	booking, _ := eventRepo.BookEvent(ctx, event.ID, users[2].ID, withStatus(models.BookingWaitlisted))
+
+	position, err := eventRepo.GetWaitlistPosition(ctx, booking)
+	if err != nil {
+		t.Errorf("Error when get waitlist position, when not expected. Error: %v", err)
+	}
+	if position != 2 {
+		t.Errorf("Waitlist position is not same, got: %d, want: %d", position, 2)
+	}
+}
+
+func TestCancelBooking(t *testing.T) {
+	ctx := context.Background()
+	db := utils.ConnectToTestDatabase()
+	eventRepo, _ := NewEventRepository(db)
+	event := models.Event{
+		Title:            "event",
+		ShortDescription: "short description",
+		Description:      "description",
+		Location:         "location",
+		StartsAt:         time.Now().UTC().Truncate(time.Second),
+		EndsAt:           time.Now().UTC().Truncate(time.Second).Add(time.Hour),
+		TimeZone:         "UTC",
+		Capacity:         1,
+		CreatedBy:        1,
+	}
+	users := createUsers(db, 3)
+	eventRepo.Save(ctx, &event)
+	eventRepo.BookEvent(ctx, event.ID, users[0].ID, withStatus(models.BookingConfirmed))
+	first, _ := eventRepo.BookEvent(ctx, event.ID, users[1].ID, withStatus(models.BookingWaitlisted))
+	eventRepo.BookEvent(ctx, event.ID, users[2].ID, withStatus(models.BookingWaitlisted))
+
+	t.Run("Non existing booking", func(t *testing.T) {
+		_, err := eventRepo.CancelBooking(ctx, event.ID, 1000)
+		if err != gorm.ErrRecordNotFound {
+			t.Errorf("Error is not gorm.ErrRecordNotFound, when expected. Error: %v", err)
+		}
+	})
+	t.Run("Promote first waitlisted", func(t *testing.T) {
+		promoted, err := eventRepo.CancelBooking(ctx, event.ID, users[0].ID)
+		if err != nil {
+			t.Errorf("Error when cancel booking, when not expected. Error: %v", err)
+		}
+		if len(promoted) != 1 || promoted[0].ID != first.ID {
+			t.Errorf("Promoted bookings are not same, got: %v, want: %v", promoted, first)
+		}
+		booking, _ := eventRepo.GetBooking(ctx, event.ID, users[1].ID)
+		if booking.Status != models.BookingConfirmed {
+			t.Errorf("Booking status is not same, got: %s, want: %s", booking.Status, models.BookingConfirmed)
+		}
+		_, err = eventRepo.GetBooking(ctx, event.ID, users[0].ID)
+		if err != gorm.ErrRecordNotFound {
+			t.Errorf("Error is not gorm.ErrRecordNotFound, when expected. Error: %v", err)
+		}
+	})
+	t.Run("Book again", func(t *testing.T) {
+		_, err := eventRepo.BookEvent(ctx, event.ID, users[0].ID, withStatus(models.BookingWaitlisted))
+		if err != nil {
+			t.Errorf("Error when book event again, when not expected. Error: %v", err)
+		}
+	})
+}
+
+func TestPromoteWaitlisted(t *testing.T) {
+	ctx := context.Background()
+	db := utils.ConnectToTestDatabase()
+	eventRepo, _ := NewEventRepository(db)
+	event := models.Event{
+		Title:            "event",
+		ShortDescription: "short description",
+		Description:      "description",
+		Location:         "location",
+		StartsAt:         time.Now().UTC().Truncate(time.Second),
+		EndsAt:           time.Now().UTC().Truncate(time.Second).Add(time.Hour),
+		TimeZone:         "UTC",
+		Capacity:         2,
+		CreatedBy:        1,
+	}
+	users := createUsers(db, 3)
+	eventRepo.Save(ctx, &event)
+	eventRepo.BookEvent(ctx, event.ID, users[0].ID, withStatus(models.BookingConfirmed))
+	eventRepo.BookEvent(ctx, event.ID, users[1].ID, withStatus(models.BookingWaitlisted))
+	eventRepo.BookEvent(ctx, event.ID, users[2].ID, withStatus(models.BookingWaitlisted))
+
+	promoted, err := eventRepo.PromoteWaitlisted(ctx, event.ID)
+	if err != nil {
+		t.Errorf("Error when promote waitlisted, when not expected. Error: %v", err)
+	}
+	if len(promoted) != 1 {
+		t.Errorf("Promoted count is not same, got: %d, want: %d", len(promoted), 1)
+	}
+	confirmed, _ := eventRepo.CountBookings(ctx, event.ID, models.BookingConfirmed)
+	if confirmed != 2 {
+		t.Errorf("Confirmed count is not same, got: %d, want: %d", confirmed, 2)
+	}
+}
+
+func TestBookEventConcurrently(t *testing.T) {
+	ctx := context.Background()
+	const capacity = 5
+	const bookings = 25
+	db := utils.ConnectToTestDatabase()
+	eventRepo, _ := NewEventRepository(db)
+	event := models.Event{
+		Title:            "event",
+		ShortDescription: "short description",
+		Description:      "description",
+		Location:         "location",
+		StartsAt:         time.Now().UTC().Truncate(time.Second),
+		EndsAt:           time.Now().UTC().Truncate(time.Second).Add(time.Hour),
+		TimeZone:         "UTC",
+		Capacity:         capacity,
+		CreatedBy:        1,
+	}
+	users := createUsers(db, bookings)
+	eventRepo.Save(ctx, &event)
+
+	t.Run("Last seats", func(t *testing.T) {
+		var wg sync.WaitGroup
+		var booked, full atomic.Int64
+		for _, user := range users {
+			wg.Add(1)
+			go func(userID int) {
+				defer wg.Done()
+				_, err := eventRepo.BookEvent(ctx, event.ID, userID, capacityPolicy)
+				switch {
+				case err == nil:
+					booked.Add(1)
+				case errors.Is(err, errEventFull):
+					full.Add(1)
+				default:
+					t.Errorf("Error when book event, when not expected. Error: %v", err)
+				}
+			}(user.ID)
+		}
+		wg.Wait()
+		if booked.Load() != capacity {
+			t.Errorf("Booked count is not same, got: %d, want: %d", booked.Load(), capacity)
+		}
+		if full.Load() != bookings-capacity {
+			t.Errorf("Rejected count is not same, got: %d, want: %d", full.Load(), bookings-capacity)
+		}
+		confirmed, _ := eventRepo.CountBookings(ctx, event.ID, models.BookingConfirmed)
+		if confirmed != capacity {
+			t.Errorf("Confirmed count is not same, got: %d, want: %d", confirmed, capacity)
+		}
+	})
+	t.Run("Same user", func(t *testing.T) {
+		eventRepo.CancelBooking(ctx, event.ID, users[0].ID)
+		var wg sync.WaitGroup
+		var booked, duplicated atomic.Int64
+		for i := 0; i < bookings; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				_, err := eventRepo.BookEvent(ctx, event.ID, users[0].ID, withStatus(models.BookingConfirmed))
+				switch {
+				case err == nil:
+					booked.Add(1)
+				case errors.Is(err, gorm.ErrDuplicatedKey):
+					duplicated.Add(1)
+				default:
+					t.Errorf("Error when book event, when not expected. Error: %v", err)
+				}
+			}()
+		}
+		wg.Wait()
+		if booked.Load() != 1 {
+			t.Errorf("Booked count is not same, got: %d, want: %d", booked.Load(), 1)
+		}
+		if duplicated.Load() != bookings-1 {
+			t.Errorf("Duplicated count is not same, got: %d, want: %d", duplicated.Load(), bookings-1)
+		}
+	})
+}
+
+func TestGetAttendees(t *testing.T) {
+	ctx := context.Background()
+	db := utils.ConnectToTestDatabase()
+	eventRepo, _ := NewEventRepository(db)
+	event := models.Event{
+		Title:            "event",
+		ShortDescription: "short description",
+		Description:      "description",
+		Location:         "location",
+		StartsAt:         time.Now().UTC().Truncate(time.Second),
+		EndsAt:           time.Now().UTC().Truncate(time.Second).Add(time.Hour),
+		TimeZone:         "UTC",
+		CreatedBy:        1,
+	}
+	users := createUsers(db, 2)
+	eventRepo.Save(ctx, &event)
+	eventRepo.BookEvent(ctx, event.ID, users[1].ID, withStatus(models.BookingConfirmed))
+	eventRepo.BookEvent(ctx, event.ID, users[0].ID, withStatus(models.BookingWaitlisted))
+
+	attendees, err := eventRepo.GetAttendees(ctx, event.ID)
+	if err != nil {
+		t.Errorf("Error when get attendees, when not expected. Error: %v", err)
+	}
+	if len(attendees) != 2 {
+		t.Errorf("Attendees count is not same, got: %d, want: %d", len(attendees), 2)
+		return
+	}
+	if attendees[0].UserID != users[1].ID || attendees[0].User.Email != users[1].Email {
+		t.Errorf("First attendee is not same, got: %v, want: %v", attendees[0].User, users[1])
+	}
+	if attendees[1].Status != models.BookingWaitlisted {
+		t.Errorf("Booking status is not same, got: %s, want: %s", attendees[1].Status, models.BookingWaitlisted)
+	}
+}
+
+func TestStreamAttendees(t *testing.T) {
+	ctx := context.Background()
+	db := utils.ConnectToTestDatabase()
+	eventRepo, _ := NewEventRepository(db)
+	users := createUsers(db, 2)
+	event, err := eventRepo.Save(ctx, &models.Event{
+		Title:     "event",
+		StartsAt:  time.Now().UTC(),
+		EndsAt:    time.Now().UTC().Add(time.Hour),
+		TimeZone:  "UTC",
+		CreatedBy: users[0].ID,
+	})
+	if err != nil {
+		t.Fatalf("Error when save event, when not expected. Error: %v", err)
+	}
+	eventRepo.BookEvent(ctx, event.ID, users[1].ID, withStatus(models.BookingConfirmed))
+	eventRepo.BookEvent(ctx, event.ID, users[0].ID, withStatus(models.BookingWaitlisted))
+
+	var attendees []models.EventUser
+	err = eventRepo.StreamAttendees(ctx, event.ID, func(booking models.EventUser) error {
+		attendees = append(attendees, booking)
+		return nil
+	})
+	if err != nil {
+		t.Errorf("Error when stream attendees, when not expected. Error: %v", err)
+	}
+	if len(attendees) != 2 {
+		t.Fatalf("Attendees count is not same, got: %d, want: %d", len(attendees), 2)
+	}
+	if attendees[0].UserID != users[1].ID || attendees[0].User.Name != users[1].Name || attendees[0].User.Email != users[1].Email || attendees[0].CreatedAt.IsZero() {
+		t.Errorf("First attendee is not same, got: %+v, want: %v", attendees[0], users[1])
+	}
+	if attendees[1].Status != models.BookingWaitlisted {
+		t.Errorf("Booking status is not same, got: %s, want: %s", attendees[1].Status, models.BookingWaitlisted)
+	}
+
+	stop := errors.New("stop")
+	calls := 0
+	err = eventRepo.StreamAttendees(ctx, event.ID, func(booking models.EventUser) error {
+		calls++
+		return stop
+	})
+	if err != stop || calls != 1 {
+		t.Errorf("Stream did not stop at the first error, got: %v after %d calls", err, calls)
+	}
+}
+
+func TestStreamEvents(t *testing.T) {
+	ctx := context.Background()
+	db := utils.ConnectToTestDatabase()
+	eventRepo, _ := NewEventRepository(db)
+	users := createUsers(db, 3)
+	start := time.Now().UTC().Truncate(time.Second)
+	var saved []models.Event
+	for i, createdBy := range []int{users[0].ID, users[1].ID, users[2].ID} {
+		event, err := eventRepo.Save(ctx, &models.Event{
+			Title:     fmt.Sprintf("event %d", i),
+			StartsAt:  start.Add(time.Duration(3-i) * time.Hour),
+			EndsAt:    start.Add(time.Duration(4-i) * time.Hour),
+			TimeZone:  "UTC",
+			CreatedBy: createdBy,
+		})
+		if err != nil {
+			t.Fatalf("Error when save event, when not expected. Error: %v", err)
+		}
+		saved = append(saved, event)
+	}
+	_, err := eventRepo.AddOrganizer(ctx, saved[1].ID, users[0].ID)
+	if err != nil {
+		t.Fatalf("Error when add organizer, when not expected. Error: %v", err)
+	}
+
+	stream := func(query EventQuery) []int {
+		var ids []int
+		err := eventRepo.StreamEvents(ctx, query, func(event models.Event) error {
+			ids = append(ids, event.ID)
+			return nil
+		})
+		if err != nil {
+			t.Errorf("Error when stream events, when not expected. Error: %v", err)
+		}
+		return ids
+	}
+	if ids := stream(EventQuery{}); fmt.Sprint(ids) != fmt.Sprint([]int{saved[2].ID, saved[1].ID, saved[0].ID}) {
+		t.Errorf("Events are not same, got: %v, want all of them by start time", ids)
+	}
+	if ids := stream(EventQuery{ManagedBy: users[0].ID}); fmt.Sprint(ids) != fmt.Sprint([]int{saved[1].ID, saved[0].ID}) {
+		t.Errorf("Managed events are not same, got: %v, want the created and the co-organized event", ids)
+	}
+	if ids := stream(EventQuery{ManagedBy: users[0].ID, Search: "event 0"}); fmt.Sprint(ids) != fmt.Sprint([]int{saved[0].ID}) {
+		t.Errorf("Filtered managed events are not same, got: %v", ids)
+	}
+}
+
+func TestOrganizers(t *testing.T) {
+	ctx := context.Background()
+	db := utils.ConnectToTestDatabase()
+	eventRepo, _ := NewEventRepository(db)
+	users := createUsers(db, 2)
+	event, err := eventRepo.Save(ctx, &models.Event{
+		Title:     "event",
+		StartsAt:  time.Now().UTC(),
+		EndsAt:    time.Now().UTC().Add(time.Hour),
+		TimeZone:  "UTC",
+		CreatedBy: users[0].ID,
+	})
+	if err != nil {
+		t.Fatalf("Error when save event, when not expected. Error: %v", err)
+	}
+	_, err = eventRepo.AddOrganizer(ctx, event.ID, users[1].ID)
+	if err != nil {
+		t.Fatalf("Error when add organizer, when not expected. Error: %v", err)
+	}
+	_, err = eventRepo.AddOrganizer(ctx, event.ID, users[1].ID)
+	if !errors.Is(err, gorm.ErrDuplicatedKey) {
+		t.Errorf("Error is %v, when expected gorm.ErrDuplicatedKey", err)
+	}
+	for userID, want := range map[int]bool{users[0].ID: false, users[1].ID: true} {
+		isOrganizer, err := eventRepo.IsOrganizer(ctx, event.ID, userID)
+		if err != nil {
+			t.Errorf("Error when check organizer, when not expected. Error: %v", err)
+		}
+		if isOrganizer != want {
+			t.Errorf("IsOrganizer(%d) is %v, when expected %v", userID, isOrganizer, want)
+		}
+	}
+	organizers, err := eventRepo.GetOrganizers(ctx, event.ID)
+	if err != nil {
+		t.Errorf("Error when get organizers, when not expected. Error: %v", err)
+	}
+	if len(organizers) != 1 || organizers[0].User.Email != users[1].Email {
+		t.Errorf("Organizers are %v, when expected only %v", organizers, users[1].Email)
+	}
+	err = eventRepo.RemoveOrganizer(ctx, event.ID, users[1].ID)
+	if err != nil {
+		t.Errorf("Error when remove organizer, when not expected. Error: %v", err)
+	}
+	err = eventRepo.RemoveOrganizer(ctx, event.ID, users[1].ID)
+	if err != gorm.ErrRecordNotFound {
+		t.Errorf("Error is %v, when expected gorm.ErrRecordNotFound", err)
+	}
+	_, err = eventRepo.AddOrganizer(ctx, event.ID, users[1].ID)
+	if err != nil {
+		t.Errorf("Error when add removed organizer again, when not expected. Error: %v", err)
+	}
+}
+
+var errEventFull = errors.New("event full")
+
+func capacityPolicy(event models.Event, confirmed int64) (models.BookingStatus, error) {
+	if confirmed >= int64(event.Capacity) {
+		return "", errEventFull
+	}
+	return models.BookingConfirmed, nil
+}
+
+func withStatus(status models.BookingStatus) BookingPolicy {
+	return func(models.Event, int64) (models.BookingStatus, error) {
+		return status, nil
+	}
+}
+
+func createUsers(db *gorm.DB, n int) []models.User {
+	ctx := context.Background()
+	userRepo, _ := NewUserRepository(db)
+	users := make([]models.User, 0, n)
+	for i := 0; i < n; i++ {
+		user := models.User{
+			Email:    fmt.Sprintf("email%d", i),
+			Password: "password",
+			Role:     models.UserRole,
+		}
+		userRepo.Save(ctx, &user)
+		users = append(users, user)
+	}
+	return users
+}
+
 func createUser(db *gorm.DB) {
+	ctx := context.Background()
 	user := models.User{
 		Email:    "email",
 		Password: "password",
 		Role:     models.UserRole,
 	}
 	userRepo, _ := NewUserRepository(db)
-	userRepo.Save(&user)
+	userRepo.Save(ctx, &user)
 }
 
 func compareEvent(t *testing.T, got, want models.Event) {
@@ -229,11 +792,14 @@ func compareEvent(t *testing.T, got, want models.Event) {
 	if got.Location != want.Location {
 		t.Errorf("Location is not same, got: %s, want: %s", got.Location, want.Location)
 	}
-	if got.Date != want.Date {
-		t.Errorf("Date is not same, got: %s, want: %s", got.Date, want.Date)
+	if !got.StartsAt.Equal(want.StartsAt) {
+		t.Errorf("StartsAt is not same, got: %s, want: %s", got.StartsAt, want.StartsAt)
+	}
+	if !got.EndsAt.Equal(want.EndsAt) {
+		t.Errorf("EndsAt is not same, got: %s, want: %s", got.EndsAt, want.EndsAt)
 	}
-	if got.Time != want.Time {
-		t.Errorf("Time is not same, got: %s, want: %s", got.Time, want.Time)
+	if got.TimeZone != want.TimeZone {
+		t.Errorf("TimeZone is not same, got: %s, want: %s", got.TimeZone, want.TimeZone)
 	}
 	if got.CreatedBy != want.CreatedBy {
 		t.Errorf("CreatedBy is not same, got: %d, want: %d", got.CreatedBy, want.CreatedBy)
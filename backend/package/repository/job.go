@@ -0,0 +1,192 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/HermanPlay/web-app-backend/package/domain/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type JobRepository interface {
+	WithTx(tx *gorm.DB) JobRepository
+	Enqueue(ctx context.Context, job *models.Job) error
+	Claim(ctx context.Context, now time.Time, lease time.Duration) (models.Job, error)
+	Complete(ctx context.Context, job models.Job) error
+	Fail(ctx context.Context, job models.Job, message string, retryAt *time.Time) error
+	GetAll(ctx context.Context, status models.JobStatus, limit int, offset int) ([]models.Job, int64, error)
+	GetByID(ctx context.Context, id int) (models.Job, error)
+	Retry(ctx context.Context, id int, now time.Time) (models.Job, error)
+	DeleteDone(ctx context.Context, before time.Time) (int64, error)
+}
+
+type JobRepositoryImpl struct {
+	db *gorm.DB
+}
+
+func (j JobRepositoryImpl) WithTx(tx *gorm.DB) JobRepository {
+	return &JobRepositoryImpl{db: tx}
+}
+
+func (j JobRepositoryImpl) Enqueue(ctx context.Context, job *models.Job) error {
+	ctx, span := tracer.Start(ctx, "JobRepository.Enqueue")
+	defer span.End()
+	db := j.db.WithContext(ctx)
+	job.Status = models.JobPending
+	if job.RunAt.IsZero() {
+		job.RunAt = time.Now()
+	}
+	return db.Create(job).Error
+}
+
+// Claim takes the next job that is due at now and leases it for lease, or
+// returns gorm.ErrRecordNotFound when there is none. Running jobs whose lease
+// ran out belonged to a worker that died and are handed out again, unless that
+// was their last attempt: they are moved to the dead letters instead, so a job
+// crashing its worker cannot run forever.
+func (j JobRepositoryImpl) Claim(ctx context.Context, now time.Time, lease time.Duration) (models.Job, error) {
+	ctx, span := tracer.Start(ctx, "JobRepository.Claim")
+	defer span.End()
+	db := j.db.WithContext(ctx)
+	err := db.Model(&models.Job{}).
+		Where("status = ? AND locked_until < ? AND attempts >= max_attempts", models.JobRunning, now).
+		Updates(map[string]any{"status": models.JobDead, "locked_until": nil, "last_error": "worker stopped during the last attempt"}).Error
+	if err != nil {
+		return models.Job{}, err
+	}
+	var job models.Job
+	err = db.Transaction(func(tx *gorm.DB) error {
+		// SKIP LOCKED lets concurrent workers pass over each other's rows
+		// instead of queueing up behind them
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("(status = ? AND run_at <= ?) OR (status = ? AND locked_until < ? AND attempts < max_attempts)", models.JobPending, now, models.JobRunning, now).
+			Order("run_at, id").
+			First(&job).Error
+		if err != nil {
+			return err
+		}
+		lockedUntil := now.Add(lease)
+		// The attempts check makes the claim safe on databases without row locks
+		result := tx.Model(&models.Job{}).
+			Where("id = ? AND attempts = ?", job.ID, job.Attempts).
+			Updates(map[string]any{"status": models.JobRunning, "attempts": job.Attempts + 1, "locked_until": lockedUntil})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		job.Status = models.JobRunning
+		job.Attempts++
+		job.LockedUntil = &lockedUntil
+		return nil
+	})
+	if err != nil {
+		return models.Job{}, err
+	}
+	return job, nil
+}
+
+// Complete marks a claimed job as done and clears its payload, which is not
+// needed anymore and may hold secrets such as the link of a reset email.
+func (j JobRepositoryImpl) Complete(ctx context.Context, job models.Job) error {
+	ctx, span := tracer.Start(ctx, "JobRepository.Complete")
+	defer span.End()
+	return j.finish(ctx, job, map[string]any{"status": models.JobDone, "locked_until": nil, "last_error": "", "payload": ""})
+}
+
+// Fail records a failed attempt. The job runs again at retryAt, or is moved
+// to the dead letters when retryAt is nil.
+func (j JobRepositoryImpl) Fail(ctx context.Context, job models.Job, message string, retryAt *time.Time) error {
+	ctx, span := tracer.Start(ctx, "JobRepository.Fail")
+	defer span.End()
+	updates := map[string]any{"status": models.JobDead, "locked_until": nil, "last_error": message}
+	if retryAt != nil {
+		updates["status"] = models.JobPending
+		updates["run_at"] = *retryAt
+	}
+	return j.finish(ctx, job, updates)
+}
+
+// finish updates a job only while it is still the caller's attempt, so a
+// worker whose lease ran out cannot overwrite the one that took over.
+func (j JobRepositoryImpl) finish(ctx context.Context, job models.Job, updates map[string]any) error {
+	return j.db.WithContext(ctx).Model(&models.Job{}).
+		Where("id = ? AND status = ? AND attempts = ?", job.ID, models.JobRunning, job.Attempts).
+		Updates(updates).Error
+}
+
+// GetAll returns jobs with the given status, or all jobs when status is
+// empty, newest first, along with their total count.
+func (j JobRepositoryImpl) GetAll(ctx context.Context, status models.JobStatus, limit int, offset int) ([]models.Job, int64, error) {
+	ctx, span := tracer.Start(ctx, "JobRepository.GetAll")
+	defer span.End()
+	db := j.db.WithContext(ctx).Model(&models.Job{})
+	if status != "" {
+		db = db.Where("status = ?", status)
+	}
+	var total int64
+	err := db.Session(&gorm.Session{}).Count(&total).Error
+	if err != nil {
+		return nil, 0, err
+	}
+	if limit > 0 {
+		db = db.Limit(limit)
+	}
+	if offset > 0 {
+		db = db.Offset(offset)
+	}
+	var jobs []models.Job
+	err = db.Order("id DESC").Find(&jobs).Error
+	if err != nil {
+		return nil, 0, err
+	}
+	return jobs, total, nil
+}
+
+func (j JobRepositoryImpl) GetByID(ctx context.Context, id int) (models.Job, error) {
+	ctx, span := tracer.Start(ctx, "JobRepository.GetByID")
+	defer span.End()
+	db := j.db.WithContext(ctx)
+	var job models.Job
+	err := db.First(&job, id).Error
+	if err != nil {
+		return models.Job{}, err
+	}
+	return job, nil
+}
+
+// Retry puts a dead or waiting job at the front of the queue with a fresh set
+// of attempts. Jobs that are running or done are reported as
+// gorm.ErrRecordNotFound.
+func (j JobRepositoryImpl) Retry(ctx context.Context, id int, now time.Time) (models.Job, error) {
+	ctx, span := tracer.Start(ctx, "JobRepository.Retry")
+	defer span.End()
+	db := j.db.WithContext(ctx)
+	result := db.Model(&models.Job{}).
+		Where("id = ? AND status IN ?", id, []models.JobStatus{models.JobDead, models.JobPending}).
+		Updates(map[string]any{"status": models.JobPending, "attempts": 0, "run_at": now})
+	if result.Error != nil {
+		return models.Job{}, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return models.Job{}, gorm.ErrRecordNotFound
+	}
+	return j.GetByID(ctx, id)
+}
+
+// DeleteDone removes the jobs that were done before before and returns how
+// many there were.
+func (j JobRepositoryImpl) DeleteDone(ctx context.Context, before time.Time) (int64, error) {
+	ctx, span := tracer.Start(ctx, "JobRepository.DeleteDone")
+	defer span.End()
+	result := j.db.WithContext(ctx).Where("status = ? AND updated_at < ?", models.JobDone, before).Delete(&models.Job{})
+	return result.RowsAffected, result.Error
+}
+
+func NewJobRepository(db *gorm.DB) (*JobRepositoryImpl, error) {
+	return &JobRepositoryImpl{
+		db: db,
+	}, nil
+}
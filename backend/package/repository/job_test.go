@@ -0,0 +1,168 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/HermanPlay/web-app-backend/package/domain/models"
+	"github.com/HermanPlay/web-app-backend/package/utils"
+	"gorm.io/gorm"
+)
+
+func TestNewJobRepository(t *testing.T) {
+	db := utils.ConnectToTestDatabase()
+	jobRepositoryImpl, err := NewJobRepository(db)
+	if err != nil {
+		t.Errorf("Error when create new job repository, when not expected. Error: %v", err)
+	}
+	if jobRepositoryImpl == nil {
+		t.Errorf("Job repository is nil, when not expected")
+	}
+}
+
+func TestJobQueue(t *testing.T) {
+	ctx := context.Background()
+	db := utils.ConnectToTestDatabase()
+	jobRepository, err := NewJobRepository(db)
+	if err != nil {
+		t.Errorf("Error when create new job repository, when not expected. Error: %v", err)
+	}
+	now := time.Date(2024, 11, 15, 8, 0, 0, 0, time.UTC)
+
+	t.Run("Rolled back enqueue", func(t *testing.T) {
+		err := NewTransactor(db).Transaction(ctx, func(tx *gorm.DB) error {
+			err := jobRepository.WithTx(tx).Enqueue(ctx, &models.Job{Kind: "kind", Payload: "{}", RunAt: now, MaxAttempts: 1})
+			if err != nil {
+				return err
+			}
+			return errors.New("write failed")
+		})
+		if err == nil {
+			t.Fatalf("Error is nil, when expected")
+		}
+		_, err = jobRepository.Claim(ctx, now, time.Minute)
+		if err != gorm.ErrRecordNotFound {
+			t.Errorf("Error is not gorm.ErrRecordNotFound after a rollback, when expected. Error: %v", err)
+		}
+	})
+
+	later := models.Job{Kind: "later", Payload: "{}", RunAt: now.Add(time.Hour), MaxAttempts: 3}
+	due := models.Job{Kind: "due", Payload: "{}", RunAt: now, MaxAttempts: 3}
+	for _, job := range []*models.Job{&later, &due} {
+		err := jobRepository.Enqueue(ctx, job)
+		if err != nil {
+			t.Errorf("Error when enqueue job, when not expected. Error: %v", err)
+		}
+	}
+
+	var claimed models.Job
+	t.Run("Claim", func(t *testing.T) {
+		claimed, err = jobRepository.Claim(ctx, now, time.Minute)
+		if err != nil {
+			t.Fatalf("Error when claim job, when not expected. Error: %v", err)
+		}
+		if claimed.ID != due.ID || claimed.Status != models.JobRunning || claimed.Attempts != 1 {
+			t.Errorf("Claimed %+v, when expected the due job on its first attempt", claimed)
+		}
+		_, err = jobRepository.Claim(ctx, now, time.Minute)
+		if err != gorm.ErrRecordNotFound {
+			t.Errorf("Error is not gorm.ErrRecordNotFound while the job is leased, when expected. Error: %v", err)
+		}
+	})
+	t.Run("Expired lease", func(t *testing.T) {
+		takeover, err := jobRepository.Claim(ctx, now.Add(2*time.Minute), time.Minute)
+		if err != nil {
+			t.Fatalf("Error when claim job, when not expected. Error: %v", err)
+		}
+		if takeover.ID != due.ID || takeover.Attempts != 2 {
+			t.Errorf("Claimed %+v, when expected the due job on its second attempt", takeover)
+		}
+		// The first worker finished late and must not overwrite the takeover
+		jobRepository.Complete(ctx, claimed)
+		job, _ := jobRepository.GetByID(ctx, due.ID)
+		if job.Status != models.JobRunning {
+			t.Errorf("Job status is %v, when expected %v", job.Status, models.JobRunning)
+		}
+		claimed = takeover
+	})
+	t.Run("Fail and retry", func(t *testing.T) {
+		retryAt := now.Add(time.Minute)
+		err := jobRepository.Fail(ctx, claimed, "failed", &retryAt)
+		if err != nil {
+			t.Fatalf("Error when fail job, when not expected. Error: %v", err)
+		}
+		job, _ := jobRepository.GetByID(ctx, due.ID)
+		if job.Status != models.JobPending || !job.RunAt.Equal(retryAt) || job.LastError != "failed" {
+			t.Errorf("Failed job is %+v, when expected pending at %v", job, retryAt)
+		}
+		claimed, _ = jobRepository.Claim(ctx, retryAt, time.Minute)
+		if claimed.ID != due.ID {
+			t.Errorf("Claimed %+v, when expected the retried job", claimed)
+		}
+	})
+	t.Run("Dead letter", func(t *testing.T) {
+		jobRepository.Fail(ctx, claimed, "failed for good", nil)
+		jobs, total, err := jobRepository.GetAll(ctx, models.JobDead, 0, 0)
+		if err != nil || total != 1 || jobs[0].ID != due.ID {
+			t.Fatalf("Dead jobs are %+v, when expected the failed job. Error: %v", jobs, err)
+		}
+		job, err := jobRepository.Retry(ctx, due.ID, now)
+		if err != nil {
+			t.Fatalf("Error when retry job, when not expected. Error: %v", err)
+		}
+		if job.Status != models.JobPending || job.Attempts != 0 {
+			t.Errorf("Retried job is %+v, when expected pending without attempts", job)
+		}
+	})
+	t.Run("Complete", func(t *testing.T) {
+		claimed, _ = jobRepository.Claim(ctx, now, time.Minute)
+		err := jobRepository.Complete(ctx, claimed)
+		if err != nil {
+			t.Fatalf("Error when complete job, when not expected. Error: %v", err)
+		}
+		_, err = jobRepository.Retry(ctx, claimed.ID, now)
+		if err != gorm.ErrRecordNotFound {
+			t.Errorf("Error is not gorm.ErrRecordNotFound for a done job, when expected. Error: %v", err)
+		}
+		_, total, _ := jobRepository.GetAll(ctx, "", 0, 0)
+		if total != 2 {
+			t.Errorf("Got %d jobs, when expected 2", total)
+		}
+		job, _ := jobRepository.GetByID(ctx, claimed.ID)
+		if job.Payload != "" {
+			t.Errorf("Payload of a done job is %q, when expected it cleared", job.Payload)
+		}
+	})
+	t.Run("Delete done", func(t *testing.T) {
+		deleted, err := jobRepository.DeleteDone(ctx, time.Now().Add(-time.Hour))
+		if err != nil || deleted != 0 {
+			t.Errorf("Deleted %d jobs, when expected none finished an hour ago. Error: %v", deleted, err)
+		}
+		deleted, err = jobRepository.DeleteDone(ctx, time.Now().Add(time.Hour))
+		if err != nil || deleted != 1 {
+			t.Errorf("Deleted %d jobs, when expected the done one. Error: %v", deleted, err)
+		}
+		_, total, _ := jobRepository.GetAll(ctx, "", 0, 0)
+		if total != 1 {
+			t.Errorf("Got %d jobs, when expected 1", total)
+		}
+	})
+	t.Run("Expired lease on the last attempt", func(t *testing.T) {
+		last := models.Job{Kind: "crash", Payload: "{}", RunAt: now, MaxAttempts: 1}
+		jobRepository.Enqueue(ctx, &last)
+		claimed, _ = jobRepository.Claim(ctx, now, time.Minute)
+		if claimed.ID != last.ID {
+			t.Fatalf("Claimed %+v, when expected the new job", claimed)
+		}
+		_, err := jobRepository.Claim(ctx, now.Add(2*time.Minute), time.Minute)
+		if err != gorm.ErrRecordNotFound {
+			t.Errorf("Error is not gorm.ErrRecordNotFound for a job out of attempts, when expected. Error: %v", err)
+		}
+		job, _ := jobRepository.GetByID(ctx, last.ID)
+		if job.Status != models.JobDead || job.LockedUntil != nil {
+			t.Errorf("Job is %+v, when expected it in the dead letters", job)
+		}
+	})
+}
@@ -0,0 +1,72 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/HermanPlay/web-app-backend/package/domain/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type ReminderRepository interface {
+	WithTx(tx *gorm.DB) ReminderRepository
+	GetDueReminders(ctx context.Context, kind models.ReminderKind, from time.Time, to time.Time) ([]models.EventUser, error)
+	ClaimReminder(ctx context.Context, booking models.EventUser, kind models.ReminderKind, sentAt time.Time) (bool, error)
+}
+
+type ReminderRepositoryImpl struct {
+	db *gorm.DB
+}
+
+func (r ReminderRepositoryImpl) WithTx(tx *gorm.DB) ReminderRepository {
+	return &ReminderRepositoryImpl{db: tx}
+}
+
+// GetDueReminders returns the confirmed bookings of events starting in
+// (from, to] that have not had a reminder of this kind yet. Bookings of users
+// who opted out of reminders are left out. Event and User are loaded.
+func (r ReminderRepositoryImpl) GetDueReminders(ctx context.Context, kind models.ReminderKind, from time.Time, to time.Time) ([]models.EventUser, error) {
+	ctx, span := tracer.Start(ctx, "ReminderRepository.GetDueReminders")
+	defer span.End()
+	db := r.db.WithContext(ctx)
+	var bookings []models.EventUser
+	err := db.Preload("Event").Preload("User").
+		Joins("JOIN events ON events.id = event_users.event_id AND events.deleted_at IS NULL").
+		Joins("JOIN users ON users.id = event_users.user_id AND users.deleted_at IS NULL").
+		Where("event_users.status = ?", models.BookingConfirmed).
+		Where("events.starts_at > ? AND events.starts_at <= ?", from, to).
+		Where("users.reminders_disabled = ?", false).
+		Where("NOT EXISTS (SELECT 1 FROM event_reminders WHERE event_reminders.event_id = event_users.event_id AND event_reminders.user_id = event_users.user_id AND event_reminders.kind = ?)", kind).
+		Order("event_users.id").
+		Find(&bookings).Error
+	if err != nil {
+		return nil, err
+	}
+	return bookings, nil
+}
+
+// ClaimReminder records the reminder and reports whether this call was the
+// one to do it. A false result means another replica got there first.
+func (r ReminderRepositoryImpl) ClaimReminder(ctx context.Context, booking models.EventUser, kind models.ReminderKind, sentAt time.Time) (bool, error) {
+	ctx, span := tracer.Start(ctx, "ReminderRepository.ClaimReminder")
+	defer span.End()
+	db := r.db.WithContext(ctx)
+	reminder := models.EventReminder{
+		EventID: booking.EventID,
+		UserID:  booking.UserID,
+		Kind:    kind,
+		SentAt:  sentAt,
+	}
+	result := db.Clauses(clause.OnConflict{DoNothing: true}).Create(&reminder)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
+
+func NewReminderRepository(db *gorm.DB) (*ReminderRepositoryImpl, error) {
+	return &ReminderRepositoryImpl{
+		db: db,
+	}, nil
+}
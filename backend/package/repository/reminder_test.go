@@ -0,0 +1,82 @@
+package repository
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/HermanPlay/web-app-backend/package/domain/models"
+	"github.com/HermanPlay/web-app-backend/package/utils"
+)
+
+func TestNewReminderRepository(t *testing.T) {
+	db := utils.ConnectToTestDatabase()
+	reminderRepositoryImpl, err := NewReminderRepository(db)
+	if err != nil {
+		t.Errorf("Error when create new reminder repository, when not expected. Error: %v", err)
+	}
+	if reminderRepositoryImpl == nil {
+		t.Errorf("Reminder repository is nil, when not expected")
+	}
+}
+
+func TestReminders(t *testing.T) {
+	ctx := context.Background()
+	db := utils.ConnectToTestDatabase()
+	reminderRepository, err := NewReminderRepository(db)
+	if err != nil {
+		t.Errorf("Error when create new reminder repository, when not expected. Error: %v", err)
+	}
+	eventRepository, _ := NewEventRepository(db)
+	userRepository, _ := NewUserRepository(db)
+	user, _ := userRepository.Save(ctx, &models.User{Name: "name", Email: "email", Password: "password", Role: models.UserRole})
+	other, _ := userRepository.Save(ctx, &models.User{Name: "other", Email: "other", Password: "password", Role: models.UserRole, RemindersDisabled: true})
+	waitlisted, _ := userRepository.Save(ctx, &models.User{Name: "waitlisted", Email: "waitlisted", Password: "password", Role: models.UserRole})
+
+	now := time.Date(2024, 11, 15, 8, 0, 0, 0, time.UTC)
+	soon, _ := eventRepository.Save(ctx, &models.Event{Title: "soon", StartsAt: now.Add(30 * time.Minute), EndsAt: now.Add(time.Hour), TimeZone: "UTC", Capacity: 2, WaitlistEnabled: true, CreatedBy: user.ID})
+	later, _ := eventRepository.Save(ctx, &models.Event{Title: "later", StartsAt: now.Add(48 * time.Hour), EndsAt: now.Add(49 * time.Hour), TimeZone: "UTC", CreatedBy: user.ID})
+	for _, userID := range []int{user.ID, other.ID} {
+		eventRepository.BookEvent(ctx, soon.ID, userID, func(models.Event, int64) (models.BookingStatus, error) { return models.BookingConfirmed, nil })
+	}
+	eventRepository.BookEvent(ctx, soon.ID, waitlisted.ID, func(models.Event, int64) (models.BookingStatus, error) { return models.BookingWaitlisted, nil })
+	eventRepository.BookEvent(ctx, later.ID, user.ID, func(models.Event, int64) (models.BookingStatus, error) { return models.BookingConfirmed, nil })
+
+	var booking models.EventUser
+	t.Run("Due reminders", func(t *testing.T) {
+		bookings, err := reminderRepository.GetDueReminders(ctx, models.HourReminder, now, now.Add(time.Hour))
+		if err != nil {
+			t.Fatalf("Error when get due reminders, when not expected. Error: %v", err)
+		}
+		// The opted out user, the waitlisted booking and the later event are left out
+		if len(bookings) != 1 {
+			t.Fatalf("Got %d due reminders, when expected 1: %v", len(bookings), bookings)
+		}
+		booking = bookings[0]
+		if booking.UserID != user.ID || booking.EventID != soon.ID {
+			t.Errorf("Due reminder is for user %d and event %d, when expected user %d and event %d", booking.UserID, booking.EventID, user.ID, soon.ID)
+		}
+		if booking.User.Email != user.Email || booking.Event.Title != soon.Title {
+			t.Errorf("Due reminder does not come with its user and event, got: %+v", booking)
+		}
+	})
+	t.Run("Claim", func(t *testing.T) {
+		claimed, err := reminderRepository.ClaimReminder(ctx, booking, models.HourReminder, now)
+		if err != nil || !claimed {
+			t.Fatalf("Reminder was not claimed, when expected. Error: %v", err)
+		}
+		claimed, err = reminderRepository.ClaimReminder(ctx, booking, models.HourReminder, now)
+		if err != nil || claimed {
+			t.Errorf("Reminder was claimed twice, when not expected. Error: %v", err)
+		}
+		bookings, _ := reminderRepository.GetDueReminders(ctx, models.HourReminder, now, now.Add(time.Hour))
+		if len(bookings) != 0 {
+			t.Errorf("Got %d due reminders after the claim, when expected none", len(bookings))
+		}
+		// Other kinds of reminders are tracked separately
+		claimed, _ = reminderRepository.ClaimReminder(ctx, booking, models.DayReminder, now)
+		if !claimed {
+			t.Errorf("Day reminder was not claimed, when expected")
+		}
+	})
+}
@@ -0,0 +1,7 @@
+package repository
+
+import "go.opentelemetry.io/otel"
+
+// tracer starts a span for every repository call, the queries it runs get
+// spans of their own below it.
+var tracer = otel.Tracer("github.com/HermanPlay/web-app-backend/package/repository")
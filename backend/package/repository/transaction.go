@@ -0,0 +1,36 @@
+package repository
+
+import (
+	"context"
+
+	"gorm.io/gorm"
+)
+
+// Transactor runs work spanning several repositories in one transaction.
+// Repositories join it through their WithTx method:
+//
+//	err := transactor.Transaction(ctx, func(tx *gorm.DB) error {
+//		_, err := userRepository.WithTx(tx).Save(ctx, &user)
+//		...
+//		return jobRepository.WithTx(tx).Enqueue(ctx, &job)
+//	})
+type Transactor interface {
+	Transaction(ctx context.Context, fn func(tx *gorm.DB) error) error
+}
+
+type TransactorImpl struct {
+	db *gorm.DB
+}
+
+// Transaction commits when fn returns nil and rolls back otherwise.
+func (t TransactorImpl) Transaction(ctx context.Context, fn func(tx *gorm.DB) error) error {
+	ctx, span := tracer.Start(ctx, "Transactor.Transaction")
+	defer span.End()
+	return t.db.WithContext(ctx).Transaction(fn)
+}
+
+func NewTransactor(db *gorm.DB) *TransactorImpl {
+	return &TransactorImpl{
+		db: db,
+	}
+}
@@ -1,29 +1,38 @@
 package repository
 
 import (
+	"context"
 	"github.com/HermanPlay/web-app-backend/package/domain/models"
 	"github.com/sirupsen/logrus"
 	"gorm.io/gorm"
 )
 
 type UserRepository interface {
-	FindAllUser() ([]models.User, error)
-	FindUserById(id int) (models.User, error)
-	Save(user *models.User) (models.User, error)
-	DeleteUserById(id int) error
-	CheckUserExist(email string) (bool, error)
-	Update(user *models.User) (models.User, error)
-	GetUserByEmail(email string) (models.User, error)
+	WithTx(tx *gorm.DB) UserRepository
+	FindAllUser(ctx context.Context) ([]models.User, error)
+	FindUserById(ctx context.Context, id int) (models.User, error)
+	Save(ctx context.Context, user *models.User) (models.User, error)
+	DeleteUserById(ctx context.Context, id int) error
+	CheckUserExist(ctx context.Context, email string) (bool, error)
+	Update(ctx context.Context, user *models.User) (models.User, error)
+	GetUserByEmail(ctx context.Context, email string) (models.User, error)
 }
 
 type UserRepositoryImpl struct {
 	db *gorm.DB
 }
 
-func (u UserRepositoryImpl) FindAllUser() ([]models.User, error) {
+func (u UserRepositoryImpl) WithTx(tx *gorm.DB) UserRepository {
+	return &UserRepositoryImpl{db: tx}
+}
+
+func (u UserRepositoryImpl) FindAllUser(ctx context.Context) ([]models.User, error) {
+	ctx, span := tracer.Start(ctx, "UserRepository.FindAllUser")
+	defer span.End()
+	db := u.db.WithContext(ctx)
 	var users []models.User
 
-	var err = u.db.Find(&users).Error
+	var err = db.Find(&users).Error
 	if err != nil {
 		logrus.Error("Got an error finding all couples. Error: ", err)
 		return nil, err
@@ -32,11 +41,14 @@ func (u UserRepositoryImpl) FindAllUser() ([]models.User, error) {
 	return users, nil
 }
 
-func (u UserRepositoryImpl) FindUserById(id int) (models.User, error) {
+func (u UserRepositoryImpl) FindUserById(ctx context.Context, id int) (models.User, error) {
+	ctx, span := tracer.Start(ctx, "UserRepository.FindUserById")
+	defer span.End()
+	db := u.db.WithContext(ctx)
 	user := models.User{
 		ID: id,
 	}
-	err := u.db.First(&user).Error
+	err := db.First(&user).Error
 	if err != nil {
 		logrus.Error("Got and error when find user by id. Error: ", err)
 		return models.User{}, err
@@ -44,8 +56,11 @@ func (u UserRepositoryImpl) FindUserById(id int) (models.User, error) {
 	return user, nil
 }
 
-func (u UserRepositoryImpl) Save(user *models.User) (models.User, error) {
-	err := u.db.Save(user).Error
+func (u UserRepositoryImpl) Save(ctx context.Context, user *models.User) (models.User, error) {
+	ctx, span := tracer.Start(ctx, "UserRepository.Save")
+	defer span.End()
+	db := u.db.WithContext(ctx)
+	err := db.Save(user).Error
 	if err != nil {
 		logrus.Error("Got an error when save user. Error: ", err)
 		return models.User{}, err
@@ -53,8 +68,11 @@ func (u UserRepositoryImpl) Save(user *models.User) (models.User, error) {
 	return *user, nil
 }
 
-func (u UserRepositoryImpl) DeleteUserById(id int) error {
-	err := u.db.Delete(&models.User{}, id).Error
+func (u UserRepositoryImpl) DeleteUserById(ctx context.Context, id int) error {
+	ctx, span := tracer.Start(ctx, "UserRepository.DeleteUserById")
+	defer span.End()
+	db := u.db.WithContext(ctx)
+	err := db.Delete(&models.User{}, id).Error
 	if err != nil {
 		logrus.Error("Got an error when delete user. Error: ", err)
 		return err
@@ -62,9 +80,12 @@ func (u UserRepositoryImpl) DeleteUserById(id int) error {
 	return nil
 }
 
-func (u UserRepositoryImpl) CheckUserExist(email string) (bool, error) {
+func (u UserRepositoryImpl) CheckUserExist(ctx context.Context, email string) (bool, error) {
+	ctx, span := tracer.Start(ctx, "UserRepository.CheckUserExist")
+	defer span.End()
+	db := u.db.WithContext(ctx)
 	var user models.User
-	err := u.db.Model(&user).Where("email = ?", email).First(&user).Error
+	err := db.Model(&user).Where("email = ?", email).First(&user).Error
 	if err != nil {
 		if err == gorm.ErrRecordNotFound {
 			return false, nil
@@ -74,8 +95,11 @@ func (u UserRepositoryImpl) CheckUserExist(email string) (bool, error) {
 	return true, nil
 }
 
-func (u UserRepositoryImpl) Update(user *models.User) (models.User, error) {
-	err := u.db.Save(user).Error
+func (u UserRepositoryImpl) Update(ctx context.Context, user *models.User) (models.User, error) {
+	ctx, span := tracer.Start(ctx, "UserRepository.Update")
+	defer span.End()
+	db := u.db.WithContext(ctx)
+	err := db.Save(user).Error
 	if err != nil {
 		logrus.Error("Got an error when update user. Error: ", err)
 		return models.User{}, err
@@ -83,9 +107,12 @@ func (u UserRepositoryImpl) Update(user *models.User) (models.User, error) {
 	return *user, nil
 }
 
-func (u UserRepositoryImpl) GetUserByEmail(email string) (models.User, error) {
+func (u UserRepositoryImpl) GetUserByEmail(ctx context.Context, email string) (models.User, error) {
+	ctx, span := tracer.Start(ctx, "UserRepository.GetUserByEmail")
+	defer span.End()
+	db := u.db.WithContext(ctx)
 	var user models.User
-	err := u.db.Model(&user).Where("email = ?", email).First(&user).Error
+	err := db.Model(&user).Where("email = ?", email).First(&user).Error
 	if err != nil {
 		logrus.Error("Got an error when get user by email. Error: ", err)
 		return models.User{}, err
@@ -94,10 +121,6 @@ func (u UserRepositoryImpl) GetUserByEmail(email string) (models.User, error) {
 }
 
 func NewUserRepository(db *gorm.DB) (*UserRepositoryImpl, error) {
-	err := db.AutoMigrate(&models.User{})
-	if err != nil {
-		return nil, err
-	}
 	return &UserRepositoryImpl{
 		db: db,
 	}, nil
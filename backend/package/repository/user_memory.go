@@ -0,0 +1,158 @@
+package repository
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/HermanPlay/web-app-backend/package/domain/models"
+	"gorm.io/gorm"
+)
+
+// MemoryUserRepository keeps users in memory, for unit tests that need no
+// database. It is safe for concurrent use and behaves like UserRepositoryImpl:
+// deletes are soft, missing users yield gorm.ErrRecordNotFound and a taken
+// email yields gorm.ErrDuplicatedKey. There are no transactions, WithTx
+// returns the repository itself and its writes are never rolled back.
+type MemoryUserRepository struct {
+	mu     sync.Mutex
+	users  map[int]models.User // including deleted ones, with DeletedAt set
+	nextID int
+}
+
+func (u *MemoryUserRepository) WithTx(tx *gorm.DB) UserRepository {
+	return u
+}
+
+func (u *MemoryUserRepository) FindAllUser(ctx context.Context) ([]models.User, error) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	users := []models.User{}
+	for _, user := range u.users {
+		if !user.DeletedAt.Valid {
+			users = append(users, loadedUser(user))
+		}
+	}
+	sort.Slice(users, func(i, j int) bool { return users[i].ID < users[j].ID })
+	return users, nil
+}
+
+func (u *MemoryUserRepository) FindUserById(ctx context.Context, id int) (models.User, error) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	user, ok := u.liveUser(id)
+	if !ok {
+		return models.User{}, gorm.ErrRecordNotFound
+	}
+	return user, nil
+}
+
+// Save creates the user when its ID is 0 and replaces it otherwise. The
+// password is hashed unless it already is.
+func (u *MemoryUserRepository) Save(ctx context.Context, user *models.User) (models.User, error) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	return u.save(user)
+}
+
+func (u *MemoryUserRepository) DeleteUserById(ctx context.Context, id int) error {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	user, ok := u.users[id]
+	if ok && !user.DeletedAt.Valid {
+		user.DeletedAt = gorm.DeletedAt{Time: time.Now(), Valid: true}
+		u.users[id] = user
+	}
+	return nil
+}
+
+func (u *MemoryUserRepository) CheckUserExist(ctx context.Context, email string) (bool, error) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	_, ok := u.userByEmail(email)
+	return ok, nil
+}
+
+func (u *MemoryUserRepository) Update(ctx context.Context, user *models.User) (models.User, error) {
+	return u.Save(ctx, user)
+}
+
+func (u *MemoryUserRepository) GetUserByEmail(ctx context.Context, email string) (models.User, error) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	user, ok := u.userByEmail(email)
+	if !ok {
+		return models.User{}, gorm.ErrRecordNotFound
+	}
+	return user, nil
+}
+
+// save expects u.mu to be held.
+func (u *MemoryUserRepository) save(user *models.User) (models.User, error) {
+	if other, ok := u.userByEmail(user.Email); ok && other.ID != user.ID {
+		return models.User{}, gorm.ErrDuplicatedKey
+	}
+	err := user.BeforeSave(nil)
+	if err != nil {
+		return models.User{}, err
+	}
+
+	now := time.Now()
+	if user.ID == 0 {
+		u.nextID++
+		user.ID = u.nextID
+	} else if user.ID > u.nextID {
+		u.nextID = user.ID
+	}
+	if user.CreatedAt.IsZero() {
+		user.CreatedAt = now
+		if stored, ok := u.users[user.ID]; ok {
+			user.CreatedAt = stored.CreatedAt
+		}
+	}
+	user.UpdatedAt = now
+	u.users[user.ID] = loadedUser(*user)
+	return *user, nil
+}
+
+// liveUser expects u.mu to be held.
+func (u *MemoryUserRepository) liveUser(id int) (models.User, bool) {
+	user, ok := u.users[id]
+	if !ok || user.DeletedAt.Valid {
+		return models.User{}, false
+	}
+	return loadedUser(user), true
+}
+
+// userByEmail expects u.mu to be held.
+func (u *MemoryUserRepository) userByEmail(email string) (models.User, bool) {
+	for _, user := range u.users {
+		if user.Email == email && !user.DeletedAt.Valid {
+			return loadedUser(user), true
+		}
+	}
+	return models.User{}, false
+}
+
+// lookup returns a live user, and whether a user with the id was ever
+// stored, which is what foreign keys check.
+func (u *MemoryUserRepository) lookup(id int) (user models.User, live bool, exists bool) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	_, exists = u.users[id]
+	user, live = u.liveUser(id)
+	return user, live, exists
+}
+
+// loadedUser returns the user as a query would, deleted_at is never read.
+func loadedUser(user models.User) models.User {
+	user.DeletedAt = gorm.DeletedAt{}
+	return user
+}
+
+func NewMemoryUserRepository() *MemoryUserRepository {
+	return &MemoryUserRepository{
+		users: map[int]models.User{},
+	}
+}
@@ -1,6 +1,7 @@
 package repository
 
 import (
+	"context"
 	"testing"
 
 	"github.com/HermanPlay/web-app-backend/package/domain/models"
@@ -19,9 +20,10 @@ func TestNewUserRepository(t *testing.T) {
 }
 
 func TestFindAllUser(t *testing.T) {
+	ctx := context.Background()
 	db := utils.ConnectToTestDatabase()
 	userRepositoryImpl, _ := NewUserRepository(db)
-	users, err := userRepositoryImpl.FindAllUser()
+	users, err := userRepositoryImpl.FindAllUser(ctx)
 	if err != nil {
 		t.Errorf("Error when find all user, when not expected. Error: %v", err)
 	}
@@ -31,6 +33,7 @@ func TestFindAllUser(t *testing.T) {
 }
 
 func TestSave(t *testing.T) {
+	ctx := context.Background()
 	db := utils.ConnectToTestDatabase()
 	userRepositoryImpl, _ := NewUserRepository(db)
 	want := models.User{
@@ -38,21 +41,21 @@ func TestSave(t *testing.T) {
 		Password: "password",
 		Role:     models.UserRole,
 	}
-	got, err := userRepositoryImpl.Save(&want)
+	got, err := userRepositoryImpl.Save(ctx, &want)
 	if err != nil {
 		t.Errorf("Error when save user, when not expected. Error: %v", err)
 	}
 	compareUser(t, got, want)
 	want.Email = "email2@email.com"
 	want.Role = models.ManagerRole
-	got, err = userRepositoryImpl.Save(&want)
+	got, err = userRepositoryImpl.Save(ctx, &want)
 	if err != nil {
 		t.Errorf("Error when save user, when not expected. Error: %v", err)
 	}
 	compareUser(t, got, want)
 	want.Email = "email3@email.com"
 	want.Role = models.AdminRole
-	got, err = userRepositoryImpl.Save(&want)
+	got, err = userRepositoryImpl.Save(ctx, &want)
 	if err != nil {
 		t.Errorf("Error when save user, when not expected. Error: %v", err)
 	}
@@ -60,6 +63,7 @@ func TestSave(t *testing.T) {
 }
 
 func TestFindUserById(t *testing.T) {
+	ctx := context.Background()
 	db := utils.ConnectToTestDatabase()
 	userRepositoryImpl, _ := NewUserRepository(db)
 	want := models.User{
@@ -67,8 +71,8 @@ func TestFindUserById(t *testing.T) {
 		Password: "password",
 		Role:     models.UserRole,
 	}
-	got, _ := userRepositoryImpl.Save(&want)
-	user, err := userRepositoryImpl.FindUserById(got.ID)
+	got, _ := userRepositoryImpl.Save(ctx, &want)
+	user, err := userRepositoryImpl.FindUserById(ctx, got.ID)
 	if err != nil {
 		t.Errorf("Error when find user by id, when not expected. Error: %v", err)
 	}
@@ -76,6 +80,7 @@ func TestFindUserById(t *testing.T) {
 }
 
 func TestDeleteUserById(t *testing.T) {
+	ctx := context.Background()
 	db := utils.ConnectToTestDatabase()
 	userRepositoryImpl, _ := NewUserRepository(db)
 	want := models.User{
@@ -83,21 +88,22 @@ func TestDeleteUserById(t *testing.T) {
 		Password: "password",
 		Role:     models.UserRole,
 	}
-	got, _ := userRepositoryImpl.Save(&want)
-	err := userRepositoryImpl.DeleteUserById(got.ID)
+	got, _ := userRepositoryImpl.Save(ctx, &want)
+	err := userRepositoryImpl.DeleteUserById(ctx, got.ID)
 	if err != nil {
 		t.Errorf("Error when delete user by id, when not expected. Error: %v", err)
 	}
-	user, err := userRepositoryImpl.FindUserById(got.ID)
+	user, err := userRepositoryImpl.FindUserById(ctx, got.ID)
 	if err == nil {
 		t.Errorf("User is not deleted, when expected. User: %v", user)
 	}
 }
 
 func TestCheckUserExist(t *testing.T) {
+	ctx := context.Background()
 	db := utils.ConnectToTestDatabase()
 	userRepositoryImpl, _ := NewUserRepository(db)
-	exist, err := userRepositoryImpl.CheckUserExist("email7@email.com")
+	exist, err := userRepositoryImpl.CheckUserExist(ctx, "email7@email.com")
 	if err != nil {
 		t.Errorf("Error when check user exist, when not expected. Error: %v", err)
 	}
@@ -110,8 +116,8 @@ func TestCheckUserExist(t *testing.T) {
 		Password: "password",
 		Role:     models.UserRole,
 	}
-	got, _ := userRepositoryImpl.Save(&want)
-	exist, err = userRepositoryImpl.CheckUserExist(got.Email)
+	got, _ := userRepositoryImpl.Save(ctx, &want)
+	exist, err = userRepositoryImpl.CheckUserExist(ctx, got.Email)
 	if err != nil {
 		t.Errorf("Error when check user exist, when not expected. Error: %v", err)
 	}
@@ -121,6 +127,7 @@ func TestCheckUserExist(t *testing.T) {
 }
 
 func TestUpdate(t *testing.T) {
+	ctx := context.Background()
 	db := utils.ConnectToTestDatabase()
 	userRepositoryImpl, _ := NewUserRepository(db)
 	want := models.User{
@@ -128,20 +135,44 @@ func TestUpdate(t *testing.T) {
 		Password: "password",
 		Role:     models.UserRole,
 	}
-	userRepositoryImpl.Save(&want)
+	userRepositoryImpl.Save(ctx, &want)
 	want.Email = "newemail8@email.com"
 	want.Role = models.ManagerRole
-	got, err := userRepositoryImpl.Update(&want)
+	got, err := userRepositoryImpl.Update(ctx, &want)
 	if err != nil {
 		t.Errorf("Error when update user, when not expected. Error: %v", err)
 	}
 	compareUser(t, got, want)
 }
 
+func TestUpdateKeepsPassword(t *testing.T) {
+	ctx := context.Background()
+	db := utils.ConnectToTestDatabase()
+	userRepositoryImpl, _ := NewUserRepository(db)
+	authRepositoryImpl, _ := NewAuthRepository(db)
+	user := models.User{
+		Email:    "email@email.com",
+		Password: "password",
+		Role:     models.UserRole,
+	}
+	userRepositoryImpl.Save(ctx, &user)
+	loaded, _ := userRepositoryImpl.FindUserById(ctx, user.ID)
+	loaded.Name = "new name"
+	_, err := userRepositoryImpl.Update(ctx, &loaded)
+	if err != nil {
+		t.Fatalf("Error when update user, when not expected. Error: %v", err)
+	}
+	_, err = authRepositoryImpl.LoginUser(ctx, user.Email, "password")
+	if err != nil {
+		t.Errorf("Error when login after update, when not expected. Error: %v", err)
+	}
+}
+
 func TestGetUserByEmail(t *testing.T) {
+	ctx := context.Background()
 	db := utils.ConnectToTestDatabase()
 	userRepositoryImpl, _ := NewUserRepository(db)
-	got, err := userRepositoryImpl.GetUserByEmail("email9@email.com")
+	got, err := userRepositoryImpl.GetUserByEmail(ctx, "email9@email.com")
 	if err == nil {
 		t.Errorf("User is exist, when not expected. User: %v", got)
 	}
@@ -151,8 +182,8 @@ func TestGetUserByEmail(t *testing.T) {
 		Password: "password",
 		Role:     models.UserRole,
 	}
-	got, _ = userRepositoryImpl.Save(&want)
-	user, err := userRepositoryImpl.GetUserByEmail(got.Email)
+	got, _ = userRepositoryImpl.Save(ctx, &want)
+	user, err := userRepositoryImpl.GetUserByEmail(ctx, got.Email)
 	if err != nil {
 		t.Errorf("Error when get user by email, when not expected. Error: %v", err)
 	}
@@ -0,0 +1,147 @@
+package repository
+
+import (
+	"context"
+	"github.com/HermanPlay/web-app-backend/package/domain/models"
+	"gorm.io/gorm"
+)
+
+type WebhookRepository interface {
+	WithTx(tx *gorm.DB) WebhookRepository
+	GetAll(ctx context.Context) ([]models.Webhook, error)
+	GetByID(ctx context.Context, id int) (models.Webhook, error)
+	GetSubscribed(ctx context.Context, event models.WebhookEvent) ([]models.Webhook, error)
+	Save(ctx context.Context, webhook *models.Webhook) (models.Webhook, error)
+	Update(ctx context.Context, webhook *models.Webhook) (models.Webhook, error)
+	Delete(ctx context.Context, id int) error
+	SaveDelivery(ctx context.Context, delivery *models.WebhookDelivery) error
+	GetDeliveries(ctx context.Context, webhookID int, limit int, offset int) ([]models.WebhookDelivery, int64, error)
+}
+
+type WebhookRepositoryImpl struct {
+	db *gorm.DB
+}
+
+func (w WebhookRepositoryImpl) WithTx(tx *gorm.DB) WebhookRepository {
+	return &WebhookRepositoryImpl{db: tx}
+}
+
+func (w WebhookRepositoryImpl) GetAll(ctx context.Context) ([]models.Webhook, error) {
+	ctx, span := tracer.Start(ctx, "WebhookRepository.GetAll")
+	defer span.End()
+	db := w.db.WithContext(ctx)
+	var webhooks []models.Webhook
+	err := db.Order("id").Find(&webhooks).Error
+	if err != nil {
+		return nil, err
+	}
+	return webhooks, nil
+}
+
+func (w WebhookRepositoryImpl) GetByID(ctx context.Context, id int) (models.Webhook, error) {
+	ctx, span := tracer.Start(ctx, "WebhookRepository.GetByID")
+	defer span.End()
+	db := w.db.WithContext(ctx)
+	var webhook models.Webhook
+	err := db.First(&webhook, id).Error
+	if err != nil {
+		return models.Webhook{}, err
+	}
+	return webhook, nil
+}
+
+// GetSubscribed returns the active webhooks subscribed to event.
+func (w WebhookRepositoryImpl) GetSubscribed(ctx context.Context, event models.WebhookEvent) ([]models.Webhook, error) {
+	ctx, span := tracer.Start(ctx, "WebhookRepository.GetSubscribed")
+	defer span.End()
+	db := w.db.WithContext(ctx)
+	var active []models.Webhook
+	err := db.Where("active = ?", true).Order("id").Find(&active).Error
+	if err != nil {
+		return nil, err
+	}
+	// There are only ever a handful of webhooks, matching them here keeps the
+	// events column a plain list
+	var webhooks []models.Webhook
+	for _, webhook := range active {
+		if webhook.Subscribes(event) {
+			webhooks = append(webhooks, webhook)
+		}
+	}
+	return webhooks, nil
+}
+
+func (w WebhookRepositoryImpl) Save(ctx context.Context, webhook *models.Webhook) (models.Webhook, error) {
+	ctx, span := tracer.Start(ctx, "WebhookRepository.Save")
+	defer span.End()
+	db := w.db.WithContext(ctx)
+	err := db.Create(webhook).Error
+	if err != nil {
+		return models.Webhook{}, err
+	}
+	return *webhook, nil
+}
+
+func (w WebhookRepositoryImpl) Update(ctx context.Context, webhook *models.Webhook) (models.Webhook, error) {
+	ctx, span := tracer.Start(ctx, "WebhookRepository.Update")
+	defer span.End()
+	db := w.db.WithContext(ctx)
+	err := db.Save(webhook).Error
+	if err != nil {
+		return models.Webhook{}, err
+	}
+	return *webhook, nil
+}
+
+// Delete removes the webhook. Its delivery history is kept.
+func (w WebhookRepositoryImpl) Delete(ctx context.Context, id int) error {
+	ctx, span := tracer.Start(ctx, "WebhookRepository.Delete")
+	defer span.End()
+	db := w.db.WithContext(ctx)
+	result := db.Delete(&models.Webhook{}, id)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+func (w WebhookRepositoryImpl) SaveDelivery(ctx context.Context, delivery *models.WebhookDelivery) error {
+	ctx, span := tracer.Start(ctx, "WebhookRepository.SaveDelivery")
+	defer span.End()
+	db := w.db.WithContext(ctx)
+	return db.Create(delivery).Error
+}
+
+// GetDeliveries returns the delivery attempts of a webhook, newest first,
+// along with their total count.
+func (w WebhookRepositoryImpl) GetDeliveries(ctx context.Context, webhookID int, limit int, offset int) ([]models.WebhookDelivery, int64, error) {
+	ctx, span := tracer.Start(ctx, "WebhookRepository.GetDeliveries")
+	defer span.End()
+	db := w.db.WithContext(ctx).Model(&models.WebhookDelivery{}).Where("webhook_id = ?", webhookID)
+	var total int64
+	err := db.Session(&gorm.Session{}).Count(&total).Error
+	if err != nil {
+		return nil, 0, err
+	}
+	if limit > 0 {
+		db = db.Limit(limit)
+	}
+	if offset > 0 {
+		db = db.Offset(offset)
+	}
+	var deliveries []models.WebhookDelivery
+	err = db.Order("id DESC").Find(&deliveries).Error
+	if err != nil {
+		return nil, 0, err
+	}
+	return deliveries, total, nil
+}
+
+func NewWebhookRepository(db *gorm.DB) (*WebhookRepositoryImpl, error) {
+	return &WebhookRepositoryImpl{
+		db: db,
+	}, nil
+}
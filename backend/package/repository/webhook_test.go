@@ -0,0 +1,119 @@
+package repository
+
+import (
+	"context"
+	"testing"
+
+	"github.com/HermanPlay/web-app-backend/package/domain/models"
+	"github.com/HermanPlay/web-app-backend/package/utils"
+	"gorm.io/gorm"
+)
+
+func TestNewWebhookRepository(t *testing.T) {
+	db := utils.ConnectToTestDatabase()
+	webhookRepositoryImpl, err := NewWebhookRepository(db)
+	if err != nil {
+		t.Errorf("Error when create new webhook repository, when not expected. Error: %v", err)
+	}
+	if webhookRepositoryImpl == nil {
+		t.Errorf("Webhook repository is nil, when not expected")
+	}
+}
+
+func TestWebhooks(t *testing.T) {
+	ctx := context.Background()
+	db := utils.ConnectToTestDatabase()
+	webhookRepository, err := NewWebhookRepository(db)
+	if err != nil {
+		t.Errorf("Error when create new webhook repository, when not expected. Error: %v", err)
+	}
+
+	events, err := webhookRepository.Save(ctx, &models.Webhook{URL: "http://events", Secret: "secret", Events: "event.created,event.deleted", Active: true})
+	if err != nil {
+		t.Errorf("Error when save webhook, when not expected. Error: %v", err)
+	}
+	bookings, err := webhookRepository.Save(ctx, &models.Webhook{URL: "http://bookings", Secret: "secret", Events: "booking.created", Active: true})
+	if err != nil {
+		t.Errorf("Error when save webhook, when not expected. Error: %v", err)
+	}
+	paused, err := webhookRepository.Save(ctx, &models.Webhook{URL: "http://paused", Secret: "secret", Events: "event.created", Active: true})
+	if err != nil {
+		t.Errorf("Error when save webhook, when not expected. Error: %v", err)
+	}
+	paused.Active = false
+	_, err = webhookRepository.Update(ctx, &paused)
+	if err != nil {
+		t.Errorf("Error when update webhook, when not expected. Error: %v", err)
+	}
+
+	t.Run("Get all", func(t *testing.T) {
+		webhooks, err := webhookRepository.GetAll(ctx)
+		if err != nil {
+			t.Errorf("Error when get all webhooks, when not expected. Error: %v", err)
+		}
+		if len(webhooks) != 3 {
+			t.Errorf("Got %d webhooks, when expected 3", len(webhooks))
+		}
+	})
+	t.Run("Get subscribed", func(t *testing.T) {
+		webhooks, err := webhookRepository.GetSubscribed(ctx, models.WebhookEventCreated)
+		if err != nil {
+			t.Errorf("Error when get subscribed webhooks, when not expected. Error: %v", err)
+		}
+		if len(webhooks) != 1 || webhooks[0].ID != events.ID {
+			t.Errorf("Got %+v, when expected only the active webhook subscribed to event.created", webhooks)
+		}
+		webhooks, err = webhookRepository.GetSubscribed(ctx, models.WebhookBookingCancelled)
+		if err != nil {
+			t.Errorf("Error when get subscribed webhooks, when not expected. Error: %v", err)
+		}
+		if len(webhooks) != 0 {
+			t.Errorf("Got %+v, when expected no webhooks", webhooks)
+		}
+	})
+	t.Run("Deliveries", func(t *testing.T) {
+		for _, statusCode := range []int{500, 502, 200} {
+			err := webhookRepository.SaveDelivery(ctx, &models.WebhookDelivery{
+				WebhookID:  bookings.ID,
+				DeliveryID: "delivery",
+				Event:      models.WebhookBookingCreated,
+				Payload:    "{}",
+				StatusCode: statusCode,
+			})
+			if err != nil {
+				t.Errorf("Error when save delivery, when not expected. Error: %v", err)
+			}
+		}
+		deliveries, total, err := webhookRepository.GetDeliveries(ctx, bookings.ID, 2, 0)
+		if err != nil {
+			t.Errorf("Error when get deliveries, when not expected. Error: %v", err)
+		}
+		if total != 3 || len(deliveries) != 2 {
+			t.Fatalf("Got %d of %d deliveries, when expected 2 of 3", len(deliveries), total)
+		}
+		if deliveries[0].StatusCode != 200 {
+			t.Errorf("First delivery has status %d, when expected the latest one", deliveries[0].StatusCode)
+		}
+		_, total, err = webhookRepository.GetDeliveries(ctx, events.ID, 2, 0)
+		if err != nil {
+			t.Errorf("Error when get deliveries, when not expected. Error: %v", err)
+		}
+		if total != 0 {
+			t.Errorf("Got %d deliveries of another webhook, when expected none", total)
+		}
+	})
+	t.Run("Delete", func(t *testing.T) {
+		err := webhookRepository.Delete(ctx, bookings.ID)
+		if err != nil {
+			t.Errorf("Error when delete webhook, when not expected. Error: %v", err)
+		}
+		_, err = webhookRepository.GetByID(ctx, bookings.ID)
+		if err != gorm.ErrRecordNotFound {
+			t.Errorf("Error is not gorm.ErrRecordNotFound, when expected. Error: %v", err)
+		}
+		err = webhookRepository.Delete(ctx, bookings.ID)
+		if err != gorm.ErrRecordNotFound {
+			t.Errorf("Error is not gorm.ErrRecordNotFound, when expected. Error: %v", err)
+		}
+	})
+}
@@ -1,7 +1,20 @@
 package service
 
 import (
-	"github.com/HermanPlay/web-app-backend/internal/api/http/util"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/HermanPlay/web-app-backend/internal/api/http/util/token"
+	"github.com/HermanPlay/web-app-backend/internal/config"
+	"github.com/HermanPlay/web-app-backend/internal/metrics"
+	"github.com/HermanPlay/web-app-backend/internal/notification"
 	"github.com/HermanPlay/web-app-backend/package/domain/models"
 	"github.com/HermanPlay/web-app-backend/package/domain/schemas"
 	"github.com/HermanPlay/web-app-backend/package/repository"
@@ -11,18 +24,28 @@ import (
 )
 
 type AuthService interface {
-	RegisterUser(data schemas.UserRegister) (*schemas.User, error)
-	LoginUser(data schemas.UserLogin) (string, error)
-	ResetPassword(data schemas.UserResetPassword) (string, error)
+	RegisterUser(ctx context.Context, data schemas.UserRegister) (*schemas.User, error)
+	LoginUser(ctx context.Context, data schemas.UserLogin) (*schemas.TokenPair, error)
+	RefreshToken(ctx context.Context, refreshToken string) (*schemas.TokenPair, error)
+	Logout(ctx context.Context, refreshToken string) error
+	ResetPassword(ctx context.Context, data schemas.UserResetPassword) error
+	ConfirmPasswordReset(ctx context.Context, data schemas.UserResetPasswordConfirm) error
 }
 
+const minPasswordLength = 8
+
 type AuthServiceImpl struct {
 	authRepository repository.AuthRepository
 	userRepository repository.UserRepository
+	jobRepository  repository.JobRepository
+	transactor     repository.Transactor
+	cfg            *config.Config
 }
 
-func (a AuthServiceImpl) RegisterUser(data schemas.UserRegister) (*schemas.User, error) {
-	exists, err := a.userRepository.CheckUserExist(data.Email)
+func (a AuthServiceImpl) RegisterUser(ctx context.Context, data schemas.UserRegister) (*schemas.User, error) {
+	ctx, span := tracer.Start(ctx, "AuthService.RegisterUser")
+	defer span.End()
+	exists, err := a.userRepository.CheckUserExist(ctx, data.Email)
 	if err != nil {
 		logrus.Error(err)
 		return nil, err
@@ -32,50 +55,210 @@ func (a AuthServiceImpl) RegisterUser(data schemas.UserRegister) (*schemas.User,
 	}
 
 	model := a.createUserModel(&data)
-	user, err := a.userRepository.Save(&model)
+	var user models.User
+	err = a.transactor.Transaction(ctx, func(tx *gorm.DB) error {
+		user, err = a.userRepository.WithTx(tx).Save(ctx, &model)
+		if err != nil {
+			return err
+		}
+		return enqueueNotification(ctx, a.jobRepository.WithTx(tx), user.Email, notification.Welcome, notification.WelcomeData{
+			Name: user.Name,
+			Link: publicLink(a.cfg, "/events"),
+		})
+	})
 	if err != nil {
+		// Another registration took the email since the check above
+		if errors.Is(err, gorm.ErrDuplicatedKey) {
+			return nil, ErrAlreadyExists
+		}
 		logrus.Error(err)
 		return nil, err
 	}
+
 	returnData := createUserSchema(&user)
 	return returnData, nil
 }
 
-func (a AuthServiceImpl) LoginUser(data schemas.UserLogin) (string, error) {
+func (a AuthServiceImpl) LoginUser(ctx context.Context, data schemas.UserLogin) (*schemas.TokenPair, error) {
+	ctx, span := tracer.Start(ctx, "AuthService.LoginUser")
+	defer span.End()
 	// Skip the check if user exists because it is already checked in the repository
-	token, err := a.authRepository.LoginUser(data.Email, data.Password)
+	user, err := a.authRepository.LoginUser(ctx, data.Email, data.Password)
 	if err != nil {
 		if err == gorm.ErrRecordNotFound {
-			return "", ErrNotFound
+			metrics.Logins.WithLabelValues("failed").Inc()
+			return nil, ErrNotFound
 		}
 		if err == bcrypt.ErrMismatchedHashAndPassword {
-			return "", ErrInvalidPassword
+			metrics.Logins.WithLabelValues("failed").Inc()
+			return nil, ErrInvalidPassword
 		}
 		logrus.Error(err)
-		return "", err
+		return nil, err
+	}
+
+	// Every login starts a new family of refresh tokens
+	familyID, err := randomToken()
+	if err != nil {
+		return nil, err
+	}
+	tokens, err := a.issueTokens(ctx, user.ID, familyID)
+	if err != nil {
+		return nil, err
 	}
-	return token, nil
+	metrics.Logins.WithLabelValues("succeeded").Inc()
+	return tokens, nil
 }
 
-func (a AuthServiceImpl) ResetPassword(data schemas.UserResetPassword) (string, error) {
-	user, err := a.userRepository.GetUserByEmail(data.Email)
+// RefreshToken exchanges a refresh token for a new token pair. Each refresh
+// token works once; presenting one again revokes every token of its family,
+// since either the client or an attacker holds a stolen copy.
+func (a AuthServiceImpl) RefreshToken(ctx context.Context, refreshToken string) (*schemas.TokenPair, error) {
+	ctx, span := tracer.Start(ctx, "AuthService.RefreshToken")
+	defer span.End()
+	stored, consumed, err := a.authRepository.ConsumeRefreshToken(ctx, hashToken(refreshToken))
 	if err != nil {
 		if err == gorm.ErrRecordNotFound {
-			return "", ErrNotFound
+			return nil, ErrInvalidToken
 		}
 		logrus.Error(err)
-		return "", err
+		return nil, err
 	}
+	if !consumed {
+		if stored.RevokedAt != nil {
+			return nil, ErrInvalidToken
+		}
+		logrus.Warnf("Refresh token reuse detected for user %d, revoking token family", stored.UserID)
+		err = a.authRepository.RevokeTokenFamily(ctx, stored.FamilyID)
+		if err != nil {
+			logrus.Error(err)
+			return nil, err
+		}
+		return nil, ErrTokenReused
+	}
+	if time.Now().After(stored.ExpiresAt) {
+		return nil, ErrInvalidToken
+	}
+	return a.issueTokens(ctx, stored.UserID, stored.FamilyID)
+}
+
+// Logout revokes the refresh token together with the rest of its family.
+// Unknown tokens are ignored.
+func (a AuthServiceImpl) Logout(ctx context.Context, refreshToken string) error {
+	ctx, span := tracer.Start(ctx, "AuthService.Logout")
+	defer span.End()
+	stored, _, err := a.authRepository.ConsumeRefreshToken(ctx, hashToken(refreshToken))
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil
+		}
+		logrus.Error(err)
+		return err
+	}
+	return a.authRepository.RevokeTokenFamily(ctx, stored.FamilyID)
+}
 
-	new_password := util.GenerateRandomString(8)
-	user.Password = new_password
-	_, err = a.userRepository.Update(&user)
+func (a AuthServiceImpl) issueTokens(ctx context.Context, userID int, familyID string) (*schemas.TokenPair, error) {
+	accessToken, err := token.GenerateToken(userID, a.cfg)
+	if err != nil {
+		return nil, err
+	}
+	refreshToken, err := randomToken()
+	if err != nil {
+		return nil, err
+	}
+	err = a.authRepository.SaveRefreshToken(ctx, &models.RefreshToken{
+		UserID:    userID,
+		FamilyID:  familyID,
+		TokenHash: hashToken(refreshToken),
+		ExpiresAt: time.Now().Add(a.cfg.Auth.RefreshTokenLifetime),
+	})
 	if err != nil {
 		logrus.Error(err)
+		return nil, err
+	}
+	return &schemas.TokenPair{
+		AccessToken:  accessToken,
+		RefreshToken: refreshToken,
+		ExpiresIn:    int(a.cfg.Auth.AccessTokenLifetime.Seconds()),
+	}, nil
+}
+
+func randomToken() (string, error) {
+	b := make([]byte, 32)
+	_, err := rand.Read(b)
+	if err != nil {
 		return "", err
 	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// publicLink points at a page of the frontend.
+func publicLink(cfg *config.Config, path string) string {
+	return strings.TrimSuffix(cfg.App.PublicURL, "/") + path
+}
+
+// ResetPassword mails a single-use reset link to the user. Unknown emails are
+// not reported, so the endpoint cannot be used to probe for accounts.
+func (a AuthServiceImpl) ResetPassword(ctx context.Context, data schemas.UserResetPassword) error {
+	ctx, span := tracer.Start(ctx, "AuthService.ResetPassword")
+	defer span.End()
+	user, err := a.userRepository.GetUserByEmail(ctx, data.Email)
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil
+		}
+		logrus.Error(err)
+		return err
+	}
+
+	resetToken, err := randomToken()
+	if err != nil {
+		return err
+	}
+	err = a.transactor.Transaction(ctx, func(tx *gorm.DB) error {
+		err := a.authRepository.WithTx(tx).SavePasswordResetToken(ctx, &models.PasswordResetToken{
+			UserID:    user.ID,
+			TokenHash: hashToken(resetToken),
+			ExpiresAt: time.Now().Add(a.cfg.Auth.ResetTokenLifetime),
+		})
+		if err != nil {
+			return err
+		}
+		return enqueueNotification(ctx, a.jobRepository.WithTx(tx), user.Email, notification.PasswordReset, notification.PasswordResetData{
+			Name:      user.Name,
+			Link:      publicLink(a.cfg, "/auth/reset?token="+url.QueryEscape(resetToken)),
+			ExpiresIn: a.cfg.Auth.ResetTokenLifetime,
+		})
+	})
+	if err != nil {
+		logrus.Error(err)
+		return err
+	}
+	return nil
+}
 
-	return new_password, nil
+// ConfirmPasswordReset sets a new password using a token from ResetPassword.
+func (a AuthServiceImpl) ConfirmPasswordReset(ctx context.Context, data schemas.UserResetPasswordConfirm) error {
+	ctx, span := tracer.Start(ctx, "AuthService.ConfirmPasswordReset")
+	defer span.End()
+	if len(data.Password) < minPasswordLength {
+		return ErrInvalidPassword
+	}
+	_, err := a.authRepository.ResetPassword(ctx, hashToken(data.Token), data.Password)
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return ErrInvalidToken
+		}
+		logrus.Error(err)
+		return err
+	}
+	return nil
 }
 
 func (a AuthServiceImpl) createUserModel(requestData *schemas.UserRegister) models.User {
@@ -87,9 +270,12 @@ func (a AuthServiceImpl) createUserModel(requestData *schemas.UserRegister) mode
 	}
 }
 
-func NewAuthService(authRepository repository.AuthRepository, userRepository repository.UserRepository) *AuthServiceImpl {
+func NewAuthService(authRepository repository.AuthRepository, userRepository repository.UserRepository, jobRepository repository.JobRepository, transactor repository.Transactor, cfg *config.Config) *AuthServiceImpl {
 	return &AuthServiceImpl{
 		authRepository: authRepository,
 		userRepository: userRepository,
+		jobRepository:  jobRepository,
+		transactor:     transactor,
+		cfg:            cfg,
 	}
 }
@@ -1,7 +1,11 @@
 package service
 
 import (
+	"context"
+	"net/url"
+	"regexp"
 	"testing"
+	"time"
 
 	"github.com/HermanPlay/web-app-backend/internal/config"
 	"github.com/HermanPlay/web-app-backend/package/domain/models"
@@ -11,31 +15,41 @@ import (
 )
 
 func TestRegisterUser(t *testing.T) {
+	ctx := context.Background()
 
 	db := utils.ConnectToTestDatabase()
 
 	cfg := config.Config{
-		Db:  config.Db{},
-		App: config.App{ApiSecret: "secret"},
+		Db:   config.Db{},
+		App:  config.App{ApiSecret: "secret"},
+		Auth: config.Auth{AccessTokenLifetime: 15 * time.Minute, RefreshTokenLifetime: time.Hour, ResetTokenLifetime: time.Hour},
+	}
+	authRepository, err := repository.NewAuthRepository(db)
+	if err != nil {
+		t.Errorf("Error when create new auth repository, when not expected. Error: %v", err)
 	}
-	authRepository := repository.NewAuthRepository(db, &cfg)
 	userRepository, err := repository.NewUserRepository(db)
 	if err != nil {
 		t.Errorf("Error when create new user repository, when not expected. Error: %v", err)
 	}
 
-	authService := NewAuthService(authRepository, userRepository)
+	mails := newTestOutbox(t, db)
+	authService := NewAuthService(authRepository, userRepository, mails.jobs, mails.transactor, &cfg)
 	t.Run("correct user", func(t *testing.T) {
 		user := schemas.UserRegister{
 			Name:     "name",
 			Email:    "email",
 			Password: "password",
 		}
-		registered, err := authService.RegisterUser(user)
+		registered, err := authService.RegisterUser(ctx, user)
 		if err != nil {
 			t.Errorf("Error when register user, when not expected. Error: %v", err)
 		}
 		compareUser(t, *registered, models.User{Name: user.Name, Email: user.Email, Role: models.UserRole})
+		messages := mails.Messages()
+		if len(messages) != 1 || messages[0].To != user.Email {
+			t.Errorf("Emails are %v, when expected a welcome email to %v", messages, user.Email)
+		}
 	})
 	t.Run("wrong user", func(t *testing.T) {
 		// Create existing user
@@ -44,7 +58,7 @@ func TestRegisterUser(t *testing.T) {
 			Email:    "email",
 			Password: "password",
 		}
-		userRepository.Save(user)
+		userRepository.Save(ctx, user)
 
 		// Try to register user with the same email
 		userRegister := schemas.UserRegister{
@@ -52,7 +66,7 @@ func TestRegisterUser(t *testing.T) {
 			Email:    "email",
 			Password: "password",
 		}
-		registered, err := authService.RegisterUser(userRegister)
+		registered, err := authService.RegisterUser(ctx, userRegister)
 		if err == nil {
 			t.Errorf("Error is nil, when expected")
 		}
@@ -67,36 +81,41 @@ func TestRegisterUser(t *testing.T) {
 }
 
 func TestLoginUser(t *testing.T) {
+	ctx := context.Background()
 	db := utils.ConnectToTestDatabase()
 	cfg := config.Config{
 		Db:  config.Db{},
 		App: config.App{},
 	}
-	authRepository := repository.NewAuthRepository(db, &cfg)
+	authRepository, err := repository.NewAuthRepository(db)
+	if err != nil {
+		t.Errorf("Error when create new auth repository, when not expected. Error: %v", err)
+	}
 	userRepository, err := repository.NewUserRepository(db)
 	if err != nil {
 		t.Errorf("Error when create new user repository, when not expected. Error: %v", err)
 	}
 
-	authService := NewAuthService(authRepository, userRepository)
+	outbox := newTestOutbox(t, db)
+	authService := NewAuthService(authRepository, userRepository, outbox.jobs, outbox.transactor, &cfg)
 	password := "passwordlong"
 	want := models.User{
 		Email:    "email",
 		Password: password,
 		Role:     models.UserRole,
 	}
-	userRepository.Save(&want)
+	userRepository.Save(ctx, &want)
 	t.Run("Invalid email", func(t *testing.T) {
 		userLogin := schemas.UserLogin{
 			Email:    "invalid",
 			Password: password,
 		}
-		got, err := authService.LoginUser(userLogin)
+		got, err := authService.LoginUser(ctx, userLogin)
 		if err != ErrNotFound {
 			t.Errorf("Error is not ErrNotFound, when expected. Error: %v", err)
 		}
-		if got != "" {
-			t.Errorf("Token is not empty, when expected, got: %v", got)
+		if got != nil {
+			t.Errorf("Tokens are not nil, when expected, got: %v", got)
 		}
 	})
 	t.Run("Invalid password", func(t *testing.T) {
@@ -104,12 +123,12 @@ func TestLoginUser(t *testing.T) {
 			Email:    want.Email,
 			Password: "invalid",
 		}
-		got, err := authService.LoginUser(userLogin)
+		got, err := authService.LoginUser(ctx, userLogin)
 		if err != ErrInvalidPassword {
 			t.Errorf("Error is not ErrInvalidPassword, when expected")
 		}
-		if got != "" {
-			t.Errorf("Token is not empty, when expected, got: %v", got)
+		if got != nil {
+			t.Errorf("Tokens are not nil, when expected, got: %v", got)
 		}
 	})
 	t.Run("Valid login", func(t *testing.T) {
@@ -117,30 +136,151 @@ func TestLoginUser(t *testing.T) {
 			Email:    want.Email,
 			Password: password,
 		}
-		token, err := authService.LoginUser(userLogin)
+		tokens, err := authService.LoginUser(ctx, userLogin)
 		if err != nil {
 			t.Errorf("Error when login, when not expected. Error: %v", err)
 			return
 		}
-		if token == "" {
+		if tokens.AccessToken == "" || tokens.RefreshToken == "" {
 			t.Errorf("Token is empty, when not expected")
 		}
 	})
 }
 
+func TestRefreshToken(t *testing.T) {
+	ctx := context.Background()
+	db := utils.ConnectToTestDatabase()
+	cfg := config.Config{
+		Db:   config.Db{},
+		App:  config.App{ApiSecret: "secret"},
+		Auth: config.Auth{AccessTokenLifetime: 15 * time.Minute, RefreshTokenLifetime: time.Hour, ResetTokenLifetime: time.Hour},
+	}
+	authRepository, err := repository.NewAuthRepository(db)
+	if err != nil {
+		t.Errorf("Error when create new auth repository, when not expected. Error: %v", err)
+	}
+	userRepository, err := repository.NewUserRepository(db)
+	if err != nil {
+		t.Errorf("Error when create new user repository, when not expected. Error: %v", err)
+	}
+	outbox := newTestOutbox(t, db)
+	authService := NewAuthService(authRepository, userRepository, outbox.jobs, outbox.transactor, &cfg)
+	userService := NewUserService(userRepository, &cfg)
+	password := "passwordlong"
+	user := models.User{Email: "email", Password: password, Role: models.UserRole}
+	userRepository.Save(ctx, &user)
+	login := schemas.UserLogin{Email: user.Email, Password: password}
+
+	t.Run("Unknown token", func(t *testing.T) {
+		_, err := authService.RefreshToken(ctx, "unknown")
+		if err != ErrInvalidToken {
+			t.Errorf("Error is not ErrInvalidToken, when expected. Error: %v", err)
+		}
+	})
+	t.Run("Rotation", func(t *testing.T) {
+		first, _ := authService.LoginUser(ctx, login)
+		second, err := authService.RefreshToken(ctx, first.RefreshToken)
+		if err != nil {
+			t.Fatalf("Error when refresh token, when not expected. Error: %v", err)
+		}
+		if second.RefreshToken == first.RefreshToken {
+			t.Errorf("Refresh token was not rotated")
+		}
+		decoded, err := userService.DecodeToken(ctx, second.AccessToken)
+		if err != nil || decoded.ID != user.ID {
+			t.Errorf("Access token does not belong to the user, got: %v, error: %v", decoded, err)
+		}
+		_, err = authService.RefreshToken(ctx, second.RefreshToken)
+		if err != nil {
+			t.Errorf("Error when refresh rotated token, when not expected. Error: %v", err)
+		}
+	})
+	t.Run("Reuse revokes family", func(t *testing.T) {
+		first, _ := authService.LoginUser(ctx, login)
+		other, _ := authService.LoginUser(ctx, login)
+		second, _ := authService.RefreshToken(ctx, first.RefreshToken)
+		_, err := authService.RefreshToken(ctx, first.RefreshToken)
+		if err != ErrTokenReused {
+			t.Errorf("Error is not ErrTokenReused, when expected. Error: %v", err)
+		}
+		_, err = authService.RefreshToken(ctx, second.RefreshToken)
+		if err != ErrInvalidToken {
+			t.Errorf("Error is not ErrInvalidToken for a revoked family, when expected. Error: %v", err)
+		}
+		_, err = authService.RefreshToken(ctx, other.RefreshToken)
+		if err != nil {
+			t.Errorf("Error when refresh token of another session, when not expected. Error: %v", err)
+		}
+	})
+	t.Run("Expired token", func(t *testing.T) {
+		err := authRepository.SaveRefreshToken(ctx, &models.RefreshToken{UserID: user.ID, FamilyID: "expired", TokenHash: hashToken("expired"), ExpiresAt: time.Now().Add(-time.Minute)})
+		if err != nil {
+			t.Fatalf("Error when save refresh token, when not expected. Error: %v", err)
+		}
+		_, err = authService.RefreshToken(ctx, "expired")
+		if err != ErrInvalidToken {
+			t.Errorf("Error is not ErrInvalidToken, when expected. Error: %v", err)
+		}
+	})
+}
+
+func TestLogout(t *testing.T) {
+	ctx := context.Background()
+	db := utils.ConnectToTestDatabase()
+	cfg := config.Config{
+		Db:   config.Db{},
+		App:  config.App{ApiSecret: "secret"},
+		Auth: config.Auth{AccessTokenLifetime: 15 * time.Minute, RefreshTokenLifetime: time.Hour, ResetTokenLifetime: time.Hour},
+	}
+	authRepository, err := repository.NewAuthRepository(db)
+	if err != nil {
+		t.Errorf("Error when create new auth repository, when not expected. Error: %v", err)
+	}
+	userRepository, err := repository.NewUserRepository(db)
+	if err != nil {
+		t.Errorf("Error when create new user repository, when not expected. Error: %v", err)
+	}
+	outbox := newTestOutbox(t, db)
+	authService := NewAuthService(authRepository, userRepository, outbox.jobs, outbox.transactor, &cfg)
+	password := "passwordlong"
+	user := models.User{Email: "email", Password: password, Role: models.UserRole}
+	userRepository.Save(ctx, &user)
+
+	first, _ := authService.LoginUser(ctx, schemas.UserLogin{Email: user.Email, Password: password})
+	second, _ := authService.RefreshToken(ctx, first.RefreshToken)
+	err = authService.Logout(ctx, second.RefreshToken)
+	if err != nil {
+		t.Errorf("Error when logout, when not expected. Error: %v", err)
+	}
+	_, err = authService.RefreshToken(ctx, second.RefreshToken)
+	if err != ErrInvalidToken {
+		t.Errorf("Error is not ErrInvalidToken after logout, when expected. Error: %v", err)
+	}
+	err = authService.Logout(ctx, "unknown")
+	if err != nil {
+		t.Errorf("Error when logout with unknown token, when not expected. Error: %v", err)
+	}
+}
+
 func TestResetPassword(t *testing.T) {
+	ctx := context.Background()
 	db := utils.ConnectToTestDatabase()
 	cfg := config.Config{
-		Db:  config.Db{},
-		App: config.App{},
+		Db:   config.Db{},
+		App:  config.App{ApiSecret: "secret", PublicURL: "http://frontend/"},
+		Auth: config.Auth{AccessTokenLifetime: 15 * time.Minute, RefreshTokenLifetime: time.Hour, ResetTokenLifetime: time.Hour},
+	}
+	authRepository, err := repository.NewAuthRepository(db)
+	if err != nil {
+		t.Errorf("Error when create new auth repository, when not expected. Error: %v", err)
 	}
-	authRepository := repository.NewAuthRepository(db, &cfg)
 	userRepository, err := repository.NewUserRepository(db)
 	if err != nil {
 		t.Errorf("Error when create new user repository, when not expected. Error: %v", err)
 	}
 
-	authService := NewAuthService(authRepository, userRepository)
+	mails := newTestOutbox(t, db)
+	authService := NewAuthService(authRepository, userRepository, mails.jobs, mails.transactor, &cfg)
 
 	// Create user
 	user := models.User{
@@ -148,24 +288,65 @@ func TestResetPassword(t *testing.T) {
 		Password: "password",
 		Role:     models.UserRole,
 	}
-	userRepository.Save(&user)
+	userRepository.Save(ctx, &user)
+	session, _ := authService.LoginUser(ctx, schemas.UserLogin{Email: user.Email, Password: "password"})
 
 	t.Run("Invalid email", func(t *testing.T) {
 		resetPassword := schemas.UserResetPassword{
 			Email: "invalid",
 		}
-		_, err := authService.ResetPassword(resetPassword)
-		if err != ErrNotFound {
-			t.Errorf("Error is not ErrNotFound, when expected. Error: %v", err)
+		err := authService.ResetPassword(ctx, resetPassword)
+		if err != nil {
+			t.Errorf("Error when reset password of unknown email, when not expected. Error: %v", err)
+		}
+		if len(mails.Messages()) != 0 {
+			t.Errorf("Sent %d emails, when expected none", len(mails.Messages()))
 		}
 	})
+
+	var resetToken string
 	t.Run("Valid email", func(t *testing.T) {
 		resetPassword := schemas.UserResetPassword{
 			Email: user.Email,
 		}
-		_, err := authService.ResetPassword(resetPassword)
+		err := authService.ResetPassword(ctx, resetPassword)
 		if err != nil {
 			t.Errorf("Error when reset password, when not expected. Error: %v", err)
 		}
+		messages := mails.Messages()
+		if len(messages) != 1 || messages[0].To != user.Email {
+			t.Fatalf("Emails are %v, when expected one to %v", messages, user.Email)
+		}
+		match := regexp.MustCompile(`http://frontend/auth/reset\?token=(\S+)`).FindStringSubmatch(messages[0].Text)
+		if match == nil {
+			t.Fatalf("Email does not contain a reset link, got: %v", messages[0].Text)
+		}
+		resetToken, _ = url.QueryUnescape(match[1])
+	})
+	t.Run("Short password", func(t *testing.T) {
+		err := authService.ConfirmPasswordReset(ctx, schemas.UserResetPasswordConfirm{Token: resetToken, Password: "short"})
+		if err != ErrInvalidPassword {
+			t.Errorf("Error is not ErrInvalidPassword, when expected. Error: %v", err)
+		}
+	})
+	t.Run("Confirm", func(t *testing.T) {
+		err := authService.ConfirmPasswordReset(ctx, schemas.UserResetPasswordConfirm{Token: resetToken, Password: "new password"})
+		if err != nil {
+			t.Fatalf("Error when confirm password reset, when not expected. Error: %v", err)
+		}
+		_, err = authService.LoginUser(ctx, schemas.UserLogin{Email: user.Email, Password: "new password"})
+		if err != nil {
+			t.Errorf("Error when login with the new password, when not expected. Error: %v", err)
+		}
+		_, err = authService.RefreshToken(ctx, session.RefreshToken)
+		if err != ErrInvalidToken {
+			t.Errorf("Error is not ErrInvalidToken for a session from before the reset, when expected. Error: %v", err)
+		}
+	})
+	t.Run("Token used twice", func(t *testing.T) {
+		err := authService.ConfirmPasswordReset(ctx, schemas.UserResetPasswordConfirm{Token: resetToken, Password: "another password"})
+		if err != ErrInvalidToken {
+			t.Errorf("Error is not ErrInvalidToken, when expected. Error: %v", err)
+		}
 	})
 }
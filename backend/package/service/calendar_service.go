@@ -0,0 +1,143 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/HermanPlay/web-app-backend/internal/calendar"
+	"github.com/HermanPlay/web-app-backend/internal/config"
+	"github.com/HermanPlay/web-app-backend/package/domain/models"
+	"github.com/HermanPlay/web-app-backend/package/repository"
+	"gorm.io/gorm"
+)
+
+type CalendarService interface {
+	GetEventCalendar(ctx context.Context, eventID int) ([]byte, error)
+	GetFeed(ctx context.Context, token string) ([]byte, error)
+	CreateFeedToken(ctx context.Context, userID int) (string, error)
+	RevokeFeedToken(ctx context.Context, userID int) error
+}
+
+type CalendarServiceImpl struct {
+	eventRepository repository.EventRepository
+	authRepository  repository.AuthRepository
+	cfg             *config.Config
+}
+
+// GetEventCalendar exports a single event as an iCalendar file.
+func (c CalendarServiceImpl) GetEventCalendar(ctx context.Context, eventID int) ([]byte, error) {
+	ctx, span := tracer.Start(ctx, "CalendarService.GetEventCalendar")
+	defer span.End()
+	event, err := c.eventRepository.GetByID(ctx, eventID)
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	return calendar.Encode(calendar.Calendar{
+		Events: []calendar.Event{c.createCalendarEvent(event)},
+	}, time.Now()), nil
+}
+
+// GetFeed exports the events GetMyEvents lists for the owner of a feed token.
+func (c CalendarServiceImpl) GetFeed(ctx context.Context, token string) ([]byte, error) {
+	ctx, span := tracer.Start(ctx, "CalendarService.GetFeed")
+	defer span.End()
+	feedToken, err := c.authRepository.GetFeedToken(ctx, hashToken(token))
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, ErrInvalidToken
+		}
+		return nil, err
+	}
+	events, err := c.eventRepository.GetMyEvents(ctx, feedToken.UserID)
+	if err != nil {
+		return nil, err
+	}
+	createdEvents, err := c.eventRepository.GetCreatedEvents(ctx, feedToken.UserID)
+	if err != nil {
+		return nil, err
+	}
+	events = append(events, createdEvents...)
+
+	// Creators who booked their own event would see it twice
+	seen := map[int]bool{}
+	feed := calendar.Calendar{Name: "My events", Events: []calendar.Event{}}
+	for _, event := range events {
+		if seen[event.ID] {
+			continue
+		}
+		seen[event.ID] = true
+		feed.Events = append(feed.Events, c.createCalendarEvent(event))
+	}
+	return calendar.Encode(feed, time.Now()), nil
+}
+
+// CreateFeedToken issues a new feed token for the user and revokes the
+// previous one. The token is only ever returned here.
+func (c CalendarServiceImpl) CreateFeedToken(ctx context.Context, userID int) (string, error) {
+	ctx, span := tracer.Start(ctx, "CalendarService.CreateFeedToken")
+	defer span.End()
+	token, err := randomToken()
+	if err != nil {
+		return "", err
+	}
+	err = c.authRepository.SaveFeedToken(ctx, &models.FeedToken{
+		UserID:    userID,
+		TokenHash: hashToken(token),
+	})
+	if err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+func (c CalendarServiceImpl) RevokeFeedToken(ctx context.Context, userID int) error {
+	ctx, span := tracer.Start(ctx, "CalendarService.RevokeFeedToken")
+	defer span.End()
+	err := c.authRepository.DeleteFeedToken(ctx, userID)
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return ErrNotFound
+		}
+		return err
+	}
+	return nil
+}
+
+func (c CalendarServiceImpl) createCalendarEvent(event models.Event) calendar.Event {
+	return calendar.Event{
+		UID:         fmt.Sprintf("event-%d@%s", event.ID, c.uidDomain()),
+		Sequence:    event.Sequence,
+		Summary:     event.Title,
+		Description: event.Description,
+		Location:    event.Location,
+		URL:         publicLink(c.cfg, fmt.Sprintf("/events/%d", event.ID)),
+		Start:       event.StartsAt,
+		End:         event.EndsAt,
+		TimeZone:    event.TimeZone,
+		Created:     event.CreatedAt,
+		Modified:    event.UpdatedAt,
+	}
+}
+
+// uidDomain makes event UIDs globally unique, as RFC 5545 asks for, by
+// qualifying them with the host the frontend is served from.
+func (c CalendarServiceImpl) uidDomain() string {
+	public, err := url.Parse(c.cfg.App.PublicURL)
+	if err != nil || public.Hostname() == "" {
+		return "event-manager"
+	}
+	return public.Hostname()
+}
+
+func NewCalendarService(eventRepository repository.EventRepository, authRepository repository.AuthRepository, cfg *config.Config) CalendarService {
+	return &CalendarServiceImpl{
+		eventRepository: eventRepository,
+		authRepository:  authRepository,
+		cfg:             cfg,
+	}
+}
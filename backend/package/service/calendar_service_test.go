@@ -0,0 +1,131 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/HermanPlay/web-app-backend/internal/config"
+	"github.com/HermanPlay/web-app-backend/package/domain/models"
+	"github.com/HermanPlay/web-app-backend/package/repository"
+	"github.com/HermanPlay/web-app-backend/package/utils"
+)
+
+func TestCalendar(t *testing.T) {
+	ctx := context.Background()
+	db := utils.ConnectToTestDatabase()
+	eventRepository, err := repository.NewEventRepository(db)
+	if err != nil {
+		t.Errorf("Error when create new event repository, when not expected. Error: %v", err)
+	}
+	authRepository, err := repository.NewAuthRepository(db)
+	if err != nil {
+		t.Errorf("Error when create new auth repository, when not expected. Error: %v", err)
+	}
+	userRepository, err := repository.NewUserRepository(db)
+	if err != nil {
+		t.Errorf("Error when create new user repository, when not expected. Error: %v", err)
+	}
+	user, err := userRepository.Save(ctx, &models.User{Name: "name", Email: "email", Password: "password", Role: "user"})
+	if err != nil {
+		t.Errorf("Error when save user, when not expected. Error: %v", err)
+	}
+	other, err := userRepository.Save(ctx, &models.User{Name: "other", Email: "other", Password: "password", Role: "user"})
+	if err != nil {
+		t.Errorf("Error when save user, when not expected. Error: %v", err)
+	}
+	calendarService := NewCalendarService(eventRepository, authRepository, &config.Config{App: config.App{PublicURL: "https://events.example.com"}})
+
+	created, err := eventRepository.Save(ctx, &models.Event{Title: "created", Description: "description", Location: "location", StartsAt: eventStart, EndsAt: eventEnd, TimeZone: "Europe/Warsaw", CreatedBy: user.ID, Sequence: 3})
+	if err != nil {
+		t.Errorf("Error when save event, when not expected. Error: %v", err)
+	}
+	booked, err := eventRepository.Save(ctx, &models.Event{Title: "booked", Description: "description", Location: "location", StartsAt: eventStart, EndsAt: eventEnd, TimeZone: "UTC", CreatedBy: other.ID})
+	if err != nil {
+		t.Errorf("Error when save event, when not expected. Error: %v", err)
+	}
+	_, err = eventRepository.Save(ctx, &models.Event{Title: "unrelated", Description: "description", Location: "location", StartsAt: eventStart, EndsAt: eventEnd, TimeZone: "UTC", CreatedBy: other.ID})
+	if err != nil {
+		t.Errorf("Error when save event, when not expected. Error: %v", err)
+	}
+	for _, eventID := range []int{booked.ID, created.ID} {
+		_, err = eventRepository.BookEvent(ctx, eventID, user.ID, bookingPolicy)
+		if err != nil {
+			t.Errorf("Error when book event, when not expected. Error: %v", err)
+		}
+	}
+
+	t.Run("Event export", func(t *testing.T) {
+		ics, err := calendarService.GetEventCalendar(ctx, created.ID)
+		if err != nil {
+			t.Fatalf("Error when export event, when not expected. Error: %v", err)
+		}
+		for _, line := range []string{
+			fmt.Sprintf("UID:event-%d@events.example.com", created.ID),
+			"SEQUENCE:3",
+			"DTSTART;TZID=Europe/Warsaw:20241115T090000",
+			"BEGIN:VTIMEZONE",
+			fmt.Sprintf("URL:https://events.example.com/events/%d", created.ID),
+		} {
+			if !strings.Contains(string(ics), "\r\n"+line+"\r\n") {
+				t.Errorf("Export does not contain %q, got: %q", line, ics)
+			}
+		}
+		_, err = calendarService.GetEventCalendar(ctx, 1000)
+		if err != ErrNotFound {
+			t.Errorf("Error is not ErrNotFound, when expected. Error: %v", err)
+		}
+	})
+
+	var token string
+	t.Run("Feed", func(t *testing.T) {
+		_, err := calendarService.GetFeed(ctx, "unknown")
+		if err != ErrInvalidToken {
+			t.Errorf("Error is not ErrInvalidToken, when expected. Error: %v", err)
+		}
+		token, err = calendarService.CreateFeedToken(ctx, user.ID)
+		if err != nil {
+			t.Fatalf("Error when create feed token, when not expected. Error: %v", err)
+		}
+		ics, err := calendarService.GetFeed(ctx, token)
+		if err != nil {
+			t.Fatalf("Error when get feed, when not expected. Error: %v", err)
+		}
+		if strings.Count(string(ics), "BEGIN:VEVENT") != 2 {
+			t.Errorf("Feed has %d events, when expected the created and the booked one once each", strings.Count(string(ics), "BEGIN:VEVENT"))
+		}
+		if !strings.Contains(string(ics), "SUMMARY:booked\r\n") || strings.Contains(string(ics), "SUMMARY:unrelated\r\n") {
+			t.Errorf("Feed does not list the events of the user, got: %q", ics)
+		}
+	})
+	t.Run("Rotated token", func(t *testing.T) {
+		rotated, err := calendarService.CreateFeedToken(ctx, user.ID)
+		if err != nil {
+			t.Fatalf("Error when create feed token, when not expected. Error: %v", err)
+		}
+		_, err = calendarService.GetFeed(ctx, token)
+		if err != ErrInvalidToken {
+			t.Errorf("Error for the previous token is not ErrInvalidToken, when expected. Error: %v", err)
+		}
+		_, err = calendarService.GetFeed(ctx, rotated)
+		if err != nil {
+			t.Errorf("Error when get feed, when not expected. Error: %v", err)
+		}
+		token = rotated
+	})
+	t.Run("Revoked token", func(t *testing.T) {
+		err := calendarService.RevokeFeedToken(ctx, user.ID)
+		if err != nil {
+			t.Errorf("Error when revoke feed token, when not expected. Error: %v", err)
+		}
+		_, err = calendarService.GetFeed(ctx, token)
+		if err != ErrInvalidToken {
+			t.Errorf("Error for a revoked token is not ErrInvalidToken, when expected. Error: %v", err)
+		}
+		err = calendarService.RevokeFeedToken(ctx, user.ID)
+		if err != ErrNotFound {
+			t.Errorf("Error is not ErrNotFound, when expected. Error: %v", err)
+		}
+	})
+}
@@ -9,4 +9,6 @@ var (
 	ErrInvalidPassword = fmt.Errorf("invalid password")
 	ErrInvalidInput    = fmt.Errorf("invalid input")
 	ErrInputTooLong    = fmt.Errorf("input too long")
+	ErrForbidden       = fmt.Errorf("forbidden")
+	ErrTokenReused     = fmt.Errorf("refresh token reused")
 )
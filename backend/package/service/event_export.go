@@ -0,0 +1,95 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/HermanPlay/web-app-backend/package/domain/models"
+	"github.com/HermanPlay/web-app-backend/package/domain/schemas"
+	"gorm.io/gorm"
+)
+
+var (
+	attendeeExportColumns = []string{"Name", "Email", "Booked at", "Status", "Waitlist position"}
+	eventExportColumns    = []string{"ID", "Title", "Short description", "Location", "Starts at", "Ends at", "Time zone", "Capacity", "Waitlist enabled", "Featured", "Created by"}
+)
+
+// ExportAttendees returns the bookings of the event as a table, with booking
+// times in the event's time zone. Like GetAttendees it is only open to those
+// who manage the event. The rows are read from the database while they are
+// written.
+func (e EventServiceImpl) ExportAttendees(ctx context.Context, eventID int, requester *schemas.User) (*schemas.Export, error) {
+	ctx, span := tracer.Start(ctx, "EventService.ExportAttendees")
+	defer span.End()
+	event, err := e.eventRepository.GetByID(ctx, eventID)
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	err = e.checkCanManage(ctx, event, requester)
+	if err != nil {
+		return nil, err
+	}
+
+	location := eventLocation(event)
+	return &schemas.Export{
+		Name:    fmt.Sprintf("event-%d-attendees", event.ID),
+		Columns: attendeeExportColumns,
+		Rows: func(write func(values ...any) error) error {
+			position := 0
+			return e.eventRepository.StreamAttendees(ctx, event.ID, func(booking models.EventUser) error {
+				var waitlistPosition any
+				if booking.Status == models.BookingWaitlisted {
+					position++
+					waitlistPosition = position
+				}
+				return write(booking.User.Name, booking.User.Email, booking.CreatedAt.In(location), string(booking.Status), waitlistPosition)
+			})
+		},
+	}, nil
+}
+
+// ExportEvents returns the events matching the filter as a table, ignoring
+// pagination. Admins get all of them, other users only the events they
+// created or co-organize. Unlimited capacity is left empty.
+func (e EventServiceImpl) ExportEvents(ctx context.Context, filter schemas.EventFilter, requester *schemas.User) (*schemas.Export, error) {
+	ctx, span := tracer.Start(ctx, "EventService.ExportEvents")
+	defer span.End()
+	query, err := eventQuery(filter)
+	if err != nil {
+		return nil, err
+	}
+	if !isAdmin(requester) {
+		query.ManagedBy = requester.ID
+	}
+
+	return &schemas.Export{
+		Name:    "events",
+		Columns: eventExportColumns,
+		Rows: func(write func(values ...any) error) error {
+			return e.eventRepository.StreamEvents(ctx, query, func(event models.Event) error {
+				location := eventLocation(event)
+				var capacity any
+				if event.Capacity > 0 {
+					capacity = event.Capacity
+				}
+				return write(
+					event.ID, event.Title, event.ShortDescription, event.Location,
+					event.StartsAt.In(location), event.EndsAt.In(location), event.TimeZone,
+					capacity, event.WaitlistEnabled, event.IsFeatured, event.CreatedBy,
+				)
+			})
+		},
+	}, nil
+}
+
+func eventLocation(event models.Event) *time.Location {
+	location, err := time.LoadLocation(event.TimeZone)
+	if err != nil {
+		return time.UTC
+	}
+	return location
+}
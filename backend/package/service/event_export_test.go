@@ -0,0 +1,186 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/HermanPlay/web-app-backend/internal/config"
+	"github.com/HermanPlay/web-app-backend/package/domain/models"
+	"github.com/HermanPlay/web-app-backend/package/domain/schemas"
+	"github.com/HermanPlay/web-app-backend/package/repository"
+	"github.com/HermanPlay/web-app-backend/package/utils"
+)
+
+func exportRows(t *testing.T, export *schemas.Export) [][]any {
+	var rows [][]any
+	err := export.Rows(func(values ...any) error {
+		if len(values) != len(export.Columns) {
+			t.Errorf("Row has %d values, when expected %d", len(values), len(export.Columns))
+		}
+		rows = append(rows, values)
+		return nil
+	})
+	if err != nil {
+		t.Errorf("Error when write export rows, when not expected. Error: %v", err)
+	}
+	return rows
+}
+
+func TestExportAttendees(t *testing.T) {
+	ctx := context.Background()
+	db := utils.ConnectToTestDatabase()
+	eventRepository, err := repository.NewEventRepository(db)
+	if err != nil {
+		t.Errorf("Error when create new event repository, when not expected. Error: %v", err)
+	}
+	outbox := newTestOutbox(t, db)
+	eventService := NewEventService(eventRepository, outbox.webhooks, outbox.jobs, outbox.transactor, &config.Config{})
+	userRepository, err := repository.NewUserRepository(db)
+	if err != nil {
+		t.Errorf("Error when create new user repository, when not expected. Error: %v", err)
+	}
+	creator, _ := userRepository.Save(ctx, &models.User{Name: "creator", Email: "creator", Password: "password", Role: models.ManagerRole})
+	admin, _ := userRepository.Save(ctx, &models.User{Name: "admin", Email: "admin", Password: "password", Role: models.AdminRole})
+	attendee, _ := userRepository.Save(ctx, &models.User{Name: "attendee", Email: "attendee", Password: "password", Role: models.UserRole})
+	waitlisted, _ := userRepository.Save(ctx, &models.User{Name: "waitlisted", Email: "waitlisted", Password: "password", Role: models.UserRole})
+	event, err := eventRepository.Save(ctx, &models.Event{
+		Title:            "title",
+		ShortDescription: "short description",
+		Description:      "description",
+		Location:         "location",
+		StartsAt:         eventStart,
+		EndsAt:           eventEnd,
+		TimeZone:         "Europe/Warsaw",
+		Capacity:         1,
+		WaitlistEnabled:  true,
+		CreatedBy:        creator.ID,
+	})
+	if err != nil {
+		t.Errorf("Error when save event, when not expected. Error: %v", err)
+	}
+	eventService.BookEvent(ctx, event.ID, attendee.ID)
+	eventService.BookEvent(ctx, event.ID, waitlisted.ID)
+
+	t.Run("Creator", func(t *testing.T) {
+		export, err := eventService.ExportAttendees(ctx, event.ID, createUserSchema(&creator))
+		if err != nil {
+			t.Fatalf("Error when export attendees, when not expected. Error: %v", err)
+		}
+		if export.Name != fmt.Sprintf("event-%d-attendees", event.ID) {
+			t.Errorf("Export name is not the same, got: %v", export.Name)
+		}
+		rows := exportRows(t, export)
+		if len(rows) != 2 {
+			t.Fatalf("Rows count is not the same, got: %v, want: %v", len(rows), 2)
+		}
+		if rows[0][0] != attendee.Name || rows[0][1] != attendee.Email || rows[0][3] != string(models.BookingConfirmed) || rows[0][4] != nil {
+			t.Errorf("First row is not the same, got: %v", rows[0])
+		}
+		if rows[1][3] != string(models.BookingWaitlisted) || rows[1][4] != 1 {
+			t.Errorf("Second row is not the same, got: %v", rows[1])
+		}
+		bookedAt, ok := rows[0][2].(time.Time)
+		if !ok || bookedAt.Location().String() != "Europe/Warsaw" {
+			t.Errorf("Booking time is not in the event's time zone, got: %v", rows[0][2])
+		}
+	})
+	t.Run("Admin", func(t *testing.T) {
+		_, err := eventService.ExportAttendees(ctx, event.ID, createUserSchema(&admin))
+		if err != nil {
+			t.Errorf("Error when export attendees, when not expected. Error: %v", err)
+		}
+	})
+	t.Run("Other user", func(t *testing.T) {
+		export, err := eventService.ExportAttendees(ctx, event.ID, createUserSchema(&attendee))
+		if err != ErrForbidden {
+			t.Errorf("Error is not ErrForbidden, when expected. Error: %v", err)
+		}
+		if export != nil {
+			t.Errorf("Export is not nil, when expected")
+		}
+	})
+	t.Run("Non existing event", func(t *testing.T) {
+		_, err := eventService.ExportAttendees(ctx, 1000, createUserSchema(&admin))
+		if err != ErrNotFound {
+			t.Errorf("Error is not ErrNotFound, when expected. Error: %v", err)
+		}
+	})
+}
+
+func TestExportEvents(t *testing.T) {
+	ctx := context.Background()
+	db := utils.ConnectToTestDatabase()
+	eventRepository, err := repository.NewEventRepository(db)
+	if err != nil {
+		t.Errorf("Error when create new event repository, when not expected. Error: %v", err)
+	}
+	outbox := newTestOutbox(t, db)
+	eventService := NewEventService(eventRepository, outbox.webhooks, outbox.jobs, outbox.transactor, &config.Config{})
+	userRepository, err := repository.NewUserRepository(db)
+	if err != nil {
+		t.Errorf("Error when create new user repository, when not expected. Error: %v", err)
+	}
+	creator, _ := userRepository.Save(ctx, &models.User{Name: "creator", Email: "creator", Password: "password", Role: models.ManagerRole})
+	other, _ := userRepository.Save(ctx, &models.User{Name: "other", Email: "other", Password: "password", Role: models.ManagerRole})
+	admin, _ := userRepository.Save(ctx, &models.User{Name: "admin", Email: "admin", Password: "password", Role: models.AdminRole})
+	for i, createdBy := range []int{creator.ID, creator.ID, other.ID} {
+		_, err := eventRepository.Save(ctx, &models.Event{
+			Title:            fmt.Sprintf("event %d", i),
+			ShortDescription: "short description",
+			Description:      "description",
+			Location:         "location",
+			StartsAt:         eventStart.Add(time.Duration(i) * time.Hour),
+			EndsAt:           eventEnd.Add(time.Duration(i) * time.Hour),
+			TimeZone:         "Europe/Warsaw",
+			CreatedBy:        createdBy,
+		})
+		if err != nil {
+			t.Errorf("Error when save event, when not expected. Error: %v", err)
+		}
+	}
+
+	t.Run("Own events", func(t *testing.T) {
+		export, err := eventService.ExportEvents(ctx, schemas.EventFilter{Sort: "-starts_at"}, createUserSchema(&creator))
+		if err != nil {
+			t.Fatalf("Error when export events, when not expected. Error: %v", err)
+		}
+		rows := exportRows(t, export)
+		if len(rows) != 2 {
+			t.Fatalf("Rows count is not the same, got: %v, want: %v", len(rows), 2)
+		}
+		if rows[0][1] != "event 1" || rows[1][1] != "event 0" || rows[0][7] != nil || rows[0][10] != creator.ID {
+			t.Errorf("Rows are not the same, got: %v", rows)
+		}
+	})
+	t.Run("Admin", func(t *testing.T) {
+		export, err := eventService.ExportEvents(ctx, schemas.EventFilter{Search: "event"}, createUserSchema(&admin))
+		if err != nil {
+			t.Fatalf("Error when export events, when not expected. Error: %v", err)
+		}
+		if rows := exportRows(t, export); len(rows) != 3 {
+			t.Errorf("Rows count is not the same, got: %v, want: %v", len(rows), 3)
+		}
+	})
+	t.Run("Invalid filter", func(t *testing.T) {
+		_, err := eventService.ExportEvents(ctx, schemas.EventFilter{Sort: "location"}, createUserSchema(&admin))
+		if err != ErrInvalidSort {
+			t.Errorf("Error is not ErrInvalidSort, when expected. Error: %v", err)
+		}
+	})
+	t.Run("Write error", func(t *testing.T) {
+		export, err := eventService.ExportEvents(ctx, schemas.EventFilter{}, createUserSchema(&admin))
+		if err != nil {
+			t.Fatalf("Error when export events, when not expected. Error: %v", err)
+		}
+		failed := errors.New("client went away")
+		err = export.Rows(func(values ...any) error {
+			return failed
+		})
+		if err != failed {
+			t.Errorf("Error is not the write error, when expected. Error: %v", err)
+		}
+	})
+}
@@ -0,0 +1,373 @@
+package service
+
+import (
+	"context"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/HermanPlay/web-app-backend/internal/calendar"
+	"github.com/HermanPlay/web-app-backend/internal/metrics"
+	"github.com/HermanPlay/web-app-backend/package/domain/models"
+	"github.com/HermanPlay/web-app-backend/package/domain/schemas"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrImportFormat   = fmt.Errorf("import format must be csv or ics")
+	ErrImportFile     = fmt.Errorf("import file could not be read")
+	ErrImportMapping  = fmt.Errorf("invalid column mapping")
+	ErrImportEmpty    = fmt.Errorf("import file has no events")
+	ErrImportTooLarge = fmt.Errorf("import file has too many events")
+)
+
+const maxImportEvents = 1000
+
+// importFields are the event fields a CSV import fills, named like the JSON
+// fields of EventInput.
+var importFields = []string{"title", "short_description", "description", "location", "starts_at", "ends_at", "time_zone", "capacity", "waitlist_enabled", "is_featured"}
+
+// importTimeLayouts are accepted for CSV times without an offset, which are
+// read in the zone of the event.
+var importTimeLayouts = []string{"2006-01-02 15:04", "2006-01-02 15:04:05", "2006-01-02T15:04", "2006-01-02T15:04:05"}
+
+type importRow struct {
+	row    int
+	input  schemas.EventInput
+	errors []schemas.ImportError
+}
+
+// ImportEvents reads events from a CSV or iCalendar file and checks each of
+// them with the rules of CreateEvent. Unless options.Commit is set nothing is
+// saved, so the report can be reviewed first. A commit saves either all
+// events or, when any of them is invalid, none.
+func (e EventServiceImpl) ImportEvents(ctx context.Context, file io.Reader, options schemas.EventImport, createdBy int) (*schemas.EventImportResult, error) {
+	ctx, span := tracer.Start(ctx, "EventService.ImportEvents")
+	defer span.End()
+	floating := time.UTC
+	if options.TimeZone != "" {
+		if options.TimeZone == "Local" {
+			return nil, ErrInvalidZone
+		}
+		var err error
+		floating, err = time.LoadLocation(options.TimeZone)
+		if err != nil {
+			return nil, ErrInvalidZone
+		}
+	}
+
+	var rows []importRow
+	var err error
+	switch strings.ToLower(options.Format) {
+	case "csv":
+		rows, err = readCSVImport(file, options)
+	case "ics":
+		rows, err = readCalendarImport(file, floating)
+	default:
+		return nil, ErrImportFormat
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(rows) == 0 {
+		return nil, ErrImportEmpty
+	}
+
+	result := &schemas.EventImportResult{
+		Total:  len(rows),
+		Errors: []schemas.ImportError{},
+		Events: []*schemas.Event{},
+	}
+	var eventModels []*models.Event
+	for _, row := range rows {
+		rowErrors := row.errors
+		if len(rowErrors) == 0 {
+			rowErrors = validateImportRow(row)
+		}
+		if len(rowErrors) > 0 {
+			result.Errors = append(result.Errors, rowErrors...)
+			continue
+		}
+		eventModel := e.createEventModel(&row.input)
+		eventModel.CreatedBy = createdBy
+		eventModels = append(eventModels, eventModel)
+	}
+	if len(result.Errors) > 0 || !options.Commit {
+		for _, eventModel := range eventModels {
+			result.Events = append(result.Events, createEventSchema(eventModel))
+		}
+		return result, nil
+	}
+
+	err = e.transactor.Transaction(ctx, func(tx *gorm.DB) error {
+		events := e.eventRepository.WithTx(tx)
+		webhooks := e.webhookRepository.WithTx(tx)
+		jobs := e.jobRepository.WithTx(tx)
+		for _, eventModel := range eventModels {
+			event, err := events.Save(ctx, eventModel)
+			if err != nil {
+				return err
+			}
+			eventResponse := createEventSchema(&event)
+			result.Events = append(result.Events, eventResponse)
+			err = enqueueWebhooks(ctx, webhooks, jobs, models.WebhookEventCreated, eventResponse)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	result.Committed = true
+	metrics.EventsCreated.Add(float64(len(eventModels)))
+	return result, nil
+}
+
+// validateImportRow reports the fields the request binding of CreateEvent
+// requires and then its other rules.
+func validateImportRow(row importRow) []schemas.ImportError {
+	input := row.input
+	required := []struct {
+		field string
+		empty bool
+	}{
+		{"title", input.Title == ""},
+		{"short_description", input.ShortDescription == ""},
+		{"description", input.Description == ""},
+		{"location", input.Location == ""},
+		{"starts_at", input.StartsAt.IsZero()},
+		{"ends_at", input.EndsAt.IsZero()},
+		{"time_zone", input.TimeZone == ""},
+	}
+	var rowErrors []schemas.ImportError
+	for _, r := range required {
+		if r.empty {
+			rowErrors = append(rowErrors, schemas.ImportError{Row: row.row, Field: r.field, Message: "is required"})
+		}
+	}
+	if len(rowErrors) > 0 {
+		return rowErrors
+	}
+
+	err := validateEventInput(&input)
+	if err == nil {
+		return nil
+	}
+	field := ""
+	switch err {
+	case ErrInputTooLong:
+		field = "short_description"
+	case ErrInvalidInput:
+		field = "capacity"
+	case ErrInvalidZone:
+		field = "time_zone"
+	case ErrInvalidTimes:
+		field = "ends_at"
+	}
+	return []schemas.ImportError{{Row: row.row, Field: field, Message: err.Error()}}
+}
+
+// readCSVImport reads one event per line after the header. Rows are numbered
+// by the line they start on, so they match what spreadsheets show.
+func readCSVImport(file io.Reader, options schemas.EventImport) ([]importRow, error) {
+	reader := csv.NewReader(file)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+	header, err := reader.Read()
+	if err != nil {
+		return nil, ErrImportFile
+	}
+	columns := map[string]int{}
+	for i, name := range header {
+		if i == 0 {
+			// Spreadsheet apps like to start UTF-8 files with a byte order mark
+			name = strings.TrimPrefix(name, "\ufeff")
+		}
+		columns[strings.TrimSpace(name)] = i
+	}
+
+	for field := range options.Mapping {
+		if !isImportField(field) {
+			return nil, fmt.Errorf("%w: unknown field %s", ErrImportMapping, field)
+		}
+	}
+	fieldColumns := map[string]int{}
+	for _, field := range importFields {
+		column, mapped := options.Mapping[field]
+		if !mapped {
+			column = field
+		}
+		index, ok := columns[column]
+		if !ok {
+			if mapped {
+				return nil, fmt.Errorf("%w: no column %s", ErrImportMapping, column)
+			}
+			continue
+		}
+		fieldColumns[field] = index
+	}
+
+	var rows []importRow
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrImportFile, err)
+		}
+		if strings.TrimSpace(strings.Join(record, "")) == "" {
+			continue
+		}
+		if len(rows) == maxImportEvents {
+			return nil, ErrImportTooLarge
+		}
+		line, _ := reader.FieldPos(0)
+		cell := func(field string) string {
+			index, ok := fieldColumns[field]
+			if !ok || index >= len(record) {
+				return ""
+			}
+			return strings.TrimSpace(record[index])
+		}
+		rows = append(rows, readCSVRow(line, cell, options.TimeZone))
+	}
+	return rows, nil
+}
+
+func readCSVRow(line int, cell func(field string) string, defaultZone string) importRow {
+	row := importRow{row: line}
+	fieldError := func(field, message string) {
+		row.errors = append(row.errors, schemas.ImportError{Row: line, Field: field, Message: message})
+	}
+
+	row.input = schemas.EventInput{
+		Title:            cell("title"),
+		ShortDescription: cell("short_description"),
+		Description:      cell("description"),
+		Location:         cell("location"),
+		TimeZone:         cell("time_zone"),
+	}
+	if row.input.TimeZone == "" {
+		row.input.TimeZone = defaultZone
+	}
+	for _, field := range []string{"starts_at", "ends_at"} {
+		value := cell(field)
+		if value == "" {
+			continue
+		}
+		t, ok := parseImportTime(value, row.input.TimeZone)
+		if !ok {
+			fieldError(field, "must be an RFC 3339 time or a local time like 2024-11-15 19:00")
+			continue
+		}
+		if field == "starts_at" {
+			row.input.StartsAt = t
+		} else {
+			row.input.EndsAt = t
+		}
+	}
+	if value := cell("capacity"); value != "" {
+		capacity, err := strconv.Atoi(value)
+		if err != nil {
+			fieldError("capacity", "must be a whole number")
+		}
+		row.input.Capacity = capacity
+	}
+	for _, field := range []string{"waitlist_enabled", "is_featured"} {
+		value := cell(field)
+		if value == "" {
+			continue
+		}
+		enabled, err := strconv.ParseBool(strings.ToLower(value))
+		if err != nil {
+			fieldError(field, "must be true or false")
+		}
+		if field == "waitlist_enabled" {
+			row.input.WaitlistEnabled = enabled
+		} else {
+			row.input.IsFeatured = enabled
+		}
+	}
+	return row
+}
+
+// parseImportTime reads value as an RFC 3339 time, or as a local time in the
+// given zone. Local times in an unknown zone are accepted as UTC here, the
+// zone itself is reported by the validation.
+func parseImportTime(value string, zone string) (time.Time, bool) {
+	t, err := time.Parse(time.RFC3339, value)
+	if err == nil {
+		return t, true
+	}
+	loc, err := time.LoadLocation(zone)
+	if err != nil || zone == "Local" {
+		loc = time.UTC
+	}
+	for _, layout := range importTimeLayouts {
+		t, err := time.ParseInLocation(layout, value, loc)
+		if err == nil {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
+
+func isImportField(field string) bool {
+	for _, f := range importFields {
+		if f == field {
+			return true
+		}
+	}
+	return false
+}
+
+// readCalendarImport reads the VEVENTs of an iCalendar file. Calendars have
+// no short description, the first line of the description stands in for it.
+func readCalendarImport(file io.Reader, floating *time.Location) ([]importRow, error) {
+	events, err := calendar.Decode(file, floating)
+	if err != nil {
+		return nil, ErrImportFile
+	}
+	if len(events) > maxImportEvents {
+		return nil, ErrImportTooLarge
+	}
+	rows := make([]importRow, 0, len(events))
+	for i, event := range events {
+		rows = append(rows, importRow{
+			row: i + 1,
+			input: schemas.EventInput{
+				Title:            event.Summary,
+				ShortDescription: shortDescription(event.Description, event.Summary),
+				Description:      event.Description,
+				Location:         event.Location,
+				StartsAt:         event.Start,
+				EndsAt:           event.End,
+				TimeZone:         event.TimeZone,
+			},
+		})
+	}
+	return rows, nil
+}
+
+func shortDescription(description, fallback string) string {
+	short, _, _ := strings.Cut(strings.TrimSpace(description), "\n")
+	if short == "" {
+		short = fallback
+	}
+	if len(short) <= 100 {
+		return short
+	}
+	// Cut at 100 bytes, the limit of CreateEvent, without splitting a character
+	cut := 100
+	for cut > 0 && !utf8.RuneStart(short[cut]) {
+		cut--
+	}
+	return short[:cut]
+}
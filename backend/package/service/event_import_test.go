@@ -0,0 +1,177 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/HermanPlay/web-app-backend/internal/config"
+	"github.com/HermanPlay/web-app-backend/package/domain/models"
+	"github.com/HermanPlay/web-app-backend/package/domain/schemas"
+	"github.com/HermanPlay/web-app-backend/package/repository"
+	"github.com/HermanPlay/web-app-backend/package/utils"
+)
+
+func TestImportEvents(t *testing.T) {
+	ctx := context.Background()
+	db := utils.ConnectToTestDatabase()
+	eventRepository, err := repository.NewEventRepository(db)
+	if err != nil {
+		t.Errorf("Error when create new event repository, when not expected. Error: %v", err)
+	}
+	userRepository, err := repository.NewUserRepository(db)
+	if err != nil {
+		t.Errorf("Error when create new user repository, when not expected. Error: %v", err)
+	}
+	user, err := userRepository.Save(ctx, &models.User{Name: "name", Email: "email", Password: "password", Role: "manager"})
+	if err != nil {
+		t.Errorf("Error when save user, when not expected. Error: %v", err)
+	}
+	outbox := newTestOutbox(t, db)
+	eventService := NewEventService(eventRepository, outbox.webhooks, outbox.jobs, outbox.transactor, &config.Config{})
+	savedEvents := func() int {
+		events, err := eventRepository.GetCreatedEvents(ctx, user.ID)
+		if err != nil {
+			t.Fatalf("Error when get created events, when not expected. Error: %v", err)
+		}
+		return len(events)
+	}
+
+	valid := strings.Join([]string{
+		"title,short_description,description,location,starts_at,ends_at,time_zone,capacity",
+		"Jazz,Live jazz,A night of jazz,Warsaw,2024-11-15 19:00,2024-11-15 22:00,Europe/Warsaw,50",
+		`Talk,A talk,"Two
+lines",Online,2024-11-16T10:00:00Z,2024-11-16T11:00:00Z,UTC,`,
+	}, "\n")
+	invalid := valid + "\n" + strings.Join([]string{
+		"Backwards,Short,Description,Warsaw,2024-11-15 22:00,2024-11-15 19:00,Europe/Warsaw,",
+		",Short,Description,Warsaw,2024-11-15 19:00,2024-11-15 22:00,Europe/Warsaw,",
+		"Typos,Short,Description,Warsaw,tomorrow,2024-11-15 22:00,Europe/Warsaw,many",
+		"Nowhere,Short,Description,Warsaw,2024-11-15 19:00,2024-11-15 22:00,Mars/Olympus,",
+	}, "\n")
+
+	t.Run("Dry run", func(t *testing.T) {
+		result, err := eventService.ImportEvents(ctx, strings.NewReader(valid), schemas.EventImport{Format: "csv"}, user.ID)
+		if err != nil {
+			t.Fatalf("Error when import events, when not expected. Error: %v", err)
+		}
+		if result.Committed || result.Total != 2 || len(result.Errors) != 0 || len(result.Events) != 2 {
+			t.Errorf("Result is %+v, when expected two valid events that are not saved", result)
+		}
+		warsaw, _ := time.LoadLocation("Europe/Warsaw")
+		if !result.Events[0].StartsAt.Equal(time.Date(2024, 11, 15, 19, 0, 0, 0, warsaw)) || result.Events[0].Capacity != 50 {
+			t.Errorf("Event is %+v, when expected the local time read in Europe/Warsaw", result.Events[0])
+		}
+		if result.Events[1].Description != "Two\nlines" {
+			t.Errorf("Description is %q, when expected the quoted cell", result.Events[1].Description)
+		}
+		if savedEvents() != 0 {
+			t.Errorf("Dry run saved events, when not expected")
+		}
+	})
+	t.Run("Row errors", func(t *testing.T) {
+		result, err := eventService.ImportEvents(ctx, strings.NewReader(invalid), schemas.EventImport{Format: "csv", Commit: true}, user.ID)
+		if err != nil {
+			t.Fatalf("Error when import events, when not expected. Error: %v", err)
+		}
+		want := []schemas.ImportError{
+			{Row: 5, Field: "ends_at", Message: ErrInvalidTimes.Error()},
+			{Row: 6, Field: "title", Message: "is required"},
+			{Row: 7, Field: "starts_at"},
+			{Row: 7, Field: "capacity"},
+			{Row: 8, Field: "time_zone", Message: ErrInvalidZone.Error()},
+		}
+		if len(result.Errors) != len(want) {
+			t.Fatalf("Errors are %+v, when expected %d of them", result.Errors, len(want))
+		}
+		for i, got := range result.Errors {
+			if got.Row != want[i].Row || got.Field != want[i].Field || (want[i].Message != "" && got.Message != want[i].Message) {
+				t.Errorf("Error %d is %+v, when expected %+v", i, got, want[i])
+			}
+		}
+		if result.Committed || result.Total != 6 || len(result.Events) != 2 {
+			t.Errorf("Result is %+v, when expected nothing committed", result)
+		}
+		if savedEvents() != 0 {
+			t.Errorf("Import with invalid rows saved events, when not expected")
+		}
+	})
+	t.Run("Commit", func(t *testing.T) {
+		result, err := eventService.ImportEvents(ctx, strings.NewReader(valid), schemas.EventImport{Format: "csv", Commit: true}, user.ID)
+		if err != nil {
+			t.Fatalf("Error when import events, when not expected. Error: %v", err)
+		}
+		if !result.Committed || len(result.Events) != 2 || result.Events[0].ID == 0 || result.Events[0].CreatedBy != user.ID {
+			t.Errorf("Result is %+v, when expected two saved events", result)
+		}
+		if savedEvents() != 2 {
+			t.Errorf("Saved %d events, when expected 2", savedEvents())
+		}
+	})
+	t.Run("Column mapping", func(t *testing.T) {
+		csv := "Name,Summary,Details,Venue,Start,End\nJazz,Live jazz,A night of jazz,Warsaw,2024-11-15 19:00,2024-11-15 22:00"
+		mapping := map[string]string{
+			"title":             "Name",
+			"short_description": "Summary",
+			"description":       "Details",
+			"location":          "Venue",
+			"starts_at":         "Start",
+			"ends_at":           "End",
+		}
+		result, err := eventService.ImportEvents(ctx, strings.NewReader(csv), schemas.EventImport{Format: "csv", Mapping: mapping, TimeZone: "America/New_York"}, user.ID)
+		if err != nil {
+			t.Fatalf("Error when import events, when not expected. Error: %v", err)
+		}
+		if len(result.Errors) != 0 || result.Events[0].Title != "Jazz" || result.Events[0].TimeZone != "America/New_York" || result.Events[0].StartsAt.Hour() != 19 {
+			t.Errorf("Result is %+v, when expected the mapped columns in the default zone", result)
+		}
+	})
+	t.Run("Calendar", func(t *testing.T) {
+		ics := strings.Join([]string{
+			"BEGIN:VCALENDAR",
+			"BEGIN:VEVENT",
+			"SUMMARY:Jazz",
+			"DESCRIPTION:A night of jazz\\nBring friends",
+			"LOCATION:Warsaw",
+			"DTSTART;TZID=Europe/Warsaw:20241115T190000",
+			"DTEND;TZID=Europe/Warsaw:20241115T220000",
+			"END:VEVENT",
+			"END:VCALENDAR",
+		}, "\r\n")
+		result, err := eventService.ImportEvents(ctx, strings.NewReader(ics), schemas.EventImport{Format: "ics", Commit: true}, user.ID)
+		if err != nil {
+			t.Fatalf("Error when import events, when not expected. Error: %v", err)
+		}
+		if !result.Committed || len(result.Events) != 1 {
+			t.Fatalf("Result is %+v, when expected one saved event", result)
+		}
+		event := result.Events[0]
+		if event.ShortDescription != "A night of jazz" || event.TimeZone != "Europe/Warsaw" || event.StartsAt.Hour() != 19 {
+			t.Errorf("Event is %+v, when expected the calendar event", event)
+		}
+	})
+	t.Run("Invalid file", func(t *testing.T) {
+		cases := []struct {
+			name    string
+			file    string
+			options schemas.EventImport
+			err     error
+		}{
+			{"Unknown format", valid, schemas.EventImport{Format: "xlsx"}, ErrImportFormat},
+			{"Empty file", "", schemas.EventImport{Format: "csv"}, ErrImportFile},
+			{"Header only", "title,description", schemas.EventImport{Format: "csv"}, ErrImportEmpty},
+			{"Unknown field", valid, schemas.EventImport{Format: "csv", Mapping: map[string]string{"organizer": "title"}}, ErrImportMapping},
+			{"Missing column", valid, schemas.EventImport{Format: "csv", Mapping: map[string]string{"title": "Name"}}, ErrImportMapping},
+			{"Malformed calendar", "BEGIN:VEVENT", schemas.EventImport{Format: "ics"}, ErrImportFile},
+			{"Unknown zone", valid, schemas.EventImport{Format: "csv", TimeZone: "Mars/Olympus"}, ErrInvalidZone},
+		}
+		for _, tc := range cases {
+			_, err := eventService.ImportEvents(ctx, strings.NewReader(tc.file), tc.options, user.ID)
+			if !errors.Is(err, tc.err) {
+				t.Errorf("%s: error is %v, when expected %v", tc.name, err, tc.err)
+			}
+		}
+	})
+}
@@ -1,8 +1,16 @@
 package service
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"io"
+	"strings"
+	"time"
 
+	"github.com/HermanPlay/web-app-backend/internal/config"
+	"github.com/HermanPlay/web-app-backend/internal/metrics"
+	"github.com/HermanPlay/web-app-backend/internal/notification"
 	"github.com/HermanPlay/web-app-backend/package/domain/models"
 	"github.com/HermanPlay/web-app-backend/package/domain/schemas"
 	"github.com/HermanPlay/web-app-backend/package/repository"
@@ -10,126 +18,573 @@ import (
 )
 
 type EventService interface {
-	GetAllEvent() ([]*schemas.Event, error)
-	GetEventByID(id int) (*schemas.Event, error)
-	CreateEvent(event *schemas.EventInput, createdBy int) (*schemas.Event, error)
-	UpdateEvent(event *schemas.EventUpdate, id int) (*schemas.Event, error)
-	DeleteEvent(id int) error
-	GetFeaturedEvents() ([]*schemas.Event, error)
-	GetMyEvents(userId int) ([]*schemas.Event, error)
-	BookEvent(eventID int, userID int) error
+	GetAllEvent(ctx context.Context, filter schemas.EventFilter) ([]*schemas.Event, *schemas.Pagination, error)
+	GetEventByID(ctx context.Context, id int) (*schemas.Event, error)
+	CreateEvent(ctx context.Context, event *schemas.EventInput, createdBy int) (*schemas.Event, error)
+	UpdateEvent(ctx context.Context, event *schemas.EventUpdate, id int, requester *schemas.User) (*schemas.Event, error)
+	DeleteEvent(ctx context.Context, id int, requester *schemas.User) error
+	GetFeaturedEvents(ctx context.Context) ([]*schemas.Event, error)
+	GetMyEvents(ctx context.Context, userId int) ([]*schemas.Event, error)
+	BookEvent(ctx context.Context, eventID int, userID int) (*schemas.Booking, error)
+	CancelBooking(ctx context.Context, eventID int, userID int) error
+	GetAttendees(ctx context.Context, eventID int, requester *schemas.User) ([]schemas.Attendee, error)
+	GetOrganizers(ctx context.Context, eventID int) ([]schemas.User, error)
+	AddOrganizer(ctx context.Context, eventID int, userID int, requester *schemas.User) error
+	RemoveOrganizer(ctx context.Context, eventID int, userID int, requester *schemas.User) error
+	ImportEvents(ctx context.Context, file io.Reader, options schemas.EventImport, createdBy int) (*schemas.EventImportResult, error)
+	ExportAttendees(ctx context.Context, eventID int, requester *schemas.User) (*schemas.Export, error)
+	ExportEvents(ctx context.Context, filter schemas.EventFilter, requester *schemas.User) (*schemas.Export, error)
 }
 
 var (
 	ErrBookingExists = fmt.Errorf("booking already exists")
+	ErrEventFull     = fmt.Errorf("event is fully booked")
+	ErrInvalidZone   = fmt.Errorf("invalid time zone")
+	ErrInvalidTimes  = fmt.Errorf("event must have a start and end after it")
+	ErrInvalidSort   = fmt.Errorf("invalid sort field")
 )
 
+const (
+	defaultPageSize = 20
+	maxPageSize     = 100
+)
+
+// sortableEventFields maps the sort values accepted from clients to columns.
+var sortableEventFields = map[string]string{
+	"starts_at":  "starts_at",
+	"title":      "title",
+	"created_at": "created_at",
+}
+
 type EventServiceImpl struct {
-	eventRepository repository.EventRepository
+	eventRepository   repository.EventRepository
+	webhookRepository repository.WebhookRepository
+	jobRepository     repository.JobRepository
+	transactor        repository.Transactor
+	cfg               *config.Config
 }
 
-func (e EventServiceImpl) GetAllEvent() ([]*schemas.Event, error) {
-	events, err := e.eventRepository.GetAll()
+func (e EventServiceImpl) GetAllEvent(ctx context.Context, filter schemas.EventFilter) ([]*schemas.Event, *schemas.Pagination, error) {
+	ctx, span := tracer.Start(ctx, "EventService.GetAllEvent")
+	defer span.End()
+	if filter.Page == 0 {
+		filter.Page = 1
+	}
+	if filter.PageSize == 0 {
+		filter.PageSize = defaultPageSize
+	}
+	if filter.Page < 0 || filter.PageSize < 0 || filter.PageSize > maxPageSize {
+		return nil, nil, ErrInvalidInput
+	}
+
+	query, err := eventQuery(filter)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
+	}
+	query.Limit = filter.PageSize
+	query.Offset = (filter.Page - 1) * filter.PageSize
+
+	events, total, err := e.eventRepository.GetAll(ctx, query)
+	if err != nil {
+		return nil, nil, err
 	}
 	eventResponse := []*schemas.Event{}
 	for _, event := range events {
-		eventResponse = append(eventResponse, e.createEventResponse(&event))
+		eventResponse = append(eventResponse, createEventSchema(&event))
+	}
+	pagination := &schemas.Pagination{
+		Page:       filter.Page,
+		PageSize:   filter.PageSize,
+		Total:      total,
+		TotalPages: int((total + int64(filter.PageSize) - 1) / int64(filter.PageSize)),
 	}
 
-	return eventResponse, nil
+	return eventResponse, pagination, nil
 }
 
-func (e EventServiceImpl) GetEventByID(id int) (*schemas.Event, error) {
-	event, err := e.eventRepository.GetByID(id)
+func (e EventServiceImpl) GetEventByID(ctx context.Context, id int) (*schemas.Event, error) {
+	ctx, span := tracer.Start(ctx, "EventService.GetEventByID")
+	defer span.End()
+	event, err := e.eventRepository.GetByID(ctx, id)
 	if err != nil {
 		return nil, err
 	}
-	eventResponse := e.createEventResponse(&event)
+	eventResponse := createEventSchema(&event)
 	return eventResponse, nil
 }
 
-func (e EventServiceImpl) CreateEvent(eventInput *schemas.EventInput, createdBy int) (*schemas.Event, error) {
-	if len(eventInput.ShortDescription) > 100 {
-		return nil, ErrInputTooLong
+func (e EventServiceImpl) CreateEvent(ctx context.Context, eventInput *schemas.EventInput, createdBy int) (*schemas.Event, error) {
+	ctx, span := tracer.Start(ctx, "EventService.CreateEvent")
+	defer span.End()
+	err := validateEventInput(eventInput)
+	if err != nil {
+		return nil, err
 	}
 	modelEvent := e.createEventModel(eventInput)
 	modelEvent.CreatedBy = createdBy
-	event, err := e.eventRepository.Save(modelEvent)
+	var eventResponse *schemas.Event
+	err = e.transactor.Transaction(ctx, func(tx *gorm.DB) error {
+		event, err := e.eventRepository.WithTx(tx).Save(ctx, modelEvent)
+		if err != nil {
+			return err
+		}
+		eventResponse = createEventSchema(&event)
+		return enqueueWebhooks(ctx, e.webhookRepository.WithTx(tx), e.jobRepository.WithTx(tx), models.WebhookEventCreated, eventResponse)
+	})
 	if err != nil {
 		return nil, err
 	}
-	eventResponse := e.createEventResponse(&event)
+	metrics.EventsCreated.Inc()
 
 	return eventResponse, nil
 
 }
 
-func (e EventServiceImpl) UpdateEvent(eventUpdate *schemas.EventUpdate, id int) (*schemas.Event, error) {
-	eventModel, err := e.eventRepository.GetByID(id)
+// UpdateEvent changes an event on behalf of its creator, a co-organizer or an admin.
+func (e EventServiceImpl) UpdateEvent(ctx context.Context, eventUpdate *schemas.EventUpdate, id int, requester *schemas.User) (*schemas.Event, error) {
+	ctx, span := tracer.Start(ctx, "EventService.UpdateEvent")
+	defer span.End()
+	current, err := e.eventRepository.GetByID(ctx, id)
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	err = e.checkCanManage(ctx, current, requester)
 	if err != nil {
 		return nil, err
 	}
 
-	e.updateModel(&eventModel, eventUpdate)
-
-	event, err := e.eventRepository.Update(&eventModel)
+	if eventUpdate.Capacity != nil && *eventUpdate.Capacity < 0 {
+		return nil, ErrInvalidInput
+	}
 
+	var event models.Event
+	err = e.transactor.Transaction(ctx, func(tx *gorm.DB) error {
+		events := e.eventRepository.WithTx(tx)
+		jobs := e.jobRepository.WithTx(tx)
+		// The change is applied to the locked row, so concurrent updates neither
+		// lose each other's fields nor end up with the same sequence
+		eventModel, err := events.LockByID(ctx, id)
+		if err != nil {
+			if err == gorm.ErrRecordNotFound {
+				return ErrNotFound
+			}
+			return err
+		}
+		before := eventModel
+		e.updateModel(&eventModel, eventUpdate)
+		// Calendar apps only take over an exported event whose sequence grew
+		eventModel.Sequence++
+		err = validateSchedule(eventModel.StartsAt, eventModel.EndsAt, eventModel.TimeZone)
+		if err != nil {
+			return err
+		}
+		event, err = events.Update(ctx, &eventModel)
+		if err != nil {
+			return err
+		}
+		// A larger capacity may free seats for people on the waitlist
+		promoted, err := events.PromoteWaitlisted(ctx, event.ID)
+		if err != nil {
+			return err
+		}
+		for _, booking := range promoted {
+			err = e.enqueueBookingNotification(ctx, jobs, notification.BookingPromoted, booking, 0)
+			if err != nil {
+				return err
+			}
+		}
+		err = enqueueWebhooks(ctx, e.webhookRepository.WithTx(tx), jobs, models.WebhookEventUpdated, createEventSchema(&event))
+		if err != nil {
+			return err
+		}
+		if !attendeesAffected(before, event) {
+			return nil
+		}
+		return e.enqueueEventUpdated(ctx, events, jobs, event)
+	})
 	if err != nil {
 		return nil, err
 	}
 
-	eventResponse := e.createEventResponse(&event)
+	eventResponse := createEventSchema(&event)
 
 	return eventResponse, nil
 }
 
-func (e EventServiceImpl) DeleteEvent(id int) error {
-	return e.eventRepository.Delete(id)
+// DeleteEvent removes an event on behalf of its creator, a co-organizer or an admin.
+func (e EventServiceImpl) DeleteEvent(ctx context.Context, id int, requester *schemas.User) error {
+	ctx, span := tracer.Start(ctx, "EventService.DeleteEvent")
+	defer span.End()
+	event, err := e.eventRepository.GetByID(ctx, id)
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return ErrNotFound
+		}
+		return err
+	}
+	err = e.checkCanManage(ctx, event, requester)
+	if err != nil {
+		return err
+	}
+	return e.transactor.Transaction(ctx, func(tx *gorm.DB) error {
+		err := e.eventRepository.WithTx(tx).Delete(ctx, id)
+		if err != nil {
+			return err
+		}
+		return enqueueWebhooks(ctx, e.webhookRepository.WithTx(tx), e.jobRepository.WithTx(tx), models.WebhookEventDeleted, createEventSchema(&event))
+	})
 }
 
-func (e EventServiceImpl) GetFeaturedEvents() ([]*schemas.Event, error) {
-	events, err := e.eventRepository.GetFeaturedEvents()
+func (e EventServiceImpl) GetFeaturedEvents(ctx context.Context) ([]*schemas.Event, error) {
+	ctx, span := tracer.Start(ctx, "EventService.GetFeaturedEvents")
+	defer span.End()
+	events, err := e.eventRepository.GetFeaturedEvents(ctx)
 	if err != nil {
 		return nil, err
 	}
 	eventResponse := []*schemas.Event{}
 	for _, event := range events {
-		eventResponse = append(eventResponse, e.createEventResponse(&event))
+		eventResponse = append(eventResponse, createEventSchema(&event))
 	}
 
 	return eventResponse, nil
 }
 
-func (e EventServiceImpl) GetMyEvents(userId int) ([]*schemas.Event, error) {
-	events, err := e.eventRepository.GetMyEvents(userId)
+func (e EventServiceImpl) GetMyEvents(ctx context.Context, userId int) ([]*schemas.Event, error) {
+	ctx, span := tracer.Start(ctx, "EventService.GetMyEvents")
+	defer span.End()
+	events, err := e.eventRepository.GetMyEvents(ctx, userId)
 	if err != nil {
 		return nil, err
 	}
-	createdEvents, err := e.eventRepository.GetCreatedEvents(userId)
+	createdEvents, err := e.eventRepository.GetCreatedEvents(ctx, userId)
 	if err != nil {
 		return nil, err
 	}
 	events = append(events, createdEvents...)
 	eventResponse := []*schemas.Event{}
 	for _, event := range events {
-		eventResponse = append(eventResponse, e.createEventResponse(&event))
+		eventResponse = append(eventResponse, createEventSchema(&event))
 	}
 	return eventResponse, nil
 
 }
 
-func (e EventServiceImpl) BookEvent(eventID int, userID int) error {
-	_, err := e.eventRepository.GetBooking(eventID, userID)
+func (e EventServiceImpl) BookEvent(ctx context.Context, eventID int, userID int) (*schemas.Booking, error) {
+	ctx, span := tracer.Start(ctx, "EventService.BookEvent")
+	defer span.End()
+	var response *schemas.Booking
+	err := e.transactor.Transaction(ctx, func(tx *gorm.DB) error {
+		events := e.eventRepository.WithTx(tx)
+		booking, err := events.BookEvent(ctx, eventID, userID, bookingPolicy)
+		if err != nil {
+			return err
+		}
+		response, err = createBookingResponse(ctx, events, &booking)
+		if err != nil {
+			return err
+		}
+		jobs := e.jobRepository.WithTx(tx)
+		err = enqueueWebhooks(ctx, e.webhookRepository.WithTx(tx), jobs, models.WebhookBookingCreated, response)
+		if err != nil {
+			return err
+		}
+		if booking.Status == models.BookingWaitlisted {
+			return e.enqueueBookingNotification(ctx, jobs, notification.BookingWaitlisted, booking, response.WaitlistPosition)
+		}
+		return e.enqueueBookingNotification(ctx, jobs, notification.BookingConfirmed, booking, 0)
+	})
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, ErrNotFound
+		}
+		if errors.Is(err, gorm.ErrDuplicatedKey) {
+			return nil, ErrBookingExists
+		}
+		return nil, err
+	}
+	metrics.BookingsCreated.WithLabelValues(string(response.Status)).Inc()
+	return response, nil
+}
+
+func (e EventServiceImpl) CancelBooking(ctx context.Context, eventID int, userID int) error {
+	ctx, span := tracer.Start(ctx, "EventService.CancelBooking")
+	defer span.End()
+	err := e.transactor.Transaction(ctx, func(tx *gorm.DB) error {
+		events := e.eventRepository.WithTx(tx)
+		booking, err := events.GetBooking(ctx, eventID, userID)
+		if err != nil {
+			return err
+		}
+		promoted, err := events.CancelBooking(ctx, eventID, userID)
+		if err != nil {
+			return err
+		}
+		jobs := e.jobRepository.WithTx(tx)
+		// The payload carries the status the booking had
+		err = enqueueWebhooks(ctx, e.webhookRepository.WithTx(tx), jobs, models.WebhookBookingCancelled, schemas.Booking{
+			EventID: booking.EventID,
+			UserID:  booking.UserID,
+			Status:  booking.Status,
+		})
+		if err != nil {
+			return err
+		}
+		for _, booking := range promoted {
+			err = e.enqueueBookingNotification(ctx, jobs, notification.BookingPromoted, booking, 0)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return ErrNotFound
+		}
+		return err
+	}
+	return nil
+}
+
+// GetAttendees lists everybody who booked the event. Only the event creator,
+// co-organizers and admins may see it.
+func (e EventServiceImpl) GetAttendees(ctx context.Context, eventID int, requester *schemas.User) ([]schemas.Attendee, error) {
+	ctx, span := tracer.Start(ctx, "EventService.GetAttendees")
+	defer span.End()
+	event, err := e.eventRepository.GetByID(ctx, eventID)
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	err = e.checkCanManage(ctx, event, requester)
+	if err != nil {
+		return nil, err
+	}
+
+	bookings, err := e.eventRepository.GetAttendees(ctx, eventID)
+	if err != nil {
+		return nil, err
+	}
+	attendees := make([]schemas.Attendee, 0, len(bookings))
+	position := 0
+	for _, booking := range bookings {
+		attendee := schemas.Attendee{
+			UserID:   booking.UserID,
+			Name:     booking.User.Name,
+			Email:    booking.User.Email,
+			Status:   booking.Status,
+			BookedAt: booking.CreatedAt,
+		}
+		if booking.Status == models.BookingWaitlisted {
+			position++
+			attendee.WaitlistPosition = position
+		}
+		attendees = append(attendees, attendee)
+	}
+	return attendees, nil
+}
+
+// eventQuery turns the filters and sort order of the event list into a
+// repository query, leaving pagination to the caller.
+func eventQuery(filter schemas.EventFilter) (repository.EventQuery, error) {
+	if filter.From != nil && filter.To != nil && !filter.From.Before(*filter.To) {
+		return repository.EventQuery{}, ErrInvalidTimes
+	}
+	query := repository.EventQuery{
+		Search:   strings.TrimSpace(filter.Search),
+		Location: strings.TrimSpace(filter.Location),
+		From:     filter.From,
+		To:       filter.To,
+		Featured: filter.Featured,
+	}
+	if filter.Sort != "" {
+		field, descending := strings.CutPrefix(filter.Sort, "-")
+		column, ok := sortableEventFields[field]
+		if !ok {
+			return repository.EventQuery{}, ErrInvalidSort
+		}
+		query.OrderBy = column
+		query.Descending = descending
+	}
+	return query, nil
+}
+
+// validateEventInput checks the rules of a new event that request binding
+// does not cover.
+func validateEventInput(eventInput *schemas.EventInput) error {
+	if len(eventInput.ShortDescription) > 100 {
+		return ErrInputTooLong
+	}
+	if eventInput.Capacity < 0 {
+		return ErrInvalidInput
+	}
+	return validateSchedule(eventInput.StartsAt, eventInput.EndsAt, eventInput.TimeZone)
+}
+
+// validateSchedule checks that the event has a known IANA time zone and a
+// non-empty time range.
+func validateSchedule(startsAt, endsAt time.Time, timeZone string) error {
+	// "Local" would depend on wherever the server happens to run
+	if timeZone == "" || timeZone == "Local" {
+		return ErrInvalidZone
+	}
+	_, err := time.LoadLocation(timeZone)
+	if err != nil {
+		return ErrInvalidZone
+	}
+	if startsAt.IsZero() || !endsAt.After(startsAt) {
+		return ErrInvalidTimes
+	}
+	return nil
+}
+
+// GetOrganizers lists the co-organizers of an event.
+func (e EventServiceImpl) GetOrganizers(ctx context.Context, eventID int) ([]schemas.User, error) {
+	ctx, span := tracer.Start(ctx, "EventService.GetOrganizers")
+	defer span.End()
+	_, err := e.eventRepository.GetByID(ctx, eventID)
 	if err != nil {
 		if err == gorm.ErrRecordNotFound {
-			return e.eventRepository.BookEvent(eventID, userID)
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	organizers, err := e.eventRepository.GetOrganizers(ctx, eventID)
+	if err != nil {
+		return nil, err
+	}
+	users := make([]schemas.User, 0, len(organizers))
+	for _, organizer := range organizers {
+		users = append(users, *createUserSchema(&organizer.User))
+	}
+	return users, nil
+}
+
+// AddOrganizer designates a co-organizer. Only the event creator and admins
+// may hand out this right.
+func (e EventServiceImpl) AddOrganizer(ctx context.Context, eventID int, userID int, requester *schemas.User) error {
+	ctx, span := tracer.Start(ctx, "EventService.AddOrganizer")
+	defer span.End()
+	event, err := e.getOwnedEvent(ctx, eventID, requester)
+	if err != nil {
+		return err
+	}
+	if userID == event.CreatedBy {
+		return ErrInvalidInput
+	}
+	_, err = e.eventRepository.AddOrganizer(ctx, eventID, userID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrDuplicatedKey) {
+			return ErrAlreadyExists
+		}
+		if errors.Is(err, gorm.ErrForeignKeyViolated) {
+			return ErrNotFound
 		}
 		return err
 	}
-	return ErrBookingExists
+	return nil
+}
 
+func (e EventServiceImpl) RemoveOrganizer(ctx context.Context, eventID int, userID int, requester *schemas.User) error {
+	ctx, span := tracer.Start(ctx, "EventService.RemoveOrganizer")
+	defer span.End()
+	_, err := e.getOwnedEvent(ctx, eventID, requester)
+	if err != nil {
+		return err
+	}
+	err = e.eventRepository.RemoveOrganizer(ctx, eventID, userID)
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return ErrNotFound
+		}
+		return err
+	}
+	return nil
+}
+
+// getOwnedEvent loads the event if requester created it or is an admin.
+func (e EventServiceImpl) getOwnedEvent(ctx context.Context, eventID int, requester *schemas.User) (models.Event, error) {
+	event, err := e.eventRepository.GetByID(ctx, eventID)
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return models.Event{}, ErrNotFound
+		}
+		return models.Event{}, err
+	}
+	if requester.ID != event.CreatedBy && !isAdmin(requester) {
+		return models.Event{}, ErrForbidden
+	}
+	return event, nil
+}
+
+// checkCanManage returns ErrForbidden unless requester created the event, was
+// designated as its co-organizer or is an admin.
+func (e EventServiceImpl) checkCanManage(ctx context.Context, event models.Event, requester *schemas.User) error {
+	if requester.ID == event.CreatedBy || isAdmin(requester) {
+		return nil
+	}
+	isOrganizer, err := e.eventRepository.IsOrganizer(ctx, event.ID, requester.ID)
+	if err != nil {
+		return err
+	}
+	if !isOrganizer {
+		return ErrForbidden
+	}
+	return nil
+}
+
+func (e EventServiceImpl) enqueueBookingNotification(ctx context.Context, jobs repository.JobRepository, template notification.Template, booking models.EventUser, waitlistPosition int) error {
+	return enqueueNotification(ctx, jobs, booking.User.Email, template, notification.EventData{
+		Name:             booking.User.Name,
+		Event:            *createEventSchema(&booking.Event),
+		Link:             publicLink(e.cfg, fmt.Sprintf("/events/%d", booking.EventID)),
+		WaitlistPosition: waitlistPosition,
+	})
+}
+
+func (e EventServiceImpl) enqueueEventUpdated(ctx context.Context, events repository.EventRepository, jobs repository.JobRepository, event models.Event) error {
+	bookings, err := events.GetAttendees(ctx, event.ID)
+	if err != nil {
+		return err
+	}
+	for _, booking := range bookings {
+		err = enqueueNotification(ctx, jobs, booking.User.Email, notification.EventUpdated, notification.EventData{
+			Name:  booking.User.Name,
+			Event: *createEventSchema(&event),
+			Link:  publicLink(e.cfg, fmt.Sprintf("/events/%d", event.ID)),
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// attendeesAffected reports whether an update changed anything attendees plan around.
+func attendeesAffected(before, after models.Event) bool {
+	return before.Title != after.Title ||
+		before.Location != after.Location ||
+		!before.StartsAt.Equal(after.StartsAt) ||
+		!before.EndsAt.Equal(after.EndsAt) ||
+		before.TimeZone != after.TimeZone
+}
+
+// bookingPolicy confirms bookings while there are free seats, then either
+// waitlists them or rejects them depending on the event settings.
+func bookingPolicy(event models.Event, confirmed int64) (models.BookingStatus, error) {
+	if event.Capacity == 0 || confirmed < int64(event.Capacity) {
+		return models.BookingConfirmed, nil
+	}
+	if !event.WaitlistEnabled {
+		return "", ErrEventFull
+	}
+	return models.BookingWaitlisted, nil
 }
 
 func (e EventServiceImpl) createEventModel(event *schemas.EventInput) *models.Event {
@@ -138,9 +593,12 @@ func (e EventServiceImpl) createEventModel(event *schemas.EventInput) *models.Ev
 		ShortDescription: event.ShortDescription,
 		Description:      event.Description,
 		Location:         event.Location,
-		Date:             event.Date,
-		Time:             event.Time,
+		StartsAt:         event.StartsAt.UTC(),
+		EndsAt:           event.EndsAt.UTC(),
+		TimeZone:         event.TimeZone,
 		IsFeatured:       event.IsFeatured,
+		Capacity:         event.Capacity,
+		WaitlistEnabled:  event.WaitlistEnabled,
 	}
 }
 
@@ -157,33 +615,70 @@ func (e EventServiceImpl) updateModel(eventModel *models.Event, eventUpdate *sch
 	if eventUpdate.Location != "" {
 		eventModel.Location = eventUpdate.Location
 	}
-	if eventUpdate.Date != "" {
-		eventModel.Date = eventUpdate.Date
+	if eventUpdate.StartsAt != nil {
+		eventModel.StartsAt = eventUpdate.StartsAt.UTC()
+	}
+	if eventUpdate.EndsAt != nil {
+		eventModel.EndsAt = eventUpdate.EndsAt.UTC()
 	}
-	if eventUpdate.Time != "" {
-		eventModel.Time = eventUpdate.Time
+	if eventUpdate.TimeZone != "" {
+		eventModel.TimeZone = eventUpdate.TimeZone
 	}
 	if eventUpdate.IsFeatured != eventModel.IsFeatured {
 		eventModel.IsFeatured = eventUpdate.IsFeatured
 	}
+	if eventUpdate.Capacity != nil {
+		eventModel.Capacity = *eventUpdate.Capacity
+	}
+	if eventUpdate.WaitlistEnabled != nil {
+		eventModel.WaitlistEnabled = *eventUpdate.WaitlistEnabled
+	}
 
 }
 
-func (e EventServiceImpl) createEventResponse(event *models.Event) *schemas.Event {
+func createEventSchema(event *models.Event) *schemas.Event {
+	location, err := time.LoadLocation(event.TimeZone)
+	if err != nil {
+		location = time.UTC
+	}
 	return &schemas.Event{
 		ID:               event.ID,
 		Title:            event.Title,
 		ShortDescription: event.ShortDescription,
 		Description:      event.Description,
 		Location:         event.Location,
-		Date:             event.Date,
-		Time:             event.Time,
+		StartsAt:         event.StartsAt.In(location),
+		EndsAt:           event.EndsAt.In(location),
+		TimeZone:         event.TimeZone,
 		IsFeatured:       event.IsFeatured,
+		Capacity:         event.Capacity,
+		WaitlistEnabled:  event.WaitlistEnabled,
 		CreatedBy:        event.CreatedBy,
 	}
 }
-func NewEventService(eventRepository repository.EventRepository) EventService {
+
+func createBookingResponse(ctx context.Context, eventRepository repository.EventRepository, booking *models.EventUser) (*schemas.Booking, error) {
+	response := &schemas.Booking{
+		EventID: booking.EventID,
+		UserID:  booking.UserID,
+		Status:  booking.Status,
+	}
+	if booking.Status == models.BookingWaitlisted {
+		position, err := eventRepository.GetWaitlistPosition(ctx, *booking)
+		if err != nil {
+			return nil, err
+		}
+		response.WaitlistPosition = int(position)
+	}
+	return response, nil
+}
+
+func NewEventService(eventRepository repository.EventRepository, webhookRepository repository.WebhookRepository, jobRepository repository.JobRepository, transactor repository.Transactor, cfg *config.Config) EventService {
 	return &EventServiceImpl{
-		eventRepository: eventRepository,
+		eventRepository:   eventRepository,
+		webhookRepository: webhookRepository,
+		jobRepository:     jobRepository,
+		transactor:        transactor,
+		cfg:               cfg,
 	}
 }
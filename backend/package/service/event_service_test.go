@@ -1,15 +1,28 @@
 package service
 
 import (
+	"context"
+	"fmt"
+	"strings"
 	"testing"
+	"time"
 
+	"github.com/HermanPlay/web-app-backend/internal/config"
 	"github.com/HermanPlay/web-app-backend/package/domain/models"
 	"github.com/HermanPlay/web-app-backend/package/domain/schemas"
 	"github.com/HermanPlay/web-app-backend/package/repository"
 	"github.com/HermanPlay/web-app-backend/package/utils"
 )
 
+var (
+	eventStart = time.Date(2024, 11, 15, 8, 0, 0, 0, time.UTC)
+	eventEnd   = eventStart.Add(2 * time.Hour)
+	newStart   = eventStart.AddDate(0, 0, 7)
+	newEnd     = newStart.Add(3 * time.Hour)
+)
+
 func TestGetAllEvent(t *testing.T) {
+	ctx := context.Background()
 	db := utils.ConnectToTestDatabase()
 	eventRepository, err := repository.NewEventRepository(db)
 	if err != nil {
@@ -20,19 +33,23 @@ func TestGetAllEvent(t *testing.T) {
 	if err != nil {
 		t.Errorf("Error when create new user repository, when not expected. Error: %v", err)
 	}
-	user, err := userRepository.Save(&models.User{Name: "name", Email: "email", Password: "password", Role: "user"})
+	user, err := userRepository.Save(ctx, &models.User{Name: "name", Email: "email", Password: "password", Role: "user"})
 	if err != nil {
 		t.Errorf("Error when save user, when not expected. Error: %v", err)
 	}
-	eventService := NewEventService(eventRepository)
+	outbox := newTestOutbox(t, db)
+	eventService := NewEventService(eventRepository, outbox.webhooks, outbox.jobs, outbox.transactor, &config.Config{})
 	t.Run("Empty events", func(t *testing.T) {
-		events, err := eventService.GetAllEvent()
+		events, pagination, err := eventService.GetAllEvent(ctx, schemas.EventFilter{})
 		if err != nil {
 			t.Errorf("Error when get all events, when not expected. Error: %v", err)
 		}
 		if len(events) != 0 {
 			t.Errorf("Events is not empty, when expected")
 		}
+		if *pagination != (schemas.Pagination{Page: 1, PageSize: 20}) {
+			t.Errorf("Pagination is %+v, when expected first empty page", *pagination)
+		}
 	})
 	t.Run("One event", func(t *testing.T) {
 		want := models.Event{
@@ -40,15 +57,16 @@ func TestGetAllEvent(t *testing.T) {
 			ShortDescription: "short description",
 			Description:      "description",
 			Location:         "location",
-			Date:             "date",
-			Time:             "time",
+			StartsAt:         eventStart,
+			EndsAt:           eventEnd,
+			TimeZone:         "Europe/Warsaw",
 			CreatedBy:        user.ID,
 		}
-		_, err := eventRepository.Save(&want)
+		_, err := eventRepository.Save(ctx, &want)
 		if err != nil {
 			t.Errorf("Error when save event, when not expected. Error: %v", err)
 		}
-		events, err := eventService.GetAllEvent()
+		events, _, err := eventService.GetAllEvent(ctx, schemas.EventFilter{})
 		if err != nil {
 			t.Errorf("Error when get all events, when not expected. Error: %v", err)
 		}
@@ -57,9 +75,52 @@ func TestGetAllEvent(t *testing.T) {
 		}
 		compareEvent(t, *events[0], want)
 	})
+	t.Run("Paginated and sorted", func(t *testing.T) {
+		for _, title := range []string{"b", "c", "a"} {
+			_, err := eventRepository.Save(ctx, &models.Event{
+				Title:            title,
+				ShortDescription: "short description",
+				Description:      "description",
+				Location:         "location",
+				StartsAt:         eventStart,
+				EndsAt:           eventEnd,
+				TimeZone:         "UTC",
+				CreatedBy:        user.ID,
+			})
+			if err != nil {
+				t.Fatalf("Error when save event, when not expected. Error: %v", err)
+			}
+		}
+		events, pagination, err := eventService.GetAllEvent(ctx, schemas.EventFilter{Sort: "-title", Page: 2, PageSize: 3})
+		if err != nil {
+			t.Fatalf("Error when get all events, when not expected. Error: %v", err)
+		}
+		if len(events) != 1 || events[0].Title != "a" {
+			t.Errorf("Second page is %v, when expected only event a", events)
+		}
+		want := schemas.Pagination{Page: 2, PageSize: 3, Total: 4, TotalPages: 2}
+		if *pagination != want {
+			t.Errorf("Pagination is %+v, when expected %+v", *pagination, want)
+		}
+	})
+	t.Run("Invalid filters", func(t *testing.T) {
+		to := eventStart.Add(-time.Hour)
+		filters := map[error]schemas.EventFilter{
+			ErrInvalidSort:  {Sort: "location"},
+			ErrInvalidInput: {PageSize: 101},
+			ErrInvalidTimes: {From: &eventStart, To: &to},
+		}
+		for want, filter := range filters {
+			_, _, err := eventService.GetAllEvent(ctx, filter)
+			if err != want {
+				t.Errorf("Error is %v, when expected %v", err, want)
+			}
+		}
+	})
 }
 
 func TestGetEventById(t *testing.T) {
+	ctx := context.Background()
 	db := utils.ConnectToTestDatabase()
 	eventRepository, err := repository.NewEventRepository(db)
 	if err != nil {
@@ -70,13 +131,14 @@ func TestGetEventById(t *testing.T) {
 	if err != nil {
 		t.Errorf("Error when create new user repository, when not expected. Error: %v", err)
 	}
-	user, err := userRepository.Save(&models.User{Name: "name", Email: "email", Password: "password", Role: "user"})
+	user, err := userRepository.Save(ctx, &models.User{Name: "name", Email: "email", Password: "password", Role: "user"})
 	if err != nil {
 		t.Errorf("Error when save user, when not expected. Error: %v", err)
 	}
-	eventService := NewEventService(eventRepository)
+	outbox := newTestOutbox(t, db)
+	eventService := NewEventService(eventRepository, outbox.webhooks, outbox.jobs, outbox.transactor, &config.Config{})
 	t.Run("Invalid id", func(t *testing.T) {
-		event, err := eventService.GetEventByID(1)
+		event, err := eventService.GetEventByID(ctx, 1)
 		if err == nil {
 			t.Errorf("Error is nil, when expected")
 		}
@@ -90,15 +152,16 @@ func TestGetEventById(t *testing.T) {
 			ShortDescription: "short description",
 			Description:      "description",
 			Location:         "location",
-			Date:             "date",
-			Time:             "time",
+			StartsAt:         eventStart,
+			EndsAt:           eventEnd,
+			TimeZone:         "Europe/Warsaw",
 			CreatedBy:        user.ID,
 		}
-		savedEvent, err := eventRepository.Save(&want)
+		savedEvent, err := eventRepository.Save(ctx, &want)
 		if err != nil {
 			t.Errorf("Error when save event, when not expected. Error: %v", err)
 		}
-		event, err := eventService.GetEventByID(savedEvent.ID)
+		event, err := eventService.GetEventByID(ctx, savedEvent.ID)
 		if err != nil {
 			t.Errorf("Error when get event by id, when not expected. Error: %v", err)
 		}
@@ -107,17 +170,19 @@ func TestGetEventById(t *testing.T) {
 }
 
 func TestCreateEvent(t *testing.T) {
+	ctx := context.Background()
 	db := utils.ConnectToTestDatabase()
 	eventRepository, err := repository.NewEventRepository(db)
 	if err != nil {
 		t.Errorf("Error when create new event repository, when not expected. Error: %v", err)
 	}
-	eventService := NewEventService(eventRepository)
+	outbox := newTestOutbox(t, db)
+	eventService := NewEventService(eventRepository, outbox.webhooks, outbox.jobs, outbox.transactor, &config.Config{})
 	userRepository, err := repository.NewUserRepository(db)
 	if err != nil {
 		t.Errorf("Error when create new user repository, when not expected. Error: %v", err)
 	}
-	user, err := userRepository.Save(&models.User{Name: "name", Email: "email", Password: "password", Role: "user"})
+	user, err := userRepository.Save(ctx, &models.User{Name: "name", Email: "email", Password: "password", Role: "user"})
 	if err != nil {
 		t.Errorf("Error when save user, when not expected. Error: %v", err)
 	}
@@ -127,107 +192,250 @@ func TestCreateEvent(t *testing.T) {
 			ShortDescription: "short description",
 			Description:      "description",
 			Location:         "location",
-			Date:             "date",
-			Time:             "time",
+			StartsAt:         eventStart,
+			EndsAt:           eventEnd,
+			TimeZone:         "Europe/Warsaw",
 		}
-		event, err := eventService.CreateEvent(&want, user.ID)
+		event, err := eventService.CreateEvent(ctx, &want, user.ID)
 		if err != nil {
 			t.Errorf("Error when create event, when not expected. Error: %v", err)
 		}
-		compareEvent(t, *event, models.Event{Title: want.Title, Description: want.Description, Location: want.Location, Date: want.Date, Time: want.Time, CreatedBy: user.ID})
+		compareEvent(t, *event, models.Event{Title: want.Title, Description: want.Description, Location: want.Location, StartsAt: want.StartsAt, EndsAt: want.EndsAt, TimeZone: want.TimeZone, CreatedBy: user.ID})
+	})
+	t.Run("Invalid time zone", func(t *testing.T) {
+		input := schemas.EventInput{
+			Title:            "title",
+			ShortDescription: "short description",
+			Description:      "description",
+			Location:         "location",
+			StartsAt:         eventStart,
+			EndsAt:           eventEnd,
+			TimeZone:         "Mars/Olympus_Mons",
+		}
+		event, err := eventService.CreateEvent(ctx, &input, user.ID)
+		if err != ErrInvalidZone {
+			t.Errorf("Error is not ErrInvalidZone, when expected. Error: %v", err)
+		}
+		if event != nil {
+			t.Errorf("Event is not nil, when expected")
+		}
+	})
+	t.Run("Ends before start", func(t *testing.T) {
+		input := schemas.EventInput{
+			Title:            "title",
+			ShortDescription: "short description",
+			Description:      "description",
+			Location:         "location",
+			StartsAt:         eventEnd,
+			EndsAt:           eventStart,
+			TimeZone:         "Europe/Warsaw",
+		}
+		_, err := eventService.CreateEvent(ctx, &input, user.ID)
+		if err != ErrInvalidTimes {
+			t.Errorf("Error is not ErrInvalidTimes, when expected. Error: %v", err)
+		}
 	})
 }
 
 func TestUpdateEvent(t *testing.T) {
+	ctx := context.Background()
 	db := utils.ConnectToTestDatabase()
 	eventRepository, err := repository.NewEventRepository(db)
 	if err != nil {
 		t.Errorf("Error when create new event repository, when not expected. Error: %v", err)
 	}
-	eventService := NewEventService(eventRepository)
+	outbox := newTestOutbox(t, db)
+	eventService := NewEventService(eventRepository, outbox.webhooks, outbox.jobs, outbox.transactor, &config.Config{})
 	userRepository, err := repository.NewUserRepository(db)
 	if err != nil {
 		t.Errorf("Error when create new user repository, when not expected. Error: %v", err)
 	}
-	user, err := userRepository.Save(&models.User{Name: "name", Email: "email", Password: "password", Role: "user"})
+	user, err := userRepository.Save(ctx, &models.User{Name: "name", Email: "email", Password: "password", Role: "user"})
+	creator := createUserSchema(&user)
+	want := models.Event{
+		Title:            "title",
+		ShortDescription: "short description",
+		Description:      "description",
+		Location:         "location",
+		StartsAt:         eventStart,
+		EndsAt:           eventEnd,
+		TimeZone:         "Europe/Warsaw",
+		CreatedBy:        user.ID,
+	}
+	savedEvent, err := eventRepository.Save(ctx, &want)
+	if err != nil {
+		t.Errorf("Error when save event, when not expected. Error: %v", err)
+	}
 	t.Run("Update event", func(t *testing.T) {
-		want := models.Event{
-			Title:            "title",
-			ShortDescription: "short description",
-			Description:      "description",
-			Location:         "location",
-			Date:             "date",
-			Time:             "time",
-			CreatedBy:        user.ID,
-		}
-		_, err := eventRepository.Save(&want)
-		if err != nil {
-			t.Errorf("Error when save event, when not expected. Error: %v", err)
-		}
 		update := schemas.EventUpdate{
 			Title:            "new title",
 			ShortDescription: "new short description",
 			Description:      "new description",
 			Location:         "new location",
-			Date:             "new date",
-			Time:             "new time",
+			StartsAt:         &newStart,
+			EndsAt:           &newEnd,
+			TimeZone:         "America/New_York",
 		}
-		event, err := eventService.UpdateEvent(&update, user.ID)
+		event, err := eventService.UpdateEvent(ctx, &update, savedEvent.ID, creator)
 		if err != nil {
 			t.Errorf("Error when update event, when not expected. Error: %v", err)
 		}
-		compareEvent(t, *event, models.Event{Title: update.Title, Description: update.Description, Location: update.Location, Date: update.Date, Time: update.Time, CreatedBy: user.ID})
+		compareEvent(t, *event, models.Event{Title: update.Title, Description: update.Description, Location: update.Location, StartsAt: newStart, EndsAt: newEnd, TimeZone: update.TimeZone, CreatedBy: user.ID})
+		if event.StartsAt.Location().String() != update.TimeZone {
+			t.Errorf("StartsAt is not in the event time zone, got: %v, want: %v", event.StartsAt.Location(), update.TimeZone)
+		}
+		updated, _ := eventRepository.GetByID(ctx, savedEvent.ID)
+		if updated.Sequence != 1 {
+			t.Errorf("Sequence is %d, when expected it to be raised to 1", updated.Sequence)
+		}
+		_, err = eventService.UpdateEvent(ctx, &schemas.EventUpdate{Location: "another location"}, savedEvent.ID, creator)
+		if err != nil {
+			t.Errorf("Error when update event, when not expected. Error: %v", err)
+		}
+		updated, _ = eventRepository.GetByID(ctx, savedEvent.ID)
+		if updated.Sequence != 2 || updated.Title != update.Title {
+			t.Errorf("Event is not updated on top of the stored one, got sequence: %d, title: %q", updated.Sequence, updated.Title)
+		}
 	})
+	t.Run("Invalid times", func(t *testing.T) {
+		update := schemas.EventUpdate{EndsAt: &eventStart}
+		_, err := eventService.UpdateEvent(ctx, &update, savedEvent.ID, creator)
+		if err != ErrInvalidTimes {
+			t.Errorf("Error is not ErrInvalidTimes, when expected. Error: %v", err)
+		}
+	})
+	t.Run("Non existing event", func(t *testing.T) {
+		_, err := eventService.UpdateEvent(ctx, &schemas.EventUpdate{}, savedEvent.ID+1, creator)
+		if err != ErrNotFound {
+			t.Errorf("Error is not ErrNotFound, when expected. Error: %v", err)
+		}
+	})
+	for _, tc := range permissionCases(t, userRepository, eventRepository, savedEvent.ID) {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := eventService.UpdateEvent(ctx, &schemas.EventUpdate{Title: tc.name}, savedEvent.ID, tc.requester)
+			if err != tc.err {
+				t.Errorf("Error is %v, when expected %v", err, tc.err)
+			}
+		})
+	}
 }
 
 func TestDeleteEvent(t *testing.T) {
+	ctx := context.Background()
 	db := utils.ConnectToTestDatabase()
 	eventRepository, err := repository.NewEventRepository(db)
 	if err != nil {
 		t.Errorf("Error when create new event repository, when not expected. Error: %v", err)
 	}
-	eventService := NewEventService(eventRepository)
+	outbox := newTestOutbox(t, db)
+	eventService := NewEventService(eventRepository, outbox.webhooks, outbox.jobs, outbox.transactor, &config.Config{})
 	userRepository, err := repository.NewUserRepository(db)
 	if err != nil {
 		t.Errorf("Error when create new user repository, when not expected. Error: %v", err)
 	}
-	user, err := userRepository.Save(&models.User{Name: "name", Email: "email", Password: "password", Role: "user"})
+	user, err := userRepository.Save(ctx, &models.User{Name: "name", Email: "email", Password: "password", Role: "user"})
+	want := models.Event{
+		Title:            "title",
+		ShortDescription: "short description",
+		Description:      "description",
+		Location:         "location",
+		StartsAt:         eventStart,
+		EndsAt:           eventEnd,
+		TimeZone:         "Europe/Warsaw",
+		CreatedBy:        user.ID,
+	}
 	t.Run("Delete event", func(t *testing.T) {
-		want := models.Event{
-			Title:            "title",
-			ShortDescription: "short description",
-			Description:      "description",
-			Location:         "location",
-			Date:             "date",
-			Time:             "time",
-			CreatedBy:        user.ID,
-		}
-		savedEvent, err := eventRepository.Save(&want)
+		savedEvent, err := eventRepository.Save(ctx, &want)
 		if err != nil {
 			t.Errorf("Error when save event, when not expected. Error: %v", err)
 		}
-		err = eventService.DeleteEvent(savedEvent.ID)
+		err = eventService.DeleteEvent(ctx, savedEvent.ID, createUserSchema(&user))
 		if err != nil {
 			t.Errorf("Error when delete event, when not expected. Error: %v", err)
 		}
-		_, err = eventRepository.GetByID(savedEvent.ID)
+		_, err = eventRepository.GetByID(ctx, savedEvent.ID)
 		if err == nil {
 			t.Errorf("Error is nil, when expected")
 		}
 	})
+	t.Run("Non existing event", func(t *testing.T) {
+		err := eventService.DeleteEvent(ctx, 1000, createUserSchema(&user))
+		if err != ErrNotFound {
+			t.Errorf("Error is not ErrNotFound, when expected. Error: %v", err)
+		}
+	})
+	eventIDs := make([]int, 3)
+	for i := range eventIDs {
+		event := want
+		event.ID = 0
+		savedEvent, err := eventRepository.Save(ctx, &event)
+		if err != nil {
+			t.Fatalf("Error when save event, when not expected. Error: %v", err)
+		}
+		eventIDs[i] = savedEvent.ID
+	}
+	for i, tc := range permissionCases(t, userRepository, eventRepository, eventIDs...) {
+		t.Run(tc.name, func(t *testing.T) {
+			err := eventService.DeleteEvent(ctx, eventIDs[i], tc.requester)
+			if err != tc.err {
+				t.Errorf("Error is %v, when expected %v", err, tc.err)
+			}
+			_, err = eventRepository.GetByID(ctx, eventIDs[i])
+			if deleted := err != nil; deleted != (tc.err == nil) {
+				t.Errorf("Event deleted is %v, when expected %v", deleted, tc.err == nil)
+			}
+		})
+	}
+}
+
+type permissionCase struct {
+	name      string
+	requester *schemas.User
+	err       error
+}
+
+// permissionCases creates a co-organizer of the given events, an admin and an
+// unrelated manager, together with the outcome expected for each of them.
+func permissionCases(t *testing.T, userRepository repository.UserRepository, eventRepository repository.EventRepository, eventIDs ...int) []permissionCase {
+	ctx := context.Background()
+	t.Helper()
+	organizer, err := userRepository.Save(ctx, &models.User{Name: "organizer", Email: "organizer@email", Password: "password", Role: models.UserRole})
+	if err != nil {
+		t.Fatalf("Error when save user, when not expected. Error: %v", err)
+	}
+	for _, eventID := range eventIDs {
+		_, err = eventRepository.AddOrganizer(ctx, eventID, organizer.ID)
+		if err != nil {
+			t.Fatalf("Error when add organizer, when not expected. Error: %v", err)
+		}
+	}
+	admin, _ := userRepository.Save(ctx, &models.User{Name: "admin", Email: "admin@email", Password: "password", Role: models.AdminRole})
+	other, _ := userRepository.Save(ctx, &models.User{Name: "other", Email: "other@email", Password: "password", Role: models.ManagerRole})
+	return []permissionCase{
+		{"Co-organizer", createUserSchema(&organizer), nil},
+		{"Admin", createUserSchema(&admin), nil},
+		{"Other user", createUserSchema(&other), ErrForbidden},
+	}
 }
+
 func TestBookEvent(t *testing.T) {
+	ctx := context.Background()
 	db := utils.ConnectToTestDatabase()
 	eventRepository, err := repository.NewEventRepository(db)
 	if err != nil {
 		t.Errorf("Error when create new event repository, when not expected. Error: %v", err)
 	}
-	eventService := NewEventService(eventRepository)
+	outbox := newTestOutbox(t, db)
+	eventService := NewEventService(eventRepository, outbox.webhooks, outbox.jobs, outbox.transactor, &config.Config{})
 	userRepository, err := repository.NewUserRepository(db)
 	if err != nil {
 		t.Errorf("Error when create new user repository, when not expected. Error: %v", err)
 	}
-	user, err := userRepository.Save(&models.User{Name: "name", Email: "email", Password: "password", Role: "user"})
+	user, err := userRepository.Save(ctx, &models.User{Name: "name", Email: "email", Password: "password", Role: "user"})
+	if err != nil {
+		t.Errorf("Error when save user, when not expected. Error: %v", err)
+	}
+	other, err := userRepository.Save(ctx, &models.User{Name: "other", Email: "other", Password: "password", Role: "user"})
 	if err != nil {
 		t.Errorf("Error when save user, when not expected. Error: %v", err)
 	}
@@ -237,33 +445,402 @@ func TestBookEvent(t *testing.T) {
 			ShortDescription: "short description",
 			Description:      "description",
 			Location:         "location",
-			Date:             "date",
-			Time:             "time",
+			StartsAt:         eventStart,
+			EndsAt:           eventEnd,
+			TimeZone:         "Europe/Warsaw",
 			CreatedBy:        user.ID,
 		}
-		savedEvent, err := eventRepository.Save(&want)
+		savedEvent, err := eventRepository.Save(ctx, &want)
 		if err != nil {
 			t.Errorf("Error when save event, when not expected. Error: %v", err)
 		}
-		err = eventService.BookEvent(savedEvent.ID, user.ID)
+		booking, err := eventService.BookEvent(ctx, savedEvent.ID, user.ID)
 		if err != nil {
 			t.Errorf("Error when book event, when not expected. Error: %v", err)
+			return
+		}
+		if booking.Status != models.BookingConfirmed {
+			t.Errorf("Booking status is not the same, got: %v, want: %v", booking.Status, models.BookingConfirmed)
+		}
+		_, err = eventService.BookEvent(ctx, savedEvent.ID, user.ID)
+		if err != ErrBookingExists {
+			t.Errorf("Error is not ErrBookingExists, when expected. Error: %v", err)
+		}
+	})
+	t.Run("Non existing event", func(t *testing.T) {
+		_, err := eventService.BookEvent(ctx, 1000, user.ID)
+		if err != ErrNotFound {
+			t.Errorf("Error is not ErrNotFound, when expected. Error: %v", err)
+		}
+	})
+	t.Run("Full event", func(t *testing.T) {
+		want := models.Event{
+			Title:            "title",
+			ShortDescription: "short description",
+			Description:      "description",
+			Location:         "location",
+			StartsAt:         eventStart,
+			EndsAt:           eventEnd,
+			TimeZone:         "Europe/Warsaw",
+			Capacity:         1,
+			CreatedBy:        user.ID,
+		}
+		savedEvent, err := eventRepository.Save(ctx, &want)
+		if err != nil {
+			t.Errorf("Error when save event, when not expected. Error: %v", err)
+		}
+		eventService.BookEvent(ctx, savedEvent.ID, user.ID)
+		booking, err := eventService.BookEvent(ctx, savedEvent.ID, other.ID)
+		if err != ErrEventFull {
+			t.Errorf("Error is not ErrEventFull, when expected. Error: %v", err)
+		}
+		if booking != nil {
+			t.Errorf("Booking is not nil, when expected")
+		}
+	})
+	t.Run("Full event with waitlist", func(t *testing.T) {
+		want := models.Event{
+			Title:            "title",
+			ShortDescription: "short description",
+			Description:      "description",
+			Location:         "location",
+			StartsAt:         eventStart,
+			EndsAt:           eventEnd,
+			TimeZone:         "Europe/Warsaw",
+			Capacity:         1,
+			WaitlistEnabled:  true,
+			CreatedBy:        user.ID,
+		}
+		savedEvent, err := eventRepository.Save(ctx, &want)
+		if err != nil {
+			t.Errorf("Error when save event, when not expected. Error: %v", err)
+		}
+		eventService.BookEvent(ctx, savedEvent.ID, user.ID)
+		booking, err := eventService.BookEvent(ctx, savedEvent.ID, other.ID)
+		if err != nil {
+			t.Errorf("Error when book event, when not expected. Error: %v", err)
+			return
+		}
+		if booking.Status != models.BookingWaitlisted {
+			t.Errorf("Booking status is not the same, got: %v, want: %v", booking.Status, models.BookingWaitlisted)
+		}
+		if booking.WaitlistPosition != 1 {
+			t.Errorf("Waitlist position is not the same, got: %v, want: %v", booking.WaitlistPosition, 1)
+		}
+	})
+}
+
+func TestCancelBooking(t *testing.T) {
+	ctx := context.Background()
+	db := utils.ConnectToTestDatabase()
+	eventRepository, err := repository.NewEventRepository(db)
+	if err != nil {
+		t.Errorf("Error when create new event repository, when not expected. Error: %v", err)
+	}
+	outbox := newTestOutbox(t, db)
+	eventService := NewEventService(eventRepository, outbox.webhooks, outbox.jobs, outbox.transactor, &config.Config{})
+	userRepository, err := repository.NewUserRepository(db)
+	if err != nil {
+		t.Errorf("Error when create new user repository, when not expected. Error: %v", err)
+	}
+	user, err := userRepository.Save(ctx, &models.User{Name: "name", Email: "email", Password: "password", Role: "user"})
+	if err != nil {
+		t.Errorf("Error when save user, when not expected. Error: %v", err)
+	}
+	other, err := userRepository.Save(ctx, &models.User{Name: "other", Email: "other", Password: "password", Role: "user"})
+	if err != nil {
+		t.Errorf("Error when save user, when not expected. Error: %v", err)
+	}
+	event, err := eventRepository.Save(ctx, &models.Event{
+		Title:            "title",
+		ShortDescription: "short description",
+		Description:      "description",
+		Location:         "location",
+		StartsAt:         eventStart,
+		EndsAt:           eventEnd,
+		TimeZone:         "Europe/Warsaw",
+		Capacity:         1,
+		WaitlistEnabled:  true,
+		CreatedBy:        user.ID,
+	})
+	if err != nil {
+		t.Errorf("Error when save event, when not expected. Error: %v", err)
+	}
+	t.Run("Non existing booking", func(t *testing.T) {
+		err := eventService.CancelBooking(ctx, event.ID, user.ID)
+		if err != ErrNotFound {
+			t.Errorf("Error is not ErrNotFound, when expected. Error: %v", err)
+		}
+	})
+	t.Run("Promote waitlisted", func(t *testing.T) {
+		eventService.BookEvent(ctx, event.ID, user.ID)
+		eventService.BookEvent(ctx, event.ID, other.ID)
+		err := eventService.CancelBooking(ctx, event.ID, user.ID)
+		if err != nil {
+			t.Errorf("Error when cancel booking, when not expected. Error: %v", err)
+		}
+		booking, err := eventRepository.GetBooking(ctx, event.ID, other.ID)
+		if err != nil {
+			t.Errorf("Error when get booking, when not expected. Error: %v", err)
+		}
+		if booking.Status != models.BookingConfirmed {
+			t.Errorf("Booking status is not the same, got: %v, want: %v", booking.Status, models.BookingConfirmed)
+		}
+	})
+}
+
+func TestBookingNotifications(t *testing.T) {
+	ctx := context.Background()
+	db := utils.ConnectToTestDatabase()
+	eventRepository, err := repository.NewEventRepository(db)
+	if err != nil {
+		t.Errorf("Error when create new event repository, when not expected. Error: %v", err)
+	}
+	mails := newTestOutbox(t, db)
+	eventService := NewEventService(eventRepository, mails.webhooks, mails.jobs, mails.transactor, &config.Config{App: config.App{PublicURL: "http://frontend"}})
+	userRepository, err := repository.NewUserRepository(db)
+	if err != nil {
+		t.Errorf("Error when create new user repository, when not expected. Error: %v", err)
+	}
+	user, err := userRepository.Save(ctx, &models.User{Name: "name", Email: "email", Password: "password", Role: "user"})
+	if err != nil {
+		t.Errorf("Error when save user, when not expected. Error: %v", err)
+	}
+	other, err := userRepository.Save(ctx, &models.User{Name: "other", Email: "other", Password: "password", Role: "user"})
+	if err != nil {
+		t.Errorf("Error when save user, when not expected. Error: %v", err)
+	}
+	event, err := eventRepository.Save(ctx, &models.Event{
+		Title:            "title",
+		ShortDescription: "short description",
+		Description:      "description",
+		Location:         "location",
+		StartsAt:         eventStart,
+		EndsAt:           eventEnd,
+		TimeZone:         "Europe/Warsaw",
+		Capacity:         1,
+		WaitlistEnabled:  true,
+		CreatedBy:        user.ID,
+	})
+	if err != nil {
+		t.Errorf("Error when save event, when not expected. Error: %v", err)
+	}
+	// expectMessage checks that exactly one message was sent since the last
+	// check, to the given address and mentioning the given text.
+	sent := 0
+	expectMessage := func(t *testing.T, to string, text string) {
+		t.Helper()
+		messages := mails.Messages()[sent:]
+		sent += len(messages)
+		if len(messages) != 1 {
+			t.Fatalf("Sent %d emails, when expected one. Emails: %v", len(messages), messages)
+		}
+		if messages[0].To != to {
+			t.Errorf("Email recipient is not the same, got: %v, want: %v", messages[0].To, to)
+		}
+		if !strings.Contains(messages[0].Text, text) {
+			t.Errorf("Email does not mention %q, got: %v", text, messages[0].Text)
+		}
+	}
+	link := fmt.Sprintf("http://frontend/events/%d", event.ID)
+
+	t.Run("Confirmed", func(t *testing.T) {
+		eventService.BookEvent(ctx, event.ID, user.ID)
+		expectMessage(t, user.Email, link)
+	})
+	t.Run("Waitlisted", func(t *testing.T) {
+		eventService.BookEvent(ctx, event.ID, other.ID)
+		expectMessage(t, other.Email, "waitlist")
+	})
+	t.Run("Promoted", func(t *testing.T) {
+		eventService.CancelBooking(ctx, event.ID, user.ID)
+		expectMessage(t, other.Email, link)
+	})
+	t.Run("Updated", func(t *testing.T) {
+		_, err := eventService.UpdateEvent(ctx, &schemas.EventUpdate{Location: "new location"}, event.ID, createUserSchema(&user))
+		if err != nil {
+			t.Fatalf("Error when update event, when not expected. Error: %v", err)
+		}
+		expectMessage(t, other.Email, "new location")
+	})
+	t.Run("Description only", func(t *testing.T) {
+		_, err := eventService.UpdateEvent(ctx, &schemas.EventUpdate{Description: "new description"}, event.ID, createUserSchema(&user))
+		if err != nil {
+			t.Fatalf("Error when update event, when not expected. Error: %v", err)
+		}
+		if len(mails.Messages()) != sent {
+			t.Errorf("Sent %d emails for a description change, when expected none", len(mails.Messages())-sent)
+		}
+	})
+}
+
+func TestGetAttendees(t *testing.T) {
+	ctx := context.Background()
+	db := utils.ConnectToTestDatabase()
+	eventRepository, err := repository.NewEventRepository(db)
+	if err != nil {
+		t.Errorf("Error when create new event repository, when not expected. Error: %v", err)
+	}
+	outbox := newTestOutbox(t, db)
+	eventService := NewEventService(eventRepository, outbox.webhooks, outbox.jobs, outbox.transactor, &config.Config{})
+	userRepository, err := repository.NewUserRepository(db)
+	if err != nil {
+		t.Errorf("Error when create new user repository, when not expected. Error: %v", err)
+	}
+	creator, _ := userRepository.Save(ctx, &models.User{Name: "creator", Email: "creator", Password: "password", Role: models.ManagerRole})
+	admin, _ := userRepository.Save(ctx, &models.User{Name: "admin", Email: "admin", Password: "password", Role: models.AdminRole})
+	attendee, _ := userRepository.Save(ctx, &models.User{Name: "attendee", Email: "attendee", Password: "password", Role: models.UserRole})
+	event, err := eventRepository.Save(ctx, &models.Event{
+		Title:            "title",
+		ShortDescription: "short description",
+		Description:      "description",
+		Location:         "location",
+		StartsAt:         eventStart,
+		EndsAt:           eventEnd,
+		TimeZone:         "Europe/Warsaw",
+		CreatedBy:        creator.ID,
+	})
+	if err != nil {
+		t.Errorf("Error when save event, when not expected. Error: %v", err)
+	}
+	eventService.BookEvent(ctx, event.ID, attendee.ID)
+
+	t.Run("Creator", func(t *testing.T) {
+		attendees, err := eventService.GetAttendees(ctx, event.ID, createUserSchema(&creator))
+		if err != nil {
+			t.Errorf("Error when get attendees, when not expected. Error: %v", err)
+		}
+		if len(attendees) != 1 {
+			t.Errorf("Attendees count is not the same, got: %v, want: %v", len(attendees), 1)
+			return
+		}
+		if attendees[0].Email != attendee.Email || attendees[0].Status != models.BookingConfirmed {
+			t.Errorf("Attendee is not the same, got: %v, want: %v", attendees[0], attendee)
+		}
+	})
+	t.Run("Admin", func(t *testing.T) {
+		_, err := eventService.GetAttendees(ctx, event.ID, createUserSchema(&admin))
+		if err != nil {
+			t.Errorf("Error when get attendees, when not expected. Error: %v", err)
+		}
+	})
+	t.Run("Other user", func(t *testing.T) {
+		attendees, err := eventService.GetAttendees(ctx, event.ID, createUserSchema(&attendee))
+		if err != ErrForbidden {
+			t.Errorf("Error is not ErrForbidden, when expected. Error: %v", err)
+		}
+		if attendees != nil {
+			t.Errorf("Attendees is not nil, when expected")
+		}
+	})
+	t.Run("Non existing event", func(t *testing.T) {
+		_, err := eventService.GetAttendees(ctx, 1000, createUserSchema(&admin))
+		if err != ErrNotFound {
+			t.Errorf("Error is not ErrNotFound, when expected. Error: %v", err)
+		}
+	})
+}
+
+func TestOrganizers(t *testing.T) {
+	ctx := context.Background()
+	db := utils.ConnectToTestDatabase()
+	eventRepository, err := repository.NewEventRepository(db)
+	if err != nil {
+		t.Errorf("Error when create new event repository, when not expected. Error: %v", err)
+	}
+	outbox := newTestOutbox(t, db)
+	eventService := NewEventService(eventRepository, outbox.webhooks, outbox.jobs, outbox.transactor, &config.Config{})
+	userRepository, err := repository.NewUserRepository(db)
+	if err != nil {
+		t.Errorf("Error when create new user repository, when not expected. Error: %v", err)
+	}
+	creator, _ := userRepository.Save(ctx, &models.User{Name: "creator", Email: "creator", Password: "password", Role: models.ManagerRole})
+	organizer, _ := userRepository.Save(ctx, &models.User{Name: "organizer", Email: "organizer", Password: "password", Role: models.UserRole})
+	event, err := eventRepository.Save(ctx, &models.Event{
+		Title:            "title",
+		ShortDescription: "short description",
+		Description:      "description",
+		Location:         "location",
+		StartsAt:         eventStart,
+		EndsAt:           eventEnd,
+		TimeZone:         "Europe/Warsaw",
+		CreatedBy:        creator.ID,
+	})
+	if err != nil {
+		t.Errorf("Error when save event, when not expected. Error: %v", err)
+	}
+
+	t.Run("Add by other user", func(t *testing.T) {
+		err := eventService.AddOrganizer(ctx, event.ID, organizer.ID, createUserSchema(&organizer))
+		if err != ErrForbidden {
+			t.Errorf("Error is not ErrForbidden, when expected. Error: %v", err)
+		}
+	})
+	t.Run("Add creator", func(t *testing.T) {
+		err := eventService.AddOrganizer(ctx, event.ID, creator.ID, createUserSchema(&creator))
+		if err != ErrInvalidInput {
+			t.Errorf("Error is not ErrInvalidInput, when expected. Error: %v", err)
+		}
+	})
+	t.Run("Add by creator", func(t *testing.T) {
+		err := eventService.AddOrganizer(ctx, event.ID, organizer.ID, createUserSchema(&creator))
+		if err != nil {
+			t.Fatalf("Error when add organizer, when not expected. Error: %v", err)
+		}
+		organizers, err := eventService.GetOrganizers(ctx, event.ID)
+		if err != nil {
+			t.Fatalf("Error when get organizers, when not expected. Error: %v", err)
+		}
+		if len(organizers) != 1 || organizers[0].ID != organizer.ID {
+			t.Errorf("Organizers are %v, when expected only %v", organizers, organizer.ID)
+		}
+		_, err = eventService.GetAttendees(ctx, event.ID, createUserSchema(&organizer))
+		if err != nil {
+			t.Errorf("Error when co-organizer gets attendees, when not expected. Error: %v", err)
+		}
+	})
+	t.Run("Add twice", func(t *testing.T) {
+		err := eventService.AddOrganizer(ctx, event.ID, organizer.ID, createUserSchema(&creator))
+		if err != ErrAlreadyExists {
+			t.Errorf("Error is not ErrAlreadyExists, when expected. Error: %v", err)
+		}
+	})
+	t.Run("Remove by co-organizer", func(t *testing.T) {
+		err := eventService.RemoveOrganizer(ctx, event.ID, organizer.ID, createUserSchema(&organizer))
+		if err != ErrForbidden {
+			t.Errorf("Error is not ErrForbidden, when expected. Error: %v", err)
+		}
+	})
+	t.Run("Remove by creator", func(t *testing.T) {
+		err := eventService.RemoveOrganizer(ctx, event.ID, organizer.ID, createUserSchema(&creator))
+		if err != nil {
+			t.Fatalf("Error when remove organizer, when not expected. Error: %v", err)
+		}
+		_, err = eventService.UpdateEvent(ctx, &schemas.EventUpdate{Title: "new title"}, event.ID, createUserSchema(&organizer))
+		if err != ErrForbidden {
+			t.Errorf("Error is not ErrForbidden after removal, when expected. Error: %v", err)
+		}
+		err = eventService.RemoveOrganizer(ctx, event.ID, organizer.ID, createUserSchema(&creator))
+		if err != ErrNotFound {
+			t.Errorf("Error is not ErrNotFound, when expected. Error: %v", err)
 		}
 	})
 }
 
 func TestGetFeaturedEvents(t *testing.T) {
+	ctx := context.Background()
 	db := utils.ConnectToTestDatabase()
 	eventRepository, err := repository.NewEventRepository(db)
 	if err != nil {
 		t.Errorf("Error when create new event repository, when not expected. Error: %v", err)
 	}
-	eventService := NewEventService(eventRepository)
+	outbox := newTestOutbox(t, db)
+	eventService := NewEventService(eventRepository, outbox.webhooks, outbox.jobs, outbox.transactor, &config.Config{})
 	userRepository, err := repository.NewUserRepository(db)
 	if err != nil {
 		t.Errorf("Error when create new user repository, when not expected. Error: %v", err)
 	}
-	user, err := userRepository.Save(&models.User{Name: "name", Email: "email", Password: "password", Role: "user"})
+	user, err := userRepository.Save(ctx, &models.User{Name: "name", Email: "email", Password: "password", Role: "user"})
 	if err != nil {
 		t.Errorf("Error when save user, when not expected. Error: %v", err)
 	}
@@ -273,16 +850,17 @@ func TestGetFeaturedEvents(t *testing.T) {
 			ShortDescription: "short description",
 			Description:      "description",
 			Location:         "location",
-			Date:             "date",
-			Time:             "time",
+			StartsAt:         eventStart,
+			EndsAt:           eventEnd,
+			TimeZone:         "Europe/Warsaw",
 			CreatedBy:        user.ID,
 			IsFeatured:       true,
 		}
-		_, err := eventRepository.Save(&want)
+		_, err := eventRepository.Save(ctx, &want)
 		if err != nil {
 			t.Errorf("Error when save event, when not expected. Error: %v", err)
 		}
-		events, err := eventService.GetFeaturedEvents()
+		events, err := eventService.GetFeaturedEvents(ctx)
 		if err != nil {
 			t.Errorf("Error when get featured events, when not expected. Error: %v", err)
 		}
@@ -294,17 +872,19 @@ func TestGetFeaturedEvents(t *testing.T) {
 }
 
 func TestGetMyEvents(t *testing.T) {
+	ctx := context.Background()
 	db := utils.ConnectToTestDatabase()
 	eventRepository, err := repository.NewEventRepository(db)
 	if err != nil {
 		t.Errorf("Error when create new event repository, when not expected. Error: %v", err)
 	}
-	eventService := NewEventService(eventRepository)
+	outbox := newTestOutbox(t, db)
+	eventService := NewEventService(eventRepository, outbox.webhooks, outbox.jobs, outbox.transactor, &config.Config{})
 	userRepository, err := repository.NewUserRepository(db)
 	if err != nil {
 		t.Errorf("Error when create new user repository, when not expected. Error: %v", err)
 	}
-	user, err := userRepository.Save(&models.User{Name: "name", Email: "email", Password: "password", Role: "user"})
+	user, err := userRepository.Save(ctx, &models.User{Name: "name", Email: "email", Password: "password", Role: "user"})
 	if err != nil {
 		t.Errorf("Error when save user, when not expected. Error: %v", err)
 	}
@@ -313,15 +893,16 @@ func TestGetMyEvents(t *testing.T) {
 			Title:       "title",
 			Description: "description",
 			Location:    "location",
-			Date:        "date",
-			Time:        "time",
+			StartsAt:    eventStart,
+			EndsAt:      eventEnd,
+			TimeZone:    "Europe/Warsaw",
 			CreatedBy:   user.ID,
 		}
-		_, err := eventRepository.Save(&want)
+		_, err := eventRepository.Save(ctx, &want)
 		if err != nil {
 			t.Errorf("Error when save event, when not expected. Error: %v", err)
 		}
-		events, err := eventService.GetMyEvents(user.ID)
+		events, err := eventService.GetMyEvents(ctx, user.ID)
 		if err != nil {
 			t.Errorf("Error when get my events, when not expected. Error: %v", err)
 		}
@@ -343,11 +924,14 @@ func compareEvent(t *testing.T, got schemas.Event, want models.Event) {
 	if got.Location != want.Location {
 		t.Errorf("Location is not the same, got: %v, want: %v", got.Location, want.Location)
 	}
-	if got.Date != want.Date {
-		t.Errorf("Date is not the same, got: %v, want: %v", got.Date, want.Date)
+	if !got.StartsAt.Equal(want.StartsAt) {
+		t.Errorf("StartsAt is not the same, got: %v, want: %v", got.StartsAt, want.StartsAt)
+	}
+	if !got.EndsAt.Equal(want.EndsAt) {
+		t.Errorf("EndsAt is not the same, got: %v, want: %v", got.EndsAt, want.EndsAt)
 	}
-	if got.Time != want.Time {
-		t.Errorf("Time is not the same, got: %v, want: %v", got.Time, want.Time)
+	if got.TimeZone != want.TimeZone {
+		t.Errorf("TimeZone is not the same, got: %v, want: %v", got.TimeZone, want.TimeZone)
 	}
 	if got.CreatedBy != want.CreatedBy {
 		t.Errorf("CreatedBy is not the same, got: %v, want: %v", got.CreatedBy, want.CreatedBy)
@@ -0,0 +1,104 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/HermanPlay/web-app-backend/internal/migration"
+	"github.com/HermanPlay/web-app-backend/package/domain/schemas"
+	"gorm.io/gorm"
+)
+
+type HealthService interface {
+	Ready(ctx context.Context) (schemas.Readiness, bool)
+}
+
+// HealthCheck tells whether a dependency can be used, it should give up
+// when ctx is done.
+type HealthCheck func(ctx context.Context) error
+
+const (
+	healthOk          = "ok"
+	healthUnavailable = "unavailable"
+	healthTimeout     = 2 * time.Second // a dependency slower than this counts as down
+)
+
+type HealthServiceImpl struct {
+	checks map[string]HealthCheck
+}
+
+// Ready runs all checks at once and reports whether every one passed.
+func (h HealthServiceImpl) Ready(ctx context.Context) (schemas.Readiness, bool) {
+	ctx, cancel := context.WithTimeout(ctx, healthTimeout)
+	defer cancel()
+
+	readiness := schemas.Readiness{Status: healthOk, Checks: map[string]schemas.DependencyStatus{}}
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for name, check := range h.checks {
+		wg.Add(1)
+		go func(name string, check HealthCheck) {
+			defer wg.Done()
+			start := time.Now()
+			err := check(ctx)
+			if err == nil {
+				err = ctx.Err()
+			}
+			status := schemas.DependencyStatus{
+				Status:    healthOk,
+				LatencyMs: float64(time.Since(start).Microseconds()) / 1000,
+			}
+			if err != nil {
+				status.Status = healthUnavailable
+				status.Error = err.Error()
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			readiness.Checks[name] = status
+			if err != nil {
+				readiness.Status = healthUnavailable
+			}
+		}(name, check)
+	}
+	wg.Wait()
+	return readiness, readiness.Status == healthOk
+}
+
+// DatabaseCheck pings the database.
+func DatabaseCheck(db *gorm.DB) HealthCheck {
+	return func(ctx context.Context) error {
+		sqlDb, err := db.DB()
+		if err != nil {
+			return err
+		}
+		return sqlDb.PingContext(ctx)
+	}
+}
+
+// MigrationCheck fails while the schema lacks migrations of this build. It
+// only reads, so probes never create the state table of a database that was
+// not migrated yet.
+func MigrationCheck(db *gorm.DB) HealthCheck {
+	return func(ctx context.Context) error {
+		migrator, err := migration.ForDatabase(db.WithContext(ctx))
+		if err != nil {
+			return err
+		}
+		pending, err := migrator.Pending()
+		if err != nil {
+			return err
+		}
+		if len(pending) > 0 {
+			return fmt.Errorf("%d pending migrations", len(pending))
+		}
+		return nil
+	}
+}
+
+func NewHealthService(checks map[string]HealthCheck) HealthService {
+	return HealthServiceImpl{
+		checks: checks,
+	}
+}
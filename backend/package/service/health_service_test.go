@@ -0,0 +1,92 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/HermanPlay/web-app-backend/package/utils"
+)
+
+func TestReady(t *testing.T) {
+	passing := func(ctx context.Context) error { return nil }
+	failing := func(ctx context.Context) error { return errors.New("connection refused") }
+	hanging := func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	}
+
+	t.Run("All checks pass", func(t *testing.T) {
+		healthService := NewHealthService(map[string]HealthCheck{"database": passing, "migrations": passing})
+		readiness, ok := healthService.Ready(context.Background())
+		if !ok || readiness.Status != healthOk {
+			t.Errorf("Expected ready, got %+v", readiness)
+		}
+		if len(readiness.Checks) != 2 || readiness.Checks["database"].Status != healthOk {
+			t.Errorf("Expected a passing status per check, got %+v", readiness.Checks)
+		}
+	})
+	t.Run("A check fails", func(t *testing.T) {
+		healthService := NewHealthService(map[string]HealthCheck{"database": failing, "migrations": passing})
+		readiness, ok := healthService.Ready(context.Background())
+		if ok || readiness.Status != healthUnavailable {
+			t.Errorf("Expected unavailable, got %+v", readiness)
+		}
+		database := readiness.Checks["database"]
+		if database.Status != healthUnavailable || database.Error != "connection refused" {
+			t.Errorf("Expected failed database check, got %+v", database)
+		}
+		if readiness.Checks["migrations"].Status != healthOk {
+			t.Errorf("Expected passing migrations check, got %+v", readiness.Checks["migrations"])
+		}
+	})
+	t.Run("A check times out", func(t *testing.T) {
+		healthService := NewHealthService(map[string]HealthCheck{"database": hanging})
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		readiness, ok := healthService.Ready(ctx)
+		if ok || readiness.Checks["database"].Status != healthUnavailable {
+			t.Errorf("Expected unavailable after timeout, got %+v", readiness)
+		}
+	})
+}
+
+func TestDependencyChecks(t *testing.T) {
+	db := utils.ConnectToTestDatabase()
+
+	t.Run("Database", func(t *testing.T) {
+		err := DatabaseCheck(db)(context.Background())
+		if err != nil {
+			t.Errorf("Error when ping database, when not expected. Error: %v", err)
+		}
+	})
+	t.Run("Migrations applied", func(t *testing.T) {
+		err := MigrationCheck(db)(context.Background())
+		if err != nil {
+			t.Errorf("Error when check migrations, when not expected. Error: %v", err)
+		}
+	})
+	t.Run("Migrations pending", func(t *testing.T) {
+		err := db.Exec("DELETE FROM schema_migrations").Error
+		if err != nil {
+			t.Fatalf("Error when clear migrations, when not expected. Error: %v", err)
+		}
+		err = MigrationCheck(db)(context.Background())
+		if err == nil {
+			t.Errorf("Expected error when migrations are pending, got nil")
+		}
+	})
+	t.Run("Not migrated", func(t *testing.T) {
+		err := db.Migrator().DropTable("schema_migrations")
+		if err != nil {
+			t.Fatalf("Error when drop migrations, when not expected. Error: %v", err)
+		}
+		err = MigrationCheck(db)(context.Background())
+		if err == nil {
+			t.Errorf("Expected error when the database was not migrated, got nil")
+		}
+		if db.Migrator().HasTable("schema_migrations") {
+			t.Errorf("Migration check created the state table, when not expected")
+		}
+	})
+}
@@ -0,0 +1,248 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/HermanPlay/web-app-backend/internal/notification"
+	"github.com/HermanPlay/web-app-backend/package/domain/models"
+	"github.com/HermanPlay/web-app-backend/package/domain/schemas"
+	"github.com/HermanPlay/web-app-backend/package/repository"
+	"github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel/attribute"
+	"gorm.io/gorm"
+)
+
+type JobService interface {
+	RunNext(ctx context.Context, now time.Time) (bool, error)
+	GetJobs(ctx context.Context, filter schemas.JobFilter) ([]schemas.Job, *schemas.Pagination, error)
+	GetJob(ctx context.Context, id int) (*schemas.Job, error)
+	RetryJob(ctx context.Context, id int) (*schemas.Job, error)
+	PurgeDoneJobs(ctx context.Context, now time.Time) (int64, error)
+}
+
+// JobHandler carries out a job of one kind. Jobs are delivered at least once,
+// so handlers must cope with running twice for the same payload.
+type JobHandler func(ctx context.Context, payload []byte) error
+
+const (
+	NotificationJob = "notification"
+	WebhookJob      = "webhook"
+)
+
+const (
+	jobMaxAttempts = 8
+	jobLease       = 5 * time.Minute // how long a worker may take before the job is handed out again
+	jobBaseBackoff = 30 * time.Second
+	jobMaxBackoff  = time.Hour
+	jobRetention   = 7 * 24 * time.Hour // how long done jobs stay listed before they are purged
+)
+
+type JobServiceImpl struct {
+	jobRepository repository.JobRepository
+	handlers      map[string]JobHandler
+}
+
+// RunNext runs the next due job, if any, and reports whether there was one.
+// A failed job is retried with exponential backoff until it runs out of
+// attempts and is moved to the dead letters.
+func (j JobServiceImpl) RunNext(ctx context.Context, now time.Time) (bool, error) {
+	ctx, span := tracer.Start(ctx, "JobService.RunNext")
+	defer span.End()
+	job, err := j.jobRepository.Claim(ctx, now, jobLease)
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return false, nil
+		}
+		return false, err
+	}
+	span.SetAttributes(attribute.Int("job.id", job.ID), attribute.String("job.kind", job.Kind))
+
+	handler, ok := j.handlers[job.Kind]
+	if !ok {
+		err = fmt.Errorf("no handler for job kind %q", job.Kind)
+	} else {
+		err = runJobHandler(ctx, handler, job)
+	}
+	if err == nil {
+		return true, j.jobRepository.Complete(ctx, job)
+	}
+
+	var retryAt *time.Time
+	if ok && job.Attempts < job.MaxAttempts {
+		next := now.Add(jobBackoff(job.Attempts))
+		retryAt = &next
+		logrus.Warnf("Job %d (%s) failed on attempt %d, retrying at %v. Error: %v", job.ID, job.Kind, job.Attempts, next, err)
+	} else {
+		logrus.Errorf("Job %d (%s) failed on attempt %d, giving up. Error: %v", job.ID, job.Kind, job.Attempts, err)
+	}
+	return true, j.jobRepository.Fail(ctx, job, err.Error(), retryAt)
+}
+
+// runJobHandler turns a panicking handler into a failed attempt, so one bad
+// payload cannot take a worker down.
+func runJobHandler(ctx context.Context, handler JobHandler, job models.Job) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("job handler panicked: %v", r)
+		}
+	}()
+	return handler(ctx, []byte(job.Payload))
+}
+
+// jobBackoff doubles the wait after every attempt, up to jobMaxBackoff.
+func jobBackoff(attempts int) time.Duration {
+	backoff := jobBaseBackoff
+	for i := 1; i < attempts && backoff < jobMaxBackoff; i++ {
+		backoff *= 2
+	}
+	return min(backoff, jobMaxBackoff)
+}
+
+func (j JobServiceImpl) GetJobs(ctx context.Context, filter schemas.JobFilter) ([]schemas.Job, *schemas.Pagination, error) {
+	ctx, span := tracer.Start(ctx, "JobService.GetJobs")
+	defer span.End()
+	if filter.Page == 0 {
+		filter.Page = 1
+	}
+	if filter.PageSize == 0 {
+		filter.PageSize = defaultPageSize
+	}
+	if filter.Page < 0 || filter.PageSize < 0 || filter.PageSize > maxPageSize {
+		return nil, nil, ErrInvalidInput
+	}
+	switch filter.Status {
+	case "", models.JobPending, models.JobRunning, models.JobDone, models.JobDead:
+	default:
+		return nil, nil, ErrInvalidInput
+	}
+
+	jobs, total, err := j.jobRepository.GetAll(ctx, filter.Status, filter.PageSize, (filter.Page-1)*filter.PageSize)
+	if err != nil {
+		return nil, nil, err
+	}
+	response := []schemas.Job{}
+	for _, job := range jobs {
+		response = append(response, *createJobSchema(&job))
+	}
+	pagination := &schemas.Pagination{
+		Page:       filter.Page,
+		PageSize:   filter.PageSize,
+		Total:      total,
+		TotalPages: int((total + int64(filter.PageSize) - 1) / int64(filter.PageSize)),
+	}
+	return response, pagination, nil
+}
+
+func (j JobServiceImpl) GetJob(ctx context.Context, id int) (*schemas.Job, error) {
+	ctx, span := tracer.Start(ctx, "JobService.GetJob")
+	defer span.End()
+	job, err := j.jobRepository.GetByID(ctx, id)
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	return createJobSchema(&job), nil
+}
+
+// RetryJob runs a dead or waiting job again as soon as possible. Running and
+// finished jobs cannot be retried.
+func (j JobServiceImpl) RetryJob(ctx context.Context, id int) (*schemas.Job, error) {
+	ctx, span := tracer.Start(ctx, "JobService.RetryJob")
+	defer span.End()
+	job, err := j.jobRepository.GetByID(ctx, id)
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	if job.Status != models.JobDead && job.Status != models.JobPending {
+		return nil, ErrInvalidInput
+	}
+	job, err = j.jobRepository.Retry(ctx, id, time.Now())
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			// A worker picked it up in the meantime
+			return nil, ErrInvalidInput
+		}
+		return nil, err
+	}
+	return createJobSchema(&job), nil
+}
+
+// PurgeDoneJobs deletes the jobs done for longer than jobRetention and
+// returns how many there were.
+func (j JobServiceImpl) PurgeDoneJobs(ctx context.Context, now time.Time) (int64, error) {
+	ctx, span := tracer.Start(ctx, "JobService.PurgeDoneJobs")
+	defer span.End()
+	return j.jobRepository.DeleteDone(ctx, now.Add(-jobRetention))
+}
+
+// enqueueJob adds a job to the queue. Pass a repository bound to the
+// transaction of the write the job belongs to.
+func enqueueJob(ctx context.Context, jobs repository.JobRepository, kind string, payload any) error {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	return jobs.Enqueue(ctx, &models.Job{
+		Kind:        kind,
+		Payload:     string(data),
+		MaxAttempts: jobMaxAttempts,
+	})
+}
+
+// enqueueNotification renders the message right away and queues its
+// delivery, so the worker only has to send it.
+func enqueueNotification(ctx context.Context, jobs repository.JobRepository, to string, template notification.Template, data any) error {
+	msg, err := notification.Render(to, template, data)
+	if err != nil {
+		return err
+	}
+	return enqueueJob(ctx, jobs, NotificationJob, msg)
+}
+
+// NewNotificationHandler sends the messages queued by enqueueNotification.
+func NewNotificationHandler(notifier notification.Notifier) JobHandler {
+	return func(ctx context.Context, payload []byte) error {
+		var msg notification.Message
+		err := json.Unmarshal(payload, &msg)
+		if err != nil {
+			return err
+		}
+		return notifier.Send(msg)
+	}
+}
+
+// createJobSchema leaves out the payload of notifications, a rendered message
+// can hold a secret link meant only for its recipient.
+func createJobSchema(job *models.Job) *schemas.Job {
+	var payload json.RawMessage
+	if job.Kind != NotificationJob && job.Payload != "" {
+		payload = json.RawMessage(job.Payload)
+	}
+	return &schemas.Job{
+		ID:          job.ID,
+		Kind:        job.Kind,
+		Payload:     payload,
+		Status:      job.Status,
+		RunAt:       job.RunAt,
+		Attempts:    job.Attempts,
+		MaxAttempts: job.MaxAttempts,
+		LastError:   job.LastError,
+		CreatedAt:   job.CreatedAt,
+		UpdatedAt:   job.UpdatedAt,
+	}
+}
+
+func NewJobService(jobRepository repository.JobRepository, handlers map[string]JobHandler) JobService {
+	return &JobServiceImpl{
+		jobRepository: jobRepository,
+		handlers:      handlers,
+	}
+}
@@ -0,0 +1,249 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/HermanPlay/web-app-backend/internal/notification"
+	"github.com/HermanPlay/web-app-backend/package/domain/models"
+	"github.com/HermanPlay/web-app-backend/package/domain/schemas"
+	"github.com/HermanPlay/web-app-backend/package/repository"
+	"github.com/HermanPlay/web-app-backend/package/utils"
+	"gorm.io/gorm"
+)
+
+// testOutbox wires services to the job queue. Queued notifications are
+// delivered into memory, webhooks over HTTP.
+type testOutbox struct {
+	t          *testing.T
+	jobs       *repository.JobRepositoryImpl
+	webhooks   *repository.WebhookRepositoryImpl
+	transactor *repository.TransactorImpl
+	jobService JobService
+	mails      *notification.MemoryNotifier
+}
+
+func newTestOutbox(t *testing.T, db *gorm.DB) *testOutbox {
+	jobs, err := repository.NewJobRepository(db)
+	if err != nil {
+		t.Errorf("Error when create new job repository, when not expected. Error: %v", err)
+	}
+	webhooks, err := repository.NewWebhookRepository(db)
+	if err != nil {
+		t.Errorf("Error when create new webhook repository, when not expected. Error: %v", err)
+	}
+	mails := notification.NewMemoryNotifier()
+	return &testOutbox{
+		t:          t,
+		jobs:       jobs,
+		webhooks:   webhooks,
+		transactor: repository.NewTransactor(db),
+		jobService: NewJobService(jobs, map[string]JobHandler{
+			NotificationJob: NewNotificationHandler(mails),
+			WebhookJob:      NewWebhookHandler(webhooks, NewWebhookClient()),
+		}),
+		mails: mails,
+	}
+}
+
+// Messages runs the queued jobs and returns every message sent so far.
+func (o *testOutbox) Messages() []notification.Message {
+	ctx := context.Background()
+	o.t.Helper()
+	for {
+		ran, err := o.jobService.RunNext(ctx, time.Now())
+		if err != nil {
+			o.t.Fatalf("Error when run job, when not expected. Error: %v", err)
+		}
+		if !ran {
+			return o.mails.Messages()
+		}
+	}
+}
+
+func TestRunNext(t *testing.T) {
+	ctx := context.Background()
+	db := utils.ConnectToTestDatabase()
+	jobRepository, err := repository.NewJobRepository(db)
+	if err != nil {
+		t.Errorf("Error when create new job repository, when not expected. Error: %v", err)
+	}
+	var payloads []string
+	jobService := NewJobService(jobRepository, map[string]JobHandler{
+		"ok": func(ctx context.Context, payload []byte) error {
+			payloads = append(payloads, string(payload))
+			return nil
+		},
+		"fail": func(ctx context.Context, payload []byte) error {
+			return errors.New("unreachable")
+		},
+		"panic": func(ctx context.Context, payload []byte) error {
+			panic("bad payload")
+		},
+	})
+	// Jobs are enqueued to run right away, so now has to be after that
+	now := time.Now().Add(time.Minute).Truncate(time.Second)
+
+	t.Run("Empty queue", func(t *testing.T) {
+		ran, err := jobService.RunNext(ctx, now)
+		if err != nil || ran {
+			t.Errorf("Ran a job on an empty queue, ran: %v, error: %v", ran, err)
+		}
+	})
+	t.Run("Success", func(t *testing.T) {
+		enqueueJob(ctx, jobRepository, "ok", map[string]int{"id": 1})
+		ran, err := jobService.RunNext(ctx, now)
+		if err != nil || !ran {
+			t.Fatalf("Job did not run, when expected. Error: %v", err)
+		}
+		if len(payloads) != 1 || payloads[0] != `{"id":1}` {
+			t.Errorf("Handler got %v, when expected the payload", payloads)
+		}
+		jobs, _, _ := jobService.GetJobs(ctx, schemas.JobFilter{Status: models.JobDone})
+		if len(jobs) != 1 {
+			t.Errorf("Got %d done jobs, when expected 1", len(jobs))
+		}
+	})
+	t.Run("Retry with backoff", func(t *testing.T) {
+		enqueueJob(ctx, jobRepository, "fail", nil)
+		jobService.RunNext(ctx, now)
+		jobs, _, _ := jobService.GetJobs(ctx, schemas.JobFilter{Status: models.JobPending})
+		if len(jobs) != 1 {
+			t.Fatalf("Got %d pending jobs, when expected 1", len(jobs))
+		}
+		if !jobs[0].RunAt.Equal(now.Add(jobBaseBackoff)) || jobs[0].LastError != "unreachable" {
+			t.Errorf("Failed job runs at %v with error %q, when expected %v and the handler error", jobs[0].RunAt, jobs[0].LastError, now.Add(jobBaseBackoff))
+		}
+		ran, _ := jobService.RunNext(ctx, now.Add(time.Second))
+		if ran {
+			t.Errorf("Job ran before its backoff, when not expected")
+		}
+	})
+	t.Run("Dead letter", func(t *testing.T) {
+		at := now
+		for i := 1; i < jobMaxAttempts; i++ {
+			at = at.Add(jobMaxBackoff)
+			jobService.RunNext(ctx, at)
+		}
+		jobs, _, _ := jobService.GetJobs(ctx, schemas.JobFilter{Status: models.JobDead})
+		if len(jobs) != 1 || jobs[0].Attempts != jobMaxAttempts {
+			t.Fatalf("Dead jobs are %+v, when expected one after %d attempts", jobs, jobMaxAttempts)
+		}
+
+		job, err := jobService.RetryJob(ctx, jobs[0].ID)
+		if err != nil {
+			t.Fatalf("Error when retry job, when not expected. Error: %v", err)
+		}
+		if job.Status != models.JobPending || job.Attempts != 0 {
+			t.Errorf("Retried job is %v after %d attempts, when expected pending with none", job.Status, job.Attempts)
+		}
+	})
+	t.Run("Panic and unknown kind", func(t *testing.T) {
+		db.Where("1 = 1").Delete(&models.Job{})
+		enqueueJob(ctx, jobRepository, "panic", nil)
+		enqueueJob(ctx, jobRepository, "unknown", nil)
+		jobService.RunNext(ctx, now)
+		jobService.RunNext(ctx, now)
+		pending, _, _ := jobService.GetJobs(ctx, schemas.JobFilter{Status: models.JobPending})
+		if len(pending) != 1 || pending[0].Kind != "panic" {
+			t.Errorf("Pending jobs are %+v, when expected the panicking one to be retried", pending)
+		}
+		// Nothing can handle an unknown kind, so it is not retried
+		dead, _, _ := jobService.GetJobs(ctx, schemas.JobFilter{Status: models.JobDead})
+		if len(dead) != 1 || dead[0].Kind != "unknown" {
+			t.Errorf("Dead jobs are %+v, when expected the unknown one", dead)
+		}
+	})
+}
+
+func TestJobBackoff(t *testing.T) {
+	tests := []struct {
+		attempts int
+		want     time.Duration
+	}{
+		{1, 30 * time.Second},
+		{2, time.Minute},
+		{4, 4 * time.Minute},
+		{8, time.Hour},
+		{20, time.Hour},
+	}
+	for _, tt := range tests {
+		got := jobBackoff(tt.attempts)
+		if got != tt.want {
+			t.Errorf("Backoff after %d attempts is %v, when expected %v", tt.attempts, got, tt.want)
+		}
+	}
+}
+
+func TestGetJobs(t *testing.T) {
+	ctx := context.Background()
+	db := utils.ConnectToTestDatabase()
+	jobRepository, err := repository.NewJobRepository(db)
+	if err != nil {
+		t.Errorf("Error when create new job repository, when not expected. Error: %v", err)
+	}
+	jobService := NewJobService(jobRepository, nil)
+	for i := 0; i < 3; i++ {
+		enqueueJob(ctx, jobRepository, NotificationJob, i)
+	}
+
+	t.Run("Page", func(t *testing.T) {
+		jobs, pagination, err := jobService.GetJobs(ctx, schemas.JobFilter{PageSize: 2})
+		if err != nil {
+			t.Fatalf("Error when get jobs, when not expected. Error: %v", err)
+		}
+		if len(jobs) != 2 || pagination.Total != 3 || pagination.TotalPages != 2 {
+			t.Errorf("Got %d jobs and %+v, when expected 2 of 3 on 2 pages", len(jobs), pagination)
+		}
+		if jobs[0].ID < jobs[1].ID {
+			t.Errorf("Jobs are not newest first")
+		}
+	})
+	t.Run("Invalid status", func(t *testing.T) {
+		_, _, err := jobService.GetJobs(ctx, schemas.JobFilter{Status: "lost"})
+		if err != ErrInvalidInput {
+			t.Errorf("Error is not ErrInvalidInput, when expected. Error: %v", err)
+		}
+	})
+	t.Run("Get job", func(t *testing.T) {
+		_, err := jobService.GetJob(ctx, 1000)
+		if err != ErrNotFound {
+			t.Errorf("Error is not ErrNotFound, when expected. Error: %v", err)
+		}
+	})
+	t.Run("Retry running job", func(t *testing.T) {
+		job, _ := jobRepository.Claim(ctx, time.Now(), time.Minute)
+		_, err := jobService.RetryJob(ctx, job.ID)
+		if err != ErrInvalidInput {
+			t.Errorf("Error is not ErrInvalidInput, when expected. Error: %v", err)
+		}
+	})
+	t.Run("Payload", func(t *testing.T) {
+		other := models.Job{Kind: "other", Payload: `{"id":1}`, MaxAttempts: 1}
+		jobRepository.Enqueue(ctx, &other)
+		job, _ := jobService.GetJob(ctx, other.ID)
+		if string(job.Payload) != other.Payload {
+			t.Errorf("Payload is %s, when expected %s", job.Payload, other.Payload)
+		}
+		jobs, _, _ := jobService.GetJobs(ctx, schemas.JobFilter{Status: models.JobPending})
+		for _, job := range jobs {
+			if job.Kind == NotificationJob && job.Payload != nil {
+				t.Errorf("Payload of notification job %d is %s, when expected it left out", job.ID, job.Payload)
+			}
+		}
+	})
+	t.Run("Purge done jobs", func(t *testing.T) {
+		job, _ := jobRepository.Claim(ctx, time.Now(), time.Minute)
+		jobRepository.Complete(ctx, job)
+		purged, err := jobService.PurgeDoneJobs(ctx, time.Now())
+		if err != nil || purged != 0 {
+			t.Errorf("Purged %d jobs, when expected none within the retention. Error: %v", purged, err)
+		}
+		purged, err = jobService.PurgeDoneJobs(ctx, time.Now().Add(jobRetention+time.Minute))
+		if err != nil || purged != 1 {
+			t.Errorf("Purged %d jobs, when expected the done one. Error: %v", purged, err)
+		}
+	})
+}
@@ -0,0 +1,84 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/HermanPlay/web-app-backend/internal/config"
+	"github.com/HermanPlay/web-app-backend/internal/notification"
+	"github.com/HermanPlay/web-app-backend/package/domain/models"
+	"github.com/HermanPlay/web-app-backend/package/repository"
+	"gorm.io/gorm"
+)
+
+type ReminderService interface {
+	SendDueReminders(ctx context.Context, now time.Time) (int, error)
+}
+
+type ReminderServiceImpl struct {
+	reminderRepository repository.ReminderRepository
+	jobRepository      repository.JobRepository
+	transactor         repository.Transactor
+	cfg                *config.Config
+}
+
+// reminderSchedule lists the reminders from the latest to the earliest. Each
+// one covers the events starting between its lead time and the next one's, so
+// a booking made an hour before the start gets the hour reminder only.
+var reminderSchedule = []struct {
+	kind models.ReminderKind
+	lead time.Duration
+}{
+	{models.HourReminder, time.Hour},
+	{models.DayReminder, 24 * time.Hour},
+}
+
+// SendDueReminders queues every reminder that is due at now and returns how
+// many were queued. A reminder is claimed in the same transaction that queues
+// it, so running this on several replicas at once or after a restart queues
+// each reminder once, and delivery is retried by the job workers.
+func (r ReminderServiceImpl) SendDueReminders(ctx context.Context, now time.Time) (int, error) {
+	ctx, span := tracer.Start(ctx, "ReminderService.SendDueReminders")
+	defer span.End()
+	sent := 0
+	from := now
+	for _, reminder := range reminderSchedule {
+		to := now.Add(reminder.lead)
+		bookings, err := r.reminderRepository.GetDueReminders(ctx, reminder.kind, from, to)
+		if err != nil {
+			return sent, err
+		}
+		for _, booking := range bookings {
+			var claimed bool
+			err = r.transactor.Transaction(ctx, func(tx *gorm.DB) error {
+				claimed, err = r.reminderRepository.WithTx(tx).ClaimReminder(ctx, booking, reminder.kind, now)
+				if err != nil || !claimed {
+					return err
+				}
+				return enqueueNotification(ctx, r.jobRepository.WithTx(tx), booking.User.Email, notification.EventReminder, notification.EventData{
+					Name:  booking.User.Name,
+					Event: *createEventSchema(&booking.Event),
+					Link:  publicLink(r.cfg, fmt.Sprintf("/events/%d", booking.EventID)),
+				})
+			})
+			if err != nil {
+				return sent, err
+			}
+			if claimed {
+				sent++
+			}
+		}
+		from = to
+	}
+	return sent, nil
+}
+
+func NewReminderService(reminderRepository repository.ReminderRepository, jobRepository repository.JobRepository, transactor repository.Transactor, cfg *config.Config) ReminderService {
+	return &ReminderServiceImpl{
+		reminderRepository: reminderRepository,
+		jobRepository:      jobRepository,
+		transactor:         transactor,
+		cfg:                cfg,
+	}
+}
@@ -0,0 +1,97 @@
+package service
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/HermanPlay/web-app-backend/internal/config"
+	"github.com/HermanPlay/web-app-backend/internal/notification"
+	"github.com/HermanPlay/web-app-backend/package/domain/models"
+	"github.com/HermanPlay/web-app-backend/package/repository"
+	"github.com/HermanPlay/web-app-backend/package/utils"
+)
+
+func TestSendDueReminders(t *testing.T) {
+	ctx := context.Background()
+	db := utils.ConnectToTestDatabase()
+	reminderRepository, err := repository.NewReminderRepository(db)
+	if err != nil {
+		t.Errorf("Error when create new reminder repository, when not expected. Error: %v", err)
+	}
+	eventRepository, err := repository.NewEventRepository(db)
+	if err != nil {
+		t.Errorf("Error when create new event repository, when not expected. Error: %v", err)
+	}
+	userRepository, err := repository.NewUserRepository(db)
+	if err != nil {
+		t.Errorf("Error when create new user repository, when not expected. Error: %v", err)
+	}
+	mails := newTestOutbox(t, db)
+	cfg := &config.Config{App: config.App{PublicURL: "http://frontend"}}
+	reminderService := NewReminderService(reminderRepository, mails.jobs, mails.transactor, cfg)
+	eventService := NewEventService(eventRepository, mails.webhooks, mails.jobs, mails.transactor, cfg)
+
+	user, _ := userRepository.Save(ctx, &models.User{Name: "name", Email: "email", Password: "password", Role: "user"})
+	event, err := eventRepository.Save(ctx, &models.Event{
+		Title:            "title",
+		ShortDescription: "short description",
+		Description:      "description",
+		Location:         "location",
+		StartsAt:         eventStart,
+		EndsAt:           eventEnd,
+		TimeZone:         "Europe/Warsaw",
+		CreatedBy:        user.ID,
+	})
+	if err != nil {
+		t.Errorf("Error when save event, when not expected. Error: %v", err)
+	}
+	eventService.BookEvent(ctx, event.ID, user.ID)
+
+	tests := []struct {
+		name string
+		now  time.Time
+		want int
+	}{
+		{"Too early", eventStart.Add(-25 * time.Hour), 0},
+		{"Day before", eventStart.Add(-23 * time.Hour), 1},
+		{"Day reminder sent", eventStart.Add(-22 * time.Hour), 0},
+		{"Hour before", eventStart.Add(-30 * time.Minute), 1},
+		{"Hour reminder sent", eventStart.Add(-10 * time.Minute), 0},
+		{"Started", eventStart.Add(time.Minute), 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sent, err := reminderService.SendDueReminders(ctx, tt.now)
+			if err != nil {
+				t.Fatalf("Error when send due reminders, when not expected. Error: %v", err)
+			}
+			if sent != tt.want {
+				t.Errorf("Sent %d reminders, when expected %d", sent, tt.want)
+			}
+		})
+	}
+	var reminders []notification.Message
+	for _, msg := range mails.Messages() {
+		if strings.HasPrefix(msg.Subject, "Reminder:") {
+			reminders = append(reminders, msg)
+		}
+	}
+	if len(reminders) != 2 || reminders[0].To != user.Email {
+		t.Errorf("Emails are %v, when expected two reminders to %v", reminders, user.Email)
+	}
+
+	t.Run("Late booking gets the hour reminder only", func(t *testing.T) {
+		other, _ := userRepository.Save(ctx, &models.User{Name: "other", Email: "other", Password: "password", Role: "user"})
+		eventService.BookEvent(ctx, event.ID, other.ID)
+		sent, _ := reminderService.SendDueReminders(ctx, eventStart.Add(-20*time.Minute))
+		if sent != 1 {
+			t.Errorf("Sent %d reminders, when expected 1", sent)
+		}
+		sent, _ = reminderService.SendDueReminders(ctx, eventStart.Add(-10*time.Minute))
+		if sent != 0 {
+			t.Errorf("Sent %d reminders on the next sweep, when expected none", sent)
+		}
+	})
+}
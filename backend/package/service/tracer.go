@@ -0,0 +1,7 @@
+package service
+
+import "go.opentelemetry.io/otel"
+
+// tracer starts a span for every service call, between the request span and
+// the repository spans.
+var tracer = otel.Tracer("github.com/HermanPlay/web-app-backend/package/service")
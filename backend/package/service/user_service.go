@@ -3,6 +3,9 @@ package service
 // TODO: Implement PanicHandler
 
 import (
+	"context"
+	"errors"
+
 	"github.com/HermanPlay/web-app-backend/internal/api/http/util"
 	"github.com/HermanPlay/web-app-backend/internal/api/http/util/token"
 	"github.com/HermanPlay/web-app-backend/internal/config"
@@ -15,12 +18,12 @@ import (
 const randomPasswordLength = 8
 
 type UserService interface {
-	GetAllUser() ([]schemas.User, error)
-	GetUserById(userId int) (*schemas.User, error)
-	AddUserData(user schemas.UserInput) (*schemas.User, error)
-	UpdateUserData(user schemas.UserUpdate, userId int) (*schemas.User, error)
-	DeleteUser(userId int) error
-	DecodeToken(token string) (*schemas.User, error)
+	GetAllUser(ctx context.Context) ([]schemas.User, error)
+	GetUserById(ctx context.Context, userId int) (*schemas.User, error)
+	AddUserData(ctx context.Context, user schemas.UserInput, requester *schemas.User) (*schemas.User, error)
+	UpdateUserData(ctx context.Context, user schemas.UserUpdate, userId int, requester *schemas.User) (*schemas.User, error)
+	DeleteUser(ctx context.Context, userId int, requester *schemas.User) error
+	DecodeToken(ctx context.Context, token string) (*schemas.User, error)
 }
 
 type UserServiceImpl struct {
@@ -28,16 +31,31 @@ type UserServiceImpl struct {
 	cfg            *config.Config
 }
 
-func (u UserServiceImpl) UpdateUserData(user schemas.UserUpdate, userId int) (*schemas.User, error) {
-	data, err := u.userRepository.FindUserById(userId)
+// UpdateUserData lets users edit their own profile. Editing other users and
+// changing roles is reserved for admins.
+func (u UserServiceImpl) UpdateUserData(ctx context.Context, user schemas.UserUpdate, userId int, requester *schemas.User) (*schemas.User, error) {
+	ctx, span := tracer.Start(ctx, "UserService.UpdateUserData")
+	defer span.End()
+	if requester.ID != userId && !isAdmin(requester) {
+		return nil, ErrForbidden
+	}
+	data, err := u.userRepository.FindUserById(ctx, userId)
 	if err != nil {
 		if err == gorm.ErrRecordNotFound {
 			return nil, ErrNotFound
 		}
 		return nil, err
 	}
+	if user.Role != "" && user.Role != data.Role {
+		if !isAdmin(requester) {
+			return nil, ErrForbidden
+		}
+		if !user.Role.IsValid() {
+			return nil, ErrInvalidInput
+		}
+	}
 	if user.Email != data.Email {
-		exists, err := u.userRepository.CheckUserExist(user.Email)
+		exists, err := u.userRepository.CheckUserExist(ctx, user.Email)
 		if err != nil {
 			return nil, err
 		}
@@ -48,16 +66,22 @@ func (u UserServiceImpl) UpdateUserData(user schemas.UserUpdate, userId int) (*s
 
 	updateModel(&data, &user)
 
-	updated, err := u.userRepository.Update(&data)
+	updated, err := u.userRepository.Update(ctx, &data)
 	if err != nil {
+		// Another request took the email since the check above
+		if errors.Is(err, gorm.ErrDuplicatedKey) {
+			return nil, ErrAlreadyExists
+		}
 		return nil, err
 	}
 	returnData := createUserSchema(&updated)
 	return returnData, nil
 }
 
-func (u UserServiceImpl) GetUserById(userId int) (*schemas.User, error) {
-	data, err := u.userRepository.FindUserById(userId)
+func (u UserServiceImpl) GetUserById(ctx context.Context, userId int) (*schemas.User, error) {
+	ctx, span := tracer.Start(ctx, "UserService.GetUserById")
+	defer span.End()
+	data, err := u.userRepository.FindUserById(ctx, userId)
 	if err != nil {
 		if err == gorm.ErrRecordNotFound {
 			return nil, ErrNotFound
@@ -70,14 +94,22 @@ func (u UserServiceImpl) GetUserById(userId int) (*schemas.User, error) {
 
 }
 
-func (u UserServiceImpl) AddUserData(user schemas.UserInput) (*schemas.User, error) {
+func (u UserServiceImpl) AddUserData(ctx context.Context, user schemas.UserInput, requester *schemas.User) (*schemas.User, error) {
+	ctx, span := tracer.Start(ctx, "UserService.AddUserData")
+	defer span.End()
+	if !isAdmin(requester) {
+		return nil, ErrForbidden
+	}
 	userData := createUserModelWithPassword(&user)
-	if userData.Email == "" || userData.Name == "" {
+	if userData.Email == "" || userData.Name == "" || !userData.Role.IsValid() {
 		return nil, ErrInvalidInput
 	}
 
-	data, err := u.userRepository.Save(&userData)
+	data, err := u.userRepository.Save(ctx, &userData)
 	if err != nil {
+		if errors.Is(err, gorm.ErrDuplicatedKey) {
+			return nil, ErrAlreadyExists
+		}
 		return nil, err
 	}
 
@@ -86,8 +118,10 @@ func (u UserServiceImpl) AddUserData(user schemas.UserInput) (*schemas.User, err
 	return returnData, nil
 }
 
-func (u UserServiceImpl) GetAllUser() ([]schemas.User, error) {
-	data, err := u.userRepository.FindAllUser()
+func (u UserServiceImpl) GetAllUser(ctx context.Context) ([]schemas.User, error) {
+	ctx, span := tracer.Start(ctx, "UserService.GetAllUser")
+	defer span.End()
+	data, err := u.userRepository.FindAllUser(ctx)
 	if err != nil {
 		return nil, err
 	}
@@ -99,33 +133,41 @@ func (u UserServiceImpl) GetAllUser() ([]schemas.User, error) {
 
 }
 
-func (u UserServiceImpl) DeleteUser(userId int) error {
-	_, err := u.userRepository.FindUserById(userId)
+func (u UserServiceImpl) DeleteUser(ctx context.Context, userId int, requester *schemas.User) error {
+	ctx, span := tracer.Start(ctx, "UserService.DeleteUser")
+	defer span.End()
+	if !isAdmin(requester) {
+		return ErrForbidden
+	}
+	_, err := u.userRepository.FindUserById(ctx, userId)
 	if err != nil {
 		if err == gorm.ErrRecordNotFound {
 			return ErrNotFound
 		}
 		return err
 	}
-	err = u.userRepository.DeleteUserById(userId)
+	err = u.userRepository.DeleteUserById(ctx, userId)
 	if err != nil {
 		return err
 	}
 	return nil
 }
 
-func (u UserServiceImpl) DecodeToken(t string) (*schemas.User, error) {
+func (u UserServiceImpl) DecodeToken(ctx context.Context, t string) (*schemas.User, error) {
+	ctx, span := tracer.Start(ctx, "UserService.DecodeToken")
+	defer span.End()
 	claims, err := token.DecodeToken(t, u.cfg)
 	if err != nil {
 		return nil, err
 	}
 
-	userId := int(claims["user_id"].(float64))
-	if userId == 0 {
+	rawUserId, ok := claims["user_id"].(float64)
+	if !ok || rawUserId == 0 {
 		return nil, ErrInvalidToken
 	}
+	userId := int(rawUserId)
 
-	user, err := u.userRepository.FindUserById(userId)
+	user, err := u.userRepository.FindUserById(ctx, userId)
 	if err != nil {
 		if err == gorm.ErrRecordNotFound {
 			return nil, ErrNotFound
@@ -136,12 +178,17 @@ func (u UserServiceImpl) DecodeToken(t string) (*schemas.User, error) {
 	return returnData, nil
 }
 
+func isAdmin(user *schemas.User) bool {
+	return user != nil && user.Role == models.AdminRole
+}
+
 func createUserSchema(model *models.User) *schemas.User {
 	return &schemas.User{
-		ID:    model.ID,
-		Email: model.Email,
-		Name:  model.Name,
-		Role:  model.Role,
+		ID:                model.ID,
+		Email:             model.Email,
+		Name:              model.Name,
+		Role:              model.Role,
+		RemindersDisabled: model.RemindersDisabled,
 	}
 }
 
@@ -155,16 +202,23 @@ func updateModel(model *models.User, request *schemas.UserUpdate) {
 	if request.Role != "" {
 		model.Role = request.Role
 	}
+	if request.RemindersDisabled != nil {
+		model.RemindersDisabled = *request.RemindersDisabled
+	}
 }
 
 func createUserModelWithPassword(requestData *schemas.UserInput) models.User {
+	role := requestData.Role
+	if role == "" {
+		role = models.UserRole // Default role
+	}
 	return models.User{
 		Name:  requestData.Name,
 		Email: requestData.Email,
 		Password: util.GenerateRandomString(
 			randomPasswordLength,
 		),
-		Role: models.UserRole, // Default role
+		Role: role,
 	}
 }
 
@@ -1,6 +1,7 @@
 package service
 
 import (
+	"context"
 	"testing"
 
 	"github.com/HermanPlay/web-app-backend/internal/api/http/util/token"
@@ -8,22 +9,21 @@ import (
 	"github.com/HermanPlay/web-app-backend/package/domain/models"
 	"github.com/HermanPlay/web-app-backend/package/domain/schemas"
 	"github.com/HermanPlay/web-app-backend/package/repository"
-	"github.com/HermanPlay/web-app-backend/package/utils"
+	"github.com/golang-jwt/jwt"
 )
 
+var admin = &schemas.User{ID: 1000, Name: "admin", Email: "admin@email", Role: models.AdminRole}
+
 func TestGetAll(t *testing.T) {
-	db := utils.ConnectToTestDatabase()
+	ctx := context.Background()
 	cfg := config.Config{
 		Db:  config.Db{},
 		App: config.App{ApiSecret: "secret"},
 	}
-	userRepository, err := repository.NewUserRepository(db)
-	if err != nil {
-		t.Errorf("Error when create new user repository, when not expected. Error: %v", err)
-	}
+	userRepository := repository.NewMemoryUserRepository()
 	userService := NewUserService(userRepository, &cfg)
 	t.Run("Empty users", func(t *testing.T) {
-		users, err := userService.GetAllUser()
+		users, err := userService.GetAllUser(ctx)
 		if err != nil {
 			t.Errorf("Error when get all users, when not expected. Error: %v", err)
 		}
@@ -38,11 +38,11 @@ func TestGetAll(t *testing.T) {
 			Password: "password",
 			Role:     "role",
 		}
-		_, err := userRepository.Save(&want)
+		_, err := userRepository.Save(ctx, &want)
 		if err != nil {
 			t.Errorf("Error when save user, when not expected. Error: %v", err)
 		}
-		users, err := userService.GetAllUser()
+		users, err := userService.GetAllUser(ctx)
 		if err != nil {
 			t.Errorf("Error when get all users, when not expected. Error: %v", err)
 		}
@@ -55,18 +55,15 @@ func TestGetAll(t *testing.T) {
 }
 
 func TestGetUserById(t *testing.T) {
-	db := utils.ConnectToTestDatabase()
+	ctx := context.Background()
 	cfg := config.Config{
 		Db:  config.Db{},
 		App: config.App{ApiSecret: "secret"},
 	}
-	userRepository, err := repository.NewUserRepository(db)
-	if err != nil {
-		t.Errorf("Error when create new user repository, when not expected. Error: %v", err)
-	}
+	userRepository := repository.NewMemoryUserRepository()
 	userService := NewUserService(userRepository, &cfg)
 	t.Run("Empty user", func(t *testing.T) {
-		user, err := userService.GetUserById(1)
+		user, err := userService.GetUserById(ctx, 1)
 		if err == nil {
 			t.Errorf("Error is nil, when expected")
 		}
@@ -84,8 +81,8 @@ func TestGetUserById(t *testing.T) {
 			Password: "password",
 			Role:     "role",
 		}
-		got, _ := userRepository.Save(&want)
-		user, err := userService.GetUserById(got.ID)
+		got, _ := userRepository.Save(ctx, &want)
+		user, err := userService.GetUserById(ctx, got.ID)
 		if err != nil {
 			t.Errorf("Error when get user by id, when not expected. Error: %v", err)
 		}
@@ -94,18 +91,15 @@ func TestGetUserById(t *testing.T) {
 }
 
 func TestAddUserData(t *testing.T) {
-	db := utils.ConnectToTestDatabase()
+	ctx := context.Background()
 	cfg := config.Config{
 		Db:  config.Db{},
 		App: config.App{ApiSecret: "secret"},
 	}
-	userRepository, err := repository.NewUserRepository(db)
-	if err != nil {
-		t.Errorf("Error when create new user repository, when not expected. Error: %v", err)
-	}
+	userRepository := repository.NewMemoryUserRepository()
 	userService := NewUserService(userRepository, &cfg)
 	t.Run("Empty user", func(t *testing.T) {
-		user, err := userService.AddUserData(schemas.UserInput{})
+		user, err := userService.AddUserData(ctx, schemas.UserInput{}, admin)
 		if err == nil {
 			t.Errorf("Error is nil, when expected")
 		}
@@ -119,7 +113,7 @@ func TestAddUserData(t *testing.T) {
 			Email: "email",
 			Role:  models.UserRole,
 		}
-		user, err := userService.AddUserData(want)
+		user, err := userService.AddUserData(ctx, want, admin)
 		if err != nil {
 			t.Errorf("Error when add user data, when not expected. Error: %v", err)
 		}
@@ -128,21 +122,48 @@ func TestAddUserData(t *testing.T) {
 		}
 		compareUser(t, *user, models.User{Name: want.Name, Email: want.Email, Role: want.Role})
 	})
+	t.Run("Manager role", func(t *testing.T) {
+		want := schemas.UserInput{Name: "name", Email: "manager@email", Role: models.ManagerRole}
+		user, err := userService.AddUserData(ctx, want, admin)
+		if err != nil {
+			t.Fatalf("Error when add user data, when not expected. Error: %v", err)
+		}
+		compareUser(t, *user, models.User{Name: want.Name, Email: want.Email, Role: want.Role})
+	})
+	t.Run("Taken email", func(t *testing.T) {
+		_, err := userService.AddUserData(ctx, schemas.UserInput{Name: "other", Email: "manager@email"}, admin)
+		if err != ErrAlreadyExists {
+			t.Errorf("Error is not ErrAlreadyExists, when expected. got: %v", err)
+		}
+	})
+	t.Run("Unknown role", func(t *testing.T) {
+		_, err := userService.AddUserData(ctx, schemas.UserInput{Name: "name", Email: "email", Role: "root"}, admin)
+		if err != ErrInvalidInput {
+			t.Errorf("Error is not ErrInvalidInput, when expected. got: %v", err)
+		}
+	})
+	t.Run("Not an admin", func(t *testing.T) {
+		requester := &schemas.User{ID: 1, Role: models.ManagerRole}
+		user, err := userService.AddUserData(ctx, schemas.UserInput{Name: "name", Email: "email"}, requester)
+		if err != ErrForbidden {
+			t.Errorf("Error is not ErrForbidden, when expected. got: %v", err)
+		}
+		if user != nil {
+			t.Errorf("User is not nil, when expected")
+		}
+	})
 }
 
 func TestUpdateUser(t *testing.T) {
-	db := utils.ConnectToTestDatabase()
+	ctx := context.Background()
 	cfg := config.Config{
 		Db:  config.Db{},
 		App: config.App{ApiSecret: "secret"},
 	}
-	userRepository, err := repository.NewUserRepository(db)
-	if err != nil {
-		t.Errorf("Error when create new user repository, when not expected. Error: %v", err)
-	}
+	userRepository := repository.NewMemoryUserRepository()
 	userService := NewUserService(userRepository, &cfg)
 	t.Run("Non existing user", func(t *testing.T) {
-		user, err := userService.UpdateUserData(schemas.UserUpdate{}, 1)
+		user, err := userService.UpdateUserData(ctx, schemas.UserUpdate{}, 1, admin)
 		if err == nil {
 			t.Errorf("Error is nil, when expected")
 		}
@@ -168,10 +189,10 @@ func TestUpdateUser(t *testing.T) {
 			Password: "password",
 			Role:     "role",
 		}
-		got, _ := userRepository.Save(&want)
-		userRepository.Save(&want2)
+		got, _ := userRepository.Save(ctx, &want)
+		userRepository.Save(ctx, &want2)
 		t.Run("Invalid email", func(t *testing.T) {
-			user, err := userService.UpdateUserData(schemas.UserUpdate{Email: want2.Email}, got.ID)
+			user, err := userService.UpdateUserData(ctx, schemas.UserUpdate{Email: want2.Email}, got.ID, admin)
 			if err == nil {
 				t.Errorf("Error is nil, when expected")
 			}
@@ -183,7 +204,7 @@ func TestUpdateUser(t *testing.T) {
 			}
 		})
 		t.Run("Valid email", func(t *testing.T) {
-			user, err := userService.UpdateUserData(schemas.UserUpdate{Email: "email2"}, got.ID)
+			user, err := userService.UpdateUserData(ctx, schemas.UserUpdate{Email: "email2"}, got.ID, admin)
 			if err != nil {
 				t.Errorf("Error when update user data, when not expected. Error: %v", err)
 			}
@@ -193,21 +214,72 @@ func TestUpdateUser(t *testing.T) {
 			compareUser(t, *user, models.User{Name: want.Name, Email: "email2", Role: want.Role})
 		})
 	})
+
+	t.Run("Role changes", func(t *testing.T) {
+		member, _ := userRepository.Save(ctx, &models.User{Name: "member", Email: "member@email", Password: "password", Role: models.UserRole})
+		self := createUserSchema(&member)
+		t.Run("Own profile", func(t *testing.T) {
+			user, err := userService.UpdateUserData(ctx, schemas.UserUpdate{Name: "renamed", Role: models.UserRole}, member.ID, self)
+			if err != nil {
+				t.Fatalf("Error when update user data, when not expected. Error: %v", err)
+			}
+			compareUser(t, *user, models.User{Name: "renamed", Email: member.Email, Role: models.UserRole})
+		})
+		t.Run("Opt out of reminders", func(t *testing.T) {
+			disabled := true
+			user, err := userService.UpdateUserData(ctx, schemas.UserUpdate{RemindersDisabled: &disabled}, member.ID, self)
+			if err != nil {
+				t.Fatalf("Error when update user data, when not expected. Error: %v", err)
+			}
+			if !user.RemindersDisabled {
+				t.Errorf("Reminders are not disabled, when expected")
+			}
+			user, _ = userService.UpdateUserData(ctx, schemas.UserUpdate{Name: "renamed again"}, member.ID, self)
+			if !user.RemindersDisabled {
+				t.Errorf("Reminders were enabled by an unrelated update")
+			}
+		})
+		t.Run("Own role", func(t *testing.T) {
+			_, err := userService.UpdateUserData(ctx, schemas.UserUpdate{Role: models.AdminRole}, member.ID, self)
+			if err != ErrForbidden {
+				t.Errorf("Error is not ErrForbidden, when expected. got: %v", err)
+			}
+		})
+		t.Run("Other user", func(t *testing.T) {
+			manager := &schemas.User{ID: member.ID + 1, Role: models.ManagerRole}
+			_, err := userService.UpdateUserData(ctx, schemas.UserUpdate{Name: "hijacked"}, member.ID, manager)
+			if err != ErrForbidden {
+				t.Errorf("Error is not ErrForbidden, when expected. got: %v", err)
+			}
+		})
+		t.Run("Unknown role", func(t *testing.T) {
+			_, err := userService.UpdateUserData(ctx, schemas.UserUpdate{Role: "root"}, member.ID, admin)
+			if err != ErrInvalidInput {
+				t.Errorf("Error is not ErrInvalidInput, when expected. got: %v", err)
+			}
+		})
+		t.Run("Admin", func(t *testing.T) {
+			user, err := userService.UpdateUserData(ctx, schemas.UserUpdate{Role: models.ManagerRole}, member.ID, admin)
+			if err != nil {
+				t.Fatalf("Error when update user data, when not expected. Error: %v", err)
+			}
+			if user.Role != models.ManagerRole {
+				t.Errorf("Role is %v, when expected %v", user.Role, models.ManagerRole)
+			}
+		})
+	})
 }
 
 func TestDeleteUser(t *testing.T) {
-	db := utils.ConnectToTestDatabase()
+	ctx := context.Background()
 	cfg := config.Config{
 		Db:  config.Db{},
 		App: config.App{ApiSecret: "secret"},
 	}
-	userRepository, err := repository.NewUserRepository(db)
-	if err != nil {
-		t.Errorf("Error when create new user repository, when not expected. Error: %v", err)
-	}
+	userRepository := repository.NewMemoryUserRepository()
 	userService := NewUserService(userRepository, &cfg)
 	t.Run("Non existing user", func(t *testing.T) {
-		err := userService.DeleteUser(1)
+		err := userService.DeleteUser(ctx, 1, admin)
 		if err == nil {
 			t.Errorf("Error is nil, when expected")
 		}
@@ -222,27 +294,31 @@ func TestDeleteUser(t *testing.T) {
 			Password: "password",
 			Role:     "role",
 		}
-		got, _ := userRepository.Save(&want)
-		err := userService.DeleteUser(got.ID)
+		got, _ := userRepository.Save(ctx, &want)
+		err := userService.DeleteUser(ctx, got.ID, admin)
 		if err != nil {
 			t.Errorf("Error when delete user, when not expected. Error: %v", err)
 		}
 	})
+	t.Run("Not an admin", func(t *testing.T) {
+		got, _ := userRepository.Save(ctx, &models.User{Name: "name", Email: "email", Password: "password", Role: models.UserRole})
+		err := userService.DeleteUser(ctx, got.ID, createUserSchema(&got))
+		if err != ErrForbidden {
+			t.Errorf("Error is not ErrForbidden, when expected. got: %v", err)
+		}
+	})
 }
 
 func TestDecodeToken(t *testing.T) {
-	db := utils.ConnectToTestDatabase()
+	ctx := context.Background()
 	cfg := config.Config{
 		Db:  config.Db{},
 		App: config.App{ApiSecret: "secret"},
 	}
-	userRepository, err := repository.NewUserRepository(db)
-	if err != nil {
-		t.Errorf("Error when create new user repository, when not expected. Error: %v", err)
-	}
+	userRepository := repository.NewMemoryUserRepository()
 	userService := NewUserService(userRepository, &cfg)
 	t.Run("Invalid token", func(t *testing.T) {
-		_, err := userService.DecodeToken("invalid")
+		_, err := userService.DecodeToken(ctx, "invalid")
 		if err == nil {
 			t.Errorf("Error is nil, when expected")
 		}
@@ -254,12 +330,12 @@ func TestDecodeToken(t *testing.T) {
 			Password: "password",
 			Role:     "role",
 		}
-		got, _ := userRepository.Save(&want)
+		got, _ := userRepository.Save(ctx, &want)
 		token, err := token.GenerateToken(got.ID, &cfg)
 		if err != nil {
 			t.Errorf("Error when generate token, when not expected. Error: %v", err)
 		}
-		_, err = userService.DecodeToken(token)
+		_, err = userService.DecodeToken(ctx, token)
 		if err != nil {
 			t.Errorf("Error when decode token, when not expected. Error: %v", err)
 		}
@@ -267,16 +343,16 @@ func TestDecodeToken(t *testing.T) {
 	t.Run("Invalid user id", func(t *testing.T) {
 		want := models.User{
 			Name:     "name",
-			Email:    "email",
+			Email:    "other@email",
 			Password: "password",
 			Role:     "role",
 		}
-		got, _ := userRepository.Save(&want)
+		got, _ := userRepository.Save(ctx, &want)
 		token, err := token.GenerateToken(got.ID+1, &cfg)
 		if err != nil {
 			t.Errorf("Error when generate token, when not expected. Error: %v", err)
 		}
-		_, err = userService.DecodeToken(token)
+		_, err = userService.DecodeToken(ctx, token)
 		if err == nil {
 			t.Errorf("Error is nil, when expected")
 		}
@@ -284,6 +360,26 @@ func TestDecodeToken(t *testing.T) {
 			t.Errorf("Error is not ErrNotFound, when expected")
 		}
 	})
+	t.Run("Missing user id", func(t *testing.T) {
+		token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{"authorized": true}).SignedString([]byte(cfg.App.ApiSecret))
+		if err != nil {
+			t.Errorf("Error when sign token, when not expected. Error: %v", err)
+		}
+		_, err = userService.DecodeToken(ctx, token)
+		if err != ErrInvalidToken {
+			t.Errorf("Error is not ErrInvalidToken, when expected. got: %v", err)
+		}
+	})
+	t.Run("Unsigned token", func(t *testing.T) {
+		token, err := jwt.NewWithClaims(jwt.SigningMethodNone, jwt.MapClaims{"user_id": 1}).SignedString(jwt.UnsafeAllowNoneSignatureType)
+		if err != nil {
+			t.Errorf("Error when sign token, when not expected. Error: %v", err)
+		}
+		_, err = userService.DecodeToken(ctx, token)
+		if err == nil {
+			t.Errorf("Error is nil, when expected")
+		}
+	})
 }
 
 func compareUser(t *testing.T, got schemas.User, want models.User) {
@@ -0,0 +1,345 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/HermanPlay/web-app-backend/package/domain/models"
+	"github.com/HermanPlay/web-app-backend/package/domain/schemas"
+	"github.com/HermanPlay/web-app-backend/package/repository"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+type WebhookService interface {
+	GetWebhooks(ctx context.Context) ([]schemas.Webhook, error)
+	GetWebhook(ctx context.Context, id int) (*schemas.Webhook, error)
+	CreateWebhook(ctx context.Context, input schemas.WebhookInput, createdBy int) (*schemas.Webhook, error)
+	UpdateWebhook(ctx context.Context, input schemas.WebhookUpdate, id int) (*schemas.Webhook, error)
+	DeleteWebhook(ctx context.Context, id int) error
+	GetDeliveries(ctx context.Context, id int, filter schemas.DeliveryFilter) ([]schemas.WebhookDelivery, *schemas.Pagination, error)
+}
+
+const (
+	webhookTimeout      = 10 * time.Second
+	webhookResponseSize = 1024 // bytes of the response body kept in the delivery history
+)
+
+type WebhookServiceImpl struct {
+	webhookRepository repository.WebhookRepository
+}
+
+func (w WebhookServiceImpl) GetWebhooks(ctx context.Context) ([]schemas.Webhook, error) {
+	ctx, span := tracer.Start(ctx, "WebhookService.GetWebhooks")
+	defer span.End()
+	webhooks, err := w.webhookRepository.GetAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+	response := []schemas.Webhook{}
+	for _, webhook := range webhooks {
+		response = append(response, *createWebhookSchema(&webhook))
+	}
+	return response, nil
+}
+
+func (w WebhookServiceImpl) GetWebhook(ctx context.Context, id int) (*schemas.Webhook, error) {
+	ctx, span := tracer.Start(ctx, "WebhookService.GetWebhook")
+	defer span.End()
+	webhook, err := w.webhookRepository.GetByID(ctx, id)
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	return createWebhookSchema(&webhook), nil
+}
+
+// CreateWebhook registers an endpoint. The signing secret is generated unless
+// given and is only ever returned here.
+func (w WebhookServiceImpl) CreateWebhook(ctx context.Context, input schemas.WebhookInput, createdBy int) (*schemas.Webhook, error) {
+	ctx, span := tracer.Start(ctx, "WebhookService.CreateWebhook")
+	defer span.End()
+	err := validateWebhook(input.URL, input.Events)
+	if err != nil {
+		return nil, err
+	}
+	secret := input.Secret
+	if secret == "" {
+		secret, err = randomToken()
+		if err != nil {
+			return nil, err
+		}
+	}
+	webhook, err := w.webhookRepository.Save(ctx, &models.Webhook{
+		URL:       input.URL,
+		Secret:    secret,
+		Events:    joinWebhookEvents(input.Events),
+		Active:    true,
+		CreatedBy: createdBy,
+	})
+	if err != nil {
+		return nil, err
+	}
+	response := createWebhookSchema(&webhook)
+	response.Secret = webhook.Secret
+	return response, nil
+}
+
+func (w WebhookServiceImpl) UpdateWebhook(ctx context.Context, input schemas.WebhookUpdate, id int) (*schemas.Webhook, error) {
+	ctx, span := tracer.Start(ctx, "WebhookService.UpdateWebhook")
+	defer span.End()
+	webhook, err := w.webhookRepository.GetByID(ctx, id)
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	if input.URL != "" {
+		webhook.URL = input.URL
+	}
+	if input.Events != nil {
+		webhook.Events = joinWebhookEvents(input.Events)
+	}
+	if input.Active != nil {
+		webhook.Active = *input.Active
+	}
+	err = validateWebhook(webhook.URL, webhook.EventList())
+	if err != nil {
+		return nil, err
+	}
+	webhook, err = w.webhookRepository.Update(ctx, &webhook)
+	if err != nil {
+		return nil, err
+	}
+	return createWebhookSchema(&webhook), nil
+}
+
+func (w WebhookServiceImpl) DeleteWebhook(ctx context.Context, id int) error {
+	ctx, span := tracer.Start(ctx, "WebhookService.DeleteWebhook")
+	defer span.End()
+	err := w.webhookRepository.Delete(ctx, id)
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return ErrNotFound
+		}
+		return err
+	}
+	return nil
+}
+
+func (w WebhookServiceImpl) GetDeliveries(ctx context.Context, id int, filter schemas.DeliveryFilter) ([]schemas.WebhookDelivery, *schemas.Pagination, error) {
+	ctx, span := tracer.Start(ctx, "WebhookService.GetDeliveries")
+	defer span.End()
+	if filter.Page == 0 {
+		filter.Page = 1
+	}
+	if filter.PageSize == 0 {
+		filter.PageSize = defaultPageSize
+	}
+	if filter.Page < 0 || filter.PageSize < 0 || filter.PageSize > maxPageSize {
+		return nil, nil, ErrInvalidInput
+	}
+	_, err := w.webhookRepository.GetByID(ctx, id)
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, nil, ErrNotFound
+		}
+		return nil, nil, err
+	}
+
+	deliveries, total, err := w.webhookRepository.GetDeliveries(ctx, id, filter.PageSize, (filter.Page-1)*filter.PageSize)
+	if err != nil {
+		return nil, nil, err
+	}
+	response := []schemas.WebhookDelivery{}
+	for _, delivery := range deliveries {
+		response = append(response, schemas.WebhookDelivery{
+			ID:         delivery.ID,
+			DeliveryID: delivery.DeliveryID,
+			Event:      delivery.Event,
+			Payload:    json.RawMessage(delivery.Payload),
+			StatusCode: delivery.StatusCode,
+			Response:   delivery.Response,
+			Error:      delivery.Error,
+			Duration:   delivery.Duration,
+			CreatedAt:  delivery.CreatedAt,
+		})
+	}
+	pagination := &schemas.Pagination{
+		Page:       filter.Page,
+		PageSize:   filter.PageSize,
+		Total:      total,
+		TotalPages: int((total + int64(filter.PageSize) - 1) / int64(filter.PageSize)),
+	}
+	return response, pagination, nil
+}
+
+func validateWebhook(rawURL string, events []models.WebhookEvent) error {
+	target, err := url.Parse(rawURL)
+	if err != nil || (target.Scheme != "http" && target.Scheme != "https") || target.Host == "" {
+		return ErrInvalidInput
+	}
+	if len(events) == 0 {
+		return ErrInvalidInput
+	}
+	for _, event := range events {
+		if !event.IsValid() {
+			return ErrInvalidInput
+		}
+	}
+	return nil
+}
+
+func joinWebhookEvents(events []models.WebhookEvent) string {
+	names := make([]string, len(events))
+	for i, event := range events {
+		names[i] = string(event)
+	}
+	return strings.Join(names, ",")
+}
+
+func createWebhookSchema(webhook *models.Webhook) *schemas.Webhook {
+	return &schemas.Webhook{
+		ID:     webhook.ID,
+		URL:    webhook.URL,
+		Events: webhook.EventList(),
+		Active: webhook.Active,
+	}
+}
+
+// webhookJob is the payload of a WebhookJob. Body is built when the job is
+// queued, so every retry sends the same bytes.
+type webhookJob struct {
+	WebhookID  int                 `json:"webhook_id"`
+	DeliveryID string              `json:"delivery_id"`
+	Event      models.WebhookEvent `json:"event"`
+	Body       json.RawMessage     `json:"body"`
+}
+
+// enqueueWebhooks queues a delivery of data to every webhook subscribed to
+// event. Pass repositories bound to the transaction of the write.
+func enqueueWebhooks(ctx context.Context, webhooks repository.WebhookRepository, jobs repository.JobRepository, event models.WebhookEvent, data any) error {
+	subscribed, err := webhooks.GetSubscribed(ctx, event)
+	if err != nil {
+		return err
+	}
+	for _, webhook := range subscribed {
+		deliveryID, err := randomToken()
+		if err != nil {
+			return err
+		}
+		body, err := json.Marshal(schemas.WebhookPayload{
+			ID:        deliveryID,
+			Event:     event,
+			CreatedAt: time.Now().UTC(),
+			Data:      data,
+		})
+		if err != nil {
+			return err
+		}
+		err = enqueueJob(ctx, jobs, WebhookJob, webhookJob{
+			WebhookID:  webhook.ID,
+			DeliveryID: deliveryID,
+			Event:      event,
+			Body:       body,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// signWebhook computes the X-Webhook-Signature header. Receivers recompute
+// the HMAC-SHA256 of "<t>.<body>" with their secret and compare it to v1; the
+// timestamp lets them reject old replays.
+func signWebhook(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	fmt.Fprintf(mac, "%d.", timestamp)
+	mac.Write(body)
+	return fmt.Sprintf("t=%d,v1=%s", timestamp, hex.EncodeToString(mac.Sum(nil)))
+}
+
+// NewWebhookHandler delivers the jobs queued by enqueueWebhooks and records
+// every attempt. A failed delivery is retried by the job queue.
+func NewWebhookHandler(webhookRepository repository.WebhookRepository, client *http.Client) JobHandler {
+	return func(ctx context.Context, payload []byte) error {
+		var job webhookJob
+		err := json.Unmarshal(payload, &job)
+		if err != nil {
+			return err
+		}
+		webhook, err := webhookRepository.GetByID(ctx, job.WebhookID)
+		if err != nil {
+			if err == gorm.ErrRecordNotFound {
+				// Deleted since the event happened
+				return nil
+			}
+			return err
+		}
+		if !webhook.Active {
+			return nil
+		}
+
+		start := time.Now()
+		request, err := http.NewRequestWithContext(ctx, http.MethodPost, webhook.URL, bytes.NewReader(job.Body))
+		if err != nil {
+			return err
+		}
+		request.Header.Set("Content-Type", "application/json")
+		request.Header.Set("User-Agent", "event-manager-webhooks")
+		request.Header.Set("X-Webhook-Event", string(job.Event))
+		request.Header.Set("X-Webhook-Delivery", job.DeliveryID)
+		request.Header.Set("X-Webhook-Signature", signWebhook(webhook.Secret, start.Unix(), job.Body))
+
+		delivery := models.WebhookDelivery{
+			WebhookID:  webhook.ID,
+			DeliveryID: job.DeliveryID,
+			Event:      job.Event,
+			Payload:    string(job.Body),
+		}
+		response, err := client.Do(request)
+		if err == nil {
+			body, _ := io.ReadAll(io.LimitReader(response.Body, webhookResponseSize))
+			response.Body.Close()
+			delivery.StatusCode = response.StatusCode
+			delivery.Response = string(body)
+			if response.StatusCode < 200 || response.StatusCode > 299 {
+				err = fmt.Errorf("webhook responded with status %d", response.StatusCode)
+			}
+		}
+		if err != nil {
+			delivery.Error = err.Error()
+		}
+		delivery.Duration = time.Since(start).Milliseconds()
+		saveErr := webhookRepository.SaveDelivery(ctx, &delivery)
+		if saveErr != nil {
+			logrus.Errorf("Could not record delivery %s of webhook %d. Error: %v", job.DeliveryID, webhook.ID, saveErr)
+		}
+		return err
+	}
+}
+
+// NewWebhookClient returns the HTTP client webhooks are delivered with.
+func NewWebhookClient() *http.Client {
+	return &http.Client{Timeout: webhookTimeout}
+}
+
+func NewWebhookService(webhookRepository repository.WebhookRepository) WebhookService {
+	return &WebhookServiceImpl{
+		webhookRepository: webhookRepository,
+	}
+}
@@ -0,0 +1,229 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/HermanPlay/web-app-backend/internal/config"
+	"github.com/HermanPlay/web-app-backend/package/domain/models"
+	"github.com/HermanPlay/web-app-backend/package/domain/schemas"
+	"github.com/HermanPlay/web-app-backend/package/repository"
+	"github.com/HermanPlay/web-app-backend/package/utils"
+)
+
+func TestWebhookService(t *testing.T) {
+	ctx := context.Background()
+	db := utils.ConnectToTestDatabase()
+	webhookRepository, err := repository.NewWebhookRepository(db)
+	if err != nil {
+		t.Errorf("Error when create new webhook repository, when not expected. Error: %v", err)
+	}
+	webhookService := NewWebhookService(webhookRepository)
+
+	var created *schemas.Webhook
+	t.Run("Create webhook", func(t *testing.T) {
+		created, err = webhookService.CreateWebhook(ctx, schemas.WebhookInput{
+			URL:    "https://example.com/hook",
+			Events: []models.WebhookEvent{models.WebhookEventCreated},
+		}, 1)
+		if err != nil {
+			t.Fatalf("Error when create webhook, when not expected. Error: %v", err)
+		}
+		if created.Secret == "" || !created.Active {
+			t.Errorf("Created %+v, when expected an active webhook with a generated secret", created)
+		}
+		webhook, err := webhookService.GetWebhook(ctx, created.ID)
+		if err != nil {
+			t.Errorf("Error when get webhook, when not expected. Error: %v", err)
+		}
+		if webhook.Secret != "" {
+			t.Errorf("Secret is returned after creation, when not expected")
+		}
+	})
+	t.Run("Invalid webhook", func(t *testing.T) {
+		inputs := []schemas.WebhookInput{
+			{URL: "ftp://example.com", Events: []models.WebhookEvent{models.WebhookEventCreated}},
+			{URL: "https://", Events: []models.WebhookEvent{models.WebhookEventCreated}},
+			{URL: "https://example.com", Events: []models.WebhookEvent{}},
+			{URL: "https://example.com", Events: []models.WebhookEvent{"event.renamed"}},
+		}
+		for _, input := range inputs {
+			_, err := webhookService.CreateWebhook(ctx, input, 1)
+			if err != ErrInvalidInput {
+				t.Errorf("Error is not ErrInvalidInput for %+v, when expected. Error: %v", input, err)
+			}
+		}
+	})
+	t.Run("Update webhook", func(t *testing.T) {
+		active := false
+		webhook, err := webhookService.UpdateWebhook(ctx, schemas.WebhookUpdate{
+			Events: []models.WebhookEvent{models.WebhookEventCreated, models.WebhookBookingCreated},
+			Active: &active,
+		}, created.ID)
+		if err != nil {
+			t.Fatalf("Error when update webhook, when not expected. Error: %v", err)
+		}
+		if webhook.Active || len(webhook.Events) != 2 || webhook.URL != created.URL {
+			t.Errorf("Updated %+v, when expected an inactive webhook with two events", webhook)
+		}
+		_, err = webhookService.UpdateWebhook(ctx, schemas.WebhookUpdate{URL: "example.com"}, created.ID)
+		if err != ErrInvalidInput {
+			t.Errorf("Error is not ErrInvalidInput, when expected. Error: %v", err)
+		}
+	})
+	t.Run("Delete webhook", func(t *testing.T) {
+		err := webhookService.DeleteWebhook(ctx, created.ID)
+		if err != nil {
+			t.Errorf("Error when delete webhook, when not expected. Error: %v", err)
+		}
+		_, err = webhookService.GetWebhook(ctx, created.ID)
+		if err != ErrNotFound {
+			t.Errorf("Error is not ErrNotFound, when expected. Error: %v", err)
+		}
+		err = webhookService.DeleteWebhook(ctx, created.ID)
+		if err != ErrNotFound {
+			t.Errorf("Error is not ErrNotFound, when expected. Error: %v", err)
+		}
+	})
+}
+
+func TestWebhookDelivery(t *testing.T) {
+	ctx := context.Background()
+	db := utils.ConnectToTestDatabase()
+	eventRepository, err := repository.NewEventRepository(db)
+	if err != nil {
+		t.Errorf("Error when create new event repository, when not expected. Error: %v", err)
+	}
+	userRepository, err := repository.NewUserRepository(db)
+	if err != nil {
+		t.Errorf("Error when create new user repository, when not expected. Error: %v", err)
+	}
+	user, err := userRepository.Save(ctx, &models.User{Name: "name", Email: "email", Password: "password", Role: "user"})
+	if err != nil {
+		t.Errorf("Error when save user, when not expected. Error: %v", err)
+	}
+	outbox := newTestOutbox(t, db)
+	eventService := NewEventService(eventRepository, outbox.webhooks, outbox.jobs, outbox.transactor, &config.Config{})
+	webhookService := NewWebhookService(outbox.webhooks)
+
+	type received struct {
+		header http.Header
+		body   []byte
+	}
+	var requests []received
+	status := http.StatusOK
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		requests = append(requests, received{header: r.Header, body: body})
+		w.WriteHeader(status)
+	}))
+	defer server.Close()
+
+	webhook, err := webhookService.CreateWebhook(ctx, schemas.WebhookInput{
+		URL:    server.URL,
+		Events: []models.WebhookEvent{models.WebhookEventCreated, models.WebhookBookingCreated},
+		Secret: "secret",
+	}, user.ID)
+	if err != nil {
+		t.Fatalf("Error when create webhook, when not expected. Error: %v", err)
+	}
+
+	var event *schemas.Event
+	t.Run("Signed delivery", func(t *testing.T) {
+		event, err = eventService.CreateEvent(ctx, &schemas.EventInput{
+			Title:            "title",
+			ShortDescription: "short description",
+			Description:      "description",
+			Location:         "location",
+			StartsAt:         eventStart,
+			EndsAt:           eventEnd,
+			TimeZone:         "Europe/Warsaw",
+		}, user.ID)
+		if err != nil {
+			t.Fatalf("Error when create event, when not expected. Error: %v", err)
+		}
+		if len(requests) != 0 {
+			t.Errorf("Webhook was called before the job ran, when not expected")
+		}
+		outbox.Messages()
+		if len(requests) != 1 {
+			t.Fatalf("Webhook was called %d times, when expected once", len(requests))
+		}
+		request := requests[0]
+		if request.header.Get("X-Webhook-Event") != string(models.WebhookEventCreated) {
+			t.Errorf("Event header is %q, when expected %q", request.header.Get("X-Webhook-Event"), models.WebhookEventCreated)
+		}
+		signature := request.header.Get("X-Webhook-Signature")
+		timestamp, err := strconv.ParseInt(strings.TrimPrefix(strings.Split(signature, ",")[0], "t="), 10, 64)
+		if err != nil {
+			t.Fatalf("Signature %q has no timestamp. Error: %v", signature, err)
+		}
+		if signature != signWebhook("secret", timestamp, request.body) {
+			t.Errorf("Signature %q does not match the body, when expected", signature)
+		}
+		var payload struct {
+			ID    string              `json:"id"`
+			Event models.WebhookEvent `json:"event"`
+			Data  schemas.Event       `json:"data"`
+		}
+		err = json.Unmarshal(request.body, &payload)
+		if err != nil {
+			t.Fatalf("Error when decode payload, when not expected. Error: %v", err)
+		}
+		if payload.ID != request.header.Get("X-Webhook-Delivery") || payload.Data.ID != event.ID {
+			t.Errorf("Payload is %+v, when expected the created event", payload)
+		}
+	})
+	t.Run("Unsubscribed event", func(t *testing.T) {
+		sent := len(requests)
+		_, err := eventService.UpdateEvent(ctx, &schemas.EventUpdate{Title: "new title"}, event.ID, createUserSchema(&user))
+		if err != nil {
+			t.Fatalf("Error when update event, when not expected. Error: %v", err)
+		}
+		outbox.Messages()
+		if len(requests) != sent {
+			t.Errorf("Webhook was called for event.updated, when not subscribed")
+		}
+	})
+	t.Run("Failed delivery", func(t *testing.T) {
+		status = http.StatusInternalServerError
+		sent := len(requests)
+		_, err := eventService.BookEvent(ctx, event.ID, user.ID)
+		if err != nil {
+			t.Fatalf("Error when book event, when not expected. Error: %v", err)
+		}
+		outbox.Messages()
+		if len(requests) != sent+1 {
+			t.Fatalf("Webhook was called %d times, when expected once", len(requests)-sent)
+		}
+		jobs, _, err := outbox.jobService.GetJobs(ctx, schemas.JobFilter{Status: models.JobPending})
+		if err != nil {
+			t.Errorf("Error when get jobs, when not expected. Error: %v", err)
+		}
+		if len(jobs) != 1 || jobs[0].Kind != WebhookJob || jobs[0].Attempts != 1 {
+			t.Errorf("Pending jobs are %+v, when expected the webhook waiting for a retry", jobs)
+		}
+		deliveries, pagination, err := webhookService.GetDeliveries(ctx, webhook.ID, schemas.DeliveryFilter{})
+		if err != nil {
+			t.Fatalf("Error when get deliveries, when not expected. Error: %v", err)
+		}
+		if pagination.Total != 2 {
+			t.Errorf("Got %d deliveries, when expected 2", pagination.Total)
+		}
+		if deliveries[0].StatusCode != http.StatusInternalServerError || deliveries[0].Error == "" || deliveries[0].Event != models.WebhookBookingCreated {
+			t.Errorf("Latest delivery is %+v, when expected the failed booking.created", deliveries[0])
+		}
+	})
+	t.Run("Unknown webhook", func(t *testing.T) {
+		_, _, err := webhookService.GetDeliveries(ctx, 1000, schemas.DeliveryFilter{})
+		if err != ErrNotFound {
+			t.Errorf("Error is not ErrNotFound, when expected. Error: %v", err)
+		}
+	})
+}
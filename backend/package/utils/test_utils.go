@@ -1,10 +1,11 @@
 package utils
 
 import (
-	"fmt"
+	"os"
 
-	"github.com/HermanPlay/web-app-backend/package/domain/models"
-	"gorm.io/driver/postgres"
+	"github.com/HermanPlay/web-app-backend/internal/config"
+	"github.com/HermanPlay/web-app-backend/internal/database"
+	"github.com/HermanPlay/web-app-backend/internal/migration"
 	"gorm.io/gorm"
 )
 
@@ -16,26 +17,42 @@ const (
 	test_db_name     = "test_db"
 )
 
+// ConnectToTestDatabase returns an empty database with all migrations
+// applied. Tests run on an in-memory SQLite database unless the
+// test_db_driver env variable is set to "postgres".
 func ConnectToTestDatabase() *gorm.DB {
-	dsn := fmt.Sprintf(
-		"postgres://%s:%s@%s:%v/%s?sslmode=disable",
-		test_db_user,
-		test_db_password,
-		test_db_host,
-		test_db_port,
-		test_db_name,
-	)
-	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{})
+	cfg := &config.Config{Db: config.Db{Driver: "sqlite", Path: ":memory:"}}
+	if os.Getenv("test_db_driver") == "postgres" {
+		cfg.Db = config.Db{
+			Driver:   "postgres",
+			Host:     test_db_host,
+			Port:     test_db_port,
+			User:     test_db_user,
+			Password: test_db_password,
+			DBName:   test_db_name,
+		}
+	}
+	conn, err := database.New(cfg)
 	if err != nil {
 		panic("cannot open db connection")
 	}
+	db := conn.Connect()
 
-	db.Migrator().DropTable(&models.User{})
-	db.AutoMigrate(&models.User{})
-	db.Migrator().DropTable(&models.Event{})
-	db.AutoMigrate(&models.Event{})
-	db.Migrator().DropTable(&models.EventUser{})
-	db.AutoMigrate(&models.EventUser{})
+	tables, err := db.Migrator().GetTables()
+	if err != nil {
+		panic(err)
+	}
+	for _, table := range tables {
+		db.Migrator().DropTable(table)
+	}
+	migrator, err := migration.ForDatabase(db)
+	if err != nil {
+		panic(err)
+	}
+	_, err = migrator.Up()
+	if err != nil {
+		panic(err)
+	}
 
 	return db
 }